@@ -9,6 +9,21 @@ type (
 		DirectoryStructureMode            Mode
 		Mode                              Mode
 		ContentOperation                  ContentOperation
+		FollowSymlinks                    bool
+		TraversalOrder                    TraversalOrder
+		Concurrency                       int
+		ErrorPolicy                       ErrorPolicy
+		Recursive                         bool
+		Owner                             Owner
+	}
+
+	// Owner identifies user and group owning created file or directory. It's stored by backends able to keep
+	// ownership (e.g. tarfs) and ignored by others (including local filesystem).
+	Owner struct {
+		UID   int
+		GID   int
+		User  string
+		Group string
 	}
 )
 
@@ -18,6 +33,71 @@ func (a *Arguments) Apply(args []Argument) {
 	}
 }
 
+func createFileArguments(args []Argument) Arguments {
+	arg := &Arguments{
+		Mode:                              ModeAllReadWrite,
+		DirectoryStructureMode:            ModeAllReadWriteExecute,
+		AllowOverwrite:                    false,
+		AllowCreationOfDirectoryStructure: false,
+	}
+	arg.Apply(args)
+
+	return *arg
+}
+
+func writeContentArguments(args []Argument) Arguments {
+	arg := &Arguments{
+		ContentOperation: ContentOperationAppend,
+	}
+	arg.Apply(args)
+
+	return *arg
+}
+
+func createDirectoryArguments(args []Argument) Arguments {
+	arg := &Arguments{
+		Mode:                              ModeAllReadWrite,
+		DirectoryStructureMode:            ModeAllReadWriteExecute,
+		AllowCreationOfDirectoryStructure: false,
+	}
+	arg.Apply(args)
+
+	return *arg
+}
+
+func removeArguments(args []Argument) Arguments {
+	arg := &Arguments{
+		Recursive: false,
+	}
+	arg.Apply(args)
+
+	return *arg
+}
+
+func transferArguments(args []Argument) Arguments {
+	arg := &Arguments{
+		DirectoryStructureMode:            ModeAllReadWriteExecute,
+		AllowCreationOfDirectoryStructure: false,
+		AllowOverwrite:                    false,
+		Recursive:                         false,
+	}
+	arg.Apply(args)
+
+	return *arg
+}
+
+func walkArguments(args []Argument) Arguments {
+	arg := &Arguments{
+		FollowSymlinks: false,
+		TraversalOrder: TraversalOrderLexical,
+		Concurrency:    1,
+		ErrorPolicy:    ErrorPolicyAbort,
+	}
+	arg.Apply(args)
+
+	return *arg
+}
+
 func WithContentOperation(contentOperation ContentOperation) Argument {
 	return func(args *Arguments) {
 		args.ContentOperation = contentOperation
@@ -47,3 +127,39 @@ func WithMode(mode Mode) Argument {
 		args.Mode = mode
 	}
 }
+
+func WithOwner(owner Owner) Argument {
+	return func(args *Arguments) {
+		args.Owner = owner
+	}
+}
+
+func WithFollowSymlinks(follow bool) Argument {
+	return func(args *Arguments) {
+		args.FollowSymlinks = follow
+	}
+}
+
+func WithTraversalOrder(order TraversalOrder) Argument {
+	return func(args *Arguments) {
+		args.TraversalOrder = order
+	}
+}
+
+func WithConcurrency(concurrency int) Argument {
+	return func(args *Arguments) {
+		args.Concurrency = concurrency
+	}
+}
+
+func WithErrorPolicy(policy ErrorPolicy) Argument {
+	return func(args *Arguments) {
+		args.ErrorPolicy = policy
+	}
+}
+
+func WithRecursive(recursive bool) Argument {
+	return func(args *Arguments) {
+		args.Recursive = recursive
+	}
+}
@@ -0,0 +1,176 @@
+// Package boltfs provides filesystem.Filesystem storing the whole tree in a single local file with bbolt
+// (transactional key-value store).
+//
+// Directories are stored as buckets and files as values kept along with their mode and modification time.
+// Every operation is performed in its own transaction which is synced to disk before operation returns, so
+// the store is never left with partially applied change (e.g. half-written content or partially moved directory),
+// even when the process crashes or device loses power in the middle of it.
+//
+// WriteContentTo and StreamContentTo replace the whole value in a single transaction, so append and overwrite
+// are atomic. StreamContentTo reads the whole stream before the transaction starts (failing stream leaves file
+// untouched). Content of files is limited by bbolt to roughly 2GB and names of entries to 32KB.
+//
+// Store file is locked while it's open, so it can be used by a single process at a time (or by many processes
+// opening it with OptionReadOnly).
+package boltfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+	bolt "go.etcd.io/bbolt"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionBackendName options.OptionKey = `backend_name`
+	optionLockTimeout options.OptionKey = `bolt_lock_timeout`
+	optionReadOnly    options.OptionKey = `bolt_read_only`
+	optionFileMode    options.OptionKey = `bolt_file_mode`
+)
+
+const (
+	defaultBackendName = "bolt"
+	defaultLockTimeout = 5 * time.Second
+	defaultFileMode    = filesystem.ModeUserReadWrite
+)
+
+// Store is filesystem.Filesystem backed by bbolt file opened with Open. It has to be closed to release the file.
+// It's safe for concurrent use.
+type Store struct {
+	filesystem.Filesystem
+	db *bolt.DB
+}
+
+// OptionBackendName changes name of the backend reported in errors and to middlewares (default "bolt").
+func OptionBackendName(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBackendName, name)
+	}
+}
+
+// OptionLockTimeout limits time of waiting for the lock of the file held by another process (default 5s).
+// Zero waits indefinitely.
+func OptionLockTimeout(timeout time.Duration) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Duration](r, optionLockTimeout, timeout)
+	}
+}
+
+// OptionReadOnly opens existing file with shared lock (it can be opened by many processes at the same time).
+// Every mutating operation fails with filesystem.ErrReadOnly.
+func OptionReadOnly(readOnly bool) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[bool](r, optionReadOnly, readOnly)
+	}
+}
+
+// OptionFileMode sets mode of the file created by Open when it does not exist (default 0600).
+func OptionFileMode(mode filesystem.Mode) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[filesystem.Mode](r, optionFileMode, mode)
+	}
+}
+
+// Open opens (or creates) bbolt file and serves filesystem stored in it.
+func Open(name string, opts ...options.Option) (*Store, error) {
+	opt := options.Resolve(opts)
+
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("bolt filesystem initialization failed: %w", err)
+	}
+	optLockTimeout, err := options.ReadOrDefault[time.Duration](opt, optionLockTimeout, defaultLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("bolt filesystem initialization failed: %w", err)
+	}
+	optReadOnly, err := options.ReadOrDefault[bool](opt, optionReadOnly, false)
+	if err != nil {
+		return nil, fmt.Errorf("bolt filesystem initialization failed: %w", err)
+	}
+	optFileMode, err := options.ReadOrDefault[filesystem.Mode](opt, optionFileMode, defaultFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("bolt filesystem initialization failed: %w", err)
+	}
+
+	if optReadOnly {
+		// bbolt creates missing file even when it's opened read-only.
+		if _, err = os.Stat(name); err != nil {
+			return nil, fmt.Errorf("bolt filesystem initialization failed: %w", err)
+		}
+	}
+	db, err := bolt.Open(name, fs.FileMode(optFileMode), &bolt.Options{Timeout: optLockTimeout, ReadOnly: optReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("bolt filesystem initialization failed: %w", translateError(err))
+	}
+	if optReadOnly {
+		err = db.View(func(tx *bolt.Tx) error {
+			if tx.Bucket(rootBucket) == nil {
+				return fmt.Errorf("file does not contain filesystem: %w", bolt.ErrBucketNotFound)
+			}
+			return nil
+		})
+	} else {
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, cErr := tx.CreateBucketIfNotExists(rootBucket)
+			return cErr
+		})
+	}
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("bolt filesystem initialization failed: %w", err)
+	}
+
+	s := &store{db: db}
+	fsys, err := filesystem.New(
+		filesystem.OptionBackendName(optBackendName),
+		filesystem.OptionReadContentOfHandler(s.readContentOf),
+		filesystem.OptionStreamContentOfHandler(s.streamContentOf),
+		filesystem.OptionCheckIfExistsHandler(s.checkIfExists),
+		filesystem.OptionCreateFileHandler(s.createFile),
+		filesystem.OptionWriteContentToHandler(s.writeContentTo),
+		filesystem.OptionStreamContentToHandler(s.streamContentTo),
+		filesystem.OptionCreateDirectory(s.createDirectory),
+		filesystem.OptionRemoveHandler(s.remove),
+		filesystem.OptionMoveHandler(func(source, target string, arg filesystem.Arguments) error {
+			return s.transfer(source, target, arg, true)
+		}),
+		filesystem.OptionCopyHandler(func(source, target string, arg filesystem.Arguments) error {
+			return s.transfer(source, target, arg, false)
+		}),
+		filesystem.OptionListFilesInHandler(s.listFilesIn),
+	)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("bolt filesystem initialization failed: %w", err)
+	}
+	return &Store{Filesystem: fsys, db: db}, nil
+}
+
+// Close releases the file. Every operation performed afterwards fails with fs.ErrClosed.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// translateError wraps error reported by bbolt with matching sentinel error (keeping the original one
+// available for inspection with errors.Is).
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, bolt.ErrDatabaseNotOpen):
+		return fmt.Errorf("%w: %w", fs.ErrClosed, err)
+	case errors.Is(err, bolt.ErrDatabaseReadOnly), errors.Is(err, bolt.ErrTxNotWritable):
+		return fmt.Errorf("%w: %w", filesystem.ErrReadOnly, err)
+	case errors.Is(err, bolt.ErrValueTooLarge), errors.Is(err, bolt.ErrKeyTooLarge):
+		return fmt.Errorf("%w: %w", filesystem.ErrTooLarge, err)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%w: %w", filesystem.ErrPermissionDenied, err)
+	}
+	return err
+}
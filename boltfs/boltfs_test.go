@@ -0,0 +1,121 @@
+package boltfs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// openStore opens store in temporary directory closing it when test ends.
+func openStore(t *testing.T) *Store {
+	t.Helper()
+
+	s, err := Open(filepath.Join(t.TempDir(), "store.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("it should keep content after reopening the file", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		name := filepath.Join(t.TempDir(), "store.db")
+		s, err := Open(name, OptionFileMode(filesystem.ModeUserReadWrite))
+		require.NoError(t, err)
+		require.NoError(t, filesystem.CreateFile(s, "etc/app.yaml", filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.WriteContentTo(s, "etc/app.yaml", "key: value"))
+		require.NoError(t, s.Close())
+
+		// WHEN
+		reopened, err := Open(name)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = reopened.Close() })
+		content, err := filesystem.ReadContentOf(reopened, "etc/app.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("key: value"), content)
+		fi, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.Equal(t, fs.FileMode(0o600), fi.Mode().Perm())
+	})
+	t.Run("it should serve existing file read-only", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		name := filepath.Join(t.TempDir(), "store.db")
+		s, err := Open(name)
+		require.NoError(t, err)
+		require.NoError(t, filesystem.CreateFile(s, "test.txt"))
+		require.NoError(t, s.Close())
+
+		// WHEN
+		first, err := Open(name, OptionReadOnly(true), OptionBackendName("edge"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = first.Close() })
+		second, err := Open(name, OptionReadOnly(true))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = second.Close() })
+
+		// THEN
+		exists, err := filesystem.CheckIfExists(second, "test.txt")
+		require.NoError(t, err)
+		assert.True(t, exists)
+		errReadOnly := filesystem.WriteContentTo(first, "test.txt", "TEST")
+		require.ErrorIs(t, errReadOnly, filesystem.ErrReadOnly)
+		var pathErr *filesystem.PathError
+		require.ErrorAs(t, errReadOnly, &pathErr)
+		assert.Equal(t, "edge", pathErr.Backend)
+	})
+	t.Run("it should fail when file is locked or invalid", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		dir := t.TempDir()
+		locked := filepath.Join(dir, "locked.db")
+		s, err := Open(locked)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = s.Close() })
+		invalid := filepath.Join(dir, "invalid.db")
+		require.NoError(t, os.WriteFile(invalid, make([]byte, 1<<16), 0o600))
+		empty := filepath.Join(dir, "empty.db")
+		e, err := Open(empty)
+		require.NoError(t, err)
+		require.NoError(t, e.Close())
+
+		// WHEN
+		_, errLocked := Open(locked, OptionLockTimeout(50*time.Millisecond))
+		_, errInvalid := Open(invalid)
+		_, errMissing := Open(filepath.Join(dir, "missing.db"), OptionReadOnly(true))
+
+		// THEN
+		require.Error(t, errLocked)
+		require.Error(t, errInvalid)
+		require.ErrorIs(t, errMissing, os.ErrNotExist)
+	})
+	t.Run("it should fail every operation once closed", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		s := openStore(t)
+		require.NoError(t, s.Close())
+
+		// WHEN
+		_, errRead := filesystem.ReadContentOf(s, "test.txt")
+		errCreate := filesystem.CreateFile(s, "test.txt")
+
+		// THEN
+		require.ErrorIs(t, errRead, fs.ErrClosed)
+		require.ErrorIs(t, errCreate, fs.ErrClosed)
+	})
+}
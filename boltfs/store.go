@@ -0,0 +1,485 @@
+package boltfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// recordVersion is stored in front of every value, so layout of records can be changed without breaking
+// existing files.
+const recordVersion byte = 1
+
+// recordHeaderSize is length of version, mode and modification time stored in front of content.
+const recordHeaderSize = 1 + 4 + 8
+
+var (
+	// rootBucket contains the whole tree (bbolt keeps only buckets at the top level).
+	rootBucket = []byte("filesystem")
+	// metaKey holds record (without content) of directory inside its bucket. It sorts before any name.
+	metaKey = []byte{0}
+
+	errMalformedRecord = errors.New("malformed record")
+)
+
+type (
+	store struct {
+		db *bolt.DB
+	}
+
+	// record is decoded value of file (or metadata of directory).
+	record struct {
+		mode    filesystem.Mode
+		modTime time.Time
+		// content refers to memory of the transaction and cannot be used once it's finished.
+		content []byte
+	}
+
+	// node is entry found under name. Both bucket and value are nil when it does not exist.
+	node struct {
+		parent *bolt.Bucket
+		key    []byte
+		bucket *bolt.Bucket
+		value  []byte
+	}
+)
+
+func (s *store) readContentOf(p string) (filesystem.Content, error) {
+	var res filesystem.Content
+	err := s.view(func(tx *bolt.Tx) error {
+		r, err := file(tx, entryName(p))
+		if err != nil {
+			return err
+		}
+		res = bytes.Clone(r.content)
+		return nil
+	})
+	return res, err
+}
+
+func (s *store) streamContentOf(p string) (io.ReadCloser, error) {
+	content, err := s.readContentOf(p)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *store) checkIfExists(p string) (bool, error) {
+	var exists bool
+	err := s.view(func(tx *bolt.Tx) error {
+		n, err := lookup(tx, entryName(p))
+		if errors.Is(err, filesystem.ErrFileNotFound) {
+			return nil
+		}
+		exists = n.exists()
+		return err
+	})
+	return exists, err
+}
+
+func (s *store) createFile(p string, arg filesystem.Arguments) error {
+	return s.update(func(tx *bolt.Tx) error {
+		name := entryName(p)
+		if name == "" {
+			if !arg.AllowOverwrite {
+				return filesystem.ErrFileFound
+			}
+			return filesystem.ErrDirectory
+		}
+		dir, key, err := prepareParent(tx, name, arg)
+		if err != nil {
+			return err
+		}
+
+		if dir.Bucket(key) != nil || dir.Get(key) != nil {
+			if !arg.AllowOverwrite {
+				return filesystem.ErrFileFound
+			}
+			if dir.Bucket(key) != nil {
+				return filesystem.ErrDirectory
+			}
+		}
+		return dir.Put(key, encodeRecord(arg.Mode, time.Now()))
+	})
+}
+
+func (s *store) writeContentTo(p string, content []byte, arg filesystem.Arguments) error {
+	overwrite, err := isOverwrite(arg)
+	if err != nil {
+		return err
+	}
+
+	return s.update(func(tx *bolt.Tx) error {
+		n, err := lookup(tx, entryName(p))
+		if err != nil {
+			return err
+		}
+		if n.bucket != nil {
+			return filesystem.ErrDirectory
+		}
+		if n.value == nil {
+			return filesystem.ErrFileNotFound
+		}
+		r, err := decodeRecord(n.value)
+		if err != nil {
+			return err
+		}
+
+		if overwrite {
+			return n.parent.Put(n.key, encodeRecord(r.mode, time.Now(), content))
+		}
+		return n.parent.Put(n.key, encodeRecord(r.mode, time.Now(), r.content, content))
+	})
+}
+
+// streamContentTo reads the whole stream before writing it, so content of the file is never left
+// partially written.
+func (s *store) streamContentTo(p string, content io.Reader, arg filesystem.Arguments) error {
+	if _, err := isOverwrite(arg); err != nil {
+		return err
+	}
+
+	buf, err := io.ReadAll(content)
+	if err != nil {
+		return fmt.Errorf("cannot read content: %w", err)
+	}
+	return s.writeContentTo(p, buf, arg)
+}
+
+func (s *store) createDirectory(p string, arg filesystem.Arguments) error {
+	return s.update(func(tx *bolt.Tx) error {
+		name := entryName(p)
+		if name == "" {
+			return filesystem.ErrDirectoryFound
+		}
+		dir, key, err := prepareParent(tx, name, arg)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case dir.Bucket(key) != nil:
+			return filesystem.ErrDirectoryFound
+		case dir.Get(key) != nil:
+			return filesystem.ErrFile
+		}
+		return createBucket(dir, key, arg.Mode)
+	})
+}
+
+func (s *store) remove(p string, arg filesystem.Arguments) error {
+	return s.update(func(tx *bolt.Tx) error {
+		name := entryName(p)
+		n, err := lookup(tx, name)
+		if err != nil {
+			return err
+		}
+		if !n.exists() {
+			return filesystem.ErrFileNotFound
+		}
+		if name == "" {
+			return filesystem.ErrPermissionDenied
+		}
+
+		if n.bucket == nil {
+			return n.parent.Delete(n.key)
+		}
+		if !arg.Recursive && !isEmpty(n.bucket) {
+			return filesystem.ErrNotEmpty
+		}
+		return n.parent.DeleteBucket(n.key)
+	})
+}
+
+// transfer copies source (with its content if recursive) to target and removes source when moving.
+func (s *store) transfer(source, target string, arg filesystem.Arguments, move bool) error {
+	return s.update(func(tx *bolt.Tx) error {
+		src, dst := entryName(source), entryName(target)
+		sn, err := lookup(tx, src)
+		if err != nil {
+			return err
+		}
+		if !sn.exists() {
+			return filesystem.ErrFileNotFound
+		}
+		if src == "" || dst == "" {
+			return filesystem.ErrPermissionDenied
+		}
+		if sn.bucket != nil && !move && !arg.Recursive {
+			return filesystem.ErrDirectory
+		}
+		if dst == src || strings.HasPrefix(dst, src+"/") {
+			return fmt.Errorf("cannot transfer %s into itself: %w", source, filesystem.ErrUnresolvableDirectoryStructure)
+		}
+
+		tn, err := lookup(tx, dst)
+		if err != nil && !errors.Is(err, filesystem.ErrFileNotFound) {
+			return err
+		}
+		if tn.exists() {
+			if !arg.AllowOverwrite {
+				if tn.bucket != nil {
+					return filesystem.ErrDirectoryFound
+				}
+				return filesystem.ErrFileFound
+			}
+			if strings.HasPrefix(src, dst+"/") {
+				return fmt.Errorf("cannot replace %s with its own content: %w", target, filesystem.ErrUnresolvableDirectoryStructure)
+			}
+			if err = deleteNode(tn); err != nil {
+				return err
+			}
+		}
+		dir, key, err := prepareParent(tx, dst, arg)
+		if err != nil {
+			return err
+		}
+
+		// Source is looked up again as handles found before modifications of the tree may be outdated.
+		if sn, err = lookup(tx, src); err != nil {
+			return err
+		}
+		if sn.bucket != nil {
+			err = copyBucket(sn.bucket, dir, key)
+		} else {
+			err = dir.Put(key, bytes.Clone(sn.value))
+		}
+		if err != nil || !move {
+			return err
+		}
+		return deleteNode(sn)
+	})
+}
+
+func (s *store) listFilesIn(p string) ([]filesystem.Entry, error) {
+	var res []filesystem.Entry
+	err := s.view(func(tx *bolt.Tx) error {
+		name := entryName(p)
+		n, err := lookup(tx, name)
+		if err != nil {
+			return err
+		}
+		if !n.exists() {
+			return filesystem.ErrFileNotFound
+		}
+		if n.bucket == nil {
+			return filesystem.ErrFile
+		}
+
+		res = make([]filesystem.Entry, 0)
+		c := n.bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if bytes.Equal(k, metaKey) {
+				continue
+			}
+			isDirectory := v == nil
+			if isDirectory {
+				v = n.bucket.Bucket(k).Get(metaKey)
+			}
+			r, dErr := decodeRecord(v)
+			if dErr != nil {
+				return fmt.Errorf("cannot read %s: %w", path.Join(name, string(k)), dErr)
+			}
+			res = append(res, filesystem.Entry{
+				Name:        string(k),
+				Size:        int64(len(r.content)),
+				Mode:        r.mode,
+				ModTime:     r.modTime,
+				IsDirectory: isDirectory,
+			})
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (s *store) view(fn func(tx *bolt.Tx) error) error {
+	return translateError(s.db.View(fn))
+}
+
+// update performs fn in writable transaction which is committed (and synced to disk) only when fn succeeds.
+func (s *store) update(fn func(tx *bolt.Tx) error) error {
+	return translateError(s.db.Update(fn))
+}
+
+func (n node) exists() bool {
+	return n.bucket != nil || n.value != nil
+}
+
+// lookup finds entry stored under name (root bucket for empty one). It fails with filesystem.ErrFileNotFound
+// when one of parent directories does not exist.
+func lookup(tx *bolt.Tx, name string) (node, error) {
+	dir := tx.Bucket(rootBucket)
+	if name == "" {
+		return node{bucket: dir}, nil
+	}
+
+	elements, err := split(name)
+	if err != nil {
+		return node{}, err
+	}
+	for _, element := range elements[:len(elements)-1] {
+		if dir = dir.Bucket([]byte(element)); dir == nil {
+			return node{}, filesystem.ErrFileNotFound
+		}
+	}
+	key := []byte(elements[len(elements)-1])
+	return node{parent: dir, key: key, bucket: dir.Bucket(key), value: dir.Get(key)}, nil
+}
+
+// file returns decoded record of file stored under name.
+func file(tx *bolt.Tx, name string) (record, error) {
+	n, err := lookup(tx, name)
+	if err != nil {
+		return record{}, err
+	}
+	if n.bucket != nil {
+		return record{}, filesystem.ErrDirectory
+	}
+	if n.value == nil {
+		return record{}, filesystem.ErrFileNotFound
+	}
+	return decodeRecord(n.value)
+}
+
+// prepareParent verifies if parent directory of entry exists and creates missing ones (if allowed).
+// It returns bucket of the parent directory and key of the entry.
+func prepareParent(tx *bolt.Tx, name string, arg filesystem.Arguments) (*bolt.Bucket, []byte, error) {
+	elements, err := split(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dir := tx.Bucket(rootBucket)
+	for _, element := range elements[:len(elements)-1] {
+		key := []byte(element)
+		if b := dir.Bucket(key); b != nil {
+			dir = b
+			continue
+		}
+		if dir.Get(key) != nil {
+			return nil, nil, fmt.Errorf("location structure does not contain valid directory as target: %w", filesystem.ErrUnresolvableDirectoryStructure)
+		}
+		if !arg.AllowCreationOfDirectoryStructure {
+			return nil, nil, fmt.Errorf("creation of non-existing directory structure is forbidden by current settings: %w", filesystem.ErrUnresolvableDirectoryStructure)
+		}
+		if err = createBucket(dir, key, arg.DirectoryStructureMode); err != nil {
+			return nil, nil, fmt.Errorf("cannot create directory structure: %w", err)
+		}
+		dir = dir.Bucket(key)
+	}
+	return dir, []byte(elements[len(elements)-1]), nil
+}
+
+func createBucket(dir *bolt.Bucket, key []byte, mode filesystem.Mode) error {
+	b, err := dir.CreateBucket(key)
+	if err != nil {
+		return err
+	}
+	return b.Put(metaKey, encodeRecord(mode, time.Now()))
+}
+
+// copyBucket copies directory with its content (keeping modes and modification times) as key of dir.
+func copyBucket(src, dir *bolt.Bucket, key []byte) error {
+	dst, err := dir.CreateBucket(key)
+	if err != nil {
+		return err
+	}
+
+	c := src.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v == nil {
+			err = copyBucket(src.Bucket(k), dst, bytes.Clone(k))
+		} else {
+			err = dst.Put(bytes.Clone(k), bytes.Clone(v))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteNode(n node) error {
+	if n.bucket != nil {
+		return n.parent.DeleteBucket(n.key)
+	}
+	return n.parent.Delete(n.key)
+}
+
+// isEmpty checks if directory contains anything besides its metadata.
+func isEmpty(b *bolt.Bucket) bool {
+	c := b.Cursor()
+	k, _ := c.First()
+	if bytes.Equal(k, metaKey) {
+		k, _ = c.Next()
+	}
+	return k == nil
+}
+
+// isOverwrite verifies if content operation is supported and reports whether content should be replaced.
+func isOverwrite(arg filesystem.Arguments) (bool, error) {
+	switch {
+	case arg.ContentOperation.Is(filesystem.ContentOperationOverwrite):
+		return true, nil
+	case arg.ContentOperation.Is(filesystem.ContentOperationAppend):
+		return false, nil
+	}
+	return false, filesystem.ErrUnsupportedContentOperation
+}
+
+// encodeRecord builds value of the record with content being concatenation of provided parts.
+func encodeRecord(mode filesystem.Mode, modTime time.Time, content ...[]byte) []byte {
+	size := recordHeaderSize
+	for _, part := range content {
+		size += len(part)
+	}
+
+	buf := make([]byte, recordHeaderSize, size)
+	buf[0] = recordVersion
+	binary.BigEndian.PutUint32(buf[1:5], uint32(mode))
+	binary.BigEndian.PutUint64(buf[5:13], uint64(modTime.UnixNano()))
+	for _, part := range content {
+		buf = append(buf, part...)
+	}
+	return buf
+}
+
+func decodeRecord(v []byte) (record, error) {
+	if len(v) < recordHeaderSize || v[0] != recordVersion {
+		return record{}, errMalformedRecord
+	}
+	return record{
+		mode:    filesystem.Mode(binary.BigEndian.Uint32(v[1:5])),
+		modTime: time.Unix(0, int64(binary.BigEndian.Uint64(v[5:13]))),
+		content: v[recordHeaderSize:],
+	}, nil
+}
+
+// split returns elements of the name rejecting ones which cannot be stored.
+func split(name string) ([]string, error) {
+	elements := strings.Split(name, "/")
+	for _, element := range elements {
+		if element == string(metaKey) {
+			return nil, fmt.Errorf("%w: name %q is reserved", fs.ErrInvalid, element)
+		}
+	}
+	return elements, nil
+}
+
+// entryName converts path to name of the entry (slash separated, unrooted, without "." and "..", empty for root).
+func entryName(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
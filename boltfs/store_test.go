@@ -0,0 +1,174 @@
+package boltfs
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// failingReader returns part of the content and then fails.
+type failingReader struct {
+	content string
+	read    bool
+}
+
+var errStream = errors.New("stream interrupted")
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, errStream
+	}
+	r.read = true
+	return copy(p, r.content), nil
+}
+
+func TestStore(t *testing.T) {
+	t.Run("it should create, write and read files", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		s := openStore(t)
+
+		// WHEN
+		require.NoError(t, filesystem.CreateDirectory(s, "etc", filesystem.WithMode(filesystem.ModeUserReadWriteExecute)))
+		require.NoError(t, filesystem.CreateFile(s, "etc/app.yaml", filesystem.WithMode(filesystem.ModeUserReadWrite)))
+		require.NoError(t, filesystem.WriteContentTo(s, "etc/app.yaml", "key: "))
+		require.NoError(t, filesystem.StreamContentTo(s, "etc/app.yaml", strings.NewReader("value")))
+		require.NoError(t, filesystem.CreateFile(s, "var/log/app.log", filesystem.WithAllowCreationOfDirectoryStructure(true),
+			filesystem.WithDirectoryStructureMode(filesystem.ModeUserReadWriteExecute)))
+		require.NoError(t, filesystem.WriteContentTo(s, "var/log/app.log", "OLD"))
+		require.NoError(t, filesystem.WriteContentTo(s, "var/log/app.log", "NEW", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite)))
+
+		// THEN
+		content, err := filesystem.ReadContentOf(s, "/etc/app.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("key: value"), content)
+		stream, err := filesystem.StreamContentOf(s, "var/log/app.log")
+		require.NoError(t, err)
+		streamed, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, "NEW", string(streamed))
+
+		entries, err := filesystem.ListFilesIn(s, "/")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "etc", entries[0].Name)
+		assert.True(t, entries[0].IsDirectory)
+		assert.Equal(t, filesystem.ModeUserReadWriteExecute, entries[0].Mode)
+		assert.Equal(t, "var", entries[1].Name)
+		files, err := filesystem.ListFilesIn(s, "etc")
+		require.NoError(t, err)
+		assert.Equal(t, []filesystem.Entry{{Name: "app.yaml", Size: 10, Mode: filesystem.ModeUserReadWrite, ModTime: files[0].ModTime}}, files)
+		assert.False(t, files[0].ModTime.IsZero())
+	})
+	t.Run("it should remove, move and copy entries", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		s := openStore(t)
+		require.NoError(t, filesystem.CreateFile(s, "a/b/test.txt", filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.WriteContentTo(s, "a/b/test.txt", "TEST"))
+		require.NoError(t, filesystem.CreateFile(s, "old.txt"))
+
+		// WHEN
+		require.NoError(t, filesystem.Copy(s, "a", "c", filesystem.WithRecursive(true)))
+		require.NoError(t, filesystem.Move(s, "a/b", "d/e", filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.Copy(s, "c/b/test.txt", "old.txt", filesystem.WithAllowOverwrite(true)))
+		require.NoError(t, filesystem.Remove(s, "c", filesystem.WithRecursive(true)))
+		require.NoError(t, filesystem.Remove(s, "a"))
+
+		// THEN
+		for p, expected := range map[string]bool{"a": false, "c": false, "d/e/test.txt": true, "old.txt": true} {
+			exists, err := filesystem.CheckIfExists(s, p)
+			require.NoError(t, err, p)
+			assert.Equal(t, expected, exists, p)
+		}
+		for _, p := range []string{"d/e/test.txt", "old.txt"} {
+			content, err := filesystem.ReadContentOf(s, p)
+			require.NoError(t, err, p)
+			assert.Equal(t, filesystem.Content("TEST"), content, p)
+		}
+	})
+	t.Run("it should leave content untouched when operation fails", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		s := openStore(t)
+		require.NoError(t, filesystem.CreateFile(s, "test.txt"))
+		require.NoError(t, filesystem.WriteContentTo(s, "test.txt", "TEST"))
+		require.NoError(t, filesystem.CreateFile(s, "dir/test.txt", filesystem.WithAllowCreationOfDirectoryStructure(true)))
+
+		// WHEN
+		errStreamed := filesystem.StreamContentTo(s, "test.txt", &failingReader{content: "PARTIAL"},
+			filesystem.WithContentOperation(filesystem.ContentOperationOverwrite))
+		errMove := filesystem.Move(s, "dir", "test.txt/dir", filesystem.WithAllowCreationOfDirectoryStructure(true))
+
+		// THEN
+		require.ErrorIs(t, errStreamed, errStream)
+		require.ErrorIs(t, errMove, filesystem.ErrUnresolvableDirectoryStructure)
+		content, err := filesystem.ReadContentOf(s, "test.txt")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("TEST"), content)
+		exists, err := filesystem.CheckIfExists(s, "dir/test.txt")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+	t.Run("it should report sentinel errors like local filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		s := openStore(t)
+		require.NoError(t, filesystem.CreateDirectory(s, "dir"))
+		require.NoError(t, filesystem.CreateFile(s, "dir/test.txt"))
+
+		// WHEN
+		_, errReadMissing := filesystem.ReadContentOf(s, "missing.txt")
+		_, errReadDirectory := filesystem.ReadContentOf(s, "dir")
+		errWriteMissing := filesystem.WriteContentTo(s, "missing/test.txt", "TEST")
+		errWriteDirectory := filesystem.WriteContentTo(s, "dir", "TEST")
+		errFileFound := filesystem.CreateFile(s, "dir/test.txt")
+		errDirectory := filesystem.CreateFile(s, "dir", filesystem.WithAllowOverwrite(true))
+		errDirectoryFound := filesystem.CreateDirectory(s, "dir")
+		errFile := filesystem.CreateDirectory(s, "dir/test.txt")
+		errStructure := filesystem.CreateFile(s, "missing/test.txt")
+		errStructureOfFile := filesystem.CreateFile(s, "dir/test.txt/test.txt", filesystem.WithAllowCreationOfDirectoryStructure(true))
+		errNotEmpty := filesystem.Remove(s, "dir")
+		errCopyDirectory := filesystem.Copy(s, "dir", "copy")
+		errMoveFound := filesystem.Move(s, "dir/test.txt", "dir/test.txt")
+		errMoveIntoItself := filesystem.Move(s, "dir", "dir/sub")
+		errTargetFound := filesystem.Copy(s, "dir/test.txt", "dir", filesystem.WithRecursive(true))
+		_, errListFile := filesystem.ListFilesIn(s, "dir/test.txt")
+		_, errListMissing := filesystem.ListFilesIn(s, "missing")
+		errUnsupported := filesystem.WriteContentTo(s, "dir/test.txt", "TEST", filesystem.WithContentOperation(filesystem.ContentOperation(99)))
+		exists, errExists := filesystem.CheckIfExists(s, "missing/test.txt")
+
+		// THEN
+		require.ErrorIs(t, errReadMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errReadDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errWriteMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errWriteDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errFileFound, filesystem.ErrFileFound)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errDirectoryFound, filesystem.ErrDirectoryFound)
+		require.ErrorIs(t, errFile, filesystem.ErrFile)
+		require.ErrorIs(t, errStructure, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errStructureOfFile, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errNotEmpty, filesystem.ErrNotEmpty)
+		require.ErrorIs(t, errCopyDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errMoveFound, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errMoveIntoItself, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errTargetFound, filesystem.ErrDirectoryFound)
+		require.ErrorIs(t, errListFile, filesystem.ErrFile)
+		require.ErrorIs(t, errListMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errUnsupported, filesystem.ErrUnsupportedContentOperation)
+		require.NoError(t, errExists)
+		assert.False(t, exists)
+	})
+}
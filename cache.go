@@ -0,0 +1,326 @@
+package filesystem
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+)
+
+const (
+	optionCacheMaxBytes    options.OptionKey = `cache_max_bytes`
+	optionCacheMaxEntries  options.OptionKey = `cache_max_entries`
+	optionCacheTTL         options.OptionKey = `cache_ttl`
+	optionCacheNegativeTTL options.OptionKey = `cache_negative_ttl`
+	optionCacheNegative    options.OptionKey = `cache_negative`
+)
+
+const (
+	defaultCacheMaxBytes    int64 = 64 << 20
+	defaultCacheMaxEntries        = 10000
+	defaultCacheTTL               = time.Minute
+	defaultCacheNegativeTTL       = 10 * time.Second
+)
+
+// OptionCacheMaxBytes changes limit (default 64MiB) of content size kept in Cache.
+// Least recently used entries are evicted when the limit is exceeded.
+func OptionCacheMaxBytes(size int64) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[int64](r, optionCacheMaxBytes, size)
+	}
+}
+
+// OptionCacheMaxEntries changes limit (default 10000) of entries kept in Cache.
+func OptionCacheMaxEntries(entries int) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[int](r, optionCacheMaxEntries, entries)
+	}
+}
+
+// OptionCacheTTL changes time (default 1m) after which cached content and existence checks expire.
+// Zero means entries never expire and are removed only by invalidation or eviction.
+func OptionCacheTTL(ttl time.Duration) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Duration](r, optionCacheTTL, ttl)
+	}
+}
+
+// OptionCacheNegativeTTL changes time (default 10s) after which cached ErrFileNotFound
+// and false CheckIfExists results expire. Zero means entries never expire.
+func OptionCacheNegativeTTL(ttl time.Duration) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Duration](r, optionCacheNegativeTTL, ttl)
+	}
+}
+
+// OptionCacheNegative enables (default) or disables caching of ErrFileNotFound and false CheckIfExists results.
+func OptionCacheNegative(enabled bool) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[bool](r, optionCacheNegative, enabled)
+	}
+}
+
+// CacheStats is a snapshot of Cache counters.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+// HitRate returns ratio of hits to all lookups (0 when there were no lookups).
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// MissRate returns ratio of misses to all lookups (0 when there were no lookups).
+func (s CacheStats) MissRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Misses) / float64(total)
+}
+
+type (
+	cacheKey struct {
+		operation Operation
+		backend   string
+		path      string
+	}
+
+	cacheEntry struct {
+		key     cacheKey
+		content Content
+		exists  bool
+		err     error
+		expires time.Time
+	}
+)
+
+// Cache keeps results of ReadContentOf and CheckIfExists in memory (see NewCacheMiddleware).
+// It's safe for concurrent use and can be shared by multiple Filesystem instances.
+type Cache struct {
+	maxBytes    int64
+	maxEntries  int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	negative    bool
+	now         func() time.Time
+
+	mu         sync.Mutex
+	entries    map[cacheKey]*list.Element
+	lru        *list.List
+	size       int64
+	generation uint64
+	stats      CacheStats
+}
+
+func NewCache(opts ...options.Option) (*Cache, error) {
+	opt := options.Resolve(opts)
+
+	optMaxBytes, err := options.ReadOrDefault[int64](opt, optionCacheMaxBytes, defaultCacheMaxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("cache initialization failed: %w", err)
+	}
+	optMaxEntries, err := options.ReadOrDefault[int](opt, optionCacheMaxEntries, defaultCacheMaxEntries)
+	if err != nil {
+		return nil, fmt.Errorf("cache initialization failed: %w", err)
+	}
+	optTTL, err := options.ReadOrDefault[time.Duration](opt, optionCacheTTL, defaultCacheTTL)
+	if err != nil {
+		return nil, fmt.Errorf("cache initialization failed: %w", err)
+	}
+	optNegativeTTL, err := options.ReadOrDefault[time.Duration](opt, optionCacheNegativeTTL, defaultCacheNegativeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("cache initialization failed: %w", err)
+	}
+	optNegative, err := options.ReadOrDefault[bool](opt, optionCacheNegative, true)
+	if err != nil {
+		return nil, fmt.Errorf("cache initialization failed: %w", err)
+	}
+
+	return &Cache{
+		maxBytes:    optMaxBytes,
+		maxEntries:  optMaxEntries,
+		ttl:         optTTL,
+		negativeTTL: optNegativeTTL,
+		negative:    optNegative,
+		now:         time.Now,
+		entries:     make(map[cacheKey]*list.Element),
+		lru:         list.New(),
+	}, nil
+}
+
+// NewCacheMiddleware creates Middleware serving ReadContentOf and CheckIfExists from provided Cache.
+// Every other operation, apart from the ones that only read (StreamContentOf, ListFilesIn, Glob),
+// invalidates entries for its path (and target of Move and Copy), paths placed under it and its parent directories.
+func NewCacheMiddleware(cache *Cache) Middleware {
+	return func(next Invoker) Invoker {
+		return func(call Call) (Result, error) {
+			switch call.Operation {
+			case OperationReadContentOf, OperationCheckIfExists:
+				return cache.readThrough(next, call)
+			case OperationStreamContentOf, OperationListFilesIn, OperationGlob, OperationWalk:
+				return next(call)
+			default:
+				res, err := next(call)
+				cache.Invalidate(call.Path)
+				if call.Target != "" {
+					cache.Invalidate(call.Target)
+				}
+				return res, err
+			}
+		}
+	}
+}
+
+// Invalidate removes entries for provided path, paths placed under it and its parent directories.
+func (c *Cache) Invalidate(p string) {
+	p = cachePath(p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key, elem := range c.entries {
+		if cachePathRelated(key.path, p) {
+			c.remove(elem)
+		}
+	}
+}
+
+// Purge removes all entries.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[cacheKey]*list.Element)
+	c.lru.Init()
+	c.size = 0
+}
+
+// Stats returns snapshot of hit, miss and eviction counters along with current size of the Cache.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.size
+	return stats
+}
+
+func (c *Cache) readThrough(next Invoker, call Call) (Result, error) {
+	key := cacheKey{operation: call.Operation, backend: call.Backend, path: cachePath(call.Path)}
+
+	if entry, ok := c.lookup(key); ok {
+		return Result{Content: cloneContent(entry.content), Exists: entry.exists}, entry.err
+	}
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	res, err := next(call)
+
+	switch {
+	case err == nil && call.Operation == OperationReadContentOf:
+		c.store(generation, &cacheEntry{key: key, content: cloneContent(res.Content)}, c.ttl)
+	case err == nil && res.Exists:
+		c.store(generation, &cacheEntry{key: key, exists: true}, c.ttl)
+	case err == nil && c.negative:
+		c.store(generation, &cacheEntry{key: key}, c.negativeTTL)
+	case errors.Is(err, ErrFileNotFound) && c.negative:
+		c.store(generation, &cacheEntry{key: key, err: err}, c.negativeTTL)
+	}
+
+	return res, err
+}
+
+func (c *Cache) lookup(key cacheKey) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if ok {
+		entry := elem.Value.(*cacheEntry)
+		if entry.expires.IsZero() || c.now().Before(entry.expires) {
+			c.stats.Hits++
+			c.lru.MoveToFront(elem)
+			return entry, true
+		}
+		c.remove(elem)
+	}
+
+	c.stats.Misses++
+	return nil, false
+}
+
+// store adds entry unless cache was invalidated since the lookup (generation changed) or content exceeds the limit.
+func (c *Cache) store(generation uint64, entry *cacheEntry, ttl time.Duration) {
+	size := int64(len(entry.content))
+	if size > c.maxBytes {
+		return
+	}
+	if ttl > 0 {
+		entry.expires = c.now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+	if elem, ok := c.entries[entry.key]; ok {
+		c.remove(elem)
+	}
+
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += size
+
+	for c.size > c.maxBytes || (c.maxEntries > 0 && c.lru.Len() > c.maxEntries) {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.content))
+}
+
+func cachePath(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// cachePathRelated reports if cached path is the same as invalidated one, placed under it or is one of its parents.
+func cachePathRelated(cached, invalidated string) bool {
+	return cached == invalidated ||
+		strings.HasPrefix(cached, strings.TrimSuffix(invalidated, "/")+"/") ||
+		strings.HasPrefix(invalidated, strings.TrimSuffix(cached, "/")+"/") ||
+		(cached == "." && !path.IsAbs(invalidated))
+}
+
+func cloneContent(content Content) Content {
+	if content == nil {
+		return nil
+	}
+	res := make(Content, len(content))
+	copy(res, content)
+	return res
+}
@@ -0,0 +1,316 @@
+package filesystem
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCachedBackend struct {
+	mu    sync.Mutex
+	files map[string]string
+	reads map[string]int
+}
+
+func newCachedFilesystem(t *testing.T, cache *Cache, files map[string]string) (Filesystem, *fakeCachedBackend) {
+	t.Helper()
+
+	backend := &fakeCachedBackend{files: files, reads: make(map[string]int)}
+	fs, err := New(
+		OptionReadContentOfHandler(func(p string) (Content, error) {
+			backend.mu.Lock()
+			defer backend.mu.Unlock()
+
+			backend.reads[p]++
+			content, ok := backend.files[p]
+			if !ok {
+				return nil, ErrFileNotFound
+			}
+			return Content(content), nil
+		}),
+		OptionCheckIfExistsHandler(func(p string) (bool, error) {
+			backend.mu.Lock()
+			defer backend.mu.Unlock()
+
+			backend.reads[p]++
+			_, ok := backend.files[p]
+			return ok, nil
+		}),
+		OptionWriteContentToHandler(func(p string, content []byte, _ Arguments) error {
+			backend.mu.Lock()
+			defer backend.mu.Unlock()
+
+			backend.files[p] = string(content)
+			return nil
+		}),
+		OptionCreateFileHandler(func(p string, _ Arguments) error {
+			backend.mu.Lock()
+			defer backend.mu.Unlock()
+
+			backend.files[p] = ""
+			return nil
+		}),
+		OptionMiddleware(NewCacheMiddleware(cache)),
+	)
+	require.NoError(t, err)
+
+	return fs, backend
+}
+
+func (b *fakeCachedBackend) readsOf(p string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.reads[p]
+}
+
+func TestCache(t *testing.T) {
+	t.Run("it should serve repeated reads from memory", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache()
+		require.NoError(t, err)
+		fs, backend := newCachedFilesystem(t, cache, map[string]string{"config.yaml": "TEST"})
+
+		// WHEN
+		first, err1 := ReadContentOf(fs, "config.yaml")
+		second, err2 := ReadContentOf(fs, "./config.yaml")
+
+		// THEN
+		require.NoError(t, err1)
+		require.NoError(t, err2)
+		assert.Equal(t, Content("TEST"), first)
+		assert.Equal(t, Content("TEST"), second)
+		assert.Equal(t, 1, backend.readsOf("config.yaml"))
+
+		stats := cache.Stats()
+		assert.Equal(t, uint64(1), stats.Hits)
+		assert.Equal(t, uint64(1), stats.Misses)
+		assert.Equal(t, 1, stats.Entries)
+		assert.Equal(t, int64(4), stats.Bytes)
+		assert.InDelta(t, 0.5, stats.HitRate(), 0.001)
+		assert.InDelta(t, 0.5, stats.MissRate(), 0.001)
+	})
+	t.Run("it should not share content slice with caller", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache()
+		require.NoError(t, err)
+		fs, _ := newCachedFilesystem(t, cache, map[string]string{"config.yaml": "TEST"})
+
+		first, err := ReadContentOf(fs, "config.yaml")
+		require.NoError(t, err)
+
+		// WHEN
+		first[0] = 'B'
+		second, err := ReadContentOf(fs, "config.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, Content("TEST"), second)
+	})
+	t.Run("it should cache missing files and false existence checks", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache()
+		require.NoError(t, err)
+		fs, backend := newCachedFilesystem(t, cache, map[string]string{})
+
+		// WHEN
+		_, err1 := ReadContentOf(fs, "missing")
+		_, err2 := ReadContentOf(fs, "missing")
+		exists1, _ := CheckIfExists(fs, "missing")
+		exists2, _ := CheckIfExists(fs, "missing")
+
+		// THEN
+		require.ErrorIs(t, err1, ErrFileNotFound)
+		require.ErrorIs(t, err2, ErrFileNotFound)
+		assert.False(t, exists1)
+		assert.False(t, exists2)
+		assert.Equal(t, 2, backend.readsOf("missing"))
+	})
+	t.Run("it should not cache negative results when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache(OptionCacheNegative(false))
+		require.NoError(t, err)
+		fs, backend := newCachedFilesystem(t, cache, map[string]string{})
+
+		// WHEN
+		_, _ = ReadContentOf(fs, "missing")
+		_, _ = ReadContentOf(fs, "missing")
+		_, _ = CheckIfExists(fs, "missing")
+		_, _ = CheckIfExists(fs, "missing")
+
+		// THEN
+		assert.Equal(t, 4, backend.readsOf("missing"))
+	})
+	t.Run("it should not cache other errors", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache()
+		require.NoError(t, err)
+
+		calls := 0
+		fs, err := New(
+			OptionReadContentOfHandler(func(string) (Content, error) {
+				calls++
+				return nil, errors.New("connection reset")
+			}),
+			OptionMiddleware(NewCacheMiddleware(cache)),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		_, _ = ReadContentOf(fs, "source")
+		_, _ = ReadContentOf(fs, "source")
+
+		// THEN
+		assert.Equal(t, 2, calls)
+	})
+	t.Run("it should expire entries after their TTL", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache(OptionCacheTTL(time.Minute), OptionCacheNegativeTTL(time.Second))
+		require.NoError(t, err)
+
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+		fs, backend := newCachedFilesystem(t, cache, map[string]string{"config.yaml": "TEST"})
+
+		_, _ = ReadContentOf(fs, "config.yaml")
+		_, _ = ReadContentOf(fs, "missing")
+
+		// WHEN
+		now = now.Add(2 * time.Second)
+		_, _ = ReadContentOf(fs, "config.yaml")
+		_, _ = ReadContentOf(fs, "missing")
+
+		now = now.Add(time.Minute)
+		_, _ = ReadContentOf(fs, "config.yaml")
+
+		// THEN
+		assert.Equal(t, 2, backend.readsOf("config.yaml"))
+		assert.Equal(t, 2, backend.readsOf("missing"))
+	})
+	t.Run("it should be invalidated by operations changing the path", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache()
+		require.NoError(t, err)
+		fs, backend := newCachedFilesystem(t, cache, map[string]string{"config.yaml": "TEST"})
+
+		_, _ = ReadContentOf(fs, "config.yaml")
+		exists, _ := CheckIfExists(fs, "new.yaml")
+		require.False(t, exists)
+
+		// WHEN
+		require.NoError(t, WriteContentTo(fs, "config.yaml", "MORE TEST"))
+		require.NoError(t, CreateFile(fs, "new.yaml"))
+
+		content, err := ReadContentOf(fs, "config.yaml")
+		exists, _ = CheckIfExists(fs, "new.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, Content("MORE TEST"), content)
+		assert.True(t, exists)
+		assert.Equal(t, 2, backend.readsOf("config.yaml"))
+		assert.Equal(t, 2, backend.readsOf("new.yaml"))
+	})
+	t.Run("it should invalidate path along with its children and parents", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache()
+		require.NoError(t, err)
+		fs, backend := newCachedFilesystem(t, cache, map[string]string{"a/b/c": "TEST", "a/bc": "TEST", "x": "TEST"})
+
+		for _, p := range []string{"a", "a/b", "a/b/c", "a/bc", "x"} {
+			_, _ = CheckIfExists(fs, p)
+		}
+
+		// WHEN
+		cache.Invalidate("a/b")
+		for _, p := range []string{"a", "a/b", "a/b/c", "a/bc", "x"} {
+			_, _ = CheckIfExists(fs, p)
+		}
+
+		// THEN
+		assert.Equal(t, 2, backend.readsOf("a"))
+		assert.Equal(t, 2, backend.readsOf("a/b"))
+		assert.Equal(t, 2, backend.readsOf("a/b/c"))
+		assert.Equal(t, 1, backend.readsOf("a/bc"))
+		assert.Equal(t, 1, backend.readsOf("x"))
+	})
+	t.Run("it should evict least recently used entries above size limit", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache(OptionCacheMaxBytes(8))
+		require.NoError(t, err)
+		fs, backend := newCachedFilesystem(t, cache, map[string]string{"a": "TEST", "b": "TEST", "c": "TEST", "big": "TOO LARGE"})
+
+		_, _ = ReadContentOf(fs, "a")
+		_, _ = ReadContentOf(fs, "b")
+		_, _ = ReadContentOf(fs, "a")
+
+		// WHEN
+		_, _ = ReadContentOf(fs, "c")
+		_, _ = ReadContentOf(fs, "big")
+		_, _ = ReadContentOf(fs, "a")
+		_, _ = ReadContentOf(fs, "b")
+
+		// THEN
+		assert.Equal(t, 1, backend.readsOf("a"))
+		assert.Equal(t, 2, backend.readsOf("b"))
+		assert.Equal(t, 1, backend.readsOf("c"))
+
+		stats := cache.Stats()
+		assert.Equal(t, int64(8), stats.Bytes)
+		assert.Equal(t, uint64(2), stats.Evictions)
+	})
+	t.Run("it should limit amount of entries", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache(OptionCacheMaxEntries(1))
+		require.NoError(t, err)
+		fs, _ := newCachedFilesystem(t, cache, map[string]string{})
+
+		// WHEN
+		_, _ = CheckIfExists(fs, "a")
+		_, _ = CheckIfExists(fs, "b")
+
+		// THEN
+		assert.Equal(t, 1, cache.Stats().Entries)
+	})
+	t.Run("it should drop every entry on purge", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		cache, err := NewCache()
+		require.NoError(t, err)
+		fs, _ := newCachedFilesystem(t, cache, map[string]string{"a": "TEST"})
+		_, _ = ReadContentOf(fs, "a")
+
+		// WHEN
+		cache.Purge()
+
+		// THEN
+		stats := cache.Stats()
+		assert.Equal(t, 0, stats.Entries)
+		assert.Equal(t, int64(0), stats.Bytes)
+	})
+}
@@ -2,6 +2,7 @@ package filesystem
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,6 +17,11 @@ var validContentOperations = map[ContentOperation]bool{
 	ContentOperationOverwrite: true,
 }
 
+var contentOperationNames = map[ContentOperation]string{
+	ContentOperationAppend:    "append",
+	ContentOperationOverwrite: "overwrite",
+}
+
 type (
 	ContentOperation uint8
 
@@ -27,6 +33,13 @@ func (o ContentOperation) Is(val ContentOperation) bool {
 	return o == val
 }
 
+func (o ContentOperation) String() string {
+	if name, ok := contentOperationNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(o))
+}
+
 func (o ContentOperation) assetValid() error {
 	if validContentOperations[o] {
 		return nil
@@ -0,0 +1,112 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+)
+
+type contextFilesystem struct {
+	inner Filesystem
+	ctx   context.Context
+}
+
+// InContext returns Filesystem performing every operation within provided context.
+// Context is passed to middlewares (see Call) and operations are rejected with its error once it's done.
+func InContext(fs Filesystem, ctx context.Context) Filesystem {
+	if cfs, ok := fs.(*contextFilesystem); ok {
+		fs = cfs.inner
+	}
+	return &contextFilesystem{inner: fs, ctx: ctx}
+}
+
+func (fs *contextFilesystem) backendName() string {
+	return fs.inner.backendName()
+}
+
+func (fs *contextFilesystem) capabilities() capabilities {
+	return fs.inner.capabilities()
+}
+
+func (fs *contextFilesystem) handleReadContentOf(_ context.Context, path string) (Content, error) {
+	if err := fs.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.inner.handleReadContentOf(fs.ctx, path)
+}
+
+func (fs *contextFilesystem) handleStreamContentOf(_ context.Context, path string) (io.ReadCloser, error) {
+	if err := fs.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.inner.handleStreamContentOf(fs.ctx, path)
+}
+
+func (fs *contextFilesystem) handleCheckIfExists(_ context.Context, path string) (bool, error) {
+	if err := fs.ctx.Err(); err != nil {
+		return false, err
+	}
+	return fs.inner.handleCheckIfExists(fs.ctx, path)
+}
+
+func (fs *contextFilesystem) handleCreateFile(_ context.Context, path string, arg Arguments) error {
+	if err := fs.ctx.Err(); err != nil {
+		return err
+	}
+	return fs.inner.handleCreateFile(fs.ctx, path, arg)
+}
+
+func (fs *contextFilesystem) handleWriteContentTo(_ context.Context, path string, content []byte, arg Arguments) error {
+	if err := fs.ctx.Err(); err != nil {
+		return err
+	}
+	return fs.inner.handleWriteContentTo(fs.ctx, path, content, arg)
+}
+
+func (fs *contextFilesystem) handleStreamContentTo(_ context.Context, path string, content io.Reader, arg Arguments) error {
+	if err := fs.ctx.Err(); err != nil {
+		return err
+	}
+	return fs.inner.handleStreamContentTo(fs.ctx, path, content, arg)
+}
+
+func (fs *contextFilesystem) handleCreateDirectory(_ context.Context, path string, arg Arguments) error {
+	if err := fs.ctx.Err(); err != nil {
+		return err
+	}
+	return fs.inner.handleCreateDirectory(fs.ctx, path, arg)
+}
+
+func (fs *contextFilesystem) handleRemove(_ context.Context, path string, arg Arguments) error {
+	if err := fs.ctx.Err(); err != nil {
+		return err
+	}
+	return fs.inner.handleRemove(fs.ctx, path, arg)
+}
+
+func (fs *contextFilesystem) handleMove(_ context.Context, source, target string, arg Arguments) error {
+	if err := fs.ctx.Err(); err != nil {
+		return err
+	}
+	return fs.inner.handleMove(fs.ctx, source, target, arg)
+}
+
+func (fs *contextFilesystem) handleCopy(_ context.Context, source, target string, arg Arguments) error {
+	if err := fs.ctx.Err(); err != nil {
+		return err
+	}
+	return fs.inner.handleCopy(fs.ctx, source, target, arg)
+}
+
+func (fs *contextFilesystem) handleListFilesIn(_ context.Context, path string) ([]Entry, error) {
+	if err := fs.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.inner.handleListFilesIn(fs.ctx, path)
+}
+
+func (fs *contextFilesystem) handleGlob(_ context.Context, pattern string) ([]string, error) {
+	if err := fs.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.inner.handleGlob(fs.ctx, pattern)
+}
@@ -0,0 +1,95 @@
+package filesystem
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testContextKey struct{}
+
+func TestInContext(t *testing.T) {
+	t.Run("it should pass bound context to middlewares", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var received []any
+		fs, err := New(
+			OptionCheckIfExistsHandler(func(string) (bool, error) { return true, nil }),
+			OptionMiddleware(func(next Invoker) Invoker {
+				return func(call Call) (Result, error) {
+					received = append(received, call.Context.Value(testContextKey{}))
+					return next(call)
+				}
+			}),
+		)
+		require.NoError(t, err)
+
+		first := context.WithValue(context.Background(), testContextKey{}, "first")
+		second := context.WithValue(context.Background(), testContextKey{}, "second")
+
+		// WHEN
+		_, err = CheckIfExists(fs, "path/to/file")
+		require.NoError(t, err)
+		_, err = CheckIfExists(InContext(fs, first), "path/to/file")
+		require.NoError(t, err)
+		_, err = CheckIfExists(InContext(InContext(fs, first), second), "path/to/file")
+		require.NoError(t, err)
+
+		// THEN
+		assert.Equal(t, []any{nil, "first", "second"}, received)
+	})
+	t.Run("it should reject operations once context is done", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := New(OptionReadContentOfHandler(func(string) (Content, error) {
+			t.Fatal("handler is not expected to be called")
+			return nil, nil
+		}))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// WHEN
+		_, err = ReadContentOf(InContext(fs, ctx), "path/to/file")
+
+		// THEN
+		require.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("it should pass context of the operation to context-aware handlers", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var received []any
+		fs, err := New(
+			OptionCheckIfExistsContextHandler(func(ctx context.Context, _ string) (bool, error) {
+				received = append(received, ctx.Value(testContextKey{}))
+				return true, nil
+			}),
+			OptionMiddleware(func(next Invoker) Invoker {
+				return func(call Call) (Result, error) {
+					if call.Context.Value(testContextKey{}) != nil {
+						call.Context = context.WithValue(call.Context, testContextKey{}, "middleware")
+					}
+					return next(call)
+				}
+			}),
+		)
+		require.NoError(t, err)
+
+		bound := context.WithValue(context.Background(), testContextKey{}, "bound")
+
+		// WHEN
+		_, err = CheckIfExists(fs, "path/to/file")
+		require.NoError(t, err)
+		_, err = CheckIfExists(InContext(fs, bound), "path/to/file")
+		require.NoError(t, err)
+
+		// THEN
+		assert.Equal(t, []any{nil, "middleware"}, received)
+	})
+}
@@ -0,0 +1,111 @@
+package filesystem
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type (
+	// DryRunAction describes mutating operation intercepted by DryRun. Err contains error that the operation
+	// would (most likely) fail with and Bytes amount of content it would write.
+	DryRunAction struct {
+		Operation Operation
+		Backend   string
+		Path      string
+		Target    string
+		Arguments Arguments
+		Bytes     int64
+		Err       error
+	}
+
+	// DryRunReporterFunc receives every action intercepted by DryRun (in the order of calls).
+	DryRunReporterFunc func(action DryRunAction)
+)
+
+// String returns human-readable description of the action (e.g. "CreateFile config/app.yaml").
+func (a DryRunAction) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(a.Operation))
+	sb.WriteString(" ")
+	sb.WriteString(a.Path)
+	if a.Target != "" {
+		sb.WriteString(" -> ")
+		sb.WriteString(a.Target)
+	}
+	if a.Bytes > 0 {
+		_, _ = fmt.Fprintf(&sb, " (%d bytes)", a.Bytes)
+	}
+	if a.Err != nil {
+		sb.WriteString(": ")
+		sb.WriteString(a.Err.Error())
+	}
+	return sb.String()
+}
+
+// DryRun returns Filesystem passing reads through to fs and reporting every mutating operation to reporter
+// (nil reporter is allowed) instead of performing it. Mutating operations are validated (existence of target
+// and its parent, overwrite and directory structure arguments, content operation) against state of fs with every
+// accepted operation applied, so sequence of calls fails with the same errors real calls would. Accepted operations
+// are recorded in Sandbox without their content (Move and Copy never read content of the source); content passed
+// to StreamContentTo is drained to count its size. Reads always return real state of fs, without accepted
+// operations applied.
+func DryRun(fs Filesystem, reporter DryRunReporterFunc) Filesystem {
+	if reporter == nil {
+		reporter = func(DryRunAction) {}
+	}
+	intended := NewSandbox(fs)
+
+	return Wrap(fs, func(next Invoker) Invoker {
+		return func(call Call) (Result, error) {
+			if readOnlyOperations[call.Operation] {
+				return next(call)
+			}
+
+			action := DryRunAction{
+				Operation: call.Operation,
+				Backend:   call.Backend,
+				Path:      call.Path,
+				Target:    call.Target,
+				Arguments: call.Arguments,
+				Bytes:     int64(len(call.Content)),
+			}
+			if call.Reader != nil {
+				n, err := io.Copy(io.Discard, call.Reader)
+				if err != nil {
+					return Result{}, err
+				}
+				action.Bytes = n
+			}
+
+			action.Err = dryRunApply(intended, call)
+			reporter(action)
+
+			return Result{}, action.Err
+		}
+	})
+}
+
+// dryRunApply records call in Sandbox holding intended state (which validates it the same way default handlers do).
+// Content is not needed to validate following calls so files are written (and transferred) as empty.
+func dryRunApply(intended *Sandbox, call Call) error {
+	switch call.Operation {
+	case OperationWriteContentTo, OperationStreamContentTo:
+		if err := call.Arguments.ContentOperation.assetValid(); err != nil {
+			return err
+		}
+		call.Operation, call.Content, call.Reader = OperationWriteContentTo, nil, nil
+		call.Arguments.ContentOperation = ContentOperationOverwrite
+	case OperationMove:
+		arg := call.Arguments
+		arg.Recursive = true
+		if err := transferWith(call.Context, intended, call.Path, intended, call.Target, arg, transferFileStructure); err != nil {
+			return err
+		}
+		return intended.handleRemove(call.Context, call.Path, Arguments{Recursive: true})
+	case OperationCopy:
+		return transferWith(call.Context, intended, call.Path, intended, call.Target, call.Arguments, transferFileStructure)
+	}
+	_, err := dispatch(intended, call)
+	return err
+}
@@ -0,0 +1,166 @@
+package filesystem
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Run("it should report mutating operations without performing them", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			local, err := New()
+			require.NoError(t, err)
+			fp := path.Join(workdir, "test-file.txt")
+			require.NoError(t, os.WriteFile(fp, []byte("TEST"), 0600))
+
+			var actions []DryRunAction
+			fs := DryRun(local, func(action DryRunAction) {
+				actions = append(actions, action)
+			})
+
+			// WHEN
+			createErr := CreateFile(fs, path.Join(workdir, "a", "new.txt"), WithAllowCreationOfDirectoryStructure(true))
+			streamErr := StreamContentTo(fs, fp, strings.NewReader("MORE"))
+			moveErr := Move(fs, fp, path.Join(workdir, "moved.txt"))
+			content, readErr := ReadContentOf(fs, fp)
+
+			// THEN
+			require.NoError(t, createErr)
+			require.NoError(t, streamErr)
+			require.NoError(t, moveErr)
+			require.NoError(t, readErr)
+			assert.Equal(t, Content("TEST"), content)
+			assert.NoDirExists(t, path.Join(workdir, "a"))
+			assert.NoFileExists(t, path.Join(workdir, "moved.txt"))
+
+			require.Len(t, actions, 3)
+			assert.Equal(t, OperationCreateFile, actions[0].Operation)
+			assert.True(t, actions[0].Arguments.AllowCreationOfDirectoryStructure)
+			assert.Equal(t, OperationStreamContentTo, actions[1].Operation)
+			assert.Equal(t, int64(4), actions[1].Bytes)
+			assert.Equal(t, OperationMove, actions[2].Operation)
+			assert.Equal(t, path.Join(workdir, "moved.txt"), actions[2].Target)
+			assert.Equal(t, "Move "+fp+" -> "+path.Join(workdir, "moved.txt"), actions[2].String())
+		})
+	})
+	t.Run("it should fail with the same errors real operations would", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			local, err := New()
+			require.NoError(t, err)
+			fp := path.Join(workdir, "test-file.txt")
+			require.NoError(t, os.WriteFile(fp, []byte("TEST"), 0600))
+			require.NoError(t, os.MkdirAll(path.Join(workdir, "dir", "inner"), 0700))
+
+			var actions []DryRunAction
+			fs := DryRun(local, func(action DryRunAction) {
+				actions = append(actions, action)
+			})
+
+			// WHEN
+			errOverwrite := CreateFile(fs, fp)
+			errStructure := CreateFile(fs, path.Join(workdir, "a", "new.txt"))
+			errOperation := WriteContentTo(fs, fp, "TEST", WithContentOperation(ContentOperation(99)))
+			errMissing := WriteContentTo(fs, path.Join(workdir, "missing.txt"), "TEST")
+			errNotEmpty := Remove(fs, path.Join(workdir, "dir"))
+			errCopy := Copy(fs, path.Join(workdir, "dir"), path.Join(workdir, "copy"))
+			errDirectory := CreateDirectory(fs, path.Join(workdir, "dir"))
+
+			// THEN
+			require.ErrorIs(t, errOverwrite, ErrFileFound)
+			require.ErrorIs(t, errStructure, ErrUnresolvableDirectoryStructure)
+			require.ErrorIs(t, errOperation, ErrUnsupportedContentOperation)
+			require.ErrorIs(t, errMissing, ErrFileNotFound)
+			require.ErrorIs(t, errNotEmpty, ErrNotEmpty)
+			require.ErrorIs(t, errCopy, ErrDirectory)
+			require.ErrorIs(t, errDirectory, ErrDirectoryFound)
+
+			require.Len(t, actions, 7)
+			require.ErrorIs(t, actions[0].Err, ErrFileFound)
+		})
+	})
+	t.Run("it should validate operations against state left by earlier ones", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			local, err := New()
+			require.NoError(t, err)
+			fp := path.Join(workdir, "test-file.txt")
+			require.NoError(t, os.WriteFile(fp, []byte("TEST"), 0600))
+			fs := DryRun(local, nil)
+
+			// WHEN
+			errCreate := CreateFile(fs, path.Join(workdir, "new.txt"))
+			errWrite := WriteContentTo(fs, path.Join(workdir, "new.txt"), "NEW")
+			errDirectory := CreateDirectory(fs, path.Join(workdir, "d"))
+			errNested := CreateFile(fs, path.Join(workdir, "d", "x"))
+			errMove := Move(fs, fp, path.Join(workdir, "d", "moved.txt"))
+			errMovedAgain := Move(fs, fp, path.Join(workdir, "d", "again.txt"))
+			errRemove := Remove(fs, path.Join(workdir, "d"))
+			errCreatedTwice := CreateFile(fs, path.Join(workdir, "new.txt"))
+
+			// THEN
+			require.NoError(t, errCreate)
+			require.NoError(t, errWrite)
+			require.NoError(t, errDirectory)
+			require.NoError(t, errNested)
+			require.NoError(t, errMove)
+			require.ErrorIs(t, errMovedAgain, ErrFileNotFound)
+			require.ErrorIs(t, errRemove, ErrNotEmpty)
+			require.ErrorIs(t, errCreatedTwice, ErrFileFound)
+			assert.NoFileExists(t, path.Join(workdir, "new.txt"))
+			assert.NoDirExists(t, path.Join(workdir, "d"))
+			assert.FileExists(t, fp)
+		})
+	})
+	t.Run("it should validate transfers without reading content of the source", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			require.NoError(t, os.MkdirAll(path.Join(workdir, "dir", "inner"), 0700))
+			require.NoError(t, os.WriteFile(path.Join(workdir, "dir", "inner", "test-file.txt"), []byte("TEST"), 0600))
+			require.NoError(t, os.WriteFile(path.Join(workdir, "test-file.txt"), []byte("TEST"), 0600))
+
+			var reads []Operation
+			local, err := New(OptionMiddleware(func(next Invoker) Invoker {
+				return func(call Call) (Result, error) {
+					if call.Operation == OperationReadContentOf || call.Operation == OperationStreamContentOf {
+						reads = append(reads, call.Operation)
+					}
+					return next(call)
+				}
+			}))
+			require.NoError(t, err)
+			fs := DryRun(local, nil)
+
+			// WHEN
+			errCopy := Copy(fs, path.Join(workdir, "dir"), path.Join(workdir, "copied"), WithRecursive(true))
+			errMove := Move(fs, path.Join(workdir, "test-file.txt"), path.Join(workdir, "copied", "moved.txt"))
+			errCopiedFile := CreateFile(fs, path.Join(workdir, "copied", "inner", "test-file.txt"))
+			errMovedFile := CreateFile(fs, path.Join(workdir, "copied", "moved.txt"))
+			errCopiedAgain := Copy(fs, path.Join(workdir, "dir"), path.Join(workdir, "copied"), WithRecursive(true))
+
+			// THEN
+			require.NoError(t, errCopy)
+			require.NoError(t, errMove)
+			require.ErrorIs(t, errCopiedFile, ErrFileFound)
+			require.ErrorIs(t, errMovedFile, ErrFileFound)
+			require.ErrorIs(t, errCopiedAgain, ErrDirectoryFound)
+			assert.Empty(t, reads)
+			assert.NoDirExists(t, path.Join(workdir, "copied"))
+			assert.FileExists(t, path.Join(workdir, "test-file.txt"))
+		})
+	})
+}
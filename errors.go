@@ -0,0 +1,71 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PathError is returned by every wrapper function and describes which operation failed, on which path
+// and with which backend. Underlying cause is available with errors.Is and errors.As.
+type PathError struct {
+	Op      Operation
+	Path    string
+	Backend string
+	Err     error
+}
+
+func newPathError(fs Filesystem, op Operation, path string, err error) *PathError {
+	return &PathError{Op: op, Path: path, Backend: fs.backendName(), Err: err}
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Op.describeFailure(), e.Path, e.Err.Error())
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// sentinelError is an error with fixed message which is also equivalent (in terms of errors.Is)
+// to its counterpart from io/fs so code written against standard library keeps working.
+type sentinelError struct {
+	message    string
+	equivalent error
+}
+
+func newSentinelError(message string, equivalent error) error {
+	return &sentinelError{message: message, equivalent: equivalent}
+}
+
+func (e *sentinelError) Error() string {
+	return e.message
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return e.equivalent != nil && target == e.equivalent
+}
+
+// ErrorKind returns name of the sentinel error (e.g. "ErrFileNotFound") matching provided error,
+// "other" if error does not match any sentinel or empty string for nil.
+func ErrorKind(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, s := range errorKinds {
+		if errors.Is(err, s.err) {
+			return s.name
+		}
+	}
+	return "other"
+}
+
+// ErrorOfKind returns sentinel error named kind (see ErrorKind) or nil if there is no such sentinel.
+// It allows to restore sentinel errors transferred between processes by name.
+func ErrorOfKind(kind string) error {
+	for _, s := range errorKinds {
+		if s.name == kind {
+			return s.err
+		}
+	}
+	return nil
+}
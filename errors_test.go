@@ -0,0 +1,88 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	t.Run("it should be compatible with io/fs errors", func(t *testing.T) {
+		t.Parallel()
+
+		assert.ErrorIs(t, ErrFileNotFound, fs.ErrNotExist)
+		assert.ErrorIs(t, ErrFileFound, fs.ErrExist)
+		assert.ErrorIs(t, ErrDirectoryFound, fs.ErrExist)
+	})
+	t.Run("it should not match unrelated io/fs errors", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NotErrorIs(t, ErrFileNotFound, fs.ErrExist)
+		assert.NotErrorIs(t, ErrFileFound, fs.ErrNotExist)
+		assert.NotErrorIs(t, ErrUnresolvableDirectoryStructure, fs.ErrNotExist)
+	})
+}
+
+func TestErrorOfKind(t *testing.T) {
+	t.Run("it should restore sentinel error from its kind", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, ErrDirectoryFound, ErrorOfKind(ErrorKind(ErrDirectoryFound)))
+		assert.Equal(t, ErrPathOutsideRoot, ErrorOfKind("ErrPathOutsideRoot"))
+		assert.Nil(t, ErrorOfKind("other"))
+	})
+}
+
+func TestPathError(t *testing.T) {
+	t.Run("it should describe failed operation", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := New(
+			OptionBackendName("custom"),
+			OptionReadContentOfHandler(func(string) (Content, error) {
+				return nil, ErrFileNotFound
+			}),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = ReadContentOf(fs, "path/to/file")
+
+		// THEN
+		var pErr *PathError
+		require.ErrorAs(t, err, &pErr)
+		assert.Equal(t, OperationReadContentOf, pErr.Op)
+		assert.Equal(t, "path/to/file", pErr.Path)
+		assert.Equal(t, "custom", pErr.Backend)
+		assert.ErrorIs(t, err, ErrFileNotFound)
+		assert.EqualError(t, err, "failed to read content of path/to/file: file not found")
+	})
+	t.Run("it should be compatible with io/fs errors when using default handlers", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fsys, err := New()
+			require.NoError(t, err)
+
+			fp := path.Join(workdir, "test-file.txt")
+
+			// WHEN
+			_, readErr := ReadContentOf(fsys, fp)
+			createErr := CreateDirectory(fsys, workdir)
+
+			// THEN
+			assert.ErrorIs(t, readErr, fs.ErrNotExist)
+			assert.ErrorIs(t, createErr, fs.ErrExist)
+
+			var pErr *PathError
+			require.True(t, errors.As(readErr, &pErr))
+			assert.Equal(t, "os", pErr.Backend)
+		})
+	})
+}
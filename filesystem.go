@@ -1,6 +1,8 @@
 package filesystem
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 
@@ -13,36 +15,73 @@ import (
 func New(opts ...options.Option) (Filesystem, error) {
 	opt := options.Resolve(opts)
 
-	optReadContentOfHandler, err := options.ReadOrDefault[ReadContentOfHandlerFunc](opt, optionReadContentOfHandler, readContentOfDefaultHandler)
+	optReadContentOfHandler, err := readHandler(opt, optionReadContentOfHandler, ReadContentOfHandlerFunc.withContext, ReadContentOfHandlerFunc(ReadContentOfDefaultHandler).withContext())
 	if err != nil {
 		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
 	}
-	optStreamContentOfHandler, err := options.ReadOrDefault[StreamContentOfHandlerFunc](opt, optionStreamContentOfHandler, streamContentOfDefaultHandler)
+	optStreamContentOfHandler, err := readHandler(opt, optionStreamContentOfHandler, StreamContentOfHandlerFunc.withContext, StreamContentOfHandlerFunc(StreamContentOfDefaultHandler).withContext())
 	if err != nil {
 		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
 	}
-	optCheckIfExistsHandler, err := options.ReadOrDefault[CheckIfExistsHandlerFunc](opt, optionCheckIfExistsHandler, checkIfExistsDefaultHandler)
+	optCheckIfExistsHandler, err := readHandler(opt, optionCheckIfExistsHandler, CheckIfExistsHandlerFunc.withContext, CheckIfExistsHandlerFunc(CheckIfExistsDefaultHandler).withContext())
 	if err != nil {
 		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
 	}
-	optCreateFileHandler, err := options.ReadOrDefault[CreateFileHandlerFunc](opt, optionCreateFileHandler, createFileDefaultHandler)
+	optCreateFileHandler, err := readHandler(opt, optionCreateFileHandler, CreateFileHandlerFunc.withContext, CreateFileHandlerFunc(CreateFileDefaultHandler).withContext())
 	if err != nil {
 		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
 	}
-	optWriteContentToHandler, err := options.ReadOrDefault[WriteContentToHandlerFunc](opt, optionWriteContentToHandler, writeContentToDefaultHandler)
+	optWriteContentToHandler, err := readHandler(opt, optionWriteContentToHandler, WriteContentToHandlerFunc.withContext, WriteContentToHandlerFunc(WriteContentToDefaultHandler).withContext())
 	if err != nil {
 		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
 	}
-	optStreamContentToHandler, err := options.ReadOrDefault[StreamContentToHandlerFunc](opt, optionStreamContentToHandler, streamContentToDefaultHandler)
+	optStreamContentToHandler, err := readHandler(opt, optionStreamContentToHandler, StreamContentToHandlerFunc.withContext, StreamContentToHandlerFunc(StreamContentToDefaultHandler).withContext())
 	if err != nil {
 		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
 	}
-	optCreateDirectoryHandler, err := options.ReadOrDefault[CreateDirectoryHandlerFunc](opt, optionCreateDirectoryHandler, createDirectoryDefaultHandler)
+	optCreateDirectoryHandler, err := readHandler(opt, optionCreateDirectoryHandler, CreateDirectoryHandlerFunc.withContext, CreateDirectoryHandlerFunc(CreateDirectoryDefaultHandler).withContext())
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+	optRemoveHandler, err := readHandler(opt, optionRemoveHandler, RemoveHandlerFunc.withContext, RemoveHandlerFunc(RemoveDefaultHandler).withContext())
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+	optMoveHandler, err := readHandler(opt, optionMoveHandler, MoveHandlerFunc.withContext, MoveHandlerFunc(MoveDefaultHandler).withContext())
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+	optCopyHandler, err := readHandler(opt, optionCopyHandler, CopyHandlerFunc.withContext, CopyHandlerFunc(CopyDefaultHandler).withContext())
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+	optListFilesInHandler, err := readHandler(opt, optionListFilesInHandler, ListFilesInHandlerFunc.withContext, ListFilesInHandlerFunc(ListFilesInDefaultHandler).withContext())
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+	optGlobHandler, err := readHandler(opt, optionGlobHandler, GlobHandlerFunc.withContext, nil)
 	if err != nil {
 		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
 	}
 
-	return newFilesystem(
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+
+	optMiddlewares, err := options.ReadOrDefault[[]Middleware](opt, optionMiddlewares, nil)
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+
+	optLocalDisk, err := options.ReadOrDefault[bool](opt, optionLocalDisk, !overridesHandlers(opt))
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+
+	fs, err := newFilesystem(
+		optBackendName,
+		optLocalDisk,
 		optReadContentOfHandler,
 		optStreamContentOfHandler,
 		optCheckIfExistsHandler,
@@ -50,15 +89,34 @@ func New(opts ...options.Option) (Filesystem, error) {
 		optWriteContentToHandler,
 		optStreamContentToHandler,
 		optCreateDirectoryHandler,
+		optRemoveHandler,
+		optMoveHandler,
+		optCopyHandler,
+		optListFilesInHandler,
+		optGlobHandler,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+
+	return Wrap(fs, optMiddlewares...), nil
+}
+
+func overridesHandlers(opt options.Resolver) bool {
+	for _, key := range handlerOptionKeys {
+		if _, err := options.Read[any](opt, key); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
 // ReadContentOf will return entire content of file from provided path.
 // If file does not exist it will return ErrFileNotFound error.
 func ReadContentOf(fs Filesystem, path string) (Content, error) {
-	res, err := fs.handleReadContentOf(path)
+	res, err := fs.handleReadContentOf(context.Background(), path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read content of %s: %w", path, err)
+		return nil, newPathError(fs, OperationReadContentOf, path, err)
 	}
 	return res, nil
 }
@@ -69,50 +127,137 @@ func ReadContentOf(fs Filesystem, path string) (Content, error) {
 //
 // If file does not exist it will return ErrFileNotFound error.
 func StreamContentOf(fs Filesystem, path string) (io.ReadCloser, error) {
-	res, err := fs.handleStreamContentOf(path)
+	res, err := fs.handleStreamContentOf(context.Background(), path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to attach reader to %s: %w", path, err)
+		return nil, newPathError(fs, OperationStreamContentOf, path, err)
 	}
 	return res, nil
 }
 
 // CheckIfExists will verify if file/directory exists on provided path.
 func CheckIfExists(fs Filesystem, path string) (bool, error) {
-	res, err := fs.handleCheckIfExists(path)
+	res, err := fs.handleCheckIfExists(context.Background(), path)
 	if err != nil {
-		return false, fmt.Errorf("failed to verify existence of %s: %w", path, err)
+		return false, newPathError(fs, OperationCheckIfExists, path, err)
 	}
 	return res, nil
 }
 
 // CreateFile creates empty file at provided location (along with missing parts of directory tree if allowed).
 func CreateFile(fs Filesystem, path string, args ...Argument) error {
-	if err := fs.handleCreateFile(path, args...); err != nil {
-		return fmt.Errorf("failed to create file %s: %w", path, err)
+	if err := fs.handleCreateFile(context.Background(), path, createFileArguments(args)); err != nil {
+		return newPathError(fs, OperationCreateFile, path, err)
 	}
 	return nil
 }
 
 // WriteContentTo appends/overwrites content of file with provided data.
 func WriteContentTo[T ~string | ~[]byte](fs Filesystem, path string, content T, args ...Argument) error {
-	if err := fs.handleWriteContentTo(path, []byte(content), args...); err != nil {
-		return fmt.Errorf("failed to write content to %s: %w", path, err)
+	if err := fs.handleWriteContentTo(context.Background(), path, []byte(content), writeContentArguments(args)); err != nil {
+		return newPathError(fs, OperationWriteContentTo, path, err)
 	}
 	return nil
 }
 
 // StreamContentTo appends/overwrites content of file with content of provided io.Reader.
 func StreamContentTo(fs Filesystem, path string, content io.Reader, args ...Argument) error {
-	if err := fs.handleStreamContentTo(path, content, args...); err != nil {
-		return fmt.Errorf("failed to stream content to %s: %w", path, err)
+	if err := fs.handleStreamContentTo(context.Background(), path, content, writeContentArguments(args)); err != nil {
+		return newPathError(fs, OperationStreamContentTo, path, err)
 	}
 	return nil
 }
 
 // CreateDirectory makes empty directory at provided location (along with missing parts of directory tree if allowed).
 func CreateDirectory(fs Filesystem, path string, args ...Argument) error {
-	if err := fs.handleCreateDirectory(path, args...); err != nil {
-		return fmt.Errorf("failed to create directory at %s: %w", path, err)
+	if err := fs.handleCreateDirectory(context.Background(), path, createDirectoryArguments(args)); err != nil {
+		return newPathError(fs, OperationCreateDirectory, path, err)
+	}
+	return nil
+}
+
+// Remove deletes file, symlink or empty directory at provided location (or directory with its content if allowed).
+func Remove(fs Filesystem, path string, args ...Argument) error {
+	if err := fs.handleRemove(context.Background(), path, removeArguments(args)); err != nil {
+		return newPathError(fs, OperationRemove, path, err)
+	}
+	return nil
+}
+
+// Move relocates file or directory from source to target (without overwriting existing target unless allowed).
+// Moving path onto itself or into its own subdirectory fails with ErrUnresolvableDirectoryStructure.
+// PathError returned by Move contains source path.
+func Move(fs Filesystem, source, target string, args ...Argument) error {
+	if err := checkTransfer(source, target); err != nil {
+		return newPathError(fs, OperationMove, source, err)
+	}
+	if err := fs.handleMove(context.Background(), source, target, transferArguments(args)); err != nil {
+		return newPathError(fs, OperationMove, source, err)
+	}
+	return nil
+}
+
+// Copy duplicates file (or directory with its content if allowed) from source to target
+// (without overwriting existing target unless allowed). Copying path onto itself or into its own subdirectory
+// fails with ErrUnresolvableDirectoryStructure. PathError returned by Copy contains source path.
+func Copy(fs Filesystem, source, target string, args ...Argument) error {
+	if err := checkTransfer(source, target); err != nil {
+		return newPathError(fs, OperationCopy, source, err)
+	}
+	if err := fs.handleCopy(context.Background(), source, target, transferArguments(args)); err != nil {
+		return newPathError(fs, OperationCopy, source, err)
+	}
+	return nil
+}
+
+// ListFilesIn returns entries (files, directories and symlinks) placed directly inside provided directory.
+// If directory does not exist it will return ErrFileNotFound error and ErrFile if path points to a file.
+func ListFilesIn(fs Filesystem, path string) ([]Entry, error) {
+	res, err := fs.handleListFilesIn(context.Background(), path)
+	if err != nil {
+		return nil, newPathError(fs, OperationListFilesIn, path, err)
+	}
+	return res, nil
+}
+
+// Glob returns sorted paths matching provided pattern (see Match for supported syntax).
+// Unless native handler was provided it will resolve pattern by traversing directories with ListFilesIn handler.
+func Glob(fs Filesystem, pattern string) ([]string, error) {
+	res, err := fs.handleGlob(context.Background(), pattern)
+	if err != nil {
+		return nil, newPathError(fs, OperationGlob, pattern, err)
+	}
+	return res, nil
+}
+
+// ReadContentOfMatching returns content of every file matching provided pattern indexed by its path.
+// Directories matched by the pattern are skipped.
+func ReadContentOfMatching(fs Filesystem, pattern string) (map[string]Content, error) {
+	paths, err := Glob(fs, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]Content, len(paths))
+	for _, p := range paths {
+		content, rErr := ReadContentOf(fs, p)
+		if rErr != nil {
+			if errors.Is(rErr, ErrDirectory) {
+				continue
+			}
+			return nil, rErr
+		}
+		res[p] = content
+	}
+
+	return res, nil
+}
+
+// Walk traverses tree rooted at provided path and calls fn for each file and directory (including root).
+// It works with every backend providing ListFilesIn handler and supports SkipDir and SkipAll sentinels.
+// With concurrency higher than one, fn is called from multiple goroutines and must be safe for concurrent use.
+func Walk(fs Filesystem, root string, fn WalkFunc, args ...Argument) error {
+	if err := walk(context.Background(), fs, root, fn, walkArguments(args)); err != nil {
+		return newPathError(fs, OperationWalk, root, err)
 	}
 	return nil
 }
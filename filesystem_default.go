@@ -1,29 +1,48 @@
 package filesystem
 
 import (
+	"context"
 	"io"
 )
 
+const defaultBackendName = "os"
+
 type defaultFilesystem struct {
-	readContentOfHandlerFunc   ReadContentOfHandlerFunc
-	streamContentOfHandlerFunc StreamContentOfHandlerFunc
-	checkIfExistsHandlerFunc   CheckIfExistsHandlerFunc
-	createFileHandlerFunc      CreateFileHandlerFunc
-	writeContentToHandlerFunc  WriteContentToHandlerFunc
-	streamContentToHandlerFunc StreamContentToHandlerFunc
-	createDirectoryHandlerFunc CreateDirectoryHandlerFunc
+	name                       string
+	localDisk                  bool
+	readContentOfHandlerFunc   ReadContentOfContextHandlerFunc
+	streamContentOfHandlerFunc StreamContentOfContextHandlerFunc
+	checkIfExistsHandlerFunc   CheckIfExistsContextHandlerFunc
+	createFileHandlerFunc      CreateFileContextHandlerFunc
+	writeContentToHandlerFunc  WriteContentToContextHandlerFunc
+	streamContentToHandlerFunc StreamContentToContextHandlerFunc
+	createDirectoryHandlerFunc CreateDirectoryContextHandlerFunc
+	removeHandlerFunc          RemoveContextHandlerFunc
+	moveHandlerFunc            MoveContextHandlerFunc
+	copyHandlerFunc            CopyContextHandlerFunc
+	listFilesInHandlerFunc     ListFilesInContextHandlerFunc
+	globHandlerFunc            GlobContextHandlerFunc
 }
 
 func newFilesystem(
-	readContentOfHandlerFunc ReadContentOfHandlerFunc,
-	streamContentOfHandlerFunc StreamContentOfHandlerFunc,
-	checkIfExistsHandlerFunc CheckIfExistsHandlerFunc,
-	createFileHandlerFunc CreateFileHandlerFunc,
-	writeContentToHandlerFunc WriteContentToHandlerFunc,
-	streamContentToHandlerFunc StreamContentToHandlerFunc,
-	createDirectoryHandlerFunc CreateDirectoryHandlerFunc,
+	name string,
+	localDisk bool,
+	readContentOfHandlerFunc ReadContentOfContextHandlerFunc,
+	streamContentOfHandlerFunc StreamContentOfContextHandlerFunc,
+	checkIfExistsHandlerFunc CheckIfExistsContextHandlerFunc,
+	createFileHandlerFunc CreateFileContextHandlerFunc,
+	writeContentToHandlerFunc WriteContentToContextHandlerFunc,
+	streamContentToHandlerFunc StreamContentToContextHandlerFunc,
+	createDirectoryHandlerFunc CreateDirectoryContextHandlerFunc,
+	removeHandlerFunc RemoveContextHandlerFunc,
+	moveHandlerFunc MoveContextHandlerFunc,
+	copyHandlerFunc CopyContextHandlerFunc,
+	listFilesInHandlerFunc ListFilesInContextHandlerFunc,
+	globHandlerFunc GlobContextHandlerFunc,
 ) (Filesystem, error) {
 	return &defaultFilesystem{
+		name:                       name,
+		localDisk:                  localDisk,
 		readContentOfHandlerFunc:   readContentOfHandlerFunc,
 		streamContentOfHandlerFunc: streamContentOfHandlerFunc,
 		checkIfExistsHandlerFunc:   checkIfExistsHandlerFunc,
@@ -31,58 +50,69 @@ func newFilesystem(
 		writeContentToHandlerFunc:  writeContentToHandlerFunc,
 		streamContentToHandlerFunc: streamContentToHandlerFunc,
 		createDirectoryHandlerFunc: createDirectoryHandlerFunc,
+		removeHandlerFunc:          removeHandlerFunc,
+		moveHandlerFunc:            moveHandlerFunc,
+		copyHandlerFunc:            copyHandlerFunc,
+		listFilesInHandlerFunc:     listFilesInHandlerFunc,
+		globHandlerFunc:            globHandlerFunc,
 	}, nil
 }
 
-func (fs *defaultFilesystem) handleReadContentOf(path string) (Content, error) {
-	return fs.readContentOfHandlerFunc(path)
+func (fs *defaultFilesystem) backendName() string {
+	return fs.name
 }
 
-func (fs *defaultFilesystem) handleStreamContentOf(path string) (io.ReadCloser, error) {
-	return fs.streamContentOfHandlerFunc(path)
+func (fs *defaultFilesystem) capabilities() capabilities {
+	return capabilities{localDisk: fs.localDisk, nativeGlob: fs.globHandlerFunc != nil}
 }
 
-func (fs *defaultFilesystem) handleCheckIfExists(path string) (bool, error) {
-	return fs.checkIfExistsHandlerFunc(path)
+func (fs *defaultFilesystem) handleReadContentOf(ctx context.Context, path string) (Content, error) {
+	return fs.readContentOfHandlerFunc(ctx, path)
 }
 
-func (fs *defaultFilesystem) handleCreateFile(path string, args ...Argument) error {
-	arg := &Arguments{
-		Mode:                              ModeAllReadWrite,
-		DirectoryStructureMode:            ModeAllReadWriteExecute,
-		AllowOverwrite:                    false,
-		AllowCreationOfDirectoryStructure: false,
-	}
-	arg.Apply(args)
+func (fs *defaultFilesystem) handleStreamContentOf(ctx context.Context, path string) (io.ReadCloser, error) {
+	return fs.streamContentOfHandlerFunc(ctx, path)
+}
 
-	return fs.createFileHandlerFunc(path, *arg)
+func (fs *defaultFilesystem) handleCheckIfExists(ctx context.Context, path string) (bool, error) {
+	return fs.checkIfExistsHandlerFunc(ctx, path)
 }
 
-func (fs *defaultFilesystem) handleWriteContentTo(path string, content []byte, args ...Argument) error {
-	arg := &Arguments{
-		ContentOperation: ContentOperationAppend,
-	}
-	arg.Apply(args)
+func (fs *defaultFilesystem) handleCreateFile(ctx context.Context, path string, arg Arguments) error {
+	return fs.createFileHandlerFunc(ctx, path, arg)
+}
 
-	return fs.writeContentToHandlerFunc(path, content, *arg)
+func (fs *defaultFilesystem) handleWriteContentTo(ctx context.Context, path string, content []byte, arg Arguments) error {
+	return fs.writeContentToHandlerFunc(ctx, path, content, arg)
 }
 
-func (fs *defaultFilesystem) handleStreamContentTo(path string, content io.Reader, args ...Argument) error {
-	arg := &Arguments{
-		ContentOperation: ContentOperationAppend,
-	}
-	arg.Apply(args)
+func (fs *defaultFilesystem) handleStreamContentTo(ctx context.Context, path string, content io.Reader, arg Arguments) error {
+	return fs.streamContentToHandlerFunc(ctx, path, content, arg)
+}
 
-	return fs.streamContentToHandlerFunc(path, content, *arg)
+func (fs *defaultFilesystem) handleCreateDirectory(ctx context.Context, path string, arg Arguments) error {
+	return fs.createDirectoryHandlerFunc(ctx, path, arg)
 }
 
-func (fs *defaultFilesystem) handleCreateDirectory(path string, args ...Argument) error {
-	arg := &Arguments{
-		Mode:                              ModeAllReadWrite,
-		DirectoryStructureMode:            ModeAllReadWriteExecute,
-		AllowCreationOfDirectoryStructure: false,
-	}
-	arg.Apply(args)
+func (fs *defaultFilesystem) handleRemove(ctx context.Context, path string, arg Arguments) error {
+	return fs.removeHandlerFunc(ctx, path, arg)
+}
+
+func (fs *defaultFilesystem) handleMove(ctx context.Context, source, target string, arg Arguments) error {
+	return fs.moveHandlerFunc(ctx, source, target, arg)
+}
+
+func (fs *defaultFilesystem) handleCopy(ctx context.Context, source, target string, arg Arguments) error {
+	return fs.copyHandlerFunc(ctx, source, target, arg)
+}
+
+func (fs *defaultFilesystem) handleListFilesIn(ctx context.Context, path string) ([]Entry, error) {
+	return fs.listFilesInHandlerFunc(ctx, path)
+}
 
-	return fs.createDirectoryHandlerFunc(path, *arg)
+func (fs *defaultFilesystem) handleGlob(ctx context.Context, pattern string) ([]string, error) {
+	if fs.globHandlerFunc != nil {
+		return fs.globHandlerFunc(ctx, pattern)
+	}
+	return globWithListing(ctx, fs, pattern)
 }
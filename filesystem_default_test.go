@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
 	"math/rand"
 	"os"
 	"path"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -377,6 +379,359 @@ func TestDefaultCreateDirectory(t *testing.T) {
 	})
 }
 
+func TestDefaultListFilesIn(t *testing.T) {
+	t.Run("it should list entries of provided directory", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			require.NoError(t, os.WriteFile(path.Join(workdir, "test-file.txt"), []byte("TEST"), 0640))
+			require.NoError(t, os.Mkdir(path.Join(workdir, "test-directory"), 0750))
+			require.NoError(t, os.Symlink("test-file.txt", path.Join(workdir, "test-symlink")))
+
+			// WHEN
+			entries, err := ListFilesIn(fs, workdir)
+
+			// THEN
+			require.NoError(t, err)
+			require.Len(t, entries, 3)
+
+			assert.Equal(t, "test-directory", entries[0].Name)
+			assert.True(t, entries[0].IsDirectory)
+			assert.Equal(t, Mode(0750), entries[0].Mode)
+
+			assert.Equal(t, "test-file.txt", entries[1].Name)
+			assert.False(t, entries[1].IsDirectory)
+			assert.Equal(t, int64(4), entries[1].Size)
+			assert.Equal(t, Mode(0640), entries[1].Mode)
+
+			assert.Equal(t, "test-symlink", entries[2].Name)
+			assert.True(t, entries[2].IsSymlink)
+		})
+	})
+	t.Run("it should report if directory does not exist", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			// WHEN
+			_, err = ListFilesIn(fs, path.Join(workdir, "test-directory"))
+
+			// THEN
+			require.ErrorIs(t, err, ErrFileNotFound)
+		})
+	})
+	t.Run("it should report if path points to a file", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			fp := path.Join(workdir, "test-file.txt")
+			require.NoError(t, os.WriteFile(fp, []byte("TEST"), 0600))
+
+			// WHEN
+			_, err = ListFilesIn(fs, fp)
+
+			// THEN
+			require.ErrorIs(t, err, ErrFile)
+		})
+	})
+}
+
+func TestDefaultRemove(t *testing.T) {
+	t.Run("it should remove file at provided location", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			fp := path.Join(workdir, "test-file.txt")
+			require.NoError(t, os.WriteFile(fp, []byte("TEST"), 0600))
+
+			// WHEN
+			err = Remove(fs, fp)
+
+			// THEN
+			require.NoError(t, err)
+			assert.NoFileExists(t, fp)
+		})
+	})
+	t.Run("it should report if path does not exist", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			// WHEN
+			err = Remove(fs, path.Join(workdir, "test-file.txt"))
+
+			// THEN
+			require.ErrorIs(t, err, ErrFileNotFound)
+		})
+	})
+	t.Run("it should forbid removing directory with content if not permitted by arguments", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			dp := path.Join(workdir, "test-directory")
+			require.NoError(t, os.MkdirAll(path.Join(dp, "inner"), 0700))
+
+			// WHEN
+			err = Remove(fs, dp)
+
+			// THEN
+			require.ErrorIs(t, err, ErrNotEmpty)
+			assert.DirExists(t, dp)
+		})
+	})
+	t.Run("it should remove directory with content if allowed by arguments", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			dp := path.Join(workdir, "test-directory")
+			require.NoError(t, os.MkdirAll(path.Join(dp, "inner"), 0700))
+
+			// WHEN
+			err = Remove(fs, dp, WithRecursive(true))
+
+			// THEN
+			require.NoError(t, err)
+			assert.NoDirExists(t, dp)
+		})
+	})
+}
+
+func TestDefaultMove(t *testing.T) {
+	t.Run("it should move file to provided location", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			src, dst := path.Join(workdir, "source.txt"), path.Join(workdir, "target.txt")
+			require.NoError(t, os.WriteFile(src, []byte("TEST"), 0600))
+
+			// WHEN
+			err = Move(fs, src, dst)
+
+			// THEN
+			require.NoError(t, err)
+			assert.NoFileExists(t, src)
+			content, rErr := os.ReadFile(dst)
+			require.NoError(t, rErr)
+			assert.Equal(t, []byte("TEST"), content)
+		})
+	})
+	t.Run("it should forbid overwriting existing target if not permitted by arguments", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			src, dst := path.Join(workdir, "source.txt"), path.Join(workdir, "target.txt")
+			require.NoError(t, os.WriteFile(src, []byte("TEST"), 0600))
+			require.NoError(t, os.WriteFile(dst, []byte("OLD"), 0600))
+
+			// WHEN
+			err = Move(fs, src, dst)
+
+			// THEN
+			require.ErrorIs(t, err, ErrFileFound)
+			assert.FileExists(t, src)
+		})
+	})
+	t.Run("it should report missing directory structure of target if creation is not permitted", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			src, dst := path.Join(workdir, "source.txt"), path.Join(workdir, "a", "b", "target.txt")
+			require.NoError(t, os.WriteFile(src, []byte("TEST"), 0600))
+
+			// WHEN
+			err = Move(fs, src, dst)
+			errAllowed := Move(fs, src, dst, WithAllowCreationOfDirectoryStructure(true))
+
+			// THEN
+			require.ErrorIs(t, err, ErrUnresolvableDirectoryStructure)
+			require.NoError(t, errAllowed)
+			assert.FileExists(t, dst)
+		})
+	})
+}
+
+func TestDefaultCopy(t *testing.T) {
+	t.Run("it should copy file preserving its mode", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			src, dst := path.Join(workdir, "source.txt"), path.Join(workdir, "target.txt")
+			require.NoError(t, os.WriteFile(src, []byte("TEST"), 0640))
+
+			// WHEN
+			err = Copy(fs, src, dst)
+
+			// THEN
+			require.NoError(t, err)
+			assert.FileExists(t, src)
+			content, rErr := os.ReadFile(dst)
+			require.NoError(t, rErr)
+			assert.Equal(t, []byte("TEST"), content)
+			fi, sErr := os.Stat(dst)
+			require.NoError(t, sErr)
+			assert.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+		})
+	})
+	t.Run("it should copy directory only if permitted by arguments", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			src, dst := path.Join(workdir, "source"), path.Join(workdir, "target")
+			require.NoError(t, os.MkdirAll(path.Join(src, "inner"), 0700))
+			require.NoError(t, os.WriteFile(path.Join(src, "inner", "file.txt"), []byte("TEST"), 0600))
+
+			// WHEN
+			err = Copy(fs, src, dst)
+			errRecursive := Copy(fs, src, dst, WithRecursive(true))
+
+			// THEN
+			require.ErrorIs(t, err, ErrDirectory)
+			require.NoError(t, errRecursive)
+			content, rErr := os.ReadFile(path.Join(dst, "inner", "file.txt"))
+			require.NoError(t, rErr)
+			assert.Equal(t, []byte("TEST"), content)
+		})
+	})
+	t.Run("it should keep content of file copied or moved onto itself", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			src := path.Join(workdir, "source.txt")
+			require.NoError(t, os.WriteFile(src, []byte("TEST"), 0600))
+
+			// WHEN
+			errCopy := Copy(fs, src, src, WithAllowOverwrite(true))
+			errMove := Move(fs, src, path.Join(workdir, ".", "source.txt"), WithAllowOverwrite(true))
+
+			// THEN
+			require.ErrorIs(t, errCopy, ErrUnresolvableDirectoryStructure)
+			require.ErrorIs(t, errMove, ErrUnresolvableDirectoryStructure)
+			content, rErr := os.ReadFile(src)
+			require.NoError(t, rErr)
+			assert.Equal(t, []byte("TEST"), content)
+		})
+	})
+}
+
+func TestDefaultErrorTranslation(t *testing.T) {
+	cases := []struct {
+		errno    syscall.Errno
+		expected error
+	}{
+		{errno: syscall.EACCES, expected: ErrPermissionDenied},
+		{errno: syscall.EPERM, expected: ErrPermissionDenied},
+		{errno: syscall.EROFS, expected: ErrReadOnly},
+		{errno: syscall.ENOSPC, expected: ErrNoSpace},
+		{errno: syscall.ENOTEMPTY, expected: ErrNotEmpty},
+		{errno: syscall.EFBIG, expected: ErrTooLarge},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(fmt.Sprintf("it should translate %s", c.errno.Error()), func(t *testing.T) {
+			t.Parallel()
+
+			// GIVEN
+			original := &fs.PathError{Op: "open", Path: "path/to/file", Err: c.errno}
+
+			// WHEN
+			err := translateError(original)
+
+			// THEN
+			require.ErrorIs(t, err, c.expected)
+
+			var pErr *fs.PathError
+			require.ErrorAs(t, err, &pErr)
+			assert.Same(t, original, pErr)
+		})
+	}
+	t.Run("it should keep unknown errors intact", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		original := &fs.PathError{Op: "open", Path: "path/to/file", Err: syscall.EINVAL}
+
+		// WHEN
+		err := translateError(original)
+
+		// THEN
+		assert.Same(t, original, err)
+	})
+	t.Run("it should report permission denied from default handlers", func(t *testing.T) {
+		t.Parallel()
+
+		if os.Geteuid() == 0 {
+			t.Skip("permissions are not enforced for root user")
+		}
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fsys, err := New()
+			require.NoError(t, err)
+
+			fp := path.Join(workdir, "test-file.txt")
+			require.NoError(t, os.WriteFile(fp, []byte("TEST"), 0200))
+
+			// WHEN
+			_, err = ReadContentOf(fsys, fp)
+
+			// THEN
+			require.ErrorIs(t, err, ErrPermissionDenied)
+			assert.ErrorIs(t, err, fs.ErrPermission)
+		})
+	})
+}
+
 func withinRandomDirectoryScope(fn func(workdir string)) {
 	tmpDir, err := os.MkdirTemp("", fmt.Sprintf("create-file-%d", rand.Int()))
 	if err != nil {
@@ -280,3 +280,169 @@ func TestCreateDirectory(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestRemove(t *testing.T) {
+	t.Run("it should pass execution to provided handler", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		expectedPath := "path/to/file"
+		fs, err := New(OptionRemoveHandler(func(path string, arg Arguments) error {
+			assert.Equal(t, expectedPath, path)
+			assert.True(t, arg.Recursive)
+			return nil
+		}))
+		require.NoError(t, err)
+
+		// WHEN
+		err = Remove(fs, expectedPath, WithRecursive(true))
+
+		// THEN
+		require.NoError(t, err)
+	})
+}
+
+func TestMove(t *testing.T) {
+	t.Run("it should pass execution to provided handler", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		expectedSource, expectedTarget := "path/to/source", "path/to/target"
+		fs, err := New(OptionMoveHandler(func(source, target string, arg Arguments) error {
+			assert.Equal(t, expectedSource, source)
+			assert.Equal(t, expectedTarget, target)
+			assert.True(t, arg.AllowOverwrite)
+			return nil
+		}))
+		require.NoError(t, err)
+
+		// WHEN
+		err = Move(fs, expectedSource, expectedTarget, WithAllowOverwrite(true))
+
+		// THEN
+		require.NoError(t, err)
+	})
+	t.Run("it should reject transfer of path onto itself or into its subdirectory", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var called []string
+		fs, err := New(OptionMoveHandler(func(source, target string, _ Arguments) error {
+			called = append(called, source+" -> "+target)
+			return nil
+		}))
+		require.NoError(t, err)
+
+		// WHEN
+		errs := make(map[string]error)
+		for _, c := range [][2]string{
+			{"a.txt", "a.txt"}, {"dir", "./dir/"}, {"/dir", "/dir/../dir"}, {"dir/sub", "dir/sub/inner"},
+			{"/", "/dir"}, {".", "dir"}, {"path/to/..", "path/x"},
+		} {
+			errs[c[0]+" -> "+c[1]] = Move(fs, c[0], c[1], WithAllowOverwrite(true))
+		}
+		for _, c := range [][2]string{{"dir", "dir2"}, {"dir/sub", "dir"}, {".", "../dir"}} {
+			require.NoError(t, Move(fs, c[0], c[1]))
+		}
+
+		// THEN
+		for transfer, tErr := range errs {
+			require.ErrorIs(t, tErr, ErrUnresolvableDirectoryStructure, transfer)
+			var pathErr *PathError
+			require.ErrorAs(t, tErr, &pathErr)
+			assert.Equal(t, OperationMove, pathErr.Op)
+		}
+		assert.Equal(t, []string{"dir -> dir2", "dir/sub -> dir", ". -> ../dir"}, called)
+	})
+}
+
+func TestCopy(t *testing.T) {
+	t.Run("it should pass execution to provided handler", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		expectedSource, expectedTarget := "path/to/source", "path/to/target"
+		fs, err := New(OptionCopyHandler(func(source, target string, arg Arguments) error {
+			assert.Equal(t, expectedSource, source)
+			assert.Equal(t, expectedTarget, target)
+			assert.True(t, arg.Recursive)
+			return nil
+		}))
+		require.NoError(t, err)
+
+		// WHEN
+		err = Copy(fs, expectedSource, expectedTarget, WithRecursive(true))
+
+		// THEN
+		require.NoError(t, err)
+	})
+	t.Run("it should reject transfer of path onto itself or into its subdirectory", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var called []string
+		fs, err := New(OptionCopyHandler(func(source, target string, _ Arguments) error {
+			called = append(called, source+" -> "+target)
+			return nil
+		}))
+		require.NoError(t, err)
+
+		// WHEN
+		errs := make(map[string]error)
+		for _, c := range [][2]string{
+			{"a.txt", "a.txt"}, {"dir", "./dir/"}, {"/dir", "/dir/../dir"}, {"dir/sub", "dir/sub/inner"},
+			{"/", "/dir"}, {".", "dir"}, {"path/to/..", "path/x"},
+		} {
+			errs[c[0]+" -> "+c[1]] = Copy(fs, c[0], c[1], WithRecursive(true), WithAllowOverwrite(true))
+		}
+		for _, c := range [][2]string{{"dir", "dir2"}, {"dir/sub", "dir"}, {".", "../dir"}} {
+			require.NoError(t, Copy(fs, c[0], c[1]))
+		}
+
+		// THEN
+		for transfer, tErr := range errs {
+			require.ErrorIs(t, tErr, ErrUnresolvableDirectoryStructure, transfer)
+			var pathErr *PathError
+			require.ErrorAs(t, tErr, &pathErr)
+			assert.Equal(t, OperationCopy, pathErr.Op)
+		}
+		assert.Equal(t, []string{"dir -> dir2", "dir/sub -> dir", ". -> ../dir"}, called)
+	})
+}
+
+func TestListFilesIn(t *testing.T) {
+	t.Run("it should pass execution to provided handler", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		expectedPath := "path/to/directory"
+		fs, err := New(OptionListFilesInHandler(func(path string) ([]Entry, error) {
+			assert.Equal(t, expectedPath, path)
+			return []Entry{{Name: "file", Mode: ModeUserReadWrite}}, nil
+		}))
+		require.NoError(t, err)
+
+		// WHEN
+		result, err := ListFilesIn(fs, expectedPath)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []Entry{{Name: "file", Mode: ModeUserReadWrite}}, result)
+	})
+	t.Run("it should return up-stream error", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := New(OptionListFilesInHandler(func(string) ([]Entry, error) {
+			return nil, errors.New("something went wrong")
+		}))
+		require.NoError(t, err)
+
+		// WHEN
+		result, err := ListFilesIn(fs, "path/to/directory")
+
+		// THEN
+		require.EqualError(t, err, "failed to list files in path/to/directory: something went wrong")
+		assert.Nil(t, result)
+	})
+}
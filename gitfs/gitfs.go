@@ -0,0 +1,211 @@
+// Package gitfs provides filesystem.Filesystem serving tree of a commit stored in local git repository (bare or not).
+//
+// Repository is read natively (loose objects and packfiles, without git executable). Tree of the commit is indexed
+// when repository is opened and content of files is read on demand. Every entry reports time of the commit
+// as its modification time and mode derived from the tree (0644, 0755 for executables and directories, 0777 for
+// symlinks). Symlinks are followed within the tree (absolute targets are resolved against its root) and submodules
+// are skipped.
+//
+// Reader (see Open) serves single commit and fails every mutating operation with filesystem.ErrReadOnly.
+// Writer (see NewWriter) accumulates changes on top of the last commit of a branch in memory and records them
+// as a new commit of that branch with Commit. Working tree and index of non-bare repository are not updated.
+package gitfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionBackendName options.OptionKey = `backend_name`
+	optionRevision    options.OptionKey = `git_revision`
+	optionBranch      options.OptionKey = `git_branch`
+	optionAuthor      options.OptionKey = `git_author`
+)
+
+const (
+	defaultBackendName = "git"
+	defaultRevision    = "HEAD"
+)
+
+// Reader is read-only filesystem.Filesystem serving tree of single commit. It's safe for concurrent use.
+type Reader struct {
+	filesystem.Filesystem
+
+	mu     sync.Mutex
+	repo   *git.Repository
+	idx    *index
+	commit plumbing.Hash
+	closed bool
+}
+
+// OptionBackendName changes name of the backend reported in errors and to middlewares (default "git").
+func OptionBackendName(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBackendName, name)
+	}
+}
+
+// OptionRevision selects commit served by Reader (default "HEAD"). Branches, tags, hashes (also abbreviated)
+// and expressions like "main~2" are accepted.
+func OptionRevision(revision string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionRevision, revision)
+	}
+}
+
+// Open opens repository stored in local directory (working tree of non-bare repository or bare repository itself)
+// and serves tree of the commit selected with OptionRevision.
+func Open(dir string, opts ...options.Option) (*Reader, error) {
+	opt := options.Resolve(opts)
+
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("git filesystem initialization failed: %w", err)
+	}
+	optRevision, err := options.ReadOrDefault[string](opt, optionRevision, defaultRevision)
+	if err != nil {
+		return nil, fmt.Errorf("git filesystem initialization failed: %w", err)
+	}
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("git filesystem initialization failed: %w", err)
+	}
+	commit, err := resolveCommit(repo, optRevision)
+	if err == nil {
+		r := &Reader{repo: repo, commit: commit.Hash}
+		if r.idx, err = loadIndex(repo, commit); err == nil {
+			r.Filesystem, err = r.filesystem(optBackendName)
+		}
+		if err == nil {
+			return r, nil
+		}
+	}
+	_ = closeRepository(repo)
+	return nil, fmt.Errorf("git filesystem initialization failed: %w", err)
+}
+
+// Revision returns hash of the served commit.
+func (r *Reader) Revision() string {
+	return r.commit.String()
+}
+
+// Close releases files of the repository. Every operation performed afterwards fails with fs.ErrClosed.
+func (r *Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return fs.ErrClosed
+	}
+	r.closed = true
+	return closeRepository(r.repo)
+}
+
+func (r *Reader) filesystem(backendName string) (filesystem.Filesystem, error) {
+	return filesystem.New(
+		filesystem.OptionBackendName(backendName),
+		filesystem.OptionReadContentOfHandler(func(p string) (filesystem.Content, error) {
+			var res filesystem.Content
+			err := r.with(func() (err error) {
+				res, err = r.idx.readContentOf(p)
+				return err
+			})
+			return res, err
+		}),
+		filesystem.OptionStreamContentOfHandler(func(p string) (io.ReadCloser, error) {
+			var res io.ReadCloser
+			err := r.with(func() (err error) {
+				res, err = r.idx.open(p)
+				return err
+			})
+			return res, err
+		}),
+		filesystem.OptionCheckIfExistsHandler(func(p string) (bool, error) {
+			var res bool
+			err := r.with(func() (err error) {
+				res, err = r.idx.checkIfExists(p)
+				return err
+			})
+			return res, err
+		}),
+		filesystem.OptionCreateFileHandler(func(string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionWriteContentToHandler(func(string, []byte, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionStreamContentToHandler(func(string, io.Reader, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionCreateDirectory(func(string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionRemoveHandler(func(string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionMoveHandler(func(string, string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionCopyHandler(func(string, string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionListFilesInHandler(func(p string) ([]filesystem.Entry, error) {
+			var res []filesystem.Entry
+			err := r.with(func() (err error) {
+				res, err = r.idx.listFilesIn(p)
+				return err
+			})
+			return res, err
+		}),
+	)
+}
+
+// with performs fn while holding the lock (storage of go-git is not safe for concurrent use).
+func (r *Reader) with(fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return fs.ErrClosed
+	}
+	return fn()
+}
+
+// resolveCommit finds commit pointed by revision (annotated tags are peeled).
+func resolveCommit(repo *git.Repository, revision string) (*object.Commit, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve revision %s: %w", revision, err)
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		if tag, tErr := repo.TagObject(*hash); tErr == nil {
+			commit, err = tag.Commit()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read commit %s: %w", hash, err)
+	}
+	return commit, nil
+}
+
+// closeRepository releases files (e.g. packfiles) held by storage of the repository.
+func closeRepository(repo *git.Repository) error {
+	if c, ok := repo.Storer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package gitfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+var signature = &object.Signature{Name: "Test", Email: "test@example.com", When: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+// newRepository initializes non-bare repository in temporary directory.
+func newRepository(t *testing.T) (string, *git.Repository) {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	return dir, repo
+}
+
+// commitFiles writes files (and symlinks prefixed with "->") to working tree and commits all of them.
+func commitFiles(t *testing.T, dir string, repo *git.Repository, files map[string]string) plumbing.Hash {
+	t.Helper()
+
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		if len(content) > 2 && content[:2] == "->" {
+			require.NoError(t, os.Symlink(content[2:], p))
+		} else {
+			require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+		}
+		_, err = wt.Add(name)
+		require.NoError(t, err)
+	}
+	hash, err := wt.Commit("update", &git.CommitOptions{Author: signature})
+	require.NoError(t, err)
+
+	return hash
+}
+
+// packInto stores every object of the repository in packfile of new bare repository (as if it was cloned)
+// and copies references.
+func packInto(t *testing.T, repo *git.Repository) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	bare, err := git.PlainInit(dir, true)
+	require.NoError(t, err)
+
+	var hashes []plumbing.Hash
+	objects, err := repo.Storer.IterEncodedObjects(plumbing.AnyObject)
+	require.NoError(t, err)
+	require.NoError(t, objects.ForEach(func(obj plumbing.EncodedObject) error {
+		hashes = append(hashes, obj.Hash())
+		return nil
+	}))
+	pw, err := bare.Storer.(storer.PackfileWriter).PackfileWriter()
+	require.NoError(t, err)
+	_, err = packfile.NewEncoder(pw, repo.Storer, false).Encode(hashes, 10)
+	require.NoError(t, err)
+	require.NoError(t, pw.Close())
+
+	refs, err := repo.References()
+	require.NoError(t, err)
+	require.NoError(t, refs.ForEach(func(ref *plumbing.Reference) error {
+		return bare.Storer.SetReference(ref)
+	}))
+	require.NoError(t, bare.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.Master)))
+
+	return dir
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("it should serve commit selected by revision", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		dir, repo := newRepository(t)
+		first := commitFiles(t, dir, repo, map[string]string{"config/app.yaml": "version: 1"})
+		_, err := repo.CreateTag("v1", first, &git.CreateTagOptions{Tagger: signature, Message: "v1"})
+		require.NoError(t, err)
+		second := commitFiles(t, dir, repo, map[string]string{"config/app.yaml": "version: 2"})
+
+		// WHEN
+		for revision, expected := range map[string]string{
+			"HEAD":                  "version: 2",
+			"master":                "version: 2",
+			"v1":                    "version: 1",
+			"HEAD~1":                "version: 1",
+			first.String()[:7]:      "version: 1",
+			second.String():         "version: 2",
+			"refs/heads/master~1^0": "version: 1",
+		} {
+			r, oErr := Open(dir, OptionRevision(revision))
+			require.NoError(t, oErr, revision)
+			content, rErr := filesystem.ReadContentOf(r, "config/app.yaml")
+
+			// THEN
+			require.NoError(t, rErr, revision)
+			assert.Equal(t, filesystem.Content(expected), content, revision)
+			require.NoError(t, r.Close())
+		}
+		r, err := Open(dir, OptionRevision("v1"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = r.Close() })
+		assert.Equal(t, first.String(), r.Revision())
+	})
+	t.Run("it should read packfiles of bare repository, list entries and follow symlinks", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		dir, repo := newRepository(t)
+		commitFiles(t, dir, repo, map[string]string{
+			"etc/app.yaml":     "key: value",
+			"etc/current.yaml": "->app.yaml",
+			"config":           "->/etc",
+			"loop":             "->loop",
+			"bin/tool":         "#!/bin/sh",
+		})
+		bare := packInto(t, repo)
+		packs, err := filepath.Glob(filepath.Join(bare, "objects", "pack", "*.pack"))
+		require.NoError(t, err)
+		require.NotEmpty(t, packs)
+		loose, err := filepath.Glob(filepath.Join(bare, "objects", "??"))
+		require.NoError(t, err)
+		require.Empty(t, loose)
+
+		// WHEN
+		r, err := Open(bare)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = r.Close() })
+
+		// THEN
+		for _, p := range []string{"etc/app.yaml", "/etc/current.yaml", "config/app.yaml", "config/current.yaml"} {
+			content, rErr := filesystem.ReadContentOf(r, p)
+			require.NoError(t, rErr, p)
+			assert.Equal(t, filesystem.Content("key: value"), content, p)
+		}
+		stream, err := filesystem.StreamContentOf(r, "bin/tool")
+		require.NoError(t, err)
+		streamed, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, "#!/bin/sh", string(streamed))
+
+		entries, err := filesystem.ListFilesIn(r, "config")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, filesystem.Entry{Name: "app.yaml", Size: 10, Mode: regularMode, ModTime: entries[0].ModTime}, entries[0])
+		assert.True(t, entries[0].ModTime.Equal(signature.When))
+		assert.True(t, entries[1].IsSymlink)
+		assert.Equal(t, "app.yaml", entries[1].LinkTarget)
+		root, err := filesystem.ListFilesIn(r, "/")
+		require.NoError(t, err)
+		names := make([]string, 0, len(root))
+		for _, e := range root {
+			names = append(names, e.Name)
+		}
+		assert.Equal(t, []string{"bin", "config", "etc", "loop"}, names)
+		assert.True(t, root[0].IsDirectory)
+		paths, err := filesystem.Glob(r, "**/*.yaml")
+		require.NoError(t, err)
+		assert.Contains(t, paths, "etc/app.yaml")
+		for p, expected := range map[string]bool{"etc": true, "config": true, "etc/app.yaml/x": false, "missing": false, ".": true} {
+			exists, eErr := filesystem.CheckIfExists(r, p)
+			require.NoError(t, eErr, p)
+			assert.Equal(t, expected, exists, p)
+		}
+	})
+	t.Run("it should report sentinel errors", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		dir, repo := newRepository(t)
+		commitFiles(t, dir, repo, map[string]string{"dir/test.txt": "TEST", "loop": "->loop"})
+		r, err := Open(dir, OptionBackendName("config"))
+		require.NoError(t, err)
+
+		// WHEN
+		_, errMissing := filesystem.ReadContentOf(r, "missing.txt")
+		_, errDirectory := filesystem.ReadContentOf(r, "dir")
+		_, errLoop := filesystem.ReadContentOf(r, "loop")
+		_, errListFile := filesystem.ListFilesIn(r, "dir/test.txt")
+		errReadOnly := filesystem.CreateFile(r, "new.txt")
+		_, errRevision := Open(dir, OptionRevision("missing"))
+		_, errRepository := Open(t.TempDir())
+		require.NoError(t, r.Close())
+		_, errClosed := filesystem.ReadContentOf(r, "dir/test.txt")
+
+		// THEN
+		require.ErrorIs(t, errMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errLoop, filesystem.ErrSymlinkLoop)
+		require.ErrorIs(t, errListFile, filesystem.ErrFile)
+		require.ErrorIs(t, errReadOnly, filesystem.ErrReadOnly)
+		var pathErr *filesystem.PathError
+		require.ErrorAs(t, errReadOnly, &pathErr)
+		assert.Equal(t, "config", pathErr.Backend)
+		require.ErrorIs(t, errRevision, plumbing.ErrReferenceNotFound)
+		require.ErrorIs(t, errRepository, git.ErrRepositoryNotExists)
+		require.ErrorIs(t, errClosed, fs.ErrClosed)
+	})
+}
@@ -0,0 +1,260 @@
+package gitfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// maxSymlinkHops limits amount of symlinks followed while resolving single path.
+const maxSymlinkHops = 40
+
+// Modes reported for entries (git keeps only executable bit of files).
+const (
+	regularMode    = filesystem.ModeUserReadWrite | filesystem.ModeGroupRead | filesystem.ModeOthersRead
+	executableMode = filesystem.ModeUserReadWriteExecute | filesystem.ModeGroupRead | filesystem.ModeGroupExecute |
+		filesystem.ModeOthersRead | filesystem.ModeOthersExecute
+	directoryMode = executableMode
+	symlinkMode   = filesystem.ModeAllReadWriteExecute
+)
+
+type (
+	// index holds every entry of the tree (without content, which is read from repository on demand).
+	// It's not safe for concurrent use.
+	index struct {
+		repo    *git.Repository
+		entries map[string]*entry
+	}
+
+	entry struct {
+		isDirectory bool
+		mode        filemode.FileMode
+		// hash of the blob stored in repository (zero when content was written by Writer).
+		hash    plumbing.Hash
+		content []byte
+		modTime time.Time
+	}
+)
+
+// loadIndex indexes tree of the commit (nil commit gives empty tree). Entries are reported with time
+// of the commit as modification time.
+func loadIndex(repo *git.Repository, commit *object.Commit) (*index, error) {
+	idx := &index{repo: repo, entries: make(map[string]*entry)}
+	if commit == nil {
+		return idx, nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read tree of commit %s: %w", commit.Hash, err)
+	}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, te, wErr := walker.Next()
+		if errors.Is(wErr, io.EOF) {
+			return idx, nil
+		}
+		if wErr != nil {
+			return nil, fmt.Errorf("cannot read tree of commit %s: %w", commit.Hash, wErr)
+		}
+		if te.Mode == filemode.Submodule {
+			// Content of submodules is stored in other repositories.
+			continue
+		}
+		idx.entries[name] = &entry{
+			isDirectory: te.Mode == filemode.Dir,
+			mode:        te.Mode,
+			hash:        te.Hash,
+			modTime:     commit.Committer.When,
+		}
+	}
+}
+
+func (idx *index) open(p string) (io.ReadCloser, error) {
+	_, e, err := idx.resolve(entryName(p), true, 0)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, filesystem.ErrFileNotFound
+	}
+	if e.isDirectory {
+		return nil, filesystem.ErrDirectory
+	}
+	return idx.reader(e)
+}
+
+func (idx *index) readContentOf(p string) (filesystem.Content, error) {
+	rc, err := idx.open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(rc)
+}
+
+func (idx *index) checkIfExists(p string) (bool, error) {
+	_, e, err := idx.resolve(entryName(p), true, 0)
+	if errors.Is(err, filesystem.ErrFileNotFound) {
+		return false, nil
+	}
+	return e != nil, err
+}
+
+func (idx *index) listFilesIn(p string) ([]filesystem.Entry, error) {
+	name, e, err := idx.resolve(entryName(p), true, 0)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, filesystem.ErrFileNotFound
+	}
+	if !e.isDirectory {
+		return nil, filesystem.ErrFile
+	}
+
+	res := make([]filesystem.Entry, 0)
+	for child, ce := range idx.entries {
+		if parent := strings.TrimPrefix(path.Dir(child), "."); parent != name {
+			continue
+		}
+		entry := filesystem.Entry{
+			Name:        path.Base(child),
+			Mode:        modeOf(ce),
+			ModTime:     ce.modTime,
+			IsDirectory: ce.isDirectory,
+			IsSymlink:   ce.mode == filemode.Symlink,
+		}
+		if !ce.isDirectory {
+			if entry.Size, err = idx.size(ce); err != nil {
+				return nil, err
+			}
+		}
+		if entry.IsSymlink {
+			target, tErr := idx.read(ce)
+			if tErr != nil {
+				return nil, tErr
+			}
+			entry.LinkTarget = string(target)
+		}
+		res = append(res, entry)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// resolve follows symlinks found on the path (and in its last element if follow is set) and returns resolved name
+// with its entry (nil if it does not exist). Absolute targets of symlinks are resolved against root of the tree.
+func (idx *index) resolve(name string, follow bool, hops int) (string, *entry, error) {
+	if name == "" {
+		return "", idx.root(), nil
+	}
+
+	elements := strings.Split(name, "/")
+	current := ""
+	var e *entry
+	for i, element := range elements {
+		if current != "" && !e.isDirectory {
+			return "", nil, filesystem.ErrFileNotFound
+		}
+
+		candidate := path.Join(current, element)
+		found := idx.entries[candidate]
+		if found == nil {
+			return candidate, nil, nil
+		}
+
+		if found.mode == filemode.Symlink && (follow || i < len(elements)-1) {
+			if hops++; hops > maxSymlinkHops {
+				return "", nil, filesystem.ErrSymlinkLoop
+			}
+			target, err := idx.read(found)
+			if err != nil {
+				return "", nil, err
+			}
+			t := string(target)
+			if !path.IsAbs(t) {
+				t = path.Join(current, t)
+			}
+			resolved, te, err := idx.resolve(entryName(t), true, hops)
+			if err != nil {
+				return "", nil, err
+			}
+			if te == nil {
+				return "", nil, filesystem.ErrFileNotFound
+			}
+			current, e = resolved, te
+			continue
+		}
+		current, e = candidate, found
+	}
+	return current, e, nil
+}
+
+// reader returns content of the file (read from repository unless it was written by Writer).
+func (idx *index) reader(e *entry) (io.ReadCloser, error) {
+	if e.hash.IsZero() {
+		return io.NopCloser(bytes.NewReader(e.content)), nil
+	}
+	blob, err := idx.repo.BlobObject(e.hash)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read blob %s: %w", e.hash, err)
+	}
+	return blob.Reader()
+}
+
+func (idx *index) read(e *entry) ([]byte, error) {
+	rc, err := idx.reader(e)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(rc)
+}
+
+func (idx *index) size(e *entry) (int64, error) {
+	if e.hash.IsZero() {
+		return int64(len(e.content)), nil
+	}
+	obj, err := idx.repo.Storer.EncodedObject(plumbing.BlobObject, e.hash)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read blob %s: %w", e.hash, err)
+	}
+	return obj.Size(), nil
+}
+
+func (idx *index) root() *entry {
+	return &entry{isDirectory: true, mode: filemode.Dir}
+}
+
+// modeOf converts mode stored in the tree to the one reported in entries.
+func modeOf(e *entry) filesystem.Mode {
+	switch {
+	case e.isDirectory:
+		return directoryMode
+	case e.mode == filemode.Symlink:
+		return symlinkMode
+	case e.mode == filemode.Executable:
+		return executableMode
+	}
+	return regularMode
+}
+
+// entryName converts path to name of tree entry (slash separated, unrooted, without "." and "..", empty for root).
+func entryName(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
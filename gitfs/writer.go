@@ -0,0 +1,533 @@
+package gitfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+var (
+	// ErrAuthorNotConfigured is returned by NewWriter when author was neither provided with OptionAuthor
+	// nor configured in the repository (user.name and user.email).
+	ErrAuthorNotConfigured = errors.New("author of commits is not configured")
+	// ErrDetachedHead is returned by NewWriter when OptionBranch is not provided and HEAD does not point to a branch.
+	ErrDetachedHead = errors.New("HEAD does not point to a branch")
+	// ErrNothingToCommit is returned by Commit when nothing was changed since the last commit.
+	ErrNothingToCommit = errors.New("nothing to commit")
+	// ErrBranchMoved is returned by Commit when branch was updated by someone else after Writer was created
+	// (or after its last commit).
+	ErrBranchMoved = errors.New("branch was updated concurrently")
+)
+
+// Writer is filesystem.Filesystem accumulating changes on top of the last commit of a branch in memory and
+// recording them as a new commit with Commit. Git does not store directories, so empty ones are not committed
+// and their modes are ignored. Mode of a file is recorded as executable when it has user execute bit.
+// It's safe for concurrent use.
+type Writer struct {
+	filesystem.Filesystem
+
+	mu     sync.Mutex
+	repo   *git.Repository
+	idx    *index
+	branch plumbing.ReferenceName
+	head   plumbing.Hash
+	author object.Signature
+	dirty  bool
+	closed bool
+}
+
+// OptionBranch selects branch which receives commits of Writer (default is branch pointed by HEAD).
+// Branch is created with the first commit when it does not exist.
+func OptionBranch(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBranch, name)
+	}
+}
+
+// OptionAuthor sets author (and committer) of commits created by Writer (default is user configured
+// in the repository).
+func OptionAuthor(name, email string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[object.Signature](r, optionAuthor, object.Signature{Name: name, Email: email})
+	}
+}
+
+// NewWriter opens repository stored in local directory and serves tree of the last commit of a branch
+// (see OptionBranch) which can be changed and committed with Commit.
+func NewWriter(dir string, opts ...options.Option) (*Writer, error) {
+	opt := options.Resolve(opts)
+
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("git filesystem initialization failed: %w", err)
+	}
+	optBranch, err := options.ReadOrDefault[string](opt, optionBranch, "")
+	if err != nil {
+		return nil, fmt.Errorf("git filesystem initialization failed: %w", err)
+	}
+	optAuthor, err := options.ReadOrDefault[object.Signature](opt, optionAuthor, object.Signature{})
+	if err != nil {
+		return nil, fmt.Errorf("git filesystem initialization failed: %w", err)
+	}
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("git filesystem initialization failed: %w", err)
+	}
+	w := &Writer{repo: repo, author: optAuthor}
+	if err = w.init(optBranch); err == nil {
+		w.Filesystem, err = w.filesystem(optBackendName)
+	}
+	if err != nil {
+		_ = closeRepository(repo)
+		return nil, fmt.Errorf("git filesystem initialization failed: %w", err)
+	}
+	return w, nil
+}
+
+// Commit records current tree as a new commit of the branch and returns its hash.
+func (w *Writer) Commit(message string) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return "", fs.ErrClosed
+	}
+	if !w.dirty {
+		return "", ErrNothingToCommit
+	}
+
+	tree, _, err := w.storeTree(w.children(), "")
+	if err != nil {
+		return "", fmt.Errorf("cannot store tree: %w", err)
+	}
+	signature := w.author
+	signature.When = time.Now()
+	commit := &object.Commit{Author: signature, Committer: signature, Message: message, TreeHash: tree}
+	if !w.head.IsZero() {
+		commit.ParentHashes = []plumbing.Hash{w.head}
+	}
+	obj := w.repo.Storer.NewEncodedObject()
+	if err = commit.Encode(obj); err != nil {
+		return "", fmt.Errorf("cannot encode commit: %w", err)
+	}
+	hash, err := w.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return "", fmt.Errorf("cannot store commit: %w", err)
+	}
+
+	var old *plumbing.Reference
+	if !w.head.IsZero() {
+		old = plumbing.NewHashReference(w.branch, w.head)
+	}
+	if err = w.repo.Storer.CheckAndSetReference(plumbing.NewHashReference(w.branch, hash), old); err != nil {
+		if errors.Is(err, storage.ErrReferenceHasChanged) {
+			return "", fmt.Errorf("%w: %w", ErrBranchMoved, err)
+		}
+		return "", fmt.Errorf("cannot update branch %s: %w", w.branch.Short(), err)
+	}
+	w.head, w.dirty = hash, false
+	return hash.String(), nil
+}
+
+// Close releases files of the repository discarding changes which were not committed. Every operation performed
+// afterwards fails with fs.ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	return closeRepository(w.repo)
+}
+
+// init selects branch and indexes tree of its last commit.
+func (w *Writer) init(branch string) error {
+	if branch != "" {
+		w.branch = plumbing.NewBranchReferenceName(branch)
+	} else {
+		head, err := w.repo.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			return fmt.Errorf("cannot read HEAD: %w", err)
+		}
+		if head.Type() != plumbing.SymbolicReference || !head.Target().IsBranch() {
+			return ErrDetachedHead
+		}
+		w.branch = head.Target()
+	}
+
+	if w.author.Name == "" || w.author.Email == "" {
+		cfg, err := w.repo.Config()
+		if err != nil {
+			return fmt.Errorf("cannot read configuration of repository: %w", err)
+		}
+		w.author.Name, w.author.Email = cfg.User.Name, cfg.User.Email
+		if w.author.Name == "" || w.author.Email == "" {
+			return ErrAuthorNotConfigured
+		}
+	}
+
+	var commit *object.Commit
+	ref, err := w.repo.Reference(w.branch, true)
+	switch {
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+	case err != nil:
+		return fmt.Errorf("cannot read branch %s: %w", w.branch.Short(), err)
+	default:
+		if commit, err = w.repo.CommitObject(ref.Hash()); err != nil {
+			return fmt.Errorf("cannot read commit %s: %w", ref.Hash(), err)
+		}
+		w.head = commit.Hash
+	}
+	w.idx, err = loadIndex(w.repo, commit)
+	return err
+}
+
+func (w *Writer) filesystem(backendName string) (filesystem.Filesystem, error) {
+	return filesystem.New(
+		filesystem.OptionBackendName(backendName),
+		filesystem.OptionReadContentOfHandler(func(p string) (filesystem.Content, error) {
+			var res filesystem.Content
+			err := w.with(func() (err error) {
+				res, err = w.idx.readContentOf(p)
+				return err
+			})
+			return res, err
+		}),
+		filesystem.OptionStreamContentOfHandler(func(p string) (io.ReadCloser, error) {
+			var res io.ReadCloser
+			err := w.with(func() (err error) {
+				res, err = w.idx.open(p)
+				return err
+			})
+			return res, err
+		}),
+		filesystem.OptionCheckIfExistsHandler(func(p string) (bool, error) {
+			var res bool
+			err := w.with(func() (err error) {
+				res, err = w.idx.checkIfExists(p)
+				return err
+			})
+			return res, err
+		}),
+		filesystem.OptionCreateFileHandler(w.createFile),
+		filesystem.OptionWriteContentToHandler(w.writeContentTo),
+		filesystem.OptionStreamContentToHandler(func(p string, content io.Reader, arg filesystem.Arguments) error {
+			buf, err := io.ReadAll(content)
+			if err != nil {
+				return fmt.Errorf("cannot read content: %w", err)
+			}
+			return w.writeContentTo(p, buf, arg)
+		}),
+		filesystem.OptionCreateDirectory(w.createDirectory),
+		filesystem.OptionRemoveHandler(w.remove),
+		filesystem.OptionMoveHandler(func(source, target string, arg filesystem.Arguments) error {
+			return w.transfer(source, target, arg, true)
+		}),
+		filesystem.OptionCopyHandler(func(source, target string, arg filesystem.Arguments) error {
+			return w.transfer(source, target, arg, false)
+		}),
+		filesystem.OptionListFilesInHandler(func(p string) ([]filesystem.Entry, error) {
+			var res []filesystem.Entry
+			err := w.with(func() (err error) {
+				res, err = w.idx.listFilesIn(p)
+				return err
+			})
+			return res, err
+		}),
+	)
+}
+
+func (w *Writer) createFile(p string, arg filesystem.Arguments) error {
+	return w.with(func() error {
+		name := entryName(p)
+		if err := w.prepareParent(name, arg); err != nil {
+			return err
+		}
+
+		existing := w.idx.entries[name]
+		if name == "" || existing != nil {
+			if !arg.AllowOverwrite {
+				return filesystem.ErrFileFound
+			}
+			if name == "" || existing.isDirectory {
+				return filesystem.ErrDirectory
+			}
+		}
+
+		mode := filemode.Regular
+		if arg.Mode&filesystem.ModeUserExecute != 0 {
+			mode = filemode.Executable
+		}
+		w.idx.entries[name] = &entry{mode: mode, content: []byte{}, modTime: time.Now()}
+		w.dirty = true
+		return nil
+	})
+}
+
+func (w *Writer) writeContentTo(p string, content []byte, arg filesystem.Arguments) error {
+	overwrite := arg.ContentOperation.Is(filesystem.ContentOperationOverwrite)
+	if !overwrite && !arg.ContentOperation.Is(filesystem.ContentOperationAppend) {
+		return filesystem.ErrUnsupportedContentOperation
+	}
+
+	return w.with(func() error {
+		_, e, err := w.idx.resolve(entryName(p), true, 0)
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			return filesystem.ErrFileNotFound
+		}
+		if e.isDirectory {
+			return filesystem.ErrDirectory
+		}
+
+		if overwrite {
+			e.content = bytes.Clone(content)
+		} else {
+			current, rErr := w.idx.read(e)
+			if rErr != nil {
+				return rErr
+			}
+			e.content = append(current, content...)
+		}
+		e.hash, e.modTime = plumbing.ZeroHash, time.Now()
+		w.dirty = true
+		return nil
+	})
+}
+
+func (w *Writer) createDirectory(p string, arg filesystem.Arguments) error {
+	return w.with(func() error {
+		name := entryName(p)
+		if err := w.prepareParent(name, arg); err != nil {
+			return err
+		}
+
+		if existing := w.idx.entries[name]; name == "" || existing != nil {
+			if name == "" || existing.isDirectory {
+				return filesystem.ErrDirectoryFound
+			}
+			return filesystem.ErrFile
+		}
+
+		w.idx.entries[name] = &entry{isDirectory: true, mode: filemode.Dir, modTime: time.Now()}
+		return nil
+	})
+}
+
+func (w *Writer) remove(p string, arg filesystem.Arguments) error {
+	return w.with(func() error {
+		name := entryName(p)
+		if name == "" {
+			return filesystem.ErrPermissionDenied
+		}
+		e := w.idx.entries[name]
+		if e == nil {
+			return filesystem.ErrFileNotFound
+		}
+
+		descendants := w.descendantsOf(name)
+		if e.isDirectory && len(descendants) > 0 && !arg.Recursive {
+			return filesystem.ErrNotEmpty
+		}
+		for _, d := range descendants {
+			delete(w.idx.entries, d)
+		}
+		delete(w.idx.entries, name)
+		w.dirty = true
+		return nil
+	})
+}
+
+// transfer copies source (with its content if recursive) to target and removes source when moving.
+func (w *Writer) transfer(source, target string, arg filesystem.Arguments, move bool) error {
+	return w.with(func() error {
+		src, dst := entryName(source), entryName(target)
+		if src == "" || dst == "" {
+			return filesystem.ErrPermissionDenied
+		}
+		e := w.idx.entries[src]
+		if e == nil {
+			return filesystem.ErrFileNotFound
+		}
+		if e.isDirectory && !move && !arg.Recursive {
+			return filesystem.ErrDirectory
+		}
+		if dst == src || strings.HasPrefix(dst, src+"/") {
+			return fmt.Errorf("cannot transfer %s into itself: %w", source, filesystem.ErrUnresolvableDirectoryStructure)
+		}
+
+		if existing := w.idx.entries[dst]; existing != nil {
+			if !arg.AllowOverwrite {
+				if existing.isDirectory {
+					return filesystem.ErrDirectoryFound
+				}
+				return filesystem.ErrFileFound
+			}
+			if strings.HasPrefix(src, dst+"/") {
+				return fmt.Errorf("cannot replace %s with its own content: %w", target, filesystem.ErrUnresolvableDirectoryStructure)
+			}
+			for _, d := range w.descendantsOf(dst) {
+				delete(w.idx.entries, d)
+			}
+			delete(w.idx.entries, dst)
+		}
+		if err := w.prepareParent(dst, arg); err != nil {
+			return err
+		}
+
+		for _, name := range append([]string{src}, w.descendantsOf(src)...) {
+			c := *w.idx.entries[name]
+			c.content = bytes.Clone(c.content)
+			w.idx.entries[dst+strings.TrimPrefix(name, src)] = &c
+			if move {
+				delete(w.idx.entries, name)
+			}
+		}
+		w.dirty = true
+		return nil
+	})
+}
+
+// with performs fn while holding the lock (storage of go-git is not safe for concurrent use).
+func (w *Writer) with(fn func() error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fs.ErrClosed
+	}
+	return fn()
+}
+
+// prepareParent verifies if parent directory of entry exists and creates missing ones (if allowed).
+func (w *Writer) prepareParent(name string, arg filesystem.Arguments) error {
+	var missing []string
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if e := w.idx.entries[dir]; e != nil {
+			if !e.isDirectory {
+				return fmt.Errorf("location structure does not contain valid directory as target: %w", filesystem.ErrUnresolvableDirectoryStructure)
+			}
+			break
+		}
+		missing = append(missing, dir)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !arg.AllowCreationOfDirectoryStructure {
+		return fmt.Errorf("creation of non-existing directory structure is forbidden by current settings: %w", filesystem.ErrUnresolvableDirectoryStructure)
+	}
+
+	for _, dir := range missing {
+		w.idx.entries[dir] = &entry{isDirectory: true, mode: filemode.Dir, modTime: time.Now()}
+	}
+	return nil
+}
+
+// descendantsOf returns names of all entries placed (at any depth) inside directory.
+func (w *Writer) descendantsOf(name string) []string {
+	var res []string
+	for child := range w.idx.entries {
+		if strings.HasPrefix(child, name+"/") {
+			res = append(res, child)
+		}
+	}
+	return res
+}
+
+// children groups names of entries by their directories.
+func (w *Writer) children() map[string][]string {
+	res := make(map[string][]string)
+	for name := range w.idx.entries {
+		dir := strings.TrimPrefix(path.Dir(name), ".")
+		res[dir] = append(res[dir], name)
+	}
+	return res
+}
+
+// storeTree stores tree of directory (with its subtrees and blobs of changed files) in repository. It reports
+// false when directory contains no files (git does not store empty trees unless it's the root one).
+func (w *Writer) storeTree(children map[string][]string, dir string) (plumbing.Hash, bool, error) {
+	tree := &object.Tree{}
+	for _, name := range children[dir] {
+		e := w.idx.entries[name]
+		te := object.TreeEntry{Name: path.Base(name), Mode: e.mode, Hash: e.hash}
+		if e.isDirectory {
+			hash, ok, err := w.storeTree(children, name)
+			if err != nil {
+				return plumbing.ZeroHash, false, err
+			}
+			if !ok {
+				continue
+			}
+			te.Hash = hash
+		} else if e.hash.IsZero() {
+			hash, err := w.storeBlob(e.content)
+			if err != nil {
+				return plumbing.ZeroHash, false, err
+			}
+			e.hash, e.content = hash, nil
+			te.Hash = hash
+		}
+		tree.Entries = append(tree.Entries, te)
+	}
+	if len(tree.Entries) == 0 && dir != "" {
+		return plumbing.ZeroHash, false, nil
+	}
+
+	// Git sorts entries as if names of directories ended with slash.
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return sortName(tree.Entries[i]) < sortName(tree.Entries[j])
+	})
+	obj := w.repo.Storer.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, false, err
+	}
+	hash, err := w.repo.Storer.SetEncodedObject(obj)
+	return hash, true, err
+}
+
+func (w *Writer) storeBlob(content []byte) (plumbing.Hash, error) {
+	obj := w.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(content)))
+	ow, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err = ow.Write(content); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err = ow.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return w.repo.Storer.SetEncodedObject(obj)
+}
+
+func sortName(te object.TreeEntry) string {
+	if te.Mode == filemode.Dir {
+		return te.Name + "/"
+	}
+	return te.Name
+}
@@ -0,0 +1,198 @@
+package gitfs
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// newBareRepository initializes bare repository in temporary directory.
+func newBareRepository(t *testing.T) (string, *git.Repository) {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, true)
+	require.NoError(t, err)
+
+	return dir, repo
+}
+
+// newWriter creates Writer closing it when test ends.
+func newWriter(t *testing.T, dir string) *Writer {
+	t.Helper()
+
+	w, err := NewWriter(dir, OptionAuthor(signature.Name, signature.Email))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+
+	return w
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("it should commit accumulated changes to the branch", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		dir, repo := newBareRepository(t)
+		w := newWriter(t, dir)
+
+		// WHEN
+		require.NoError(t, filesystem.CreateFile(w, "config/app.yaml", filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.WriteContentTo(w, "config/app.yaml", "version: "))
+		require.NoError(t, filesystem.StreamContentTo(w, "config/app.yaml", strings.NewReader("1")))
+		require.NoError(t, filesystem.CreateFile(w, "bin/tool", filesystem.WithMode(filesystem.ModeUserReadWriteExecute),
+			filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.CreateDirectory(w, "empty"))
+		first, errFirst := w.Commit("first")
+		require.NoError(t, filesystem.WriteContentTo(w, "config/app.yaml", "version: 2", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite)))
+		require.NoError(t, filesystem.Move(w, "bin", "usr/bin", filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		second, errSecond := w.Commit("second")
+		_, errNothing := w.Commit("nothing")
+
+		// THEN
+		require.NoError(t, errFirst)
+		require.NoError(t, errSecond)
+		require.ErrorIs(t, errNothing, ErrNothingToCommit)
+		head, err := repo.Reference(plumbing.Master, true)
+		require.NoError(t, err)
+		assert.Equal(t, second, head.Hash().String())
+		commit, err := repo.CommitObject(head.Hash())
+		require.NoError(t, err)
+		assert.Equal(t, "second", commit.Message)
+		assert.Equal(t, signature.Email, commit.Author.Email)
+		require.Equal(t, []plumbing.Hash{plumbing.NewHash(first)}, commit.ParentHashes)
+
+		for revision, expected := range map[string]string{"master": "version: 2", "master~1": "version: 1"} {
+			r, oErr := Open(dir, OptionRevision(revision))
+			require.NoError(t, oErr, revision)
+			content, rErr := filesystem.ReadContentOf(r, "config/app.yaml")
+			require.NoError(t, rErr, revision)
+			assert.Equal(t, filesystem.Content(expected), content, revision)
+			require.NoError(t, r.Close())
+		}
+		r, err := Open(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = r.Close() })
+		root, err := filesystem.ListFilesIn(r, "/")
+		require.NoError(t, err)
+		names := make([]string, 0, len(root))
+		for _, e := range root {
+			names = append(names, e.Name)
+		}
+		assert.Equal(t, []string{"config", "usr"}, names)
+		tool, err := filesystem.ListFilesIn(r, "usr/bin")
+		require.NoError(t, err)
+		require.Len(t, tool, 1)
+		assert.Equal(t, executableMode, tool[0].Mode)
+		tree, err := commit.Tree()
+		require.NoError(t, err)
+		te, err := tree.FindEntry("usr/bin/tool")
+		require.NoError(t, err)
+		assert.Equal(t, filemode.Executable, te.Mode)
+	})
+	t.Run("it should build on existing commits and serve uncommitted changes", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		dir, repo := newRepository(t)
+		base := commitFiles(t, dir, repo, map[string]string{"config/app.yaml": "key: value", "config/other.yaml": "other"})
+		w := newWriter(t, dir)
+
+		// WHEN
+		require.NoError(t, filesystem.WriteContentTo(w, "config/app.yaml", "\nnext: value"))
+		require.NoError(t, filesystem.Remove(w, "config/other.yaml"))
+		require.NoError(t, filesystem.Copy(w, "config", "backup", filesystem.WithRecursive(true)))
+		content, errRead := filesystem.ReadContentOf(w, "backup/app.yaml")
+		exists, errExists := filesystem.CheckIfExists(w, "config/other.yaml")
+		hash, errCommit := w.Commit("update")
+
+		// THEN
+		require.NoError(t, errRead)
+		assert.Equal(t, filesystem.Content("key: value\nnext: value"), content)
+		require.NoError(t, errExists)
+		assert.False(t, exists)
+		require.NoError(t, errCommit)
+		commit, err := repo.CommitObject(plumbing.NewHash(hash))
+		require.NoError(t, err)
+		assert.Equal(t, []plumbing.Hash{base}, commit.ParentHashes)
+		files, err := commit.Files()
+		require.NoError(t, err)
+		var names []string
+		require.NoError(t, files.ForEach(func(f *object.File) error {
+			names = append(names, f.Name)
+			return nil
+		}))
+		assert.ElementsMatch(t, []string{"backup/app.yaml", "config/app.yaml"}, names)
+	})
+	t.Run("it should refuse to commit when branch was moved", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		dir, repo := newRepository(t)
+		commitFiles(t, dir, repo, map[string]string{"test.txt": "TEST"})
+		first, second := newWriter(t, dir), newWriter(t, dir)
+		require.NoError(t, filesystem.WriteContentTo(first, "test.txt", "1"))
+		require.NoError(t, filesystem.WriteContentTo(second, "test.txt", "2"))
+
+		// WHEN
+		_, errFirst := first.Commit("first")
+		_, errSecond := second.Commit("second")
+
+		// THEN
+		require.NoError(t, errFirst)
+		require.ErrorIs(t, errSecond, ErrBranchMoved)
+	})
+	t.Run("it should report sentinel errors like local filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		dir, _ := newBareRepository(t)
+		w := newWriter(t, dir)
+		require.NoError(t, filesystem.CreateDirectory(w, "dir"))
+		require.NoError(t, filesystem.CreateFile(w, "dir/test.txt"))
+
+		// WHEN
+		errWriteMissing := filesystem.WriteContentTo(w, "missing.txt", "TEST")
+		errWriteDirectory := filesystem.WriteContentTo(w, "dir", "TEST")
+		errFileFound := filesystem.CreateFile(w, "dir/test.txt")
+		errDirectory := filesystem.CreateFile(w, "dir", filesystem.WithAllowOverwrite(true))
+		errDirectoryFound := filesystem.CreateDirectory(w, "dir")
+		errFile := filesystem.CreateDirectory(w, "dir/test.txt")
+		errStructure := filesystem.CreateFile(w, "missing/test.txt")
+		errStructureOfFile := filesystem.CreateFile(w, "dir/test.txt/test.txt", filesystem.WithAllowCreationOfDirectoryStructure(true))
+		errNotEmpty := filesystem.Remove(w, "dir")
+		errCopyDirectory := filesystem.Copy(w, "dir", "copy")
+		errMoveIntoItself := filesystem.Move(w, "dir", "dir/sub")
+		_, errAuthor := NewWriter(dir)
+		_, errRepository := NewWriter(t.TempDir(), OptionAuthor(signature.Name, signature.Email))
+		require.NoError(t, w.Close())
+		errClosed := filesystem.CreateFile(w, "late.txt")
+		_, errCommitClosed := w.Commit("late")
+
+		// THEN
+		require.ErrorIs(t, errWriteMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errWriteDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errFileFound, filesystem.ErrFileFound)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errDirectoryFound, filesystem.ErrDirectoryFound)
+		require.ErrorIs(t, errFile, filesystem.ErrFile)
+		require.ErrorIs(t, errStructure, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errStructureOfFile, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errNotEmpty, filesystem.ErrNotEmpty)
+		require.ErrorIs(t, errCopyDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errMoveIntoItself, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errAuthor, ErrAuthorNotConfigured)
+		require.ErrorIs(t, errRepository, git.ErrRepositoryNotExists)
+		require.ErrorIs(t, errClosed, fs.ErrClosed)
+		require.ErrorIs(t, errCommitClosed, fs.ErrClosed)
+	})
+}
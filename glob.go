@@ -0,0 +1,198 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+const globDoubleStar = "**"
+
+// Match reports whether name matches provided pattern.
+// Pattern is split into '/' separated segments and each of them supports syntax of path.Match
+// ('*', '?' and character classes like '[a-z]' or '[^0-9]').
+// Segment consisting only of '**' matches zero or more directories.
+func Match(pattern, name string) (bool, error) {
+	if err := validatePattern(pattern); err != nil {
+		return false, err
+	}
+	return matchSegments(splitSegments(pattern), splitSegments(name)), nil
+}
+
+func validatePattern(pattern string) error {
+	for _, seg := range splitSegments(pattern) {
+		if _, err := path.Match(seg, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitSegments(p string) []string {
+	res := make([]string, 0, strings.Count(p, "/")+1)
+	for _, seg := range strings.Split(p, "/") {
+		if seg != "" {
+			res = append(res, seg)
+		}
+	}
+	return res
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == globDoubleStar {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func hasMeta(segment string) bool {
+	return strings.ContainsAny(segment, `*?[\`)
+}
+
+// globWithListing resolves pattern using only ListFilesIn and CheckIfExists handlers, so it works for every backend.
+func globWithListing(ctx context.Context, fs Filesystem, pattern string) ([]string, error) {
+	if err := validatePattern(pattern); err != nil {
+		return nil, err
+	}
+
+	root := ""
+	if strings.HasPrefix(pattern, "/") {
+		root = "/"
+	}
+
+	segments := splitSegments(pattern)
+	for len(segments) > 0 && !hasMeta(segments[0]) {
+		root = joinGlobPath(root, segments[0])
+		segments = segments[1:]
+	}
+
+	found := make(map[string]struct{})
+	if len(segments) == 0 {
+		if root == "" {
+			return []string{}, nil
+		}
+		exists, err := fs.handleCheckIfExists(ctx, root)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			found[root] = struct{}{}
+		}
+	} else if err := expandGlob(ctx, fs, root, segments, found); err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(found))
+	for p := range found {
+		res = append(res, p)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+func expandGlob(ctx context.Context, fs Filesystem, dir string, segments []string, found map[string]struct{}) error {
+	seg, rest := segments[0], segments[1:]
+
+	if !hasMeta(seg) {
+		candidate := joinGlobPath(dir, seg)
+		if len(rest) > 0 {
+			return expandGlob(ctx, fs, candidate, rest, found)
+		}
+		exists, err := fs.handleCheckIfExists(ctx, candidate)
+		if err != nil {
+			return err
+		}
+		if exists {
+			found[candidate] = struct{}{}
+		}
+		return nil
+	}
+
+	entries, exists, err := listGlobDirectory(ctx, fs, dir)
+	if err != nil || !exists {
+		return err
+	}
+
+	if seg == globDoubleStar {
+		// Trailing '**' matches zero directories as well (see Match), so the directory itself is found too.
+		if len(rest) == 0 && dir != "" {
+			found[dir] = struct{}{}
+		}
+		if len(rest) > 0 {
+			if err := expandGlob(ctx, fs, dir, rest, found); err != nil {
+				return err
+			}
+		}
+		for _, e := range entries {
+			child := joinGlobPath(dir, e.Name)
+			if len(rest) == 0 {
+				found[child] = struct{}{}
+			}
+			if e.IsDirectory && !e.IsSymlink {
+				if err := expandGlob(ctx, fs, child, segments, found); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, e := range entries {
+		if ok, _ := path.Match(seg, e.Name); !ok {
+			continue
+		}
+		child := joinGlobPath(dir, e.Name)
+		if len(rest) == 0 {
+			found[child] = struct{}{}
+			continue
+		}
+		if e.IsDirectory || e.IsSymlink {
+			if err := expandGlob(ctx, fs, child, rest, found); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// listGlobDirectory lists directory treating files as empty directories and reports if dir exists at all.
+func listGlobDirectory(ctx context.Context, fs Filesystem, dir string) ([]Entry, bool, error) {
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := fs.handleListFilesIn(ctx, dir)
+	switch {
+	case errors.Is(err, ErrFileNotFound):
+		return nil, false, nil
+	case errors.Is(err, ErrFile):
+		return nil, true, nil
+	case err != nil:
+		return nil, false, err
+	}
+	return entries, true, nil
+}
+
+func joinGlobPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return path.Join(dir, name)
+}
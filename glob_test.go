@@ -0,0 +1,342 @@
+package filesystem
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeTreeFilesystem creates Filesystem backed only by custom handlers serving provided files.
+// Directories are derived from file paths, path ending with '/' describes empty directory.
+func newFakeTreeFilesystem(t *testing.T, files map[string]string, opts ...func(*fakeTree)) Filesystem {
+	t.Helper()
+
+	tree := &fakeTree{files: make(map[string]string), directories: map[string]bool{".": true}}
+	for p, content := range files {
+		if strings.HasSuffix(p, "/") {
+			tree.addDirectory(path.Clean(p))
+			continue
+		}
+		tree.files[p] = content
+		tree.addDirectory(path.Dir(p))
+	}
+	for _, opt := range opts {
+		opt(tree)
+	}
+
+	fs, err := New(
+		OptionListFilesInHandler(tree.list),
+		OptionCheckIfExistsHandler(tree.exists),
+		OptionReadContentOfHandler(tree.read),
+	)
+	require.NoError(t, err)
+
+	return fs
+}
+
+type fakeTree struct {
+	files       map[string]string
+	directories map[string]bool
+
+	mu     sync.Mutex
+	listed []string
+}
+
+func (ft *fakeTree) addDirectory(dir string) {
+	for dir != "." && dir != "/" {
+		ft.directories[dir] = true
+		dir = path.Dir(dir)
+	}
+}
+
+func (ft *fakeTree) list(dir string) ([]Entry, error) {
+	dir = path.Clean(dir)
+
+	ft.mu.Lock()
+	ft.listed = append(ft.listed, dir)
+	ft.mu.Unlock()
+
+	if _, ok := ft.files[dir]; ok {
+		return nil, ErrFile
+	}
+	if !ft.directories[dir] {
+		return nil, ErrFileNotFound
+	}
+
+	var res []Entry
+	for p, content := range ft.files {
+		if path.Dir(p) == dir {
+			res = append(res, Entry{Name: path.Base(p), Size: int64(len(content)), Mode: ModeAllReadWrite})
+		}
+	}
+	for p := range ft.directories {
+		if p != dir && path.Dir(p) == dir {
+			res = append(res, Entry{Name: path.Base(p), Mode: ModeAllReadWriteExecute, IsDirectory: true})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
+
+func (ft *fakeTree) exists(p string) (bool, error) {
+	p = path.Clean(p)
+	_, ok := ft.files[p]
+	return ok || ft.directories[p], nil
+}
+
+func (ft *fakeTree) read(p string) (Content, error) {
+	if ft.directories[path.Clean(p)] {
+		return nil, ErrDirectory
+	}
+	content, ok := ft.files[path.Clean(p)]
+	if !ok {
+		return nil, ErrFileNotFound
+	}
+	return Content(content), nil
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{pattern: "*.yaml", name: "config.yaml", expected: true},
+		{pattern: "*.yaml", name: "conf.d/config.yaml", expected: false},
+		{pattern: "conf.d/?.yaml", name: "conf.d/a.yaml", expected: true},
+		{pattern: "conf.d/?.yaml", name: "conf.d/ab.yaml", expected: false},
+		{pattern: "conf.d/[a-c].yaml", name: "conf.d/b.yaml", expected: true},
+		{pattern: "conf.d/[^a-c].yaml", name: "conf.d/b.yaml", expected: false},
+		{pattern: "conf.d/**/*.yaml", name: "conf.d/config.yaml", expected: true},
+		{pattern: "conf.d/**/*.yaml", name: "conf.d/a/b/c/config.yaml", expected: true},
+		{pattern: "conf.d/**/*.yaml", name: "other/config.yaml", expected: false},
+		{pattern: "**", name: "a/b/c", expected: true},
+		{pattern: "a/**", name: "a", expected: true},
+		{pattern: "a/**/b/*.txt", name: "a/x/b/y/file.txt", expected: false},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.pattern+" against "+c.name, func(t *testing.T) {
+			t.Parallel()
+
+			// WHEN
+			result, err := Match(c.pattern, c.name)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, result)
+		})
+	}
+	t.Run("it should report malformed pattern", func(t *testing.T) {
+		t.Parallel()
+
+		// WHEN
+		_, err := Match("conf.d/[a-", "conf.d/a")
+
+		// THEN
+		require.ErrorIs(t, err, path.ErrBadPattern)
+	})
+}
+
+func TestGlob(t *testing.T) {
+	files := map[string]string{
+		"conf.d/a.yaml":          "a: 1",
+		"conf.d/b.yml":           "b: 2",
+		"conf.d/nested/c.yaml":   "c: 3",
+		"conf.d/nested/x/d.yaml": "d: 4",
+		"conf.d/empty/":          "",
+		"other/e.yaml":           "e: 5",
+	}
+
+	t.Run("it should resolve pattern with listing handler of custom backend", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		result, err := Glob(fs, "conf.d/*.y*ml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conf.d/a.yaml", "conf.d/b.yml"}, result)
+	})
+	t.Run("it should resolve doublestar pattern", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		result, err := Glob(fs, "conf.d/**/*.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conf.d/a.yaml", "conf.d/nested/c.yaml", "conf.d/nested/x/d.yaml"}, result)
+	})
+	t.Run("it should resolve trailing doublestar to directory itself and every descendant", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		result, err := Glob(fs, "conf.d/nested/**")
+		empty, emptyErr := Glob(fs, "conf.d/empty/**")
+
+		// THEN
+		require.NoError(t, err)
+		require.NoError(t, emptyErr)
+		assert.Equal(t, []string{"conf.d/nested", "conf.d/nested/c.yaml", "conf.d/nested/x", "conf.d/nested/x/d.yaml"}, result)
+		assert.Equal(t, []string{"conf.d/empty"}, empty)
+		for _, p := range append(result, empty...) {
+			matched, mErr := Match("conf.d/*/**", p)
+			require.NoError(t, mErr)
+			assert.True(t, matched, p)
+		}
+	})
+	t.Run("it should list directories through middlewares of wrapped filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var listed []string
+		fs := Wrap(newFakeTreeFilesystem(t, files), func(next Invoker) Invoker {
+			return func(call Call) (Result, error) {
+				if call.Operation == OperationListFilesIn {
+					listed = append(listed, call.Path)
+				}
+				return next(call)
+			}
+		})
+
+		// WHEN
+		result, err := Glob(fs, "conf.d/*/*.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conf.d/nested/c.yaml"}, result)
+		assert.Equal(t, []string{"conf.d", "conf.d/empty", "conf.d/nested"}, listed)
+	})
+	t.Run("it should resolve character classes and wildcards in directories", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		result, err := Glob(fs, "[co]*/*.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conf.d/a.yaml", "other/e.yaml"}, result)
+	})
+	t.Run("it should not list directories that are part of static prefix", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		tree := &fakeTree{}
+		fs := newFakeTreeFilesystem(t, files, func(ft *fakeTree) { tree = ft })
+
+		// WHEN
+		result, err := Glob(fs, "conf.d/nested/*.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conf.d/nested/c.yaml"}, result)
+		assert.Equal(t, []string{"conf.d/nested"}, tree.listed)
+	})
+	t.Run("it should return empty result if nothing matches", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		result, err := Glob(fs, "missing/**/*.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Empty(t, result)
+	})
+	t.Run("it should pass execution to native handler if provided", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := New(
+			OptionGlobHandler(func(pattern string) ([]string, error) {
+				assert.Equal(t, "conf.d/**/*.yaml", pattern)
+				return []string{"conf.d/native.yaml"}, nil
+			}),
+			OptionListFilesInHandler(func(string) ([]Entry, error) {
+				t.Fatal("listing handler is not expected to be called")
+				return nil, nil
+			}),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		result, err := Glob(fs, "conf.d/**/*.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conf.d/native.yaml"}, result)
+	})
+	t.Run("it should resolve pattern with default handlers", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			require.NoError(t, os.MkdirAll(path.Join(workdir, "conf.d", "nested"), 0700))
+			require.NoError(t, os.WriteFile(path.Join(workdir, "conf.d", "a.yaml"), []byte("a: 1"), 0600))
+			require.NoError(t, os.WriteFile(path.Join(workdir, "conf.d", "nested", "b.yaml"), []byte("b: 2"), 0600))
+			require.NoError(t, os.WriteFile(path.Join(workdir, "conf.d", "nested", "c.txt"), []byte("c"), 0600))
+
+			// WHEN
+			result, err := Glob(fs, path.Join(workdir, "conf.d", "**", "*.yaml"))
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, []string{
+				path.Join(workdir, "conf.d", "a.yaml"),
+				path.Join(workdir, "conf.d", "nested", "b.yaml"),
+			}, result)
+		})
+	})
+}
+
+func TestReadContentOfMatching(t *testing.T) {
+	t.Run("it should read content of every matching file", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, map[string]string{
+			"conf.d/a.yaml":        "yamlValue: true",
+			"conf.d/nested/b.yaml": "yamlValue: false",
+			"conf.d/dir.yaml/":     "",
+		})
+
+		// WHEN
+		result, err := ReadContentOfMatching(fs, "conf.d/**/*.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+
+		var dto testDecodeResult
+		require.NoError(t, result["conf.d/a.yaml"].YAMLDecode(&dto))
+		assert.True(t, dto.Value)
+		require.NoError(t, result["conf.d/nested/b.yaml"].YAMLDecode(&dto))
+		assert.False(t, dto.Value)
+	})
+}
@@ -1,12 +1,24 @@
 package filesystem
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"syscall"
 )
 
+var errnoSentinels = map[syscall.Errno]error{
+	syscall.EACCES:    ErrPermissionDenied,
+	syscall.EPERM:     ErrPermissionDenied,
+	syscall.EROFS:     ErrReadOnly,
+	syscall.ENOSPC:    ErrNoSpace,
+	syscall.ENOTEMPTY: ErrNotEmpty,
+	syscall.EFBIG:     ErrTooLarge,
+}
+
 type (
 	// ReadContentOfHandlerFunc is expected to be provided for as handler for ReadContentOf.
 	ReadContentOfHandlerFunc func(string) (Content, error)
@@ -28,42 +40,77 @@ type (
 
 	// CreateDirectoryHandlerFunc is expected to be provided for as handler for CreateDirectory.
 	CreateDirectoryHandlerFunc func(string, Arguments) error
+
+	// RemoveHandlerFunc is expected to be provided for as handler for Remove.
+	RemoveHandlerFunc func(string, Arguments) error
+
+	// MoveHandlerFunc is expected to be provided for as handler for Move (source, target).
+	MoveHandlerFunc func(string, string, Arguments) error
+
+	// CopyHandlerFunc is expected to be provided for as handler for Copy (source, target).
+	CopyHandlerFunc func(string, string, Arguments) error
+
+	// ListFilesInHandlerFunc is expected to be provided for as handler for ListFilesIn.
+	ListFilesInHandlerFunc func(string) ([]Entry, error)
+
+	// GlobHandlerFunc can be provided as handler for Glob when backend is capable of resolving patterns natively.
+	// Without it Glob is resolved with ListFilesInHandlerFunc.
+	GlobHandlerFunc func(string) ([]string, error)
 )
 
-func readContentOfDefaultHandler(path string) (Content, error) {
-	s, err := streamContentOfDefaultHandler(path)
+// ReadContentOfDefaultHandler reads entire content of file from local filesystem.
+func ReadContentOfDefaultHandler(path string) (Content, error) {
+	s, err := StreamContentOfDefaultHandler(path)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		_ = s.Close()
+	}()
+
 	res, err := io.ReadAll(s)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	return res, nil
 }
 
-func streamContentOfDefaultHandler(path string) (io.ReadCloser, error) {
+// StreamContentOfDefaultHandler opens file from local filesystem for reading.
+func StreamContentOfDefaultHandler(path string) (io.ReadCloser, error) {
 	f, err := os.OpenFile(path, os.O_RDONLY, os.FileMode(0600)) //nolint:gosec
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrFileNotFound
 		}
-		return nil, err
+		return nil, translateError(err)
+	}
+
+	fi, sErr := f.Stat()
+	if sErr != nil {
+		_ = f.Close()
+		return nil, translateError(sErr)
 	}
+	if fi.IsDir() {
+		_ = f.Close()
+		return nil, ErrDirectory
+	}
+
 	return f, nil
 }
 
-func checkIfExistsDefaultHandler(path string) (bool, error) {
+// CheckIfExistsDefaultHandler verifies existence of path in local filesystem.
+func CheckIfExistsDefaultHandler(path string) (bool, error) {
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
 			return false, nil
 		}
-		return false, err
+		return false, translateError(err)
 	}
 	return true, nil
 }
 
-func createFileDefaultHandler(path string, arg Arguments) (err error) {
+// CreateFileDefaultHandler creates empty file in local filesystem.
+func CreateFileDefaultHandler(path string, arg Arguments) (err error) {
 	dir, _ := filepath.Split(path)
 
 	di, dErr := os.Stat(dir)
@@ -75,11 +122,11 @@ func createFileDefaultHandler(path string, arg Arguments) (err error) {
 			}
 
 			if mkdErr := os.MkdirAll(dir, ModeUserReadWriteExecute.asFileMode()); mkdErr != nil {
-				err = fmt.Errorf("cannot create directory structure: %w", mkdErr)
+				err = fmt.Errorf("cannot create directory structure: %w", translateError(mkdErr))
 				return
 			}
 		} else {
-			err = dErr
+			err = translateError(dErr)
 			return
 		}
 	}
@@ -91,7 +138,7 @@ func createFileDefaultHandler(path string, arg Arguments) (err error) {
 
 	fi, fErr := os.Stat(path)
 	if fErr != nil && !os.IsNotExist(fErr) {
-		err = fErr
+		err = translateError(fErr)
 		return
 	}
 
@@ -106,19 +153,20 @@ func createFileDefaultHandler(path string, arg Arguments) (err error) {
 
 	f, fErr := os.OpenFile(path, os.O_CREATE|os.O_TRUNC, arg.Mode.asFileMode()) //nolint:gosec
 	if fErr != nil {
-		err = fErr
+		err = translateError(fErr)
 		return
 	}
 	defer func() {
 		if cErr := f.Close(); cErr != nil {
-			err = cErr
+			err = translateError(cErr)
 		}
 	}()
 
 	return nil
 }
 
-func writeContentToDefaultHandler(path string, content []byte, arg Arguments) (err error) {
+// WriteContentToDefaultHandler appends/overwrites content of file in local filesystem.
+func WriteContentToDefaultHandler(path string, content []byte, arg Arguments) (err error) {
 	if oErr := arg.ContentOperation.assetValid(); oErr != nil {
 		err = oErr
 		return
@@ -135,19 +183,19 @@ func writeContentToDefaultHandler(path string, content []byte, arg Arguments) (e
 			err = ErrFileNotFound
 			return
 		}
-		err = fErr
+		err = translateError(fErr)
 		return
 	}
 	defer func() {
 		if cErr := f.Close(); cErr != nil {
-			err = cErr
+			err = translateError(cErr)
 			return
 		}
 	}()
 
 	n, wErr := f.Write(content)
 	if wErr != nil {
-		err = wErr
+		err = translateError(wErr)
 		return
 	}
 
@@ -159,7 +207,8 @@ func writeContentToDefaultHandler(path string, content []byte, arg Arguments) (e
 	return
 }
 
-func streamContentToDefaultHandler(path string, content io.Reader, arg Arguments) (err error) {
+// StreamContentToDefaultHandler appends/overwrites content of file in local filesystem with content of io.Reader.
+func StreamContentToDefaultHandler(path string, content io.Reader, arg Arguments) (err error) {
 	if oErr := arg.ContentOperation.assetValid(); oErr != nil {
 		err = oErr
 		return
@@ -176,25 +225,26 @@ func streamContentToDefaultHandler(path string, content io.Reader, arg Arguments
 			err = ErrFileNotFound
 			return
 		}
-		err = fErr
+		err = translateError(fErr)
 		return
 	}
 	defer func() {
 		if cErr := f.Close(); cErr != nil {
-			err = cErr
+			err = translateError(cErr)
 			return
 		}
 	}()
 
 	if _, wErr := io.Copy(f, content); wErr != nil {
-		err = wErr
+		err = translateError(wErr)
 		return
 	}
 
 	return
 }
 
-func createDirectoryDefaultHandler(path string, arg Arguments) error {
+// CreateDirectoryDefaultHandler creates directory in local filesystem.
+func CreateDirectoryDefaultHandler(path string, arg Arguments) error {
 	dir, _ := filepath.Split(path)
 
 	di, dErr := os.Stat(dir)
@@ -205,10 +255,10 @@ func createDirectoryDefaultHandler(path string, arg Arguments) error {
 			}
 
 			if mkdErr := os.MkdirAll(dir, ModeUserReadWriteExecute.asFileMode()); mkdErr != nil {
-				return fmt.Errorf("cannot create directory structure: %w", mkdErr)
+				return fmt.Errorf("cannot create directory structure: %w", translateError(mkdErr))
 			}
 		} else {
-			return dErr
+			return translateError(dErr)
 		}
 	}
 
@@ -218,7 +268,7 @@ func createDirectoryDefaultHandler(path string, arg Arguments) error {
 
 	tdi, fErr := os.Stat(path)
 	if fErr != nil && !os.IsNotExist(fErr) {
-		return fErr
+		return translateError(fErr)
 	}
 
 	if tdi != nil {
@@ -229,8 +279,223 @@ func createDirectoryDefaultHandler(path string, arg Arguments) error {
 	}
 
 	if mkdErr := os.Mkdir(path, arg.Mode.asFileMode()); mkdErr != nil {
-		return mkdErr
+		return translateError(mkdErr)
 	}
 
 	return nil
 }
+
+// RemoveDefaultHandler removes file, symlink or empty directory (or directory with its content if allowed)
+// from local filesystem.
+func RemoveDefaultHandler(path string, arg Arguments) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrFileNotFound
+		}
+		return translateError(err)
+	}
+
+	if fi.IsDir() && arg.Recursive {
+		return translateError(os.RemoveAll(path))
+	}
+	return translateError(os.Remove(path))
+}
+
+// MoveDefaultHandler renames file or directory in local filesystem. When source and target are placed
+// on different devices content is copied to target and removed from source.
+func MoveDefaultHandler(source, target string, arg Arguments) error {
+	if _, err := os.Lstat(source); err != nil {
+		if os.IsNotExist(err) {
+			return ErrFileNotFound
+		}
+		return translateError(err)
+	}
+	if err := prepareTransferTarget(target, arg); err != nil {
+		return err
+	}
+
+	err := os.Rename(source, target)
+	if errors.Is(err, syscall.EXDEV) {
+		arg.Recursive = true
+		if err = CopyDefaultHandler(source, target, arg); err != nil {
+			return err
+		}
+		err = os.RemoveAll(source)
+	}
+
+	return translateError(err)
+}
+
+// CopyDefaultHandler copies file (or directory with its content if allowed) in local filesystem keeping its mode.
+func CopyDefaultHandler(source, target string, arg Arguments) error {
+	si, err := os.Stat(source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrFileNotFound
+		}
+		return translateError(err)
+	}
+	if si.IsDir() && !arg.Recursive {
+		return ErrDirectory
+	}
+	if err = prepareTransferTarget(target, arg); err != nil {
+		return err
+	}
+
+	if !si.IsDir() {
+		return copyLocalFile(source, target, si.Mode().Perm())
+	}
+
+	return filepath.WalkDir(source, func(p string, de fs.DirEntry, wErr error) error {
+		if wErr != nil {
+			return translateError(wErr)
+		}
+		rel, rErr := filepath.Rel(source, p)
+		if rErr != nil {
+			return rErr
+		}
+		fi, iErr := de.Info()
+		if iErr != nil {
+			return translateError(iErr)
+		}
+
+		dst := filepath.Join(target, rel)
+		if de.IsDir() {
+			if mkdErr := os.Mkdir(dst, fi.Mode().Perm()|ModeUserReadWriteExecute.asFileMode()); mkdErr != nil && !os.IsExist(mkdErr) {
+				return translateError(mkdErr)
+			}
+			return nil
+		}
+		return copyLocalFile(p, dst, fi.Mode().Perm())
+	})
+}
+
+// prepareTransferTarget verifies if target can be overwritten and creates its directory structure (if allowed).
+func prepareTransferTarget(target string, arg Arguments) error {
+	ti, err := os.Lstat(target)
+	if err != nil && !os.IsNotExist(err) {
+		return translateError(err)
+	}
+	if ti != nil && !arg.AllowOverwrite {
+		if ti.IsDir() {
+			return ErrDirectoryFound
+		}
+		return ErrFileFound
+	}
+
+	dir, _ := filepath.Split(target)
+	if dir == "" {
+		return nil
+	}
+	di, err := os.Stat(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return translateError(err)
+		}
+		if !arg.AllowCreationOfDirectoryStructure {
+			return fmt.Errorf("creation of non-existing directory structure is forbidden by current settings: %w", ErrUnresolvableDirectoryStructure)
+		}
+		if mkdErr := os.MkdirAll(dir, arg.DirectoryStructureMode.asFileMode()); mkdErr != nil {
+			return fmt.Errorf("cannot create directory structure: %w", translateError(mkdErr))
+		}
+		return nil
+	}
+	if !di.IsDir() {
+		return fmt.Errorf("location structure does not contain valid directory as target: %w", ErrUnresolvableDirectoryStructure)
+	}
+
+	return nil
+}
+
+func copyLocalFile(source, target string, mode os.FileMode) (err error) {
+	src, err := os.Open(source) //nolint:gosec
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode) //nolint:gosec
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		if cErr := dst.Close(); cErr != nil && err == nil {
+			err = translateError(cErr)
+		}
+	}()
+
+	if _, err = io.Copy(dst, src); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// ListFilesInDefaultHandler lists entries of directory in local filesystem.
+func ListFilesInDefaultHandler(path string) ([]Entry, error) {
+	di, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+		return nil, translateError(err)
+	}
+	if !di.IsDir() {
+		return nil, ErrFile
+	}
+
+	des, err := os.ReadDir(path)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	res := make([]Entry, 0, len(des))
+	for _, de := range des {
+		fi, iErr := de.Info()
+		if iErr != nil {
+			if os.IsNotExist(iErr) {
+				// Entry was removed between reading directory and reading its details.
+				continue
+			}
+			return nil, translateError(iErr)
+		}
+		entry := entryFromFileInfo(fi)
+		if entry.IsSymlink {
+			entry.LinkTarget, _ = os.Readlink(filepath.Join(path, entry.Name))
+		}
+		res = append(res, entry)
+	}
+
+	return res, nil
+}
+
+func entryFromFileInfo(fi os.FileInfo) Entry {
+	return Entry{
+		Name:        fi.Name(),
+		Size:        fi.Size(),
+		Mode:        Mode(fi.Mode().Perm()),
+		ModTime:     fi.ModTime(),
+		IsDirectory: fi.IsDir(),
+		IsSymlink:   fi.Mode()&os.ModeSymlink != 0,
+	}
+}
+
+// translateError wraps error reported by operating system with matching sentinel error (keeping the original one
+// available for inspection with errors.As).
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		if sentinel, ok := errnoSentinels[errno]; ok {
+			return fmt.Errorf("%w: %w", sentinel, err)
+		}
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	}
+
+	return err
+}
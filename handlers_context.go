@@ -0,0 +1,181 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/SevenOfSpades/go-just-options"
+)
+
+type (
+	// ReadContentOfContextHandlerFunc is ReadContentOfHandlerFunc receiving context of the operation (see InContext).
+	ReadContentOfContextHandlerFunc func(context.Context, string) (Content, error)
+
+	// StreamContentOfContextHandlerFunc is StreamContentOfHandlerFunc receiving context of the operation (see InContext).
+	StreamContentOfContextHandlerFunc func(context.Context, string) (io.ReadCloser, error)
+
+	// CheckIfExistsContextHandlerFunc is CheckIfExistsHandlerFunc receiving context of the operation (see InContext).
+	CheckIfExistsContextHandlerFunc func(context.Context, string) (bool, error)
+
+	// CreateFileContextHandlerFunc is CreateFileHandlerFunc receiving context of the operation (see InContext).
+	CreateFileContextHandlerFunc func(context.Context, string, Arguments) error
+
+	// WriteContentToContextHandlerFunc is WriteContentToHandlerFunc receiving context of the operation (see InContext).
+	WriteContentToContextHandlerFunc func(context.Context, string, []byte, Arguments) error
+
+	// StreamContentToContextHandlerFunc is StreamContentToHandlerFunc receiving context of the operation (see InContext).
+	StreamContentToContextHandlerFunc func(context.Context, string, io.Reader, Arguments) error
+
+	// CreateDirectoryContextHandlerFunc is CreateDirectoryHandlerFunc receiving context of the operation (see InContext).
+	CreateDirectoryContextHandlerFunc func(context.Context, string, Arguments) error
+
+	// RemoveContextHandlerFunc is RemoveHandlerFunc receiving context of the operation (see InContext).
+	RemoveContextHandlerFunc func(context.Context, string, Arguments) error
+
+	// MoveContextHandlerFunc is MoveHandlerFunc receiving context of the operation (see InContext).
+	MoveContextHandlerFunc func(context.Context, string, string, Arguments) error
+
+	// CopyContextHandlerFunc is CopyHandlerFunc receiving context of the operation (see InContext).
+	CopyContextHandlerFunc func(context.Context, string, string, Arguments) error
+
+	// ListFilesInContextHandlerFunc is ListFilesInHandlerFunc receiving context of the operation (see InContext).
+	ListFilesInContextHandlerFunc func(context.Context, string) ([]Entry, error)
+
+	// GlobContextHandlerFunc is GlobHandlerFunc receiving context of the operation (see InContext).
+	GlobContextHandlerFunc func(context.Context, string) ([]string, error)
+)
+
+func (h ReadContentOfHandlerFunc) withContext() ReadContentOfContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string) (Content, error) {
+		return h(path)
+	}
+}
+
+func (h StreamContentOfHandlerFunc) withContext() StreamContentOfContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string) (io.ReadCloser, error) {
+		return h(path)
+	}
+}
+
+func (h CheckIfExistsHandlerFunc) withContext() CheckIfExistsContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string) (bool, error) {
+		return h(path)
+	}
+}
+
+func (h CreateFileHandlerFunc) withContext() CreateFileContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string, arg Arguments) error {
+		return h(path, arg)
+	}
+}
+
+func (h WriteContentToHandlerFunc) withContext() WriteContentToContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string, content []byte, arg Arguments) error {
+		return h(path, content, arg)
+	}
+}
+
+func (h StreamContentToHandlerFunc) withContext() StreamContentToContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string, content io.Reader, arg Arguments) error {
+		return h(path, content, arg)
+	}
+}
+
+func (h CreateDirectoryHandlerFunc) withContext() CreateDirectoryContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string, arg Arguments) error {
+		return h(path, arg)
+	}
+}
+
+func (h RemoveHandlerFunc) withContext() RemoveContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string, arg Arguments) error {
+		return h(path, arg)
+	}
+}
+
+func (h MoveHandlerFunc) withContext() MoveContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, source string, target string, arg Arguments) error {
+		return h(source, target, arg)
+	}
+}
+
+func (h CopyHandlerFunc) withContext() CopyContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, source string, target string, arg Arguments) error {
+		return h(source, target, arg)
+	}
+}
+
+func (h ListFilesInHandlerFunc) withContext() ListFilesInContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string) ([]Entry, error) {
+		return h(path)
+	}
+}
+
+func (h GlobHandlerFunc) withContext() GlobContextHandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return func(_ context.Context, path string) ([]string, error) {
+		return h(path)
+	}
+}
+
+// readHandler reads handler provided under key either as context-aware one (C) or as plain one (P, adapted
+// with withContext). Default handler is returned when none was provided.
+func readHandler[P, C any](opt options.Resolver, key options.OptionKey, withContext func(P) C, defaultHandler C) (C, error) {
+	val, err := options.Read[any](opt, key)
+	if errors.Is(err, options.ErrNotFound) {
+		return defaultHandler, nil
+	}
+	if err != nil {
+		return defaultHandler, err
+	}
+
+	switch h := val.(type) {
+	case C:
+		return h, nil
+	case P:
+		return withContext(h), nil
+	}
+
+	var (
+		plain        P
+		contextAware C
+	)
+	return defaultHandler, fmt.Errorf("option '%s' is expected to be %T or %T but is %T: %w", key.String(), plain, contextAware, val, options.ErrTypeMismatch)
+}
@@ -0,0 +1,351 @@
+package httpfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionClient         options.OptionKey = `http_client`
+	optionTimeout        options.OptionKey = `http_timeout`
+	optionAuthorization  options.OptionKey = `http_authorization`
+	optionRequestEditors options.OptionKey = `http_request_editors`
+	optionBackendName    options.OptionKey = `backend_name`
+)
+
+const (
+	defaultBackendName = "http"
+
+	// maxErrorMessageLength limits amount of response body included in error of unexpected response.
+	maxErrorMessageLength = 512
+)
+
+type (
+	// RequestEditorFunc modifies every request before it's sent (e.g. to add headers).
+	RequestEditorFunc func(req *http.Request) error
+
+	client struct {
+		base    *url.URL
+		http    *http.Client
+		editors []RequestEditorFunc
+	}
+)
+
+// OptionClient changes HTTP client used for requests (default is a new http.Client without timeout).
+func OptionClient(c *http.Client) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[*http.Client](r, optionClient, c)
+	}
+}
+
+// OptionTimeout limits time of every request including reading of response body
+// (so it also limits time available for reading reader returned by StreamContentOf).
+func OptionTimeout(timeout time.Duration) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Duration](r, optionTimeout, timeout)
+	}
+}
+
+// OptionBasicAuth authenticates every request with provided username and password.
+func OptionBasicAuth(username, password string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[RequestEditorFunc](r, optionAuthorization, func(req *http.Request) error {
+			req.SetBasicAuth(username, password)
+			return nil
+		})
+	}
+}
+
+// OptionBearerToken authenticates every request with provided token.
+func OptionBearerToken(token string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[RequestEditorFunc](r, optionAuthorization, func(req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return nil
+		})
+	}
+}
+
+// OptionRequestEditor applies provided functions (in order) to every request before it's sent.
+func OptionRequestEditor(editors ...RequestEditorFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[[]RequestEditorFunc](r, optionRequestEditors, editors)
+	}
+}
+
+// OptionBackendName changes name of the backend reported in errors and to middlewares (default "http").
+func OptionBackendName(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBackendName, name)
+	}
+}
+
+// New creates filesystem.Filesystem performing every operation on server available at baseURL (see package
+// documentation for the protocol). Glob is resolved with ListFilesIn and middlewares can be applied with
+// filesystem.Wrap.
+func New(baseURL string, opts ...options.Option) (filesystem.Filesystem, error) {
+	opt := options.Resolve(opts)
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("http filesystem initialization failed: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("http filesystem initialization failed: unsupported scheme %q", base.Scheme)
+	}
+
+	optClient, err := options.ReadOrDefault[*http.Client](opt, optionClient, &http.Client{})
+	if err != nil {
+		return nil, fmt.Errorf("http filesystem initialization failed: %w", err)
+	}
+	optTimeout, err := options.ReadOrDefault[time.Duration](opt, optionTimeout, 0)
+	if err != nil {
+		return nil, fmt.Errorf("http filesystem initialization failed: %w", err)
+	}
+	optAuthorization, err := options.ReadOrDefault[RequestEditorFunc](opt, optionAuthorization, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http filesystem initialization failed: %w", err)
+	}
+	optRequestEditors, err := options.ReadOrDefault[[]RequestEditorFunc](opt, optionRequestEditors, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http filesystem initialization failed: %w", err)
+	}
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("http filesystem initialization failed: %w", err)
+	}
+
+	if optTimeout > 0 {
+		withTimeout := *optClient
+		withTimeout.Timeout = optTimeout
+		optClient = &withTimeout
+	}
+
+	c := &client{base: base, http: optClient}
+	if optAuthorization != nil {
+		c.editors = append(c.editors, optAuthorization)
+	}
+	c.editors = append(c.editors, optRequestEditors...)
+
+	return filesystem.New(
+		filesystem.OptionBackendName(optBackendName),
+		filesystem.OptionReadContentOfContextHandler(c.readContentOf),
+		filesystem.OptionStreamContentOfContextHandler(c.streamContentOf),
+		filesystem.OptionCheckIfExistsContextHandler(c.checkIfExists),
+		filesystem.OptionCreateFileContextHandler(c.createFile),
+		filesystem.OptionWriteContentToContextHandler(func(ctx context.Context, p string, content []byte, arg filesystem.Arguments) error {
+			return c.write(ctx, p, bytes.NewReader(content), arg)
+		}),
+		filesystem.OptionStreamContentToContextHandler(c.write),
+		filesystem.OptionCreateDirectoryContextHandler(c.createDirectory),
+		filesystem.OptionRemoveContextHandler(c.remove),
+		filesystem.OptionMoveContextHandler(func(ctx context.Context, source, target string, arg filesystem.Arguments) error {
+			return c.transfer(ctx, operationMove, source, target, arg)
+		}),
+		filesystem.OptionCopyContextHandler(func(ctx context.Context, source, target string, arg filesystem.Arguments) error {
+			return c.transfer(ctx, operationCopy, source, target, arg)
+		}),
+		filesystem.OptionListFilesInContextHandler(c.listFilesIn),
+	)
+}
+
+func (c *client) readContentOf(ctx context.Context, p string) (filesystem.Content, error) {
+	rc, err := c.streamContentOf(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	return io.ReadAll(rc)
+}
+
+func (c *client) streamContentOf(ctx context.Context, p string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, p, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *client) checkIfExists(ctx context.Context, p string) (bool, error) {
+	resp, err := c.do(ctx, http.MethodHead, p, nil, nil)
+	if err != nil {
+		if errors.Is(err, filesystem.ErrFileNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	_ = resp.Body.Close()
+
+	return true, nil
+}
+
+func (c *client) createFile(ctx context.Context, p string, arg filesystem.Arguments) error {
+	return c.send(ctx, http.MethodPost, p, url.Values{
+		queryOperation:   {operationCreateFile},
+		queryMode:        {formatMode(arg.Mode)},
+		queryOverwrite:   {strconv.FormatBool(arg.AllowOverwrite)},
+		queryParents:     {strconv.FormatBool(arg.AllowCreationOfDirectoryStructure)},
+		queryParentsMode: {formatMode(arg.DirectoryStructureMode)},
+	}, nil)
+}
+
+func (c *client) write(ctx context.Context, p string, content io.Reader, arg filesystem.Arguments) error {
+	method := http.MethodPatch
+	switch {
+	case arg.ContentOperation.Is(filesystem.ContentOperationOverwrite):
+		method = http.MethodPut
+	case !arg.ContentOperation.Is(filesystem.ContentOperationAppend):
+		return filesystem.ErrUnsupportedContentOperation
+	}
+	return c.send(ctx, method, p, nil, content)
+}
+
+func (c *client) createDirectory(ctx context.Context, p string, arg filesystem.Arguments) error {
+	return c.send(ctx, http.MethodPost, p, url.Values{
+		queryOperation:   {operationCreateDirectory},
+		queryMode:        {formatMode(arg.Mode)},
+		queryParents:     {strconv.FormatBool(arg.AllowCreationOfDirectoryStructure)},
+		queryParentsMode: {formatMode(arg.DirectoryStructureMode)},
+	}, nil)
+}
+
+func (c *client) remove(ctx context.Context, p string, arg filesystem.Arguments) error {
+	return c.send(ctx, http.MethodDelete, p, url.Values{
+		queryRecursive: {strconv.FormatBool(arg.Recursive)},
+	}, nil)
+}
+
+func (c *client) transfer(ctx context.Context, operation, source, target string, arg filesystem.Arguments) error {
+	return c.send(ctx, http.MethodPost, source, url.Values{
+		queryOperation:   {operation},
+		queryTarget:      {cleanPath(target)},
+		queryOverwrite:   {strconv.FormatBool(arg.AllowOverwrite)},
+		queryParents:     {strconv.FormatBool(arg.AllowCreationOfDirectoryStructure)},
+		queryParentsMode: {formatMode(arg.DirectoryStructureMode)},
+		queryRecursive:   {strconv.FormatBool(arg.Recursive)},
+	}, nil)
+}
+
+func (c *client) listFilesIn(ctx context.Context, p string) ([]filesystem.Entry, error) {
+	resp, err := c.do(ctx, http.MethodGet, p, url.Values{queryOperation: {operationList}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var entries []wireEntry
+	if err = json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("cannot decode list of entries: %w", err)
+	}
+
+	res := make([]filesystem.Entry, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, e.toEntry())
+	}
+	return res, nil
+}
+
+// send performs request and discards its response.
+func (c *client) send(ctx context.Context, method, p string, query url.Values, body io.Reader) error {
+	resp, err := c.do(ctx, method, p, query, body)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// do performs request and returns response with successful status (body has to be closed by the caller)
+// or error restored from failed one.
+func (c *client) do(ctx context.Context, method, p string, query url.Values, body io.Reader) (*http.Response, error) {
+	u := c.base.JoinPath(cleanPath(p))
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	for _, edit := range c.editors {
+		if err = edit(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+	return nil, responseError(resp)
+}
+
+// responseError restores error of failed operation from its response (header, JSON body or status code).
+func responseError(resp *http.Response) error {
+	if err := filesystem.ErrorOfKind(resp.Header.Get(HeaderErrorKind)); err != nil {
+		return err
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorMessageLength))
+	var body errorBody
+	if json.Unmarshal(message, &body) == nil && body.Code != "" {
+		for _, e := range protocolErrors {
+			if e.code == body.Code {
+				return fmt.Errorf("%w: %s", e.err, body.Message)
+			}
+		}
+		message = []byte(body.Message)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return filesystem.ErrFileNotFound
+	case http.StatusConflict:
+		return filesystem.ErrFileFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s", filesystem.ErrPermissionDenied, resp.Status)
+	case http.StatusRequestEntityTooLarge:
+		return filesystem.ErrTooLarge
+	case http.StatusInsufficientStorage:
+		return filesystem.ErrNoSpace
+	case http.StatusMethodNotAllowed:
+		return filesystem.ErrReadOnly
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	}
+
+	if msg := strings.TrimSpace(string(message)); msg != "" {
+		return fmt.Errorf("unexpected response status %s: %s", resp.Status, msg)
+	}
+	return fmt.Errorf("unexpected response status %s", resp.Status)
+}
+
+// cleanPath converts path to the form used in URLs (slash separated, rooted, without "." and "..").
+func cleanPath(p string) string {
+	return path.Clean("/" + strings.ReplaceAll(p, `\`, "/"))
+}
+
+func formatMode(mode filesystem.Mode) string {
+	return strconv.FormatUint(uint64(mode), 8)
+}
@@ -0,0 +1,268 @@
+package httpfs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+type recordedRequest struct {
+	method string
+	path   string
+	query  string
+	body   string
+	header http.Header
+}
+
+// newRecordingServer starts server recording every request and responding with provided handler.
+func newRecordingServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		requests []recordedRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, recordedRequest{
+			method: r.Method,
+			path:   r.URL.EscapedPath(),
+			query:  r.URL.RawQuery,
+			body:   string(body),
+			header: r.Header.Clone(),
+		})
+		mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("it should read content with GET request", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, requests := newRecordingServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("TEST"))
+		})
+		fs, err := New(srv.URL+"/files", OptionBearerToken("secret"))
+		require.NoError(t, err)
+
+		// WHEN
+		content, err := filesystem.ReadContentOf(fs, "dir/test file.txt")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("TEST"), content)
+		require.Len(t, requests(), 1)
+		assert.Equal(t, http.MethodGet, requests()[0].method)
+		assert.Equal(t, "/files/dir/test%20file.txt", requests()[0].path)
+		assert.Equal(t, "Bearer secret", requests()[0].header.Get("Authorization"))
+	})
+	t.Run("it should check existence with HEAD request", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, requests := newRecordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing.txt" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		fs, err := New(srv.URL)
+		require.NoError(t, err)
+
+		// WHEN
+		exists, existsErr := filesystem.CheckIfExists(fs, "test.txt")
+		missing, missingErr := filesystem.CheckIfExists(fs, "missing.txt")
+
+		// THEN
+		require.NoError(t, existsErr)
+		require.NoError(t, missingErr)
+		assert.True(t, exists)
+		assert.False(t, missing)
+		assert.Equal(t, http.MethodHead, requests()[0].method)
+	})
+	t.Run("it should write content with PUT or PATCH request depending on content operation", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, requests := newRecordingServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		fs, err := New(srv.URL, OptionBasicAuth("user", "password"))
+		require.NoError(t, err)
+
+		// WHEN
+		overwriteErr := filesystem.WriteContentTo(fs, "test.txt", "NEW", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite))
+		appendErr := filesystem.StreamContentTo(fs, "test.txt", strings.NewReader("MORE"))
+
+		// THEN
+		require.NoError(t, overwriteErr)
+		require.NoError(t, appendErr)
+		require.Len(t, requests(), 2)
+		assert.Equal(t, http.MethodPut, requests()[0].method)
+		assert.Equal(t, "NEW", requests()[0].body)
+		assert.Equal(t, http.MethodPatch, requests()[1].method)
+		assert.Equal(t, "MORE", requests()[1].body)
+		user, password, ok := (&http.Request{Header: requests()[1].header}).BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "password", password)
+	})
+	t.Run("it should pass arguments of operations as query parameters", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, requests := newRecordingServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+		fs, err := New(srv.URL)
+		require.NoError(t, err)
+
+		// WHEN
+		createErr := filesystem.CreateFile(fs, "a/test.txt", filesystem.WithMode(filesystem.ModeUserReadWrite), filesystem.WithAllowCreationOfDirectoryStructure(true))
+		moveErr := filesystem.Move(fs, "a/test.txt", "b/test.txt", filesystem.WithAllowOverwrite(true))
+		removeErr := filesystem.Remove(fs, "a", filesystem.WithRecursive(true))
+
+		// THEN
+		require.NoError(t, createErr)
+		require.NoError(t, moveErr)
+		require.NoError(t, removeErr)
+		require.Len(t, requests(), 3)
+		assert.Equal(t, http.MethodPost, requests()[0].method)
+		assert.Equal(t, "mode=600&op=create-file&overwrite=false&parents=true&parents-mode=777", requests()[0].query)
+		assert.Equal(t, http.MethodPost, requests()[1].method)
+		assert.Equal(t, "/a/test.txt", requests()[1].path)
+		assert.Contains(t, requests()[1].query, "op=move&overwrite=true")
+		assert.Contains(t, requests()[1].query, "target=%2Fb%2Ftest.txt")
+		assert.Equal(t, http.MethodDelete, requests()[2].method)
+		assert.Equal(t, "recursive=true", requests()[2].query)
+	})
+	t.Run("it should list entries and resolve glob with them", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, _ := newRecordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, operationList, r.URL.Query().Get(queryOperation))
+			_ = json.NewEncoder(w).Encode([]wireEntry{
+				{Name: "a.txt", Size: 4, Mode: uint32(filesystem.ModeAllReadWrite)},
+				{Name: "b.yaml", Size: 2, Mode: uint32(filesystem.ModeAllReadWrite)},
+			})
+		})
+		fs, err := New(srv.URL)
+		require.NoError(t, err)
+
+		// WHEN
+		entries, listErr := filesystem.ListFilesIn(fs, ".")
+		paths, globErr := filesystem.Glob(fs, "*.txt")
+
+		// THEN
+		require.NoError(t, listErr)
+		require.NoError(t, globErr)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "a.txt", entries[0].Name)
+		assert.Equal(t, int64(4), entries[0].Size)
+		assert.Equal(t, filesystem.ModeAllReadWrite, entries[0].Mode)
+		assert.Equal(t, []string{"a.txt"}, paths)
+	})
+	t.Run("it should restore sentinel errors from response", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, _ := newRecordingServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/missing.txt":
+				w.WriteHeader(http.StatusNotFound)
+			case "/conflict.txt":
+				w.WriteHeader(http.StatusConflict)
+			case "/directory":
+				w.Header().Set(HeaderErrorKind, "ErrDirectoryFound")
+				w.WriteHeader(http.StatusConflict)
+			default:
+				http.Error(w, "broken", http.StatusInternalServerError)
+			}
+		})
+		fs, err := New(srv.URL)
+		require.NoError(t, err)
+
+		// WHEN
+		_, errMissing := filesystem.ReadContentOf(fs, "missing.txt")
+		errConflict := filesystem.CreateFile(fs, "conflict.txt")
+		errDirectory := filesystem.CreateDirectory(fs, "directory")
+		errOther := filesystem.CreateDirectory(fs, "other")
+
+		// THEN
+		require.ErrorIs(t, errMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errConflict, filesystem.ErrFileFound)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectoryFound)
+		require.ErrorContains(t, errOther, "unexpected response status 500 Internal Server Error: broken")
+	})
+	t.Run("it should fail requests exceeding timeout", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, _ := newRecordingServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		})
+		fs, err := New(srv.URL, OptionClient(srv.Client()), OptionTimeout(20*time.Millisecond))
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = filesystem.ReadContentOf(fs, "test.txt")
+
+		// THEN
+		require.Error(t, err)
+		assert.Zero(t, srv.Client().Timeout)
+	})
+	t.Run("it should cancel requests once bound context is done", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, _ := newRecordingServer(t, func(_ http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		})
+		fs, err := New(srv.URL, OptionClient(srv.Client()))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		// WHEN
+		started := time.Now()
+		_, err = filesystem.ReadContentOf(filesystem.InContext(fs, ctx), "test.txt")
+
+		// THEN
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(started), 5*time.Second)
+	})
+	t.Run("it should reject unsupported base URL", func(t *testing.T) {
+		t.Parallel()
+
+		// WHEN
+		_, err := New("ftp://example.com")
+
+		// THEN
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,72 @@
+// Package httpfs provides filesystem.Filesystem backed by remote server speaking simple HTTP protocol.
+//
+// Every path is appended to base URL of the server and operations are mapped to requests as follows:
+//
+//	ReadContentOf, StreamContentOf  GET     {path}
+//	CheckIfExists                   HEAD    {path}
+//	WriteContentTo, StreamContentTo PUT     {path} (overwrite) or PATCH {path} (append)
+//	CreateFile                      POST    {path}?op=create-file&mode=&overwrite=&parents=&parents-mode=
+//	CreateDirectory                 POST    {path}?op=create-directory&mode=&parents=&parents-mode=
+//	Remove                          DELETE  {path}?recursive=
+//	Move, Copy                      POST    {path}?op=move|copy&target=&overwrite=&parents=&parents-mode=&recursive=
+//	ListFilesIn                     GET     {path}?op=list (JSON array of entries)
+//
+// GET supports Range and conditional headers, PUT and PATCH accept If-Match with ETag returned by GET.
+//
+// Modes are passed as octal numbers and flags as "true" or "false". Failures are reported with status code
+// (404 for filesystem.ErrFileNotFound, 409 for filesystem.ErrFileFound, etc.) and name of the sentinel error
+// (see filesystem.ErrorKind) in X-Filesystem-Error header, so the client restores exactly the same error.
+// Body of failed response is a JSON object: {"code": "ErrFileNotFound", "message": "..."}.
+//
+// Server side of the protocol is provided by NewHandler exposing any filesystem.Filesystem.
+package httpfs
+
+import (
+	"time"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	// HeaderErrorKind carries name of the sentinel error (see filesystem.ErrorKind) of failed operation.
+	HeaderErrorKind = "X-Filesystem-Error"
+)
+
+const (
+	queryOperation   = "op"
+	queryMode        = "mode"
+	queryOverwrite   = "overwrite"
+	queryParents     = "parents"
+	queryParentsMode = "parents-mode"
+	queryRecursive   = "recursive"
+	queryTarget      = "target"
+
+	operationCreateFile      = "create-file"
+	operationCreateDirectory = "create-directory"
+	operationMove            = "move"
+	operationCopy            = "copy"
+	operationList            = "list"
+)
+
+// wireEntry is JSON representation of filesystem.Entry returned by ListFilesIn.
+type wireEntry struct {
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	Mode        uint32    `json:"mode"`
+	ModTime     time.Time `json:"mod_time"`
+	IsDirectory bool      `json:"is_directory"`
+	IsSymlink   bool      `json:"is_symlink,omitempty"`
+	LinkTarget  string    `json:"link_target,omitempty"`
+}
+
+func (e wireEntry) toEntry() filesystem.Entry {
+	return filesystem.Entry{
+		Name:        e.Name,
+		Size:        e.Size,
+		Mode:        filesystem.Mode(e.Mode),
+		ModTime:     e.ModTime,
+		IsDirectory: e.IsDirectory,
+		IsSymlink:   e.IsSymlink,
+		LinkTarget:  e.LinkTarget,
+	}
+}
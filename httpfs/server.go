@@ -0,0 +1,575 @@
+package httpfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SevenOfSpades/go-just-options"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionAuthenticator options.OptionKey = `authenticator`
+	optionAuthorizer    options.OptionKey = `authorizer`
+)
+
+// lockStripes is amount of mutexes serializing mutating requests (paths are assigned to them by hash).
+const lockStripes = 64
+
+var (
+	// ErrPreconditionFailed is reported when ETag in If-Match header does not match current content of the file.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrInvalidRequest is reported when request does not follow the protocol (e.g. unknown operation).
+	ErrInvalidRequest = errors.New("invalid request")
+)
+
+// protocolErrors lists errors of the protocol itself (sentinels of filesystem package are matched with
+// filesystem.ErrorKind and filesystem.ErrorOfKind).
+var protocolErrors = []struct {
+	code   string
+	err    error
+	status int
+}{
+	{code: "ErrPreconditionFailed", err: ErrPreconditionFailed, status: http.StatusPreconditionFailed},
+	{code: "ErrInvalidRequest", err: ErrInvalidRequest, status: http.StatusBadRequest},
+}
+
+// errorStatuses maps sentinel errors to status codes. It's checked in order, so error wrapping multiple
+// sentinels always gets the same status.
+var errorStatuses = []struct {
+	err    error
+	status int
+}{
+	{err: filesystem.ErrFileNotFound, status: http.StatusNotFound},
+	{err: filesystem.ErrFileFound, status: http.StatusConflict},
+	{err: filesystem.ErrDirectoryFound, status: http.StatusConflict},
+	{err: filesystem.ErrUnresolvableDirectoryStructure, status: http.StatusConflict},
+	{err: filesystem.ErrDirectory, status: http.StatusConflict},
+	{err: filesystem.ErrFile, status: http.StatusConflict},
+	{err: filesystem.ErrNotEmpty, status: http.StatusConflict},
+	{err: filesystem.ErrUnsupportedContentOperation, status: http.StatusBadRequest},
+	{err: filesystem.ErrPathOutsideRoot, status: http.StatusBadRequest},
+	{err: filesystem.ErrPermissionDenied, status: http.StatusForbidden},
+	{err: filesystem.ErrReadOnly, status: http.StatusMethodNotAllowed},
+	{err: filesystem.ErrNoSpace, status: http.StatusInsufficientStorage},
+	{err: filesystem.ErrTooLarge, status: http.StatusRequestEntityTooLarge},
+	{err: filesystem.ErrSymlinkLoop, status: http.StatusLoopDetected},
+}
+
+type (
+	// AuthenticatorFunc identifies author of the request. Returned error rejects request with 401 status.
+	AuthenticatorFunc func(r *http.Request) (principal string, err error)
+
+	// AuthorizerFunc decides if access is allowed. Returned error rejects request with 403 status.
+	AuthorizerFunc func(r *http.Request, access Access) error
+
+	// Access describes operation requested by authenticated principal (empty without AuthenticatorFunc).
+	// Target is set only for Move and Copy.
+	Access struct {
+		Principal string
+		Operation filesystem.Operation
+		Path      string
+		Target    string
+	}
+
+	// errorBody is JSON representation of failure returned by Handler.
+	errorBody struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	handler struct {
+		fs            filesystem.Filesystem
+		authenticator AuthenticatorFunc
+		authorizer    AuthorizerFunc
+		locks         [lockStripes]sync.Mutex
+	}
+)
+
+// OptionAuthenticator identifies author of every request before it's handled.
+func OptionAuthenticator(authenticator AuthenticatorFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[AuthenticatorFunc](r, optionAuthenticator, authenticator)
+	}
+}
+
+// OptionAuthorizer verifies every operation before it's performed.
+func OptionAuthorizer(authorizer AuthorizerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[AuthorizerFunc](r, optionAuthorizer, authorizer)
+	}
+}
+
+// BasicAuthenticator authenticates requests with HTTP basic authentication against provided users
+// (username mapped to password). Username becomes principal.
+func BasicAuthenticator(users map[string]string) AuthenticatorFunc {
+	return func(r *http.Request) (string, error) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return "", errors.New("missing credentials")
+		}
+		expected, known := users[username]
+		if !known || subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+			return "", errors.New("invalid credentials")
+		}
+		return username, nil
+	}
+}
+
+// BearerAuthenticator authenticates requests with bearer tokens (token mapped to principal).
+func BearerAuthenticator(tokens map[string]string) AuthenticatorFunc {
+	return func(r *http.Request) (string, error) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return "", errors.New("missing credentials")
+		}
+		for known, principal := range tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+				return principal, nil
+			}
+		}
+		return "", errors.New("invalid credentials")
+	}
+}
+
+// NewHandler creates http.Handler exposing fs with the protocol understood by New (see package documentation).
+// Request path is resolved relative to root of fs (use http.StripPrefix to serve it under a prefix and
+// filesystem.Sub to confine it to a directory).
+//
+// GET supports range requests and conditional requests with ETag (weak one based on size and modification time
+// of the file or strong one based on hash of its content if backend does not report modification time).
+// PUT and PATCH accept If-Match header (compared weakly) and fail with 412 status when file was changed
+// in the meantime. Mutating requests for the same path are serialized within the handler.
+// Failures are described with JSON body: {"code": "...", "message": "..."} (message of unexpected errors
+// is not exposed).
+func NewHandler(fs filesystem.Filesystem, opts ...options.Option) (http.Handler, error) {
+	opt := options.Resolve(opts)
+
+	optAuthenticator, err := options.ReadOrDefault[AuthenticatorFunc](opt, optionAuthenticator, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http handler initialization failed: %w", err)
+	}
+	optAuthorizer, err := options.ReadOrDefault[AuthorizerFunc](opt, optionAuthorizer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http handler initialization failed: %w", err)
+	}
+
+	return &handler{fs: fs, authenticator: optAuthenticator, authorizer: optAuthorizer}, nil
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := requestPath(r.URL.Path)
+	query := r.URL.Query()
+
+	operation, err := requestOperation(r.Method, query.Get(queryOperation))
+	if err != nil {
+		if r.Method != http.MethodPost && r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET, HEAD, PUT, PATCH, POST, DELETE")
+		}
+		writeError(w, err)
+		return
+	}
+
+	access := Access{Operation: operation, Path: p}
+	if operation == filesystem.OperationMove || operation == filesystem.OperationCopy {
+		access.Target = requestPath(query.Get(queryTarget))
+	}
+	if h.authenticator != nil {
+		if access.Principal, err = h.authenticator(r); err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="filesystem"`)
+			writeErrorWithStatus(w, http.StatusUnauthorized, filesystem.ErrPermissionDenied, err)
+			return
+		}
+	}
+	if h.authorizer != nil {
+		if err = h.authorizer(r, access); err != nil {
+			writeErrorWithStatus(w, http.StatusForbidden, filesystem.ErrPermissionDenied, err)
+			return
+		}
+	}
+
+	fs := filesystem.InContext(h.fs, r.Context())
+	if operation != filesystem.OperationReadContentOf && operation != filesystem.OperationCheckIfExists &&
+		operation != filesystem.OperationListFilesIn {
+		unlock := h.lock(p, access.Target)
+		defer unlock()
+	}
+
+	switch operation {
+	case filesystem.OperationReadContentOf:
+		err = h.serveContent(fs, w, r, p)
+	case filesystem.OperationCheckIfExists:
+		err = h.serveExistence(fs, w, p)
+	case filesystem.OperationListFilesIn:
+		err = serveList(fs, w, p)
+	case filesystem.OperationStreamContentTo:
+		err = h.receiveContent(fs, w, r, p)
+	default:
+		err = h.perform(fs, w, operation, p, access.Target, query)
+	}
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// serveContent writes content of the file (or its ranges) to response.
+func (h *handler) serveContent(fs filesystem.Filesystem, w http.ResponseWriter, r *http.Request, p string) error {
+	rc, err := filesystem.StreamContentOf(fs, p)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	entry, err := h.entry(fs, p)
+	if err != nil {
+		return err
+	}
+	etag, err := h.etag(fs, p, entry)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", entry.ModTime, rs)
+		return nil
+	}
+	if r.Header.Get("Range") != "" || r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Match") != "" {
+		// Ranges and conditions are evaluated by http.ServeContent which needs to seek.
+		data, rErr := io.ReadAll(rc)
+		if rErr != nil {
+			return rErr
+		}
+		http.ServeContent(w, r, "", entry.ModTime, bytes.NewReader(data))
+		return nil
+	}
+
+	if !entry.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", entry.ModTime.UTC().Format(http.TimeFormat))
+	}
+	_, _ = io.Copy(w, rc)
+	return nil
+}
+
+func (h *handler) serveExistence(fs filesystem.Filesystem, w http.ResponseWriter, p string) error {
+	exists, err := filesystem.CheckIfExists(fs, p)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return filesystem.ErrFileNotFound
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func serveList(fs filesystem.Filesystem, w http.ResponseWriter, p string) error {
+	entries, err := filesystem.ListFilesIn(fs, p)
+	if err != nil {
+		return err
+	}
+
+	res := make([]wireEntry, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, toWireEntry(e))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(res)
+}
+
+// receiveContent streams request body to the file (PUT overwrites, PATCH appends) verifying If-Match first.
+func (h *handler) receiveContent(fs filesystem.Filesystem, w http.ResponseWriter, r *http.Request, p string) error {
+	if err := h.checkIfMatch(fs, r, p); err != nil {
+		return err
+	}
+
+	operation := filesystem.ContentOperationAppend
+	if r.Method == http.MethodPut {
+		operation = filesystem.ContentOperationOverwrite
+	}
+	if err := filesystem.StreamContentTo(fs, p, r.Body, filesystem.WithContentOperation(operation)); err != nil {
+		return err
+	}
+
+	if entry, err := h.entry(fs, p); err == nil {
+		if etag, eErr := h.etag(fs, p, entry); eErr == nil {
+			w.Header().Set("ETag", etag)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// perform handles operations which don't transfer content.
+func (h *handler) perform(fs filesystem.Filesystem, w http.ResponseWriter, operation filesystem.Operation, p, target string, query map[string][]string) error {
+	args, err := requestArguments(query)
+	if err != nil {
+		return err
+	}
+
+	switch operation {
+	case filesystem.OperationCreateFile:
+		err = filesystem.CreateFile(fs, p, args...)
+	case filesystem.OperationCreateDirectory:
+		err = filesystem.CreateDirectory(fs, p, args...)
+	case filesystem.OperationRemove:
+		err = filesystem.Remove(fs, p, args...)
+	case filesystem.OperationMove:
+		err = filesystem.Move(fs, p, target, args...)
+	case filesystem.OperationCopy:
+		err = filesystem.Copy(fs, p, target, args...)
+	default:
+		err = ErrInvalidRequest
+	}
+	if err != nil {
+		return err
+	}
+
+	if operation == filesystem.OperationRemove || operation == filesystem.OperationMove {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	return nil
+}
+
+// checkIfMatch verifies that current ETag of the file matches one of the tags from If-Match header.
+// Tags are compared weakly (ignoring "W/" prefix) as the ones built from modification time are weak.
+func (h *handler) checkIfMatch(fs filesystem.Filesystem, r *http.Request, p string) error {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	entry, err := h.entry(fs, p)
+	if errors.Is(err, filesystem.ErrFileNotFound) {
+		return ErrPreconditionFailed
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(header) == "*" {
+		return nil
+	}
+	current, err := h.etag(fs, p, entry)
+	if err != nil {
+		return err
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == strings.TrimPrefix(current, "W/") {
+			return nil
+		}
+	}
+	return ErrPreconditionFailed
+}
+
+// entry returns description of the file found in its parent directory.
+func (h *handler) entry(fs filesystem.Filesystem, p string) (filesystem.Entry, error) {
+	entries, err := filesystem.ListFilesIn(fs, path.Dir(p))
+	if err != nil {
+		if errors.Is(err, filesystem.ErrFile) {
+			return filesystem.Entry{}, filesystem.ErrFileNotFound
+		}
+		return filesystem.Entry{}, err
+	}
+	name := path.Base(p)
+	for _, e := range entries {
+		if e.Name == name {
+			return e, nil
+		}
+	}
+	return filesystem.Entry{}, filesystem.ErrFileNotFound
+}
+
+// etag returns weak ETag of the file built from its size and modification time (the same values can describe
+// different content) or (when backend does not report modification time) strong one built from hash of its content.
+func (h *handler) etag(fs filesystem.Filesystem, p string, entry filesystem.Entry) (string, error) {
+	if entry.IsDirectory {
+		return "", filesystem.ErrDirectory
+	}
+	if !entry.ModTime.IsZero() {
+		return fmt.Sprintf(`W/"%x-%x"`, entry.ModTime.UnixNano(), entry.Size), nil
+	}
+
+	rc, err := filesystem.StreamContentOf(fs, p)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rc.Close() }()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, rc); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`, nil
+}
+
+// lock serializes mutating requests for the same paths (source and target of Move and Copy) and returns function
+// releasing the locks. Stripes are locked in ascending order, so requests sharing them cannot deadlock.
+func (h *handler) lock(paths ...string) func() {
+	stripes := make([]int, 0, len(paths))
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(p))
+		stripes = append(stripes, int(hash.Sum32()%lockStripes))
+	}
+	sort.Ints(stripes)
+
+	locked := stripes[:0]
+	for _, stripe := range stripes {
+		if len(locked) > 0 && locked[len(locked)-1] == stripe {
+			continue
+		}
+		h.locks[stripe].Lock()
+		locked = append(locked, stripe)
+	}
+
+	return func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			h.locks[locked[i]].Unlock()
+		}
+	}
+}
+
+// requestOperation maps method and "op" query parameter to the operation.
+func requestOperation(method, op string) (filesystem.Operation, error) {
+	switch {
+	case method == http.MethodGet && op == "":
+		return filesystem.OperationReadContentOf, nil
+	case method == http.MethodGet && op == operationList:
+		return filesystem.OperationListFilesIn, nil
+	case method == http.MethodHead:
+		return filesystem.OperationCheckIfExists, nil
+	case method == http.MethodPut || method == http.MethodPatch:
+		return filesystem.OperationStreamContentTo, nil
+	case method == http.MethodDelete:
+		return filesystem.OperationRemove, nil
+	case method == http.MethodPost && op == operationCreateFile:
+		return filesystem.OperationCreateFile, nil
+	case method == http.MethodPost && op == operationCreateDirectory:
+		return filesystem.OperationCreateDirectory, nil
+	case method == http.MethodPost && op == operationMove:
+		return filesystem.OperationMove, nil
+	case method == http.MethodPost && op == operationCopy:
+		return filesystem.OperationCopy, nil
+	case method == http.MethodGet || method == http.MethodPost:
+		return "", fmt.Errorf("%w: unknown operation %q", ErrInvalidRequest, op)
+	default:
+		return "", fmt.Errorf("%w: %w", filesystem.ErrReadOnly, fmt.Errorf("method %s is not allowed", method))
+	}
+}
+
+// requestArguments converts query parameters to arguments (missing ones keep defaults of the operation).
+func requestArguments(query map[string][]string) ([]filesystem.Argument, error) {
+	var args []filesystem.Argument
+
+	modes := map[string]func(filesystem.Mode) filesystem.Argument{
+		queryMode:        filesystem.WithMode,
+		queryParentsMode: filesystem.WithDirectoryStructureMode,
+	}
+	for key, with := range modes {
+		if v := first(query, key); v != "" {
+			mode, err := strconv.ParseUint(v, 8, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid value of %q", ErrInvalidRequest, key)
+			}
+			args = append(args, with(filesystem.Mode(mode)))
+		}
+	}
+
+	flags := map[string]func(bool) filesystem.Argument{
+		queryOverwrite: filesystem.WithAllowOverwrite,
+		queryParents:   filesystem.WithAllowCreationOfDirectoryStructure,
+		queryRecursive: filesystem.WithRecursive,
+	}
+	for key, with := range flags {
+		if v := first(query, key); v != "" {
+			flag, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid value of %q", ErrInvalidRequest, key)
+			}
+			args = append(args, with(flag))
+		}
+	}
+
+	return args, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	for _, e := range protocolErrors {
+		if errors.Is(err, e.err) {
+			writeErrorBody(w, e.status, e.code, err)
+			return
+		}
+	}
+
+	for _, e := range errorStatuses {
+		if errors.Is(err, e.err) {
+			writeErrorWithStatus(w, e.status, err, err)
+			return
+		}
+	}
+	// Unexpected errors may describe internals of the backend (e.g. paths on disk), so they are not exposed.
+	status := http.StatusInternalServerError
+	writeErrorBody(w, status, filesystem.ErrorKind(err), errors.New(http.StatusText(status)))
+}
+
+// writeErrorWithStatus responds with provided status and code of sentinel error matching kind.
+func writeErrorWithStatus(w http.ResponseWriter, status int, kind, err error) {
+	code := filesystem.ErrorKind(kind)
+	w.Header().Set(HeaderErrorKind, code)
+	writeErrorBody(w, status, code, err)
+}
+
+func writeErrorBody(w http.ResponseWriter, status int, code string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorBody{Code: code, Message: err.Error()})
+}
+
+// requestPath converts path from URL to path of the filesystem (relative, without "." and "..").
+func requestPath(p string) string {
+	p = strings.TrimPrefix(cleanPath(p), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+func first(query map[string][]string, key string) string {
+	if v := query[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func toWireEntry(e filesystem.Entry) wireEntry {
+	return wireEntry{
+		Name:        e.Name,
+		Size:        e.Size,
+		Mode:        uint32(e.Mode),
+		ModTime:     e.ModTime,
+		IsDirectory: e.IsDirectory,
+		IsSymlink:   e.IsSymlink,
+		LinkTarget:  e.LinkTarget,
+	}
+}
@@ -0,0 +1,267 @@
+package httpfs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// newTestServer starts server exposing temporary directory (returned along with the server).
+func newTestServer(t *testing.T, opts ...options.Option) (*httptest.Server, string) {
+	t.Helper()
+
+	workdir := t.TempDir()
+	local, err := filesystem.New()
+	require.NoError(t, err)
+
+	h, err := NewHandler(filesystem.Sub(local, workdir), opts...)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	return srv, workdir
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Run("it should perform operations requested by client", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newTestServer(t)
+		fs, err := New(srv.URL)
+		require.NoError(t, err)
+
+		// WHEN
+		require.NoError(t, filesystem.CreateDirectory(fs, "conf.d", filesystem.WithMode(filesystem.ModeAllReadWriteExecute)))
+		require.NoError(t, filesystem.CreateFile(fs, "conf.d/a.yaml"))
+		require.NoError(t, filesystem.WriteContentTo(fs, "conf.d/a.yaml", "A"))
+		require.NoError(t, filesystem.StreamContentTo(fs, "conf.d/a.yaml", strings.NewReader(" MORE")))
+		require.NoError(t, filesystem.Copy(fs, "conf.d/a.yaml", "conf.d/b.yaml"))
+		require.NoError(t, filesystem.Move(fs, "conf.d/b.yaml", "backup/b.yaml", filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.CreateFile(fs, "tmp.txt"))
+		require.NoError(t, filesystem.Remove(fs, "tmp.txt"))
+
+		// THEN
+		content, err := filesystem.ReadContentOf(fs, "conf.d/a.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("A MORE"), content)
+		moved, err := os.ReadFile(filepath.Join(workdir, "backup", "b.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("A MORE"), moved)
+
+		exists, err := filesystem.CheckIfExists(fs, "tmp.txt")
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		paths, err := filesystem.Glob(fs, "**/*.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"backup/b.yaml", "conf.d/a.yaml"}, paths)
+	})
+	t.Run("it should report sentinel errors to client", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("TEST"), 0o600))
+		require.NoError(t, os.MkdirAll(filepath.Join(workdir, "dir", "inner"), 0o700))
+		fs, err := New(srv.URL)
+		require.NoError(t, err)
+
+		// WHEN
+		_, errMissing := filesystem.ReadContentOf(fs, "missing.txt")
+		errFileFound := filesystem.CreateFile(fs, "test.txt")
+		errDirectoryFound := filesystem.CreateDirectory(fs, "dir")
+		errNotEmpty := filesystem.Remove(fs, "dir")
+		_, errDirectory := filesystem.ReadContentOf(fs, "dir")
+		resp, errRaw := http.Get(srv.URL + "/missing.txt")
+
+		// THEN
+		require.ErrorIs(t, errMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errFileFound, filesystem.ErrFileFound)
+		require.ErrorIs(t, errDirectoryFound, filesystem.ErrDirectoryFound)
+		require.ErrorIs(t, errNotEmpty, filesystem.ErrNotEmpty)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+
+		require.NoError(t, errRaw)
+		defer func() { _ = resp.Body.Close() }()
+		var body errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "ErrFileNotFound", body.Code)
+		assert.Equal(t, "ErrFileNotFound", resp.Header.Get(HeaderErrorKind))
+	})
+	t.Run("it should serve range requests", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("0123456789"), 0o600))
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/test.txt", nil)
+		require.NoError(t, err)
+		req.Header.Set("Range", "bytes=2-5")
+
+		// WHEN
+		resp, err := http.DefaultClient.Do(req)
+
+		// THEN
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		assert.Equal(t, "2345", string(body))
+		assert.NotEmpty(t, resp.Header.Get("ETag"))
+	})
+	t.Run("it should write content only if ETag from If-Match is current", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("OLD"), 0o600))
+		resp, err := http.Get(srv.URL + "/test.txt")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		etag := resp.Header.Get("ETag")
+		require.True(t, strings.HasPrefix(etag, `W/"`), etag)
+
+		put := func(content string) *http.Response {
+			req, rErr := http.NewRequest(http.MethodPut, srv.URL+"/test.txt", strings.NewReader(content))
+			require.NoError(t, rErr)
+			req.Header.Set("If-Match", etag)
+			res, rErr := http.DefaultClient.Do(req)
+			require.NoError(t, rErr)
+			_ = res.Body.Close()
+			return res
+		}
+
+		// WHEN
+		first := put("FIRST")
+		second := put("SECOND")
+
+		// THEN
+		assert.Equal(t, http.StatusNoContent, first.StatusCode)
+		assert.NotEqual(t, etag, first.Header.Get("ETag"))
+		assert.Equal(t, http.StatusPreconditionFailed, second.StatusCode)
+		content, err := os.ReadFile(filepath.Join(workdir, "test.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("FIRST"), content)
+	})
+	t.Run("it should authenticate and authorize requests with provided hooks", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newTestServer(t,
+			OptionAuthenticator(BasicAuthenticator(map[string]string{"reader": "r", "writer": "w"})),
+			OptionAuthorizer(func(_ *http.Request, access Access) error {
+				if access.Principal == "reader" && access.Operation != filesystem.OperationReadContentOf {
+					return errors.New("reader can only read")
+				}
+				return nil
+			}),
+		)
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("TEST"), 0o600))
+
+		anonymous, err := New(srv.URL)
+		require.NoError(t, err)
+		reader, err := New(srv.URL, OptionBasicAuth("reader", "r"))
+		require.NoError(t, err)
+		writer, err := New(srv.URL, OptionBasicAuth("writer", "w"))
+		require.NoError(t, err)
+
+		// WHEN
+		_, errAnonymous := filesystem.ReadContentOf(anonymous, "test.txt")
+		content, errRead := filesystem.ReadContentOf(reader, "test.txt")
+		errReaderWrite := filesystem.CreateFile(reader, "new.txt")
+		errWriterWrite := filesystem.CreateFile(writer, "new.txt")
+
+		// THEN
+		require.ErrorIs(t, errAnonymous, filesystem.ErrPermissionDenied)
+		require.NoError(t, errRead)
+		assert.Equal(t, filesystem.Content("TEST"), content)
+		require.ErrorIs(t, errReaderWrite, filesystem.ErrPermissionDenied)
+		require.NoError(t, errWriterWrite)
+		assert.FileExists(t, filepath.Join(workdir, "new.txt"))
+	})
+	t.Run("it should reject unknown operations", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, _ := newTestServer(t)
+
+		// WHEN
+		resp, err := http.Post(srv.URL+"/test.txt?op=unknown", "text/plain", nil)
+
+		// THEN
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+	t.Run("it should not expose message of unexpected error", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		backend, err := filesystem.New(
+			filesystem.OptionReadContentOfHandler(func(string) (filesystem.Content, error) {
+				return nil, errors.New("cannot open /srv/private/test.txt")
+			}),
+			filesystem.OptionStreamContentOfHandler(func(string) (io.ReadCloser, error) {
+				return nil, errors.New("cannot open /srv/private/test.txt")
+			}),
+		)
+		require.NoError(t, err)
+		h, err := NewHandler(backend)
+		require.NoError(t, err)
+		srv := httptest.NewServer(h)
+		t.Cleanup(srv.Close)
+
+		// WHEN
+		resp, err := http.Get(srv.URL + "/test.txt")
+
+		// THEN
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		var body errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "other", body.Code)
+		assert.Equal(t, http.StatusText(http.StatusInternalServerError), body.Message)
+	})
+	t.Run("it should lock both source and target of move", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		h := &handler{}
+		unlock := h.lock("a", "b")
+
+		// WHEN
+		locked := make(chan struct{})
+		go func() {
+			h.lock("b")()
+			close(locked)
+		}()
+
+		// THEN
+		select {
+		case <-locked:
+			t.Fatal("target of move was not locked")
+		case <-time.After(20 * time.Millisecond):
+		}
+		unlock()
+		<-locked
+		h.lock("a", "a", "b")()
+	})
+}
@@ -0,0 +1,158 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/SevenOfSpades/go-just-options"
+)
+
+const iofsBackendName = "fs"
+
+// NewFromFS creates read-only Filesystem serving files from fs.FS (e.g. embed.FS), which makes it a good
+// lower layer of Overlay. Every mutating operation fails with ErrReadOnly.
+// Only OptionBackendName (default "fs") and OptionMiddleware are accepted.
+func NewFromFS(fsys fs.FS, opts ...options.Option) (Filesystem, error) {
+	opt := options.Resolve(opts)
+
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, iofsBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+	optMiddlewares, err := options.ReadOrDefault[[]Middleware](opt, optionMiddlewares, nil)
+	if err != nil {
+		return nil, fmt.Errorf("filesystem initialization failed: %w", err)
+	}
+
+	return New(
+		OptionBackendName(optBackendName),
+		OptionReadContentOfHandler(func(p string) (Content, error) {
+			name, err := iofsFile(fsys, p)
+			if err != nil {
+				return nil, err
+			}
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, iofsError(err)
+			}
+			return content, nil
+		}),
+		OptionStreamContentOfHandler(func(p string) (io.ReadCloser, error) {
+			name, err := iofsFile(fsys, p)
+			if err != nil {
+				return nil, err
+			}
+			f, err := fsys.Open(name)
+			if err != nil {
+				return nil, iofsError(err)
+			}
+			return f, nil
+		}),
+		OptionCheckIfExistsHandler(func(p string) (bool, error) {
+			name, err := iofsName(p)
+			if err != nil {
+				return false, err
+			}
+			if _, err = fs.Stat(fsys, name); err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return false, nil
+				}
+				return false, iofsError(err)
+			}
+			return true, nil
+		}),
+		OptionCreateFileHandler(func(string, Arguments) error {
+			return ErrReadOnly
+		}),
+		OptionWriteContentToHandler(func(string, []byte, Arguments) error {
+			return ErrReadOnly
+		}),
+		OptionStreamContentToHandler(func(string, io.Reader, Arguments) error {
+			return ErrReadOnly
+		}),
+		OptionCreateDirectory(func(string, Arguments) error {
+			return ErrReadOnly
+		}),
+		OptionRemoveHandler(func(string, Arguments) error {
+			return ErrReadOnly
+		}),
+		OptionMoveHandler(func(string, string, Arguments) error {
+			return ErrReadOnly
+		}),
+		OptionCopyHandler(func(string, string, Arguments) error {
+			return ErrReadOnly
+		}),
+		OptionListFilesInHandler(func(p string) ([]Entry, error) {
+			name, err := iofsName(p)
+			if err != nil {
+				return nil, err
+			}
+			fi, err := fs.Stat(fsys, name)
+			if err != nil {
+				return nil, iofsError(err)
+			}
+			if !fi.IsDir() {
+				return nil, ErrFile
+			}
+
+			des, err := fs.ReadDir(fsys, name)
+			if err != nil {
+				return nil, iofsError(err)
+			}
+			res := make([]Entry, 0, len(des))
+			for _, de := range des {
+				dfi, iErr := de.Info()
+				if iErr != nil {
+					return nil, iofsError(iErr)
+				}
+				res = append(res, entryFromFileInfo(dfi))
+			}
+			return res, nil
+		}),
+		OptionMiddleware(optMiddlewares...),
+	)
+}
+
+// iofsName converts path to the form accepted by fs.FS (slash separated, unrooted, without "." and "..").
+func iofsName(p string) (string, error) {
+	name := strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return "", ErrPathOutsideRoot
+	}
+	return name, nil
+}
+
+// iofsFile converts path (see iofsName) and verifies that it points to a file.
+func iofsFile(fsys fs.FS, p string) (string, error) {
+	name, err := iofsName(p)
+	if err != nil {
+		return "", err
+	}
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return "", iofsError(err)
+	}
+	if fi.IsDir() {
+		return "", ErrDirectory
+	}
+	return name, nil
+}
+
+func iofsError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrFileNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	default:
+		return err
+	}
+}
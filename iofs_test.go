@@ -0,0 +1,90 @@
+package filesystem
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config/app.yaml": {Data: []byte("TEST"), Mode: 0o444},
+		"config/db.yaml":  {Data: []byte("MORE TEST"), Mode: 0o444},
+	}
+
+	t.Run("it should read files from fs.FS", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := NewFromFS(fsys)
+		require.NoError(t, err)
+
+		// WHEN
+		content, readErr := ReadContentOf(fs, "config/app.yaml")
+		rc, streamErr := StreamContentOf(fs, "/config/db.yaml")
+		exists, existsErr := CheckIfExists(fs, "config")
+		missing, missingErr := CheckIfExists(fs, "config/missing.yaml")
+		entries, listErr := ListFilesIn(fs, "config")
+		paths, globErr := Glob(fs, "**/*.yaml")
+
+		// THEN
+		require.NoError(t, readErr)
+		require.NoError(t, streamErr)
+		require.NoError(t, existsErr)
+		require.NoError(t, missingErr)
+		require.NoError(t, listErr)
+		require.NoError(t, globErr)
+
+		streamed, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		assert.Equal(t, Content("TEST"), content)
+		assert.Equal(t, "MORE TEST", string(streamed))
+		assert.True(t, exists)
+		assert.False(t, missing)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "app.yaml", entries[0].Name)
+		assert.Equal(t, ModeAllRead, entries[0].Mode)
+		assert.Equal(t, []string{"config/app.yaml", "config/db.yaml"}, paths)
+	})
+	t.Run("it should report missing files, directories and paths outside of the root", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := NewFromFS(fsys, OptionBackendName("embed"))
+		require.NoError(t, err)
+
+		// WHEN
+		_, missingErr := ReadContentOf(fs, "missing.yaml")
+		_, directoryErr := ReadContentOf(fs, "config")
+		_, outsideErr := ReadContentOf(fs, "../config/app.yaml")
+		_, fileErr := ListFilesIn(fs, "config/app.yaml")
+
+		// THEN
+		require.ErrorIs(t, missingErr, ErrFileNotFound)
+		require.ErrorIs(t, directoryErr, ErrDirectory)
+		require.ErrorIs(t, outsideErr, ErrPathOutsideRoot)
+		require.ErrorIs(t, fileErr, ErrFile)
+
+		var pathErr *PathError
+		require.ErrorAs(t, missingErr, &pathErr)
+		assert.Equal(t, "embed", pathErr.Backend)
+	})
+	t.Run("it should reject every mutating operation", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := NewFromFS(fsys)
+		require.NoError(t, err)
+
+		// WHEN & THEN
+		require.ErrorIs(t, CreateFile(fs, "config/new.yaml"), ErrReadOnly)
+		require.ErrorIs(t, WriteContentTo(fs, "config/app.yaml", "TEST"), ErrReadOnly)
+		require.ErrorIs(t, CreateDirectory(fs, "other"), ErrReadOnly)
+		require.ErrorIs(t, Remove(fs, "config/app.yaml"), ErrReadOnly)
+	})
+}
@@ -0,0 +1,215 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+)
+
+const (
+	optionLoggingLevel           options.OptionKey = `logging_level`
+	optionLoggingOperationLevels options.OptionKey = `logging_operation_levels`
+	optionLoggingErrorLevel      options.OptionKey = `logging_error_level`
+	optionLoggingPathRedactor    options.OptionKey = `logging_path_redactor`
+)
+
+// PathRedactorFunc transforms path before it is written to the log (e.g. to hide user-supplied file names).
+type PathRedactorFunc func(string) string
+
+// OptionLoggingLevel changes level (default slog.LevelInfo) of entries for successful operations.
+func OptionLoggingLevel(level slog.Level) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[slog.Level](r, optionLoggingLevel, level)
+	}
+}
+
+// OptionLoggingOperationLevels changes level of entries for successful operations of selected types.
+func OptionLoggingOperationLevels(levels map[Operation]slog.Level) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[map[Operation]slog.Level](r, optionLoggingOperationLevels, levels)
+	}
+}
+
+// OptionLoggingErrorLevel changes level (default slog.LevelError) of entries for failed operations.
+func OptionLoggingErrorLevel(level slog.Level) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[slog.Level](r, optionLoggingErrorLevel, level)
+	}
+}
+
+// OptionLoggingPathRedactor provides function applied on every path before it is logged.
+func OptionLoggingPathRedactor(redactor PathRedactorFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[PathRedactorFunc](r, optionLoggingPathRedactor, redactor)
+	}
+}
+
+type loggingMiddleware struct {
+	logger          *slog.Logger
+	level           slog.Level
+	operationLevels map[Operation]slog.Level
+	errorLevel      slog.Level
+	redactor        PathRedactorFunc
+}
+
+// NewLoggingMiddleware creates Middleware writing entry for every operation to provided logger.
+// Entry contains operation, backend, path, duration, amount of read/written bytes, arguments relevant
+// for the operation and error (if any). Entry for StreamContentOf is written when returned reader gets closed.
+func NewLoggingMiddleware(logger *slog.Logger, opts ...options.Option) (Middleware, error) {
+	opt := options.Resolve(opts)
+
+	optLevel, err := options.ReadOrDefault[slog.Level](opt, optionLoggingLevel, slog.LevelInfo)
+	if err != nil {
+		return nil, fmt.Errorf("logging middleware initialization failed: %w", err)
+	}
+	optOperationLevels, err := options.ReadOrDefault[map[Operation]slog.Level](opt, optionLoggingOperationLevels, nil)
+	if err != nil {
+		return nil, fmt.Errorf("logging middleware initialization failed: %w", err)
+	}
+	optErrorLevel, err := options.ReadOrDefault[slog.Level](opt, optionLoggingErrorLevel, slog.LevelError)
+	if err != nil {
+		return nil, fmt.Errorf("logging middleware initialization failed: %w", err)
+	}
+	optRedactor, err := options.ReadOrDefault[PathRedactorFunc](opt, optionLoggingPathRedactor, nil)
+	if err != nil {
+		return nil, fmt.Errorf("logging middleware initialization failed: %w", err)
+	}
+
+	m := &loggingMiddleware{
+		logger:          logger,
+		level:           optLevel,
+		operationLevels: optOperationLevels,
+		errorLevel:      optErrorLevel,
+		redactor:        optRedactor,
+	}
+
+	return m.wrap, nil
+}
+
+func (m *loggingMiddleware) wrap(next Invoker) Invoker {
+	return func(call Call) (Result, error) {
+		started := time.Now()
+
+		var count func() int64
+		if call.Operation == OperationStreamContentTo && call.Reader != nil {
+			call.Reader, count = CountReader(call.Reader)
+		}
+
+		res, err := next(call)
+
+		switch {
+		case call.Operation == OperationStreamContentOf && err == nil:
+			res.ReadCloser = ObserveReadCloser(res.ReadCloser, func(read int64, cErr error) {
+				m.log(call, started, read, cErr)
+			})
+		case count != nil:
+			m.log(call, started, count(), err)
+		default:
+			m.log(call, started, transferredBytes(call, res), err)
+		}
+
+		return res, err
+	}
+}
+
+func (m *loggingMiddleware) log(call Call, started time.Time, bytes int64, err error) {
+	level := m.level
+	if l, ok := m.operationLevels[call.Operation]; ok {
+		level = l
+	}
+	if err != nil {
+		level = m.errorLevel
+	}
+
+	ctx := call.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !m.logger.Enabled(ctx, level) {
+		return
+	}
+
+	p := call.Path
+	if m.redactor != nil {
+		p = m.redactor(p)
+	}
+
+	attrs := []slog.Attr{
+		slog.String("operation", call.Operation.String()),
+		slog.String("backend", call.Backend),
+		slog.String("path", p),
+	}
+	if call.Target != "" {
+		target := call.Target
+		if m.redactor != nil {
+			target = m.redactor(target)
+		}
+		attrs = append(attrs, slog.String("target", target))
+	}
+	attrs = append(attrs, slog.Duration("duration", time.Since(started)))
+	if bytes >= 0 {
+		attrs = append(attrs, slog.Int64("bytes", bytes))
+	}
+	if args := argumentsAttrs(call); len(args) > 0 {
+		attrs = append(attrs, slog.Attr{Key: "arguments", Value: slog.GroupValue(args...)})
+	}
+
+	msg := "filesystem operation completed"
+	if err != nil {
+		msg = "filesystem operation failed"
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	m.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// transferredBytes returns amount of bytes read or written by operation or -1 if operation does not transfer content.
+func transferredBytes(call Call, res Result) int64 {
+	switch call.Operation {
+	case OperationReadContentOf:
+		return int64(len(res.Content))
+	case OperationWriteContentTo:
+		return int64(len(call.Content))
+	default:
+		return -1
+	}
+}
+
+func argumentsAttrs(call Call) []slog.Attr {
+	arg := call.Arguments
+	switch call.Operation {
+	case OperationCreateFile:
+		return []slog.Attr{
+			slog.String("mode", arg.Mode.String()),
+			slog.String("directory_structure_mode", arg.DirectoryStructureMode.String()),
+			slog.Bool("allow_creation_of_directory_structure", arg.AllowCreationOfDirectoryStructure),
+			slog.Bool("allow_overwrite", arg.AllowOverwrite),
+		}
+	case OperationCreateDirectory:
+		return []slog.Attr{
+			slog.String("mode", arg.Mode.String()),
+			slog.String("directory_structure_mode", arg.DirectoryStructureMode.String()),
+			slog.Bool("allow_creation_of_directory_structure", arg.AllowCreationOfDirectoryStructure),
+		}
+	case OperationWriteContentTo, OperationStreamContentTo:
+		return []slog.Attr{
+			slog.String("content_operation", arg.ContentOperation.String()),
+		}
+	case OperationRemove:
+		return []slog.Attr{
+			slog.Bool("recursive", arg.Recursive),
+		}
+	case OperationMove, OperationCopy:
+		return []slog.Attr{
+			slog.String("directory_structure_mode", arg.DirectoryStructureMode.String()),
+			slog.Bool("allow_creation_of_directory_structure", arg.AllowCreationOfDirectoryStructure),
+			slog.Bool("allow_overwrite", arg.AllowOverwrite),
+			slog.Bool("recursive", arg.Recursive),
+		}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,190 @@
+package filesystem
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger() (*slog.Logger, *bytes.Buffer) {
+	buff := &bytes.Buffer{}
+	return slog.New(slog.NewJSONHandler(buff, &slog.HandlerOptions{Level: slog.LevelDebug})), buff
+}
+
+func readLogEntries(t *testing.T, buff *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buff.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := make(map[string]any)
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	t.Run("it should log successful operation with arguments and byte count", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		logger, buff := newTestLogger()
+		mw, err := NewLoggingMiddleware(logger)
+		require.NoError(t, err)
+
+		fs, err := New(
+			OptionBackendName("test"),
+			OptionWriteContentToHandler(func(string, []byte, Arguments) error { return nil }),
+			OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = WriteContentTo(fs, "path/to/file", "TEST", WithContentOperation(ContentOperationOverwrite))
+
+		// THEN
+		require.NoError(t, err)
+
+		entries := readLogEntries(t, buff)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "INFO", entries[0]["level"])
+		assert.Equal(t, "filesystem operation completed", entries[0]["msg"])
+		assert.Equal(t, "WriteContentTo", entries[0]["operation"])
+		assert.Equal(t, "test", entries[0]["backend"])
+		assert.Equal(t, "path/to/file", entries[0]["path"])
+		assert.Equal(t, float64(4), entries[0]["bytes"])
+		assert.Contains(t, entries[0], "duration")
+		assert.Equal(t, map[string]any{"content_operation": "overwrite"}, entries[0]["arguments"])
+	})
+	t.Run("it should log failed operation with error level", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		logger, buff := newTestLogger()
+		mw, err := NewLoggingMiddleware(logger)
+		require.NoError(t, err)
+
+		fs, err := New(
+			OptionCreateFileHandler(func(string, Arguments) error { return errors.New("something went wrong") }),
+			OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = CreateFile(fs, "path/to/file", WithMode(ModeUserReadWrite))
+
+		// THEN
+		require.Error(t, err)
+
+		entries := readLogEntries(t, buff)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "ERROR", entries[0]["level"])
+		assert.Equal(t, "filesystem operation failed", entries[0]["msg"])
+		assert.Equal(t, "something went wrong", entries[0]["error"])
+		assert.Equal(t, "0600", entries[0]["arguments"].(map[string]any)["mode"])
+	})
+	t.Run("it should log stream operations with amount of transferred bytes", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		logger, buff := newTestLogger()
+		mw, err := NewLoggingMiddleware(logger)
+		require.NoError(t, err)
+
+		fs, err := New(
+			OptionStreamContentOfHandler(func(string) (io.ReadCloser, error) { return newFakeReadCloser("TEST"), nil }),
+			OptionStreamContentToHandler(func(_ string, r io.Reader, _ Arguments) error {
+				_, err := io.Copy(io.Discard, r)
+				return err
+			}),
+			OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		rc, err := StreamContentOf(fs, "path/to/source")
+		require.NoError(t, err)
+		require.Empty(t, buff.String())
+
+		_, err = io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		err = StreamContentTo(fs, "path/to/target", strings.NewReader("MORE TEST"))
+		require.NoError(t, err)
+
+		// THEN
+		entries := readLogEntries(t, buff)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "StreamContentOf", entries[0]["operation"])
+		assert.Equal(t, float64(4), entries[0]["bytes"])
+		assert.Equal(t, "StreamContentTo", entries[1]["operation"])
+		assert.Equal(t, float64(9), entries[1]["bytes"])
+	})
+	t.Run("it should use levels configured per operation and redact paths", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		logger, buff := newTestLogger()
+		mw, err := NewLoggingMiddleware(
+			logger,
+			OptionLoggingLevel(slog.LevelDebug),
+			OptionLoggingOperationLevels(map[Operation]slog.Level{OperationCheckIfExists: slog.LevelWarn}),
+			OptionLoggingPathRedactor(func(string) string { return "[redacted]" }),
+		)
+		require.NoError(t, err)
+
+		fs, err := New(
+			OptionCheckIfExistsHandler(func(string) (bool, error) { return true, nil }),
+			OptionReadContentOfHandler(func(string) (Content, error) { return Content("TEST"), nil }),
+			OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = CheckIfExists(fs, "secret/file")
+		require.NoError(t, err)
+		_, err = ReadContentOf(fs, "secret/file")
+		require.NoError(t, err)
+
+		// THEN
+		entries := readLogEntries(t, buff)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "WARN", entries[0]["level"])
+		assert.Equal(t, "[redacted]", entries[0]["path"])
+		assert.Equal(t, "DEBUG", entries[1]["level"])
+		assert.Equal(t, float64(4), entries[1]["bytes"])
+	})
+	t.Run("it should skip entries below level of the logger", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		buff := &bytes.Buffer{}
+		logger := slog.New(slog.NewJSONHandler(buff, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		mw, err := NewLoggingMiddleware(logger)
+		require.NoError(t, err)
+
+		fs, err := New(
+			OptionCheckIfExistsHandler(func(string) (bool, error) { return true, nil }),
+			OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = CheckIfExists(fs, "path/to/file")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Empty(t, buff.String())
+	})
+}
@@ -0,0 +1,306 @@
+package filesystem
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+)
+
+const (
+	optionMetricsNamespace options.OptionKey = `metrics_namespace`
+	optionMetricsBuckets   options.OptionKey = `metrics_buckets`
+)
+
+const (
+	defaultMetricsNamespace = "filesystem"
+	prometheusContentType   = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// defaultMetricsBuckets are upper bounds (in seconds) of latency histogram buckets (same as Prometheus defaults).
+var defaultMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// MetricsCollector receives measurements of operations performed on Filesystem.
+// Implementations must be safe for concurrent use.
+type MetricsCollector interface {
+	// ObserveOperation is called once for every finished operation (err is nil on success, see ErrorKind).
+	ObserveOperation(op Operation, backend string, duration time.Duration, err error)
+	// ObserveBytesRead is called with amount of bytes read by ReadContentOf and StreamContentOf.
+	ObserveBytesRead(op Operation, backend string, n int64)
+	// ObserveBytesWritten is called with amount of bytes written by WriteContentTo and StreamContentTo.
+	ObserveBytesWritten(op Operation, backend string, n int64)
+}
+
+// NewMetricsMiddleware creates Middleware reporting every operation to provided collector.
+// Bytes transferred by StreamContentOf are reported when returned reader gets closed.
+func NewMetricsMiddleware(collector MetricsCollector) Middleware {
+	return func(next Invoker) Invoker {
+		return func(call Call) (Result, error) {
+			var count func() int64
+			if call.Operation == OperationStreamContentTo && call.Reader != nil {
+				call.Reader, count = CountReader(call.Reader)
+			}
+
+			started := time.Now()
+			res, err := next(call)
+			collector.ObserveOperation(call.Operation, call.Backend, time.Since(started), err)
+
+			switch {
+			case count != nil:
+				collector.ObserveBytesWritten(call.Operation, call.Backend, count())
+			case call.Operation == OperationStreamContentOf && err == nil:
+				res.ReadCloser = ObserveReadCloser(res.ReadCloser, func(read int64, _ error) {
+					collector.ObserveBytesRead(call.Operation, call.Backend, read)
+				})
+			case call.Operation == OperationReadContentOf && err == nil:
+				collector.ObserveBytesRead(call.Operation, call.Backend, int64(len(res.Content)))
+			case call.Operation == OperationWriteContentTo && err == nil:
+				collector.ObserveBytesWritten(call.Operation, call.Backend, int64(len(call.Content)))
+			}
+
+			return res, err
+		}
+	}
+}
+
+// OptionMetricsNamespace changes prefix (default "filesystem") of metrics exposed by MetricsRegistry.
+func OptionMetricsNamespace(namespace string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionMetricsNamespace, namespace)
+	}
+}
+
+// OptionMetricsBuckets changes upper bounds (in seconds) of latency histogram buckets in MetricsRegistry.
+func OptionMetricsBuckets(buckets []float64) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[[]float64](r, optionMetricsBuckets, buckets)
+	}
+}
+
+type (
+	metricsKey struct {
+		operation Operation
+		backend   string
+	}
+
+	metricsErrorKey struct {
+		metricsKey
+		kind string
+	}
+
+	metricsHistogram struct {
+		counts []uint64
+		sum    float64
+		count  uint64
+	}
+)
+
+// MetricsRegistry is in-memory MetricsCollector exposing gathered metrics in Prometheus text format.
+// It implements http.Handler, so it can be mounted directly as scrape endpoint.
+type MetricsRegistry struct {
+	namespace string
+	buckets   []float64
+
+	mu           sync.Mutex
+	calls        map[metricsKey]uint64
+	errors       map[metricsErrorKey]uint64
+	durations    map[metricsKey]*metricsHistogram
+	bytesRead    map[metricsKey]int64
+	bytesWritten map[metricsKey]int64
+}
+
+func NewMetricsRegistry(opts ...options.Option) (*MetricsRegistry, error) {
+	opt := options.Resolve(opts)
+
+	optNamespace, err := options.ReadOrDefault[string](opt, optionMetricsNamespace, defaultMetricsNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("metrics registry initialization failed: %w", err)
+	}
+	optBuckets, err := options.ReadOrDefault[[]float64](opt, optionMetricsBuckets, defaultMetricsBuckets)
+	if err != nil {
+		return nil, fmt.Errorf("metrics registry initialization failed: %w", err)
+	}
+
+	buckets := make([]float64, len(optBuckets))
+	copy(buckets, optBuckets)
+	sort.Float64s(buckets)
+
+	return &MetricsRegistry{
+		namespace:    optNamespace,
+		buckets:      buckets,
+		calls:        make(map[metricsKey]uint64),
+		errors:       make(map[metricsErrorKey]uint64),
+		durations:    make(map[metricsKey]*metricsHistogram),
+		bytesRead:    make(map[metricsKey]int64),
+		bytesWritten: make(map[metricsKey]int64),
+	}, nil
+}
+
+func (r *MetricsRegistry) ObserveOperation(op Operation, backend string, duration time.Duration, err error) {
+	key := metricsKey{operation: op, backend: backend}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls[key]++
+	if err != nil {
+		r.errors[metricsErrorKey{metricsKey: key, kind: ErrorKind(err)}]++
+	}
+
+	h, ok := r.durations[key]
+	if !ok {
+		h = &metricsHistogram{counts: make([]uint64, len(r.buckets))}
+		r.durations[key] = h
+	}
+	seconds := duration.Seconds()
+	for i, upper := range r.buckets {
+		if seconds <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (r *MetricsRegistry) ObserveBytesRead(op Operation, backend string, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bytesRead[metricsKey{operation: op, backend: backend}] += n
+}
+
+func (r *MetricsRegistry) ObserveBytesWritten(op Operation, backend string, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bytesWritten[metricsKey{operation: op, backend: backend}] += n
+}
+
+// WriteTo writes all gathered metrics to w in Prometheus text exposition format.
+func (r *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{writer: bufio.NewWriter(w)}
+
+	r.mu.Lock()
+	r.writeCounters(cw, "operations_total", "Total number of performed operations.", r.calls)
+	r.writeErrors(cw)
+	r.writeDurations(cw)
+	r.writeCounters(cw, "bytes_read_total", "Total number of bytes read from files.", toUint64Values(r.bytesRead))
+	r.writeCounters(cw, "bytes_written_total", "Total number of bytes written to files.", toUint64Values(r.bytesWritten))
+	r.mu.Unlock()
+
+	if cw.err != nil {
+		return cw.count, cw.err
+	}
+	return cw.count, cw.writer.Flush()
+}
+
+func (r *MetricsRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	_, _ = r.WriteTo(w)
+}
+
+func (r *MetricsRegistry) writeHeader(w *countingWriter, name, help, kind string) string {
+	fullName := r.namespace + "_" + name
+	w.printf("# HELP %s %s\n", fullName, help)
+	w.printf("# TYPE %s %s\n", fullName, kind)
+	return fullName
+}
+
+func (r *MetricsRegistry) writeCounters(w *countingWriter, name, help string, values map[metricsKey]uint64) {
+	fullName := r.writeHeader(w, name, help, "counter")
+	for _, key := range sortedMetricsKeys(values) {
+		w.printf("%s{%s} %d\n", fullName, key.labels(), values[key])
+	}
+}
+
+func (r *MetricsRegistry) writeErrors(w *countingWriter) {
+	fullName := r.writeHeader(w, "operation_errors_total", "Total number of failed operations by error kind.", "counter")
+
+	keys := make([]metricsErrorKey, 0, len(r.errors))
+	for key := range r.errors {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].metricsKey != keys[j].metricsKey {
+			return keys[i].metricsKey.less(keys[j].metricsKey)
+		}
+		return keys[i].kind < keys[j].kind
+	})
+
+	for _, key := range keys {
+		w.printf("%s{%s,error=\"%s\"} %d\n", fullName, key.labels(), escapeLabelValue(key.kind), r.errors[key])
+	}
+}
+
+func (r *MetricsRegistry) writeDurations(w *countingWriter) {
+	fullName := r.writeHeader(w, "operation_duration_seconds", "Duration of performed operations.", "histogram")
+	for _, key := range sortedMetricsKeys(r.durations) {
+		h := r.durations[key]
+		labels := key.labels()
+		for i, upper := range r.buckets {
+			w.printf("%s_bucket{%s,le=\"%s\"} %d\n", fullName, labels, formatFloat(upper), h.counts[i])
+		}
+		w.printf("%s_bucket{%s,le=\"+Inf\"} %d\n", fullName, labels, h.count)
+		w.printf("%s_sum{%s} %s\n", fullName, labels, formatFloat(h.sum))
+		w.printf("%s_count{%s} %d\n", fullName, labels, h.count)
+	}
+}
+
+func (k metricsKey) labels() string {
+	return fmt.Sprintf(`operation="%s",backend="%s"`, escapeLabelValue(k.operation.String()), escapeLabelValue(k.backend))
+}
+
+func (k metricsKey) less(other metricsKey) bool {
+	if k.operation != other.operation {
+		return k.operation < other.operation
+	}
+	return k.backend < other.backend
+}
+
+func sortedMetricsKeys[T any](values map[metricsKey]T) []metricsKey {
+	keys := make([]metricsKey, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+	return keys
+}
+
+func toUint64Values(values map[metricsKey]int64) map[metricsKey]uint64 {
+	res := make(map[metricsKey]uint64, len(values))
+	for key, val := range values {
+		res[key] = uint64(val)
+	}
+	return res
+}
+
+func escapeLabelValue(val string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(val)
+}
+
+func formatFloat(val float64) string {
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+// countingWriter remembers amount of written bytes and the first error.
+type countingWriter struct {
+	writer *bufio.Writer
+	count  int64
+	err    error
+}
+
+func (w *countingWriter) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.writer, format, args...)
+	w.count += int64(n)
+	w.err = err
+}
@@ -0,0 +1,145 @@
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	t.Run("it should expose calls, errors, latency and bytes in Prometheus text format", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		registry, err := NewMetricsRegistry(OptionMetricsBuckets([]float64{1, 0.5}))
+		require.NoError(t, err)
+
+		fs, err := New(
+			OptionBackendName("test"),
+			OptionReadContentOfHandler(func(p string) (Content, error) {
+				if p == "missing" {
+					return nil, ErrFileNotFound
+				}
+				return Content("TEST"), nil
+			}),
+			OptionWriteContentToHandler(func(string, []byte, Arguments) error {
+				return errors.New("something went wrong")
+			}),
+			OptionMiddleware(NewMetricsMiddleware(registry)),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		_, _ = ReadContentOf(fs, "existing")
+		_, _ = ReadContentOf(fs, "missing")
+		_ = WriteContentTo(fs, "existing", "TEST")
+
+		buff := &bytes.Buffer{}
+		_, err = registry.WriteTo(buff)
+
+		// THEN
+		require.NoError(t, err)
+
+		output := buff.String()
+		assert.Contains(t, output, "# TYPE filesystem_operations_total counter\n")
+		assert.Contains(t, output, `filesystem_operations_total{operation="ReadContentOf",backend="test"} 2`+"\n")
+		assert.Contains(t, output, `filesystem_operations_total{operation="WriteContentTo",backend="test"} 1`+"\n")
+		assert.Contains(t, output, `filesystem_operation_errors_total{operation="ReadContentOf",backend="test",error="ErrFileNotFound"} 1`+"\n")
+		assert.Contains(t, output, `filesystem_operation_errors_total{operation="WriteContentTo",backend="test",error="other"} 1`+"\n")
+		assert.Contains(t, output, "# TYPE filesystem_operation_duration_seconds histogram\n")
+		assert.Contains(t, output, `filesystem_operation_duration_seconds_bucket{operation="ReadContentOf",backend="test",le="0.5"} 2`+"\n")
+		assert.Contains(t, output, `filesystem_operation_duration_seconds_bucket{operation="ReadContentOf",backend="test",le="1"} 2`+"\n")
+		assert.Contains(t, output, `filesystem_operation_duration_seconds_bucket{operation="ReadContentOf",backend="test",le="+Inf"} 2`+"\n")
+		assert.Contains(t, output, `filesystem_operation_duration_seconds_count{operation="ReadContentOf",backend="test"} 2`+"\n")
+		assert.Contains(t, output, `filesystem_bytes_read_total{operation="ReadContentOf",backend="test"} 4`+"\n")
+		assert.NotContains(t, output, `filesystem_bytes_written_total{`)
+	})
+	t.Run("it should count bytes transferred by streams", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		registry, err := NewMetricsRegistry(OptionMetricsNamespace("storage"))
+		require.NoError(t, err)
+
+		fs, err := New(
+			OptionStreamContentOfHandler(func(string) (io.ReadCloser, error) { return newFakeReadCloser("TEST"), nil }),
+			OptionStreamContentToHandler(func(_ string, r io.Reader, _ Arguments) error {
+				_, err := io.Copy(io.Discard, r)
+				return err
+			}),
+			OptionMiddleware(NewMetricsMiddleware(registry)),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		rc, err := StreamContentOf(fs, "source")
+		require.NoError(t, err)
+		_, _ = io.ReadAll(rc)
+		require.NoError(t, rc.Close())
+
+		require.NoError(t, StreamContentTo(fs, "target", strings.NewReader("MORE TEST")))
+
+		res := httptest.NewRecorder()
+		registry.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		// THEN
+		assert.Equal(t, prometheusContentType, res.Header().Get("Content-Type"))
+
+		output := res.Body.String()
+		assert.Contains(t, output, `storage_bytes_read_total{operation="StreamContentOf",backend="os"} 4`+"\n")
+		assert.Contains(t, output, `storage_bytes_written_total{operation="StreamContentTo",backend="os"} 9`+"\n")
+	})
+	t.Run("it should count bytes of stream rewound by retries only once", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		registry, err := NewMetricsRegistry()
+		require.NoError(t, err)
+		retry, err := NewRetryMiddleware(OptionRetryInitialDelay(time.Millisecond))
+		require.NoError(t, err)
+
+		attempts := 0
+		fs, err := New(
+			OptionStreamContentToHandler(func(_ string, r io.Reader, _ Arguments) error {
+				_, err := io.Copy(io.Discard, r)
+				require.NoError(t, err)
+				if attempts++; attempts < 2 {
+					return errors.New("connection reset")
+				}
+				return nil
+			}),
+			OptionMiddleware(NewMetricsMiddleware(registry), retry),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = StreamContentTo(fs, "target", strings.NewReader("TEST"), WithContentOperation(ContentOperationOverwrite))
+
+		res := httptest.NewRecorder()
+		registry.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Contains(t, res.Body.String(), `filesystem_bytes_written_total{operation="StreamContentTo",backend="os"} 4`+"\n")
+	})
+}
+
+func TestErrorKind(t *testing.T) {
+	t.Run("it should name sentinel errors", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "", ErrorKind(nil))
+		assert.Equal(t, "ErrFileNotFound", ErrorKind(&PathError{Op: OperationReadContentOf, Err: ErrFileNotFound}))
+		assert.Equal(t, "ErrFileFound", ErrorKind(ErrFileFound))
+		assert.Equal(t, "other", ErrorKind(errors.New("something went wrong")))
+	})
+}
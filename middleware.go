@@ -0,0 +1,179 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+type (
+	// Call describes single operation requested from Filesystem.
+	// Depending on the operation Content (WriteContentTo) or Reader (StreamContentTo) will be set.
+	// For Glob, Path contains the pattern and for Move and Copy, Path contains source and Target destination.
+	// Unless backend has native handler for Glob, the pattern is resolved by listing directories through
+	// the whole chain again (so every ListFilesIn and CheckIfExists it performs is seen by middlewares as well).
+	// Backend contains name of the backend (see OptionBackendName) and Context the one bound with InContext
+	// (context.Background by default).
+	Call struct {
+		Context   context.Context
+		Operation Operation
+		Backend   string
+		Path      string
+		Target    string
+		Arguments Arguments
+		Content   []byte
+		Reader    io.Reader
+	}
+
+	// Result contains output of operation. Only field matching the operation is set:
+	// Content (ReadContentOf), ReadCloser (StreamContentOf), Exists (CheckIfExists),
+	// Entries (ListFilesIn) or Paths (Glob).
+	Result struct {
+		Content    Content
+		ReadCloser io.ReadCloser
+		Exists     bool
+		Entries    []Entry
+		Paths      []string
+	}
+
+	// Invoker performs Call and returns its Result.
+	Invoker func(call Call) (Result, error)
+
+	// Middleware decorates Invoker to intercept every operation performed on Filesystem.
+	Middleware func(next Invoker) Invoker
+)
+
+type middlewareFilesystem struct {
+	inner  Filesystem
+	invoke Invoker
+}
+
+// Wrap returns Filesystem passing every operation through provided middlewares before it reaches fs.
+// First middleware is the outermost one (it sees the call first and the result last).
+func Wrap(fs Filesystem, mw ...Middleware) Filesystem {
+	if len(mw) == 0 {
+		return fs
+	}
+
+	wrapped := &middlewareFilesystem{inner: fs}
+	invoke := func(call Call) (Result, error) {
+		if call.Operation == OperationGlob && !fs.capabilities().nativeGlob {
+			paths, err := globWithListing(call.Context, wrapped, call.Path)
+			return Result{Paths: paths}, err
+		}
+		return dispatch(fs, call)
+	}
+	for i := len(mw) - 1; i >= 0; i-- {
+		invoke = mw[i](invoke)
+	}
+	wrapped.invoke = invoke
+
+	return wrapped
+}
+
+// dispatch performs Call directly on fs handlers.
+func dispatch(fs Filesystem, call Call) (Result, error) {
+	var (
+		res Result
+		err error
+	)
+
+	switch call.Operation {
+	case OperationReadContentOf:
+		res.Content, err = fs.handleReadContentOf(call.Context, call.Path)
+	case OperationStreamContentOf:
+		res.ReadCloser, err = fs.handleStreamContentOf(call.Context, call.Path)
+	case OperationCheckIfExists:
+		res.Exists, err = fs.handleCheckIfExists(call.Context, call.Path)
+	case OperationCreateFile:
+		err = fs.handleCreateFile(call.Context, call.Path, call.Arguments)
+	case OperationWriteContentTo:
+		err = fs.handleWriteContentTo(call.Context, call.Path, call.Content, call.Arguments)
+	case OperationStreamContentTo:
+		err = fs.handleStreamContentTo(call.Context, call.Path, call.Reader, call.Arguments)
+	case OperationCreateDirectory:
+		err = fs.handleCreateDirectory(call.Context, call.Path, call.Arguments)
+	case OperationRemove:
+		err = fs.handleRemove(call.Context, call.Path, call.Arguments)
+	case OperationMove:
+		err = fs.handleMove(call.Context, call.Path, call.Target, call.Arguments)
+	case OperationCopy:
+		err = fs.handleCopy(call.Context, call.Path, call.Target, call.Arguments)
+	case OperationListFilesIn:
+		res.Entries, err = fs.handleListFilesIn(call.Context, call.Path)
+	case OperationGlob:
+		res.Paths, err = fs.handleGlob(call.Context, call.Path)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedOperation, call.Operation)
+	}
+
+	return res, err
+}
+
+func (fs *middlewareFilesystem) backendName() string {
+	return fs.inner.backendName()
+}
+
+func (fs *middlewareFilesystem) capabilities() capabilities {
+	return fs.inner.capabilities()
+}
+
+func (fs *middlewareFilesystem) handleReadContentOf(ctx context.Context, path string) (Content, error) {
+	res, err := fs.invoke(Call{Context: ctx, Operation: OperationReadContentOf, Backend: fs.backendName(), Path: path})
+	return res.Content, err
+}
+
+func (fs *middlewareFilesystem) handleStreamContentOf(ctx context.Context, path string) (io.ReadCloser, error) {
+	res, err := fs.invoke(Call{Context: ctx, Operation: OperationStreamContentOf, Backend: fs.backendName(), Path: path})
+	return res.ReadCloser, err
+}
+
+func (fs *middlewareFilesystem) handleCheckIfExists(ctx context.Context, path string) (bool, error) {
+	res, err := fs.invoke(Call{Context: ctx, Operation: OperationCheckIfExists, Backend: fs.backendName(), Path: path})
+	return res.Exists, err
+}
+
+func (fs *middlewareFilesystem) handleCreateFile(ctx context.Context, path string, arg Arguments) error {
+	_, err := fs.invoke(Call{Context: ctx, Operation: OperationCreateFile, Backend: fs.backendName(), Path: path, Arguments: arg})
+	return err
+}
+
+func (fs *middlewareFilesystem) handleWriteContentTo(ctx context.Context, path string, content []byte, arg Arguments) error {
+	_, err := fs.invoke(Call{Context: ctx, Operation: OperationWriteContentTo, Backend: fs.backendName(), Path: path, Arguments: arg, Content: content})
+	return err
+}
+
+func (fs *middlewareFilesystem) handleStreamContentTo(ctx context.Context, path string, content io.Reader, arg Arguments) error {
+	_, err := fs.invoke(Call{Context: ctx, Operation: OperationStreamContentTo, Backend: fs.backendName(), Path: path, Arguments: arg, Reader: content})
+	return err
+}
+
+func (fs *middlewareFilesystem) handleCreateDirectory(ctx context.Context, path string, arg Arguments) error {
+	_, err := fs.invoke(Call{Context: ctx, Operation: OperationCreateDirectory, Backend: fs.backendName(), Path: path, Arguments: arg})
+	return err
+}
+
+func (fs *middlewareFilesystem) handleRemove(ctx context.Context, path string, arg Arguments) error {
+	_, err := fs.invoke(Call{Context: ctx, Operation: OperationRemove, Backend: fs.backendName(), Path: path, Arguments: arg})
+	return err
+}
+
+func (fs *middlewareFilesystem) handleMove(ctx context.Context, source, target string, arg Arguments) error {
+	_, err := fs.invoke(Call{Context: ctx, Operation: OperationMove, Backend: fs.backendName(), Path: source, Target: target, Arguments: arg})
+	return err
+}
+
+func (fs *middlewareFilesystem) handleCopy(ctx context.Context, source, target string, arg Arguments) error {
+	_, err := fs.invoke(Call{Context: ctx, Operation: OperationCopy, Backend: fs.backendName(), Path: source, Target: target, Arguments: arg})
+	return err
+}
+
+func (fs *middlewareFilesystem) handleListFilesIn(ctx context.Context, path string) ([]Entry, error) {
+	res, err := fs.invoke(Call{Context: ctx, Operation: OperationListFilesIn, Backend: fs.backendName(), Path: path})
+	return res.Entries, err
+}
+
+func (fs *middlewareFilesystem) handleGlob(ctx context.Context, pattern string) ([]string, error) {
+	res, err := fs.invoke(Call{Context: ctx, Operation: OperationGlob, Backend: fs.backendName(), Path: pattern})
+	return res.Paths, err
+}
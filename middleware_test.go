@@ -0,0 +1,143 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recordingMiddleware(calls *[]Call, results *[]Result) Middleware {
+	return func(next Invoker) Invoker {
+		return func(call Call) (Result, error) {
+			*calls = append(*calls, call)
+			res, err := next(call)
+			*results = append(*results, res)
+			return res, err
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	t.Run("it should intercept every operation with resolved arguments", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := New(
+			OptionCreateFileHandler(func(string, Arguments) error { return nil }),
+			OptionReadContentOfHandler(func(string) (Content, error) { return Content("TEST"), nil }),
+		)
+		require.NoError(t, err)
+
+		var calls []Call
+		var results []Result
+		wrapped := Wrap(fs, recordingMiddleware(&calls, &results))
+
+		// WHEN
+		createErr := CreateFile(wrapped, "path/to/file", WithMode(ModeUserReadWrite))
+		content, readErr := ReadContentOf(wrapped, "path/to/file")
+
+		// THEN
+		require.NoError(t, createErr)
+		require.NoError(t, readErr)
+		assert.Equal(t, "TEST", content.String())
+
+		require.Len(t, calls, 2)
+		assert.Equal(t, OperationCreateFile, calls[0].Operation)
+		assert.Equal(t, "path/to/file", calls[0].Path)
+		assert.Equal(t, ModeUserReadWrite, calls[0].Arguments.Mode)
+		assert.Equal(t, ModeAllReadWriteExecute, calls[0].Arguments.DirectoryStructureMode)
+		assert.Equal(t, OperationReadContentOf, calls[1].Operation)
+		assert.Equal(t, Content("TEST"), results[1].Content)
+	})
+	t.Run("it should call middlewares in provided order", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var order []string
+		named := func(name string) Middleware {
+			return func(next Invoker) Invoker {
+				return func(call Call) (Result, error) {
+					order = append(order, name+":before")
+					res, err := next(call)
+					order = append(order, name+":after")
+					return res, err
+				}
+			}
+		}
+		fs, err := New(
+			OptionCheckIfExistsHandler(func(string) (bool, error) {
+				order = append(order, "handler")
+				return true, nil
+			}),
+			OptionMiddleware(named("first"), named("second")),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		exists, err := CheckIfExists(fs, "path/to/file")
+
+		// THEN
+		require.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, []string{"first:before", "second:before", "handler", "second:after", "first:after"}, order)
+	})
+	t.Run("it should allow middleware to short-circuit operation", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		expectedErr := errors.New("blocked")
+		fs, err := New(
+			OptionWriteContentToHandler(func(string, []byte, Arguments) error {
+				t.Fatal("handler is not expected to be called")
+				return nil
+			}),
+			OptionMiddleware(func(next Invoker) Invoker {
+				return func(call Call) (Result, error) {
+					if call.Operation == OperationWriteContentTo {
+						return Result{}, expectedErr
+					}
+					return next(call)
+				}
+			}),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = WriteContentTo(fs, "path/to/file", "TEST")
+
+		// THEN
+		require.ErrorIs(t, err, expectedErr)
+
+		var pErr *PathError
+		require.ErrorAs(t, err, &pErr)
+		assert.Equal(t, OperationWriteContentTo, pErr.Op)
+	})
+	t.Run("it should allow composing exported default handlers", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fp := path.Join(workdir, "test-file.txt")
+			require.NoError(t, os.WriteFile(fp, []byte("TEST"), 0600))
+
+			var readPaths []string
+			fs, err := New(OptionReadContentOfHandler(func(p string) (Content, error) {
+				readPaths = append(readPaths, p)
+				return ReadContentOfDefaultHandler(p)
+			}))
+			require.NoError(t, err)
+
+			// WHEN
+			content, err := ReadContentOf(fs, fp)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, "TEST", content.String())
+			assert.Equal(t, []string{fp}, readPaths)
+		})
+	})
+}
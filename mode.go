@@ -1,6 +1,9 @@
 package filesystem
 
-import "os"
+import (
+	"fmt"
+	"os"
+)
 
 const (
 	ModeModifierRead    ModeModifier = 04
@@ -45,3 +48,8 @@ type (
 func (m Mode) asFileMode() os.FileMode {
 	return os.FileMode(m)
 }
+
+// String returns octal representation of the mode (e.g. "0644").
+func (m Mode) String() string {
+	return fmt.Sprintf("%04o", uint32(m))
+}
@@ -23,4 +23,8 @@ func TestMode(t *testing.T) {
 		t.Parallel()
 		assert.Equal(t, Mode(0777), ModeAllReadWriteExecute)
 	})
+	t.Run("check string representation", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, "0644", (ModeUserReadWrite | ModeGroupRead | ModeOthersRead).String())
+	})
 }
@@ -0,0 +1,278 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const mountBackendName = "mount"
+
+type (
+	mountPoint struct {
+		prefix string
+		fs     Filesystem
+	}
+
+	mountFilesystem struct {
+		// points are sorted from the longest prefix so the first match is the most specific one.
+		points []mountPoint
+	}
+)
+
+// Mount returns Filesystem dispatching every operation to filesystem mounted at the longest prefix of its path
+// (e.g. "/data/a.txt" is passed as "a.txt" to filesystem mounted at "/data"). Paths are always resolved from "/"
+// and directories leading to mount points are listed with mounted children but can't be modified.
+// Move and Copy between different mounts stream content from one filesystem to another.
+func Mount(mounts map[string]Filesystem) (Filesystem, error) {
+	points := make([]mountPoint, 0, len(mounts))
+	seen := make(map[string]bool, len(mounts))
+	for prefix, fs := range mounts {
+		if fs == nil {
+			return nil, fmt.Errorf("mount initialization failed: missing filesystem for %q", prefix)
+		}
+		prefix = mountPath(prefix)
+		if seen[prefix] {
+			return nil, fmt.Errorf("mount initialization failed: duplicated mount point %q", prefix)
+		}
+		seen[prefix] = true
+		points = append(points, mountPoint{prefix: prefix, fs: fs})
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if len(points[i].prefix) != len(points[j].prefix) {
+			return len(points[i].prefix) > len(points[j].prefix)
+		}
+		return points[i].prefix < points[j].prefix
+	})
+
+	return &mountFilesystem{points: points}, nil
+}
+
+func (fs *mountFilesystem) backendName() string {
+	return mountBackendName
+}
+
+func (fs *mountFilesystem) capabilities() capabilities {
+	return capabilities{}
+}
+
+func (fs *mountFilesystem) handleReadContentOf(ctx context.Context, p string) (Content, error) {
+	mp, rel, err := fs.resolveForRead(p)
+	if err != nil {
+		return nil, err
+	}
+	return mp.fs.handleReadContentOf(ctx, rel)
+}
+
+func (fs *mountFilesystem) handleStreamContentOf(ctx context.Context, p string) (io.ReadCloser, error) {
+	mp, rel, err := fs.resolveForRead(p)
+	if err != nil {
+		return nil, err
+	}
+	return mp.fs.handleStreamContentOf(ctx, rel)
+}
+
+func (fs *mountFilesystem) handleCheckIfExists(ctx context.Context, p string) (bool, error) {
+	if fs.structural(p) {
+		return true, nil
+	}
+	mp, rel := fs.resolve(p)
+	if mp == nil {
+		return false, nil
+	}
+	return mp.fs.handleCheckIfExists(ctx, rel)
+}
+
+func (fs *mountFilesystem) handleCreateFile(ctx context.Context, p string, arg Arguments) error {
+	mp, rel, err := fs.resolveForWrite(p)
+	if err != nil {
+		return err
+	}
+	return mp.fs.handleCreateFile(ctx, rel, arg)
+}
+
+func (fs *mountFilesystem) handleWriteContentTo(ctx context.Context, p string, content []byte, arg Arguments) error {
+	mp, rel, err := fs.resolveForWrite(p)
+	if err != nil {
+		return err
+	}
+	return mp.fs.handleWriteContentTo(ctx, rel, content, arg)
+}
+
+func (fs *mountFilesystem) handleStreamContentTo(ctx context.Context, p string, content io.Reader, arg Arguments) error {
+	mp, rel, err := fs.resolveForWrite(p)
+	if err != nil {
+		return err
+	}
+	return mp.fs.handleStreamContentTo(ctx, rel, content, arg)
+}
+
+func (fs *mountFilesystem) handleCreateDirectory(ctx context.Context, p string, arg Arguments) error {
+	if fs.structural(p) {
+		return ErrDirectoryFound
+	}
+	mp, rel := fs.resolve(p)
+	if mp == nil {
+		return ErrReadOnly
+	}
+	return mp.fs.handleCreateDirectory(ctx, rel, arg)
+}
+
+func (fs *mountFilesystem) handleRemove(ctx context.Context, p string, arg Arguments) error {
+	if fs.structural(p) {
+		return ErrReadOnly
+	}
+	mp, rel := fs.resolve(p)
+	if mp == nil {
+		return ErrFileNotFound
+	}
+	return mp.fs.handleRemove(ctx, rel, arg)
+}
+
+func (fs *mountFilesystem) handleMove(ctx context.Context, source, target string, arg Arguments) error {
+	if fs.structural(source) {
+		return ErrReadOnly
+	}
+	src, srcRel := fs.resolve(source)
+	if src == nil {
+		return ErrFileNotFound
+	}
+	dst, dstRel, err := fs.resolveForWrite(target)
+	if err != nil {
+		return err
+	}
+
+	if src.prefix == dst.prefix {
+		return src.fs.handleMove(ctx, srcRel, dstRel, arg)
+	}
+
+	arg.Recursive = true
+	if err = transfer(ctx, src.fs, srcRel, dst.fs, dstRel, arg); err != nil {
+		return err
+	}
+	return src.fs.handleRemove(ctx, srcRel, Arguments{Recursive: true})
+}
+
+func (fs *mountFilesystem) handleCopy(ctx context.Context, source, target string, arg Arguments) error {
+	src, srcRel, err := fs.resolveForRead(source)
+	if err != nil {
+		return err
+	}
+	dst, dstRel, err := fs.resolveForWrite(target)
+	if err != nil {
+		return err
+	}
+
+	if src.prefix == dst.prefix {
+		return src.fs.handleCopy(ctx, srcRel, dstRel, arg)
+	}
+	return transfer(ctx, src.fs, srcRel, dst.fs, dstRel, arg)
+}
+
+// handleListFilesIn lists directory of mounted filesystem along with mount points placed directly inside it.
+func (fs *mountFilesystem) handleListFilesIn(ctx context.Context, p string) ([]Entry, error) {
+	children := fs.children(p)
+
+	var entries []Entry
+	if mp, rel := fs.resolve(p); mp != nil {
+		var err error
+		entries, err = mp.fs.handleListFilesIn(ctx, rel)
+		if err != nil && (len(children) == 0 || !errors.Is(err, ErrFileNotFound)) {
+			return nil, err
+		}
+	} else if len(children) == 0 {
+		return nil, ErrFileNotFound
+	}
+
+	res := make([]Entry, 0, len(entries)+len(children))
+	for _, entry := range entries {
+		if _, ok := children[entry.Name]; !ok {
+			res = append(res, entry)
+		}
+	}
+	for name := range children {
+		res = append(res, Entry{Name: name, Mode: ModeAllRead | ModeAllExecute, IsDirectory: true})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+func (fs *mountFilesystem) handleGlob(ctx context.Context, pattern string) ([]string, error) {
+	return globWithListing(ctx, fs, pattern)
+}
+
+// resolve returns mount point with the longest prefix matching path and path relative to it.
+func (fs *mountFilesystem) resolve(p string) (*mountPoint, string) {
+	p = mountPath(p)
+	for i := range fs.points {
+		prefix := fs.points[i].prefix
+		if prefix == "/" || p == prefix || strings.HasPrefix(p, prefix+"/") {
+			rel := strings.TrimPrefix(strings.TrimPrefix(p, prefix), "/")
+			if rel == "" {
+				rel = "."
+			}
+			return &fs.points[i], rel
+		}
+	}
+	return nil, ""
+}
+
+func (fs *mountFilesystem) resolveForRead(p string) (*mountPoint, string, error) {
+	mp, rel := fs.resolve(p)
+	if mp == nil {
+		if fs.structural(p) {
+			return nil, "", ErrDirectory
+		}
+		return nil, "", ErrFileNotFound
+	}
+	return mp, rel, nil
+}
+
+// resolveForWrite rejects mount points, directories leading to them and paths outside of every mount.
+func (fs *mountFilesystem) resolveForWrite(p string) (*mountPoint, string, error) {
+	if fs.structural(p) {
+		return nil, "", ErrDirectory
+	}
+	mp, rel := fs.resolve(p)
+	if mp == nil {
+		return nil, "", ErrReadOnly
+	}
+	return mp, rel, nil
+}
+
+// structural reports if path is a mount point or directory leading to one.
+func (fs *mountFilesystem) structural(p string) bool {
+	p = mountPath(p)
+	for _, mp := range fs.points {
+		if mp.prefix == p || p == "/" || strings.HasPrefix(mp.prefix, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// children returns names of mount points (or directories leading to them) placed directly inside path.
+func (fs *mountFilesystem) children(p string) map[string]struct{} {
+	p = mountPath(p)
+	prefix := strings.TrimSuffix(p, "/") + "/"
+
+	res := make(map[string]struct{})
+	for _, mp := range fs.points {
+		if mp.prefix == p || !strings.HasPrefix(mp.prefix, prefix) {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(mp.prefix, prefix), "/")
+		res[name] = struct{}{}
+	}
+	return res
+}
+
+func mountPath(p string) string {
+	return path.Clean("/" + filepath.ToSlash(p))
+}
@@ -0,0 +1,178 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMount(t *testing.T, workdir string) Filesystem {
+	t.Helper()
+
+	local, err := New()
+	require.NoError(t, err)
+	assets, err := NewFromFS(fstest.MapFS{
+		"logo.svg":     {Data: []byte("LOGO"), Mode: 0o444},
+		"css/main.css": {Data: []byte("CSS"), Mode: 0o444},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(workdir, "data", "archive"), 0o700))
+	require.NoError(t, os.MkdirAll(filepath.Join(workdir, "cache"), 0o700))
+
+	fs, err := Mount(map[string]Filesystem{
+		"/data":         Sub(local, filepath.Join(workdir, "data")),
+		"/data/archive": Sub(local, filepath.Join(workdir, "data", "archive")),
+		"/var/cache":    Sub(local, filepath.Join(workdir, "cache")),
+		"/assets":       assets,
+	})
+	require.NoError(t, err)
+
+	return fs
+}
+
+func TestMount(t *testing.T) {
+	t.Run("it should dispatch operations to filesystem with the longest matching prefix", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestMount(t, workdir)
+
+			// WHEN
+			errData := CreateFile(fs, "/data/a.txt")
+			errArchive := CreateFile(fs, "data/archive/b.txt")
+			logo, errAssets := ReadContentOf(fs, "/assets/logo.svg")
+
+			// THEN
+			require.NoError(t, errData)
+			require.NoError(t, errArchive)
+			require.NoError(t, errAssets)
+			assert.FileExists(t, filepath.Join(workdir, "data", "a.txt"))
+			assert.FileExists(t, filepath.Join(workdir, "data", "archive", "b.txt"))
+			assert.Equal(t, Content("LOGO"), logo)
+		})
+	})
+	t.Run("it should list mount points along with content of mounted filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			require.NoError(t, os.MkdirAll(filepath.Join(workdir, "data", "sub"), 0o700))
+			fs := newTestMount(t, workdir)
+
+			// WHEN
+			exists, existsErr := CheckIfExists(fs, "/var")
+			_, listErr := ListFilesIn(fs, "/missing")
+
+			// THEN
+			require.NoError(t, existsErr)
+			require.ErrorIs(t, listErr, ErrFileNotFound)
+			assert.True(t, exists)
+			assert.Equal(t, []string{"assets", "data", "var"}, listNames(t, fs, "/"))
+			assert.Equal(t, []string{"cache"}, listNames(t, fs, "/var"))
+			assert.Equal(t, []string{"archive", "sub"}, listNames(t, fs, "/data"))
+			assert.Equal(t, []string{"css", "logo.svg"}, listNames(t, fs, "/assets"))
+		})
+	})
+	t.Run("it should forbid modifying mount points and directories leading to them", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestMount(t, workdir)
+
+			// WHEN
+			errRemove := Remove(fs, "/data/archive", WithRecursive(true))
+			errCreate := CreateDirectory(fs, "/var")
+			errWrite := WriteContentTo(fs, "/var", "TEST")
+			errUnmounted := CreateFile(fs, "/tmp/a.txt")
+			_, errRead := ReadContentOf(fs, "/tmp/a.txt")
+
+			// THEN
+			require.ErrorIs(t, errRemove, ErrReadOnly)
+			require.ErrorIs(t, errCreate, ErrDirectoryFound)
+			require.ErrorIs(t, errWrite, ErrDirectory)
+			require.ErrorIs(t, errUnmounted, ErrReadOnly)
+			require.ErrorIs(t, errRead, ErrFileNotFound)
+			assert.DirExists(t, filepath.Join(workdir, "data", "archive"))
+		})
+	})
+	t.Run("it should stream content when moving and copying between mounts", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestMount(t, workdir)
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "data", "a.txt"), []byte("A"), 0o600))
+
+			// WHEN
+			errCopy := Copy(fs, "/assets/css", "/var/cache/css", WithRecursive(true))
+			errMove := Move(fs, "/data/a.txt", "/var/cache/a.txt")
+
+			// THEN
+			require.NoError(t, errCopy)
+			require.NoError(t, errMove)
+			css, err := os.ReadFile(filepath.Join(workdir, "cache", "css", "main.css"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("CSS"), css)
+			a, err := os.ReadFile(filepath.Join(workdir, "cache", "a.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("A"), a)
+			assert.NoFileExists(t, filepath.Join(workdir, "data", "a.txt"))
+		})
+	})
+	t.Run("it should reject moving and copying path onto itself within single mount", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestMount(t, workdir)
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "data", "a.txt"), []byte("A"), 0o600))
+
+			// WHEN
+			errMove := Move(fs, "/data/a.txt", "/data/./a.txt", WithAllowOverwrite(true))
+			errCopy := Copy(fs, "/data/a.txt", "/data/a.txt", WithAllowOverwrite(true))
+
+			// THEN
+			require.ErrorIs(t, errMove, ErrUnresolvableDirectoryStructure)
+			require.ErrorIs(t, errCopy, ErrUnresolvableDirectoryStructure)
+			a, err := os.ReadFile(filepath.Join(workdir, "data", "a.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("A"), a)
+		})
+	})
+	t.Run("it should glob across mounts", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestMount(t, workdir)
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "data", "a.css"), []byte("A"), 0o600))
+
+			// WHEN
+			paths, err := Glob(fs, "/*/**/*.css")
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, []string{"/assets/css/main.css", "/data/a.css"}, paths)
+		})
+	})
+	t.Run("it should reject duplicated mount points", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		local, err := New()
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = Mount(map[string]Filesystem{"/data": local, "data/": local})
+
+		// THEN
+		require.Error(t, err)
+	})
+}
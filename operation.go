@@ -0,0 +1,47 @@
+package filesystem
+
+const (
+	OperationReadContentOf   Operation = "ReadContentOf"
+	OperationStreamContentOf Operation = "StreamContentOf"
+	OperationCheckIfExists   Operation = "CheckIfExists"
+	OperationCreateFile      Operation = "CreateFile"
+	OperationWriteContentTo  Operation = "WriteContentTo"
+	OperationStreamContentTo Operation = "StreamContentTo"
+	OperationCreateDirectory Operation = "CreateDirectory"
+	OperationRemove          Operation = "Remove"
+	OperationMove            Operation = "Move"
+	OperationCopy            Operation = "Copy"
+	OperationListFilesIn     Operation = "ListFilesIn"
+	OperationGlob            Operation = "Glob"
+	OperationWalk            Operation = "Walk"
+)
+
+var operationDescriptions = map[Operation]string{
+	OperationReadContentOf:   "failed to read content of",
+	OperationStreamContentOf: "failed to attach reader to",
+	OperationCheckIfExists:   "failed to verify existence of",
+	OperationCreateFile:      "failed to create file",
+	OperationWriteContentTo:  "failed to write content to",
+	OperationStreamContentTo: "failed to stream content to",
+	OperationCreateDirectory: "failed to create directory at",
+	OperationRemove:          "failed to remove",
+	OperationMove:            "failed to move",
+	OperationCopy:            "failed to copy",
+	OperationListFilesIn:     "failed to list files in",
+	OperationGlob:            "failed to resolve pattern",
+	OperationWalk:            "failed to walk",
+}
+
+// Operation is a name of wrapper function (e.g. "ReadContentOf") used to describe what was performed on the path.
+type Operation string
+
+func (o Operation) String() string {
+	return string(o)
+}
+
+func (o Operation) describeFailure() string {
+	if desc, ok := operationDescriptions[o]; ok {
+		return desc
+	}
+	return "failed to perform " + o.String() + " on"
+}
@@ -10,8 +10,31 @@ const (
 	optionWriteContentToHandler  options.OptionKey = `write_content_to_handler`
 	optionStreamContentToHandler options.OptionKey = `stream_content_to_handler`
 	optionCreateDirectoryHandler options.OptionKey = `create_directory_handler`
+	optionRemoveHandler          options.OptionKey = `remove_handler`
+	optionMoveHandler            options.OptionKey = `move_handler`
+	optionCopyHandler            options.OptionKey = `copy_handler`
+	optionListFilesInHandler     options.OptionKey = `list_files_in_handler`
+	optionGlobHandler            options.OptionKey = `glob_handler`
+	optionBackendName            options.OptionKey = `backend_name`
+	optionMiddlewares            options.OptionKey = `middlewares`
+	optionLocalDisk              options.OptionKey = `local_disk`
 )
 
+// handlerOptionKeys lists options overriding handlers which resolve paths (see OptionLocalDisk).
+var handlerOptionKeys = []options.OptionKey{
+	optionReadContentOfHandler,
+	optionStreamContentOfHandler,
+	optionCheckIfExistsHandler,
+	optionCreateFileHandler,
+	optionWriteContentToHandler,
+	optionStreamContentToHandler,
+	optionCreateDirectoryHandler,
+	optionRemoveHandler,
+	optionMoveHandler,
+	optionCopyHandler,
+	optionListFilesInHandler,
+}
+
 // OptionReadContentOfHandler overrides default handler for ReadContentOf.
 func OptionReadContentOfHandler(handlerFunc ReadContentOfHandlerFunc) options.Option {
 	return func(r options.Resolver) {
@@ -56,3 +79,147 @@ func OptionCreateDirectory(handlerFunc CreateDirectoryHandlerFunc) options.Optio
 		options.WriteOrPanic[CreateDirectoryHandlerFunc](r, optionCreateDirectoryHandler, handlerFunc)
 	}
 }
+
+// OptionRemoveHandler overrides default handler for Remove.
+func OptionRemoveHandler(handlerFunc RemoveHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[RemoveHandlerFunc](r, optionRemoveHandler, handlerFunc)
+	}
+}
+
+// OptionMoveHandler overrides default handler for Move.
+func OptionMoveHandler(handlerFunc MoveHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[MoveHandlerFunc](r, optionMoveHandler, handlerFunc)
+	}
+}
+
+// OptionCopyHandler overrides default handler for Copy.
+func OptionCopyHandler(handlerFunc CopyHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[CopyHandlerFunc](r, optionCopyHandler, handlerFunc)
+	}
+}
+
+// OptionListFilesInHandler overrides default handler for ListFilesIn.
+func OptionListFilesInHandler(handlerFunc ListFilesInHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[ListFilesInHandlerFunc](r, optionListFilesInHandler, handlerFunc)
+	}
+}
+
+// OptionGlobHandler provides native handler for Glob (e.g. for backends with server-side prefix search).
+// When not provided Glob will traverse directories with handler for ListFilesIn.
+func OptionGlobHandler(handlerFunc GlobHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[GlobHandlerFunc](r, optionGlobHandler, handlerFunc)
+	}
+}
+
+// OptionReadContentOfContextHandler overrides default handler for ReadContentOf with one receiving context of the operation.
+func OptionReadContentOfContextHandler(handlerFunc ReadContentOfContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[ReadContentOfContextHandlerFunc](r, optionReadContentOfHandler, handlerFunc)
+	}
+}
+
+// OptionStreamContentOfContextHandler overrides default handler for StreamContentOf with one receiving context of the operation.
+func OptionStreamContentOfContextHandler(handlerFunc StreamContentOfContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[StreamContentOfContextHandlerFunc](r, optionStreamContentOfHandler, handlerFunc)
+	}
+}
+
+// OptionCheckIfExistsContextHandler overrides default handler for CheckIfExists with one receiving context of the operation.
+func OptionCheckIfExistsContextHandler(handlerFunc CheckIfExistsContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[CheckIfExistsContextHandlerFunc](r, optionCheckIfExistsHandler, handlerFunc)
+	}
+}
+
+// OptionCreateFileContextHandler overrides default handler for CreateFile with one receiving context of the operation.
+func OptionCreateFileContextHandler(handlerFunc CreateFileContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[CreateFileContextHandlerFunc](r, optionCreateFileHandler, handlerFunc)
+	}
+}
+
+// OptionWriteContentToContextHandler overrides default handler for WriteContentTo with one receiving context of the operation.
+func OptionWriteContentToContextHandler(handlerFunc WriteContentToContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[WriteContentToContextHandlerFunc](r, optionWriteContentToHandler, handlerFunc)
+	}
+}
+
+// OptionStreamContentToContextHandler overrides default handler for StreamContentTo with one receiving context of the operation.
+func OptionStreamContentToContextHandler(handlerFunc StreamContentToContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[StreamContentToContextHandlerFunc](r, optionStreamContentToHandler, handlerFunc)
+	}
+}
+
+// OptionCreateDirectoryContextHandler overrides default handler for CreateDirectory with one receiving context of the operation.
+func OptionCreateDirectoryContextHandler(handlerFunc CreateDirectoryContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[CreateDirectoryContextHandlerFunc](r, optionCreateDirectoryHandler, handlerFunc)
+	}
+}
+
+// OptionRemoveContextHandler overrides default handler for Remove with one receiving context of the operation.
+func OptionRemoveContextHandler(handlerFunc RemoveContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[RemoveContextHandlerFunc](r, optionRemoveHandler, handlerFunc)
+	}
+}
+
+// OptionMoveContextHandler overrides default handler for Move with one receiving context of the operation.
+func OptionMoveContextHandler(handlerFunc MoveContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[MoveContextHandlerFunc](r, optionMoveHandler, handlerFunc)
+	}
+}
+
+// OptionCopyContextHandler overrides default handler for Copy with one receiving context of the operation.
+func OptionCopyContextHandler(handlerFunc CopyContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[CopyContextHandlerFunc](r, optionCopyHandler, handlerFunc)
+	}
+}
+
+// OptionListFilesInContextHandler overrides default handler for ListFilesIn with one receiving context of the operation.
+func OptionListFilesInContextHandler(handlerFunc ListFilesInContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[ListFilesInContextHandlerFunc](r, optionListFilesInHandler, handlerFunc)
+	}
+}
+
+// OptionGlobContextHandler provides native handler for Glob receiving context of the operation (see OptionGlobHandler).
+func OptionGlobContextHandler(handlerFunc GlobContextHandlerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[GlobContextHandlerFunc](r, optionGlobHandler, handlerFunc)
+	}
+}
+
+// OptionBackendName changes name of the backend (default "os") reported in PathError.
+// It should be provided along with custom handlers to distinguish between swapped backends.
+func OptionBackendName(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBackendName, name)
+	}
+}
+
+// OptionLocalDisk declares whether handlers resolve paths on local disk (so Sub can follow symlinks with them).
+// By default, it's true unless any handler other than the one for Glob is overridden.
+func OptionLocalDisk(local bool) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[bool](r, optionLocalDisk, local)
+	}
+}
+
+// OptionMiddleware decorates every operation of created Filesystem with provided middlewares (see Wrap).
+// Handlers overridden with other options are still used underneath middlewares.
+func OptionMiddleware(mw ...Middleware) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[[]Middleware](r, optionMiddlewares, mw)
+	}
+}
@@ -0,0 +1,388 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	overlayBackendName = "overlay"
+
+	// whiteoutPrefix marks (in upper layer) files and directories removed from lower layers (same as in OCI images).
+	whiteoutPrefix = ".wh."
+	// opaqueMarker placed inside directory of upper layer hides content of the same directory in lower layers.
+	opaqueMarker = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+type overlayFilesystem struct {
+	upper  Filesystem
+	lowers []Filesystem
+}
+
+// Overlay returns Filesystem combining layers into single tree. Reads fall through from upper to lower layers
+// (in provided order) and every write goes to upper layer. File existing only in lower layer is copied to upper
+// layer before its content gets appended (Move and Copy always stream content to upper layer). Removal of path
+// existing in lower layer is recorded in upper layer as whiteout (".wh.<name>" file) so lower layers are never modified.
+func Overlay(upper Filesystem, lowers ...Filesystem) Filesystem {
+	return &overlayFilesystem{upper: upper, lowers: lowers}
+}
+
+func (fs *overlayFilesystem) backendName() string {
+	return overlayBackendName
+}
+
+func (fs *overlayFilesystem) capabilities() capabilities {
+	return capabilities{}
+}
+
+func (fs *overlayFilesystem) handleReadContentOf(ctx context.Context, p string) (Content, error) {
+	layer, err := fs.locate(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if layer == nil {
+		return nil, ErrFileNotFound
+	}
+	return layer.handleReadContentOf(ctx, p)
+}
+
+func (fs *overlayFilesystem) handleStreamContentOf(ctx context.Context, p string) (io.ReadCloser, error) {
+	layer, err := fs.locate(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if layer == nil {
+		return nil, ErrFileNotFound
+	}
+	return layer.handleStreamContentOf(ctx, p)
+}
+
+func (fs *overlayFilesystem) handleCheckIfExists(ctx context.Context, p string) (bool, error) {
+	layer, err := fs.locate(ctx, p)
+	return layer != nil, err
+}
+
+func (fs *overlayFilesystem) handleCreateFile(ctx context.Context, p string, arg Arguments) error {
+	layer, err := fs.locate(ctx, p)
+	if err != nil {
+		return err
+	}
+	if layer != nil && layer != fs.upper {
+		if !arg.AllowOverwrite {
+			return ErrFileFound
+		}
+		entry, eErr := entryOf(ctx, layer, p)
+		if eErr != nil {
+			return eErr
+		}
+		if entry.IsDirectory {
+			return ErrDirectory
+		}
+	}
+
+	if err = fs.copyUpParent(ctx, p); err != nil {
+		return err
+	}
+	if err = fs.upper.handleCreateFile(ctx, p, arg); err != nil {
+		return err
+	}
+	_, err = fs.removeWhiteout(ctx, p)
+	return err
+}
+
+func (fs *overlayFilesystem) handleWriteContentTo(ctx context.Context, p string, content []byte, arg Arguments) error {
+	if err := fs.copyUp(ctx, p, arg); err != nil {
+		return err
+	}
+	return fs.upper.handleWriteContentTo(ctx, p, content, arg)
+}
+
+func (fs *overlayFilesystem) handleStreamContentTo(ctx context.Context, p string, content io.Reader, arg Arguments) error {
+	if err := fs.copyUp(ctx, p, arg); err != nil {
+		return err
+	}
+	return fs.upper.handleStreamContentTo(ctx, p, content, arg)
+}
+
+func (fs *overlayFilesystem) handleCreateDirectory(ctx context.Context, p string, arg Arguments) error {
+	layer, err := fs.locate(ctx, p)
+	if err != nil {
+		return err
+	}
+	if layer != nil && layer != fs.upper {
+		entry, eErr := entryOf(ctx, layer, p)
+		if eErr != nil {
+			return eErr
+		}
+		if entry.IsDirectory {
+			return ErrDirectoryFound
+		}
+		return ErrFile
+	}
+
+	if err = fs.copyUpParent(ctx, p); err != nil {
+		return err
+	}
+	if err = fs.upper.handleCreateDirectory(ctx, p, arg); err != nil {
+		return err
+	}
+
+	removed, err := fs.removeWhiteout(ctx, p)
+	if err != nil || !removed {
+		return err
+	}
+	// Directory removed earlier from lower layer must not reveal its old content.
+	return fs.upper.handleCreateFile(ctx, path.Join(p, opaqueMarker), Arguments{Mode: ModeAllReadWrite, AllowOverwrite: true})
+}
+
+func (fs *overlayFilesystem) handleRemove(ctx context.Context, p string, arg Arguments) error {
+	layer, err := fs.locate(ctx, p)
+	if err != nil {
+		return err
+	}
+	if layer == nil {
+		return ErrFileNotFound
+	}
+
+	entry, err := entryOf(ctx, fs, p)
+	if err != nil {
+		return err
+	}
+	if entry.IsDirectory && !arg.Recursive {
+		entries, lErr := fs.handleListFilesIn(ctx, p)
+		if lErr != nil {
+			return lErr
+		}
+		if len(entries) > 0 {
+			return ErrNotEmpty
+		}
+	}
+
+	lower := layer
+	if layer == fs.upper {
+		if lower, err = fs.locateInLowers(ctx, p); err != nil {
+			return err
+		}
+		// Directory may still contain whiteouts and opaque marker even though it looks empty.
+		upperArg := arg
+		upperArg.Recursive = arg.Recursive || entry.IsDirectory
+		if err = fs.upper.handleRemove(ctx, p, upperArg); err != nil {
+			return err
+		}
+	}
+	if lower == nil {
+		return nil
+	}
+
+	if err = fs.copyUpParent(ctx, p); err != nil {
+		return err
+	}
+	return fs.upper.handleCreateFile(ctx, whiteoutOf(p), Arguments{Mode: ModeAllReadWrite, AllowOverwrite: true})
+}
+
+// handleMove copies source to upper layer and removes it (recording whiteout when needed)
+// since lower layers can't be modified.
+func (fs *overlayFilesystem) handleMove(ctx context.Context, source, target string, arg Arguments) error {
+	arg.Recursive = true
+	if err := transfer(ctx, fs, source, fs, target, arg); err != nil {
+		return err
+	}
+	return fs.handleRemove(ctx, source, Arguments{Recursive: true})
+}
+
+func (fs *overlayFilesystem) handleCopy(ctx context.Context, source, target string, arg Arguments) error {
+	return transfer(ctx, fs, source, fs, target, arg)
+}
+
+func (fs *overlayFilesystem) handleListFilesIn(ctx context.Context, p string) ([]Entry, error) {
+	hidden, err := fs.hidden(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	seen := make(map[string]struct{})
+	res := make([]Entry, 0)
+
+	for i, layer := range append([]Filesystem{fs.upper}, fs.lowers...) {
+		if i > 0 && hidden {
+			break
+		}
+
+		entries, lErr := layer.handleListFilesIn(ctx, p)
+		switch {
+		case errors.Is(lErr, ErrFileNotFound):
+			continue
+		case errors.Is(lErr, ErrFile) && found:
+			continue
+		case lErr != nil:
+			return nil, lErr
+		}
+		found = true
+
+		for _, entry := range entries {
+			if i == 0 && strings.HasPrefix(entry.Name, whiteoutPrefix) {
+				if entry.Name == opaqueMarker {
+					hidden = true
+				} else {
+					seen[strings.TrimPrefix(entry.Name, whiteoutPrefix)] = struct{}{}
+				}
+				continue
+			}
+			if _, ok := seen[entry.Name]; ok {
+				continue
+			}
+			seen[entry.Name] = struct{}{}
+			res = append(res, entry)
+		}
+	}
+	if !found {
+		return nil, ErrFileNotFound
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+func (fs *overlayFilesystem) handleGlob(ctx context.Context, pattern string) ([]string, error) {
+	return globWithListing(ctx, fs, pattern)
+}
+
+// locate returns the topmost layer containing visible path or nil if path is missing (or removed with whiteout).
+func (fs *overlayFilesystem) locate(ctx context.Context, p string) (Filesystem, error) {
+	exists, err := fs.upper.handleCheckIfExists(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return fs.upper, nil
+	}
+	return fs.locateInLowers(ctx, p)
+}
+
+func (fs *overlayFilesystem) locateInLowers(ctx context.Context, p string) (Filesystem, error) {
+	hidden, err := fs.hidden(ctx, p)
+	if err != nil || hidden {
+		return nil, err
+	}
+
+	for _, lower := range fs.lowers {
+		exists, eErr := lower.handleCheckIfExists(ctx, p)
+		if eErr != nil {
+			return nil, eErr
+		}
+		if exists {
+			return lower, nil
+		}
+	}
+	return nil, nil
+}
+
+// hidden reports if lower layers must not be consulted for path because it (or one of its parents) was removed
+// or one of its parents was created again in upper layer.
+func (fs *overlayFilesystem) hidden(ctx context.Context, p string) (bool, error) {
+	p = path.Clean(p)
+	for current := p; ; current = path.Dir(current) {
+		name, parent := path.Base(current), path.Dir(current)
+		if name == "." || name == "/" || parent == current {
+			return false, nil
+		}
+
+		whiteout, err := fs.upper.handleCheckIfExists(ctx, whiteoutOf(current))
+		if err != nil || whiteout {
+			return whiteout, err
+		}
+		if current == p {
+			continue
+		}
+		opaque, err := fs.upper.handleCheckIfExists(ctx, path.Join(current, opaqueMarker))
+		if err != nil || opaque {
+			return opaque, err
+		}
+	}
+}
+
+// copyUp creates file visible only in lower layer in upper layer. Content is copied only when it's going
+// to be appended since overwrite would replace it anyway.
+func (fs *overlayFilesystem) copyUp(ctx context.Context, p string, arg Arguments) error {
+	layer, err := fs.locate(ctx, p)
+	if err != nil || layer == nil || layer == fs.upper {
+		// Missing file is reported by handler of upper layer.
+		return err
+	}
+
+	entry, err := entryOf(ctx, layer, p)
+	if err != nil {
+		return err
+	}
+	if entry.IsDirectory {
+		return ErrDirectory
+	}
+
+	if err = fs.copyUpParent(ctx, p); err != nil {
+		return err
+	}
+	if err = fs.upper.handleCreateFile(ctx, p, Arguments{Mode: entry.Mode | ModeUserReadWrite, AllowOverwrite: true}); err != nil {
+		return err
+	}
+	if !arg.ContentOperation.Is(ContentOperationAppend) {
+		return nil
+	}
+
+	rc, err := layer.handleStreamContentOf(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	return fs.upper.handleStreamContentTo(ctx, p, rc, Arguments{ContentOperation: ContentOperationOverwrite})
+}
+
+// copyUpParent creates parent directory of path in upper layer if it's visible only in lower layer.
+func (fs *overlayFilesystem) copyUpParent(ctx context.Context, p string) error {
+	parent := path.Dir(path.Clean(p))
+	if parent == "." || parent == "/" {
+		return nil
+	}
+
+	exists, err := fs.upper.handleCheckIfExists(ctx, parent)
+	if err != nil || exists {
+		return err
+	}
+	layer, err := fs.locateInLowers(ctx, parent)
+	if err != nil || layer == nil {
+		// Missing directory structure is handled by upper layer according to arguments.
+		return err
+	}
+	if err = fs.copyUpParent(ctx, parent); err != nil {
+		return err
+	}
+
+	entry, err := entryOf(ctx, layer, parent)
+	if err != nil {
+		return err
+	}
+	return fs.upper.handleCreateDirectory(ctx, parent, Arguments{
+		Mode:                   entry.Mode | ModeUserReadWriteExecute,
+		DirectoryStructureMode: ModeUserReadWriteExecute,
+	})
+}
+
+// removeWhiteout removes whiteout of path from upper layer and reports if it was there.
+func (fs *overlayFilesystem) removeWhiteout(ctx context.Context, p string) (bool, error) {
+	whiteout := whiteoutOf(p)
+	exists, err := fs.upper.handleCheckIfExists(ctx, whiteout)
+	if err != nil || !exists {
+		return false, err
+	}
+	return true, fs.upper.handleRemove(ctx, whiteout, Arguments{})
+}
+
+func whiteoutOf(p string) string {
+	p = path.Clean(p)
+	return path.Join(path.Dir(p), whiteoutPrefix+path.Base(p))
+}
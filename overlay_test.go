@@ -0,0 +1,228 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOverlay(t *testing.T, workdir string) Filesystem {
+	t.Helper()
+
+	lower, err := NewFromFS(fstest.MapFS{
+		"app.yaml":         {Data: []byte("DEFAULT"), Mode: 0o444},
+		"conf.d/a.yaml":    {Data: []byte("A"), Mode: 0o444},
+		"conf.d/b.yaml":    {Data: []byte("B"), Mode: 0o444},
+		"conf.d/x/c.yaml":  {Data: []byte("C"), Mode: 0o444},
+		"templates/t.tmpl": {Data: []byte("T"), Mode: 0o444},
+	})
+	require.NoError(t, err)
+
+	upper, err := New()
+	require.NoError(t, err)
+
+	return Overlay(Sub(upper, workdir), lower)
+}
+
+func listNames(t *testing.T, fs Filesystem, p string) []string {
+	t.Helper()
+
+	entries, err := ListFilesIn(fs, p)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestOverlay(t *testing.T) {
+	t.Run("it should fall through to lower layers", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "app.yaml"), []byte("OVERRIDDEN"), 0o600))
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "local.yaml"), []byte("LOCAL"), 0o600))
+			fs := newTestOverlay(t, workdir)
+
+			// WHEN
+			overridden, overriddenErr := ReadContentOf(fs, "app.yaml")
+			fallback, fallbackErr := ReadContentOf(fs, "conf.d/a.yaml")
+			exists, existsErr := CheckIfExists(fs, "templates/t.tmpl")
+			_, missingErr := ReadContentOf(fs, "missing.yaml")
+
+			// THEN
+			require.NoError(t, overriddenErr)
+			require.NoError(t, fallbackErr)
+			require.NoError(t, existsErr)
+			require.ErrorIs(t, missingErr, ErrFileNotFound)
+			assert.Equal(t, Content("OVERRIDDEN"), overridden)
+			assert.Equal(t, Content("A"), fallback)
+			assert.True(t, exists)
+			assert.Equal(t, []string{"app.yaml", "conf.d", "local.yaml", "templates"}, listNames(t, fs, "."))
+		})
+	})
+	t.Run("it should copy file up before appending content to it", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestOverlay(t, workdir)
+
+			// WHEN
+			appendErr := WriteContentTo(fs, "conf.d/a.yaml", " MORE")
+			overwriteErr := WriteContentTo(fs, "conf.d/b.yaml", "NEW", WithContentOperation(ContentOperationOverwrite))
+
+			// THEN
+			require.NoError(t, appendErr)
+			require.NoError(t, overwriteErr)
+
+			appended, err := os.ReadFile(filepath.Join(workdir, "conf.d", "a.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, "A MORE", string(appended))
+
+			overwritten, err := os.ReadFile(filepath.Join(workdir, "conf.d", "b.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, "NEW", string(overwritten))
+
+			assert.Equal(t, []string{"a.yaml", "b.yaml", "x"}, listNames(t, fs, "conf.d"))
+		})
+	})
+	t.Run("it should write new files to upper layer", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestOverlay(t, workdir)
+
+			// WHEN
+			createErr := CreateFile(fs, "conf.d/x/d.yaml")
+			existingErr := CreateFile(fs, "conf.d/a.yaml")
+			directoryErr := CreateDirectory(fs, "templates")
+
+			// THEN
+			require.NoError(t, createErr)
+			require.ErrorIs(t, existingErr, ErrFileFound)
+			require.ErrorIs(t, directoryErr, ErrDirectoryFound)
+			assert.FileExists(t, filepath.Join(workdir, "conf.d", "x", "d.yaml"))
+			assert.Equal(t, []string{"c.yaml", "d.yaml"}, listNames(t, fs, "conf.d/x"))
+		})
+	})
+	t.Run("it should record removal of lower layer paths as whiteouts", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "app.yaml"), []byte("OVERRIDDEN"), 0o600))
+			fs := newTestOverlay(t, workdir)
+
+			// WHEN
+			fileErr := Remove(fs, "app.yaml")
+			notEmptyErr := Remove(fs, "conf.d")
+			directoryErr := Remove(fs, "templates", WithRecursive(true))
+			_, readErr := ReadContentOf(fs, "app.yaml")
+			_, nestedErr := ReadContentOf(fs, "templates/t.tmpl")
+			missingErr := Remove(fs, "app.yaml")
+
+			// THEN
+			require.NoError(t, fileErr)
+			require.ErrorIs(t, notEmptyErr, ErrNotEmpty)
+			require.NoError(t, directoryErr)
+			require.ErrorIs(t, readErr, ErrFileNotFound)
+			require.ErrorIs(t, nestedErr, ErrFileNotFound)
+			require.ErrorIs(t, missingErr, ErrFileNotFound)
+			assert.FileExists(t, filepath.Join(workdir, ".wh.app.yaml"))
+			assert.FileExists(t, filepath.Join(workdir, ".wh.templates"))
+			assert.Equal(t, []string{"conf.d"}, listNames(t, fs, "."))
+		})
+	})
+	t.Run("it should not reveal old content of directory created again after removal", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestOverlay(t, workdir)
+			require.NoError(t, Remove(fs, "conf.d", WithRecursive(true)))
+
+			// WHEN
+			err := CreateDirectory(fs, "conf.d")
+
+			// THEN
+			require.NoError(t, err)
+			assert.Empty(t, listNames(t, fs, "conf.d"))
+
+			exists, err := CheckIfExists(fs, "conf.d/a.yaml")
+			require.NoError(t, err)
+			assert.False(t, exists)
+
+			require.NoError(t, CreateFile(fs, "conf.d/a.yaml"))
+			content, err := ReadContentOf(fs, "conf.d/a.yaml")
+			require.NoError(t, err)
+			assert.Empty(t, content)
+
+			paths, err := Glob(fs, "**/*.yaml")
+			require.NoError(t, err)
+			for _, p := range paths {
+				assert.False(t, strings.Contains(p, whiteoutPrefix), p)
+			}
+			assert.Equal(t, []string{"app.yaml", "conf.d/a.yaml"}, paths)
+		})
+	})
+	t.Run("it should never modify lower layers", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			require.NoError(t, os.MkdirAll(filepath.Join(workdir, "upper"), 0o700))
+			require.NoError(t, os.MkdirAll(filepath.Join(workdir, "lower"), 0o700))
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "lower", "a.txt"), []byte("TEST"), 0o600))
+
+			local, err := New()
+			require.NoError(t, err)
+			fs := Overlay(Sub(local, filepath.Join(workdir, "upper")), ReadOnly(Sub(local, filepath.Join(workdir, "lower"))))
+
+			// WHEN
+			writeErr := WriteContentTo(fs, "a.txt", " MORE")
+			content, readErr := ReadContentOf(fs, "a.txt")
+			removeErr := Remove(fs, "a.txt")
+
+			// THEN
+			require.NoError(t, writeErr)
+			require.NoError(t, readErr)
+			require.NoError(t, removeErr)
+			assert.Equal(t, Content("TEST MORE"), content)
+
+			lower, err := os.ReadFile(filepath.Join(workdir, "lower", "a.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "TEST", string(lower))
+		})
+	})
+	t.Run("it should not lose file moved onto itself", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestOverlay(t, workdir)
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "local.yaml"), []byte("LOCAL"), 0o600))
+
+			// WHEN
+			errUpper := Move(fs, "local.yaml", "local.yaml", WithAllowOverwrite(true))
+			errLower := Move(fs, "conf.d", "conf.d/x", WithAllowOverwrite(true))
+
+			// THEN
+			require.ErrorIs(t, errUpper, ErrUnresolvableDirectoryStructure)
+			require.ErrorIs(t, errLower, ErrUnresolvableDirectoryStructure)
+			local, err := ReadContentOf(fs, "local.yaml")
+			require.NoError(t, err)
+			assert.Equal(t, Content("LOCAL"), local)
+			assert.Equal(t, []string{"a.yaml", "b.yaml", "x"}, listNames(t, fs, "conf.d"))
+		})
+	})
+}
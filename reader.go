@@ -0,0 +1,76 @@
+package filesystem
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// CountReader returns io.Reader counting bytes read from reader along with function reporting the count.
+// Returned reader implements io.Seeker when reader does, so middlewares placed after the one counting bytes
+// (e.g. created with NewRetryMiddleware) are still able to rewind it. Count follows the position then
+// (seeking back to the start resets it).
+func CountReader(reader io.Reader) (io.Reader, func() int64) {
+	counter := &countingReader{reader: reader}
+	if seeker, ok := reader.(io.Seeker); ok {
+		return &countingReadSeeker{countingReader: counter, seeker: seeker}, counter.Count
+	}
+	return counter, counter.Count
+}
+
+// ObserveReadCloser returns io.ReadCloser counting bytes read from rc and reporting them (once) to onClose
+// when it gets closed.
+func ObserveReadCloser(rc io.ReadCloser, onClose func(read int64, err error)) io.ReadCloser {
+	return &observedReadCloser{countingReader: &countingReader{reader: rc}, closer: rc, onClose: onClose}
+}
+
+// countingReader counts bytes read from underlying io.Reader.
+type countingReader struct {
+	reader io.Reader
+	count  atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count.Add(int64(n))
+	return n, err
+}
+
+func (r *countingReader) Count() int64 {
+	return r.count.Load()
+}
+
+// countingReadSeeker is countingReader moving the count along with position of underlying io.Seeker.
+type countingReadSeeker struct {
+	*countingReader
+	seeker io.Seeker
+}
+
+func (r *countingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	current, err := r.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	position, err := r.seeker.Seek(offset, whence)
+	if err != nil {
+		return position, err
+	}
+	r.count.Add(position - current)
+	return position, nil
+}
+
+// observedReadCloser counts bytes read from underlying io.ReadCloser and reports them (once) when it gets closed.
+type observedReadCloser struct {
+	*countingReader
+	closer  io.Closer
+	onClose func(read int64, err error)
+	once    sync.Once
+}
+
+func (r *observedReadCloser) Close() error {
+	err := r.closer.Close()
+	r.once.Do(func() {
+		r.onClose(r.Count(), err)
+	})
+	return err
+}
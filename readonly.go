@@ -0,0 +1,24 @@
+package filesystem
+
+// readOnlyOperations lists operations which never change state of the backend.
+var readOnlyOperations = map[Operation]bool{
+	OperationReadContentOf:   true,
+	OperationStreamContentOf: true,
+	OperationCheckIfExists:   true,
+	OperationListFilesIn:     true,
+	OperationGlob:            true,
+	OperationWalk:            true,
+}
+
+// ReadOnly returns Filesystem passing reads through to fs and failing every other operation with ErrReadOnly.
+// Operations that are not known to be read-only (including ones added in the future) never reach fs.
+func ReadOnly(fs Filesystem) Filesystem {
+	return Wrap(fs, func(next Invoker) Invoker {
+		return func(call Call) (Result, error) {
+			if !readOnlyOperations[call.Operation] {
+				return Result{}, ErrReadOnly
+			}
+			return next(call)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package filesystem
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnly(t *testing.T) {
+	t.Run("it should pass reads through", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := ReadOnly(newFakeTreeFilesystem(t, map[string]string{"a/b.txt": "TEST"}))
+
+		// WHEN
+		content, readErr := ReadContentOf(fs, "a/b.txt")
+		exists, existsErr := CheckIfExists(fs, "a/b.txt")
+		entries, listErr := ListFilesIn(fs, "a")
+
+		// THEN
+		require.NoError(t, readErr)
+		require.NoError(t, existsErr)
+		require.NoError(t, listErr)
+		assert.Equal(t, Content("TEST"), content)
+		assert.True(t, exists)
+		assert.Len(t, entries, 1)
+	})
+	t.Run("it should fail every mutating operation without reaching inner handlers", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		reached := false
+		inner, err := New(
+			OptionCreateFileHandler(func(string, Arguments) error {
+				reached = true
+				return nil
+			}),
+			OptionWriteContentToHandler(func(string, []byte, Arguments) error {
+				reached = true
+				return nil
+			}),
+			OptionStreamContentToHandler(func(string, io.Reader, Arguments) error {
+				reached = true
+				return nil
+			}),
+			OptionCreateDirectory(func(string, Arguments) error {
+				reached = true
+				return nil
+			}),
+		)
+		require.NoError(t, err)
+		fs := ReadOnly(inner)
+
+		// WHEN
+		errs := []error{
+			CreateFile(fs, "target"),
+			WriteContentTo(fs, "target", "TEST"),
+			StreamContentTo(fs, "target", strings.NewReader("TEST")),
+			CreateDirectory(fs, "target"),
+		}
+		_, unknownErr := fs.(*middlewareFilesystem).invoke(Call{Operation: "Remove", Path: "target"})
+
+		// THEN
+		for _, err := range append(errs, unknownErr) {
+			require.ErrorIs(t, err, ErrReadOnly)
+		}
+		assert.False(t, reached)
+	})
+}
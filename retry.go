@@ -0,0 +1,210 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+)
+
+const (
+	optionRetryAttempts      options.OptionKey = `retry_attempts`
+	optionRetryInitialDelay  options.OptionKey = `retry_initial_delay`
+	optionRetryMaxDelay      options.OptionKey = `retry_max_delay`
+	optionRetryClassifier    options.OptionKey = `retry_classifier`
+	optionRetryNonIdempotent options.OptionKey = `retry_non_idempotent`
+)
+
+const (
+	defaultRetryAttempts     = 3
+	defaultRetryInitialDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay     = 5 * time.Second
+)
+
+// RetryClassifierFunc decides if failed operation can be performed again.
+// It's never consulted for sentinel errors of this package (e.g. ErrFileNotFound) as those are never retried.
+type RetryClassifierFunc func(call Call, err error) bool
+
+// OptionRetryAttempts changes maximal amount of attempts (default 3) including the first one.
+func OptionRetryAttempts(attempts int) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[int](r, optionRetryAttempts, attempts)
+	}
+}
+
+// OptionRetryInitialDelay changes delay (default 100ms) before the second attempt.
+// Every following delay is doubled (up to maximal delay) and randomized with full jitter.
+func OptionRetryInitialDelay(delay time.Duration) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Duration](r, optionRetryInitialDelay, delay)
+	}
+}
+
+// OptionRetryMaxDelay changes upper limit (default 5s) of delay between attempts.
+func OptionRetryMaxDelay(delay time.Duration) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Duration](r, optionRetryMaxDelay, delay)
+	}
+}
+
+// OptionRetryClassifier overrides function deciding which errors are transient.
+// By default, every error except sentinels of this package and context errors is retried.
+func OptionRetryClassifier(classifier RetryClassifierFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[RetryClassifierFunc](r, optionRetryClassifier, classifier)
+	}
+}
+
+// OptionRetryNonIdempotent allows retrying operations which may not be safe to repeat (when the first attempt
+// succeeded but its outcome got lost, e.g. on network backends, the next one fails or duplicates the effect):
+// WriteContentTo and StreamContentTo in append mode, CreateFile without AllowOverwrite, CreateDirectory, Remove,
+// Move and Copy. StreamContentTo with io.Reader that cannot be rewound (doesn't implement io.Seeker) is never retried.
+func OptionRetryNonIdempotent(allow bool) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[bool](r, optionRetryNonIdempotent, allow)
+	}
+}
+
+type retryMiddleware struct {
+	attempts      int
+	initialDelay  time.Duration
+	maxDelay      time.Duration
+	classifier    RetryClassifierFunc
+	nonIdempotent bool
+}
+
+// NewRetryMiddleware creates Middleware performing failed operations again with exponential backoff.
+func NewRetryMiddleware(opts ...options.Option) (Middleware, error) {
+	opt := options.Resolve(opts)
+
+	optAttempts, err := options.ReadOrDefault[int](opt, optionRetryAttempts, defaultRetryAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("retry middleware initialization failed: %w", err)
+	}
+	optInitialDelay, err := options.ReadOrDefault[time.Duration](opt, optionRetryInitialDelay, defaultRetryInitialDelay)
+	if err != nil {
+		return nil, fmt.Errorf("retry middleware initialization failed: %w", err)
+	}
+	optMaxDelay, err := options.ReadOrDefault[time.Duration](opt, optionRetryMaxDelay, defaultRetryMaxDelay)
+	if err != nil {
+		return nil, fmt.Errorf("retry middleware initialization failed: %w", err)
+	}
+	optClassifier, err := options.ReadOrDefault[RetryClassifierFunc](opt, optionRetryClassifier, defaultRetryClassifier)
+	if err != nil {
+		return nil, fmt.Errorf("retry middleware initialization failed: %w", err)
+	}
+	optNonIdempotent, err := options.ReadOrDefault[bool](opt, optionRetryNonIdempotent, false)
+	if err != nil {
+		return nil, fmt.Errorf("retry middleware initialization failed: %w", err)
+	}
+
+	if optAttempts < 1 {
+		return nil, fmt.Errorf("retry middleware initialization failed: amount of attempts must be positive, got %d", optAttempts)
+	}
+
+	m := &retryMiddleware{
+		attempts:      optAttempts,
+		initialDelay:  optInitialDelay,
+		maxDelay:      optMaxDelay,
+		classifier:    optClassifier,
+		nonIdempotent: optNonIdempotent,
+	}
+
+	return m.wrap, nil
+}
+
+func defaultRetryClassifier(_ Call, err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func (m *retryMiddleware) wrap(next Invoker) Invoker {
+	return func(call Call) (Result, error) {
+		rewind, idempotent := m.prepare(call)
+		if call.Operation == OperationStreamContentTo && rewind == nil {
+			// Part of the content could already be consumed by failed attempt, so the next one would write the rest only.
+			return next(call)
+		}
+
+		delay := m.initialDelay
+		for attempt := 1; ; attempt++ {
+			res, err := next(call)
+			if err == nil || attempt >= m.attempts || !m.retryable(call, err, idempotent) {
+				return res, err
+			}
+
+			if wErr := m.sleep(call.Context, delay); wErr != nil {
+				return res, err
+			}
+			delay = min(delay*2, m.maxDelay)
+
+			if rewind != nil {
+				if rErr := rewind(); rErr != nil {
+					return res, err
+				}
+			}
+		}
+	}
+}
+
+// prepare returns function restoring position of StreamContentTo reader (if possible) and information
+// if operation can be safely repeated.
+func (m *retryMiddleware) prepare(call Call) (func() error, bool) {
+	switch call.Operation {
+	case OperationWriteContentTo:
+		return nil, !call.Arguments.ContentOperation.Is(ContentOperationAppend)
+	case OperationStreamContentTo:
+		seeker, ok := call.Reader.(io.Seeker)
+		if !ok {
+			return nil, false
+		}
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, false
+		}
+		rewind := func() error {
+			_, sErr := seeker.Seek(offset, io.SeekStart)
+			return sErr
+		}
+		return rewind, !call.Arguments.ContentOperation.Is(ContentOperationAppend)
+	case OperationCreateFile:
+		return nil, call.Arguments.AllowOverwrite
+	case OperationCreateDirectory, OperationRemove, OperationMove, OperationCopy:
+		return nil, false
+	default:
+		return nil, true
+	}
+}
+
+func (m *retryMiddleware) retryable(call Call, err error, idempotent bool) bool {
+	if ErrorKind(err) != "other" {
+		return false
+	}
+	if !idempotent && !m.nonIdempotent {
+		return false
+	}
+	return m.classifier(call, err)
+}
+
+// sleep waits for randomized delay (full jitter) or until context is done.
+func (m *retryMiddleware) sleep(ctx context.Context, delay time.Duration) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(delay)) + 1)) //nolint:gosec
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
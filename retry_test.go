@@ -0,0 +1,377 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryMiddleware(t *testing.T) {
+	newRetry := func(t *testing.T) Middleware {
+		t.Helper()
+
+		mw, err := NewRetryMiddleware(
+			OptionRetryInitialDelay(time.Millisecond),
+			OptionRetryMaxDelay(2*time.Millisecond),
+		)
+		require.NoError(t, err)
+		return mw
+	}
+
+	t.Run("it should retry transient error until operation succeeds", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		calls := 0
+		fs, err := New(
+			OptionReadContentOfHandler(func(string) (Content, error) {
+				calls++
+				if calls < 3 {
+					return nil, errors.New("connection reset")
+				}
+				return Content("TEST"), nil
+			}),
+			OptionMiddleware(newRetry(t)),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		content, err := ReadContentOf(fs, "source")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, Content("TEST"), content)
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("it should give up after configured amount of attempts", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		failure := errors.New("connection reset")
+		mw, err := NewRetryMiddleware(OptionRetryAttempts(2), OptionRetryInitialDelay(time.Millisecond))
+		require.NoError(t, err)
+
+		calls := 0
+		fs, err := New(
+			OptionCheckIfExistsHandler(func(string) (bool, error) {
+				calls++
+				return false, failure
+			}),
+			OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = CheckIfExists(fs, "source")
+
+		// THEN
+		require.ErrorIs(t, err, failure)
+		assert.Equal(t, 2, calls)
+	})
+	t.Run("it should never retry sentinel errors", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		calls := 0
+		fs, err := New(
+			OptionReadContentOfHandler(func(string) (Content, error) {
+				calls++
+				return nil, ErrFileNotFound
+			}),
+			OptionCreateFileHandler(func(string, Arguments) error {
+				calls++
+				return ErrFileFound
+			}),
+			OptionMiddleware(newRetry(t)),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		_, readErr := ReadContentOf(fs, "source")
+		createErr := CreateFile(fs, "target")
+
+		// THEN
+		require.ErrorIs(t, readErr, ErrFileNotFound)
+		require.ErrorIs(t, createErr, ErrFileFound)
+		assert.Equal(t, 2, calls)
+	})
+	t.Run("it should retry only errors accepted by classifier", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		permanent := errors.New("bad request")
+		mw, err := NewRetryMiddleware(
+			OptionRetryInitialDelay(time.Millisecond),
+			OptionRetryClassifier(func(_ Call, err error) bool { return !errors.Is(err, permanent) }),
+		)
+		require.NoError(t, err)
+
+		calls := 0
+		fs, err := New(
+			OptionReadContentOfHandler(func(string) (Content, error) {
+				calls++
+				return nil, permanent
+			}),
+			OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = ReadContentOf(fs, "source")
+
+		// THEN
+		require.ErrorIs(t, err, permanent)
+		assert.Equal(t, 1, calls)
+	})
+	t.Run("it should not retry non-idempotent operations unless allowed", func(t *testing.T) {
+		t.Parallel()
+
+		for _, allow := range []bool{false, true} {
+			// GIVEN
+			mw, err := NewRetryMiddleware(OptionRetryInitialDelay(time.Millisecond), OptionRetryNonIdempotent(allow))
+			require.NoError(t, err)
+
+			writes, streams := 0, 0
+			fs, err := New(
+				OptionWriteContentToHandler(func(string, []byte, Arguments) error {
+					writes++
+					return errors.New("connection reset")
+				}),
+				OptionStreamContentToHandler(func(string, io.Reader, Arguments) error {
+					streams++
+					return errors.New("connection reset")
+				}),
+				OptionMiddleware(mw),
+			)
+			require.NoError(t, err)
+
+			// WHEN
+			writeErr := WriteContentTo(fs, "target", "TEST", WithContentOperation(ContentOperationAppend))
+			streamErr := StreamContentTo(fs, "target", strings.NewReader("TEST"), WithContentOperation(ContentOperationAppend))
+
+			// THEN
+			require.Error(t, writeErr)
+			require.Error(t, streamErr)
+			if allow {
+				assert.Equal(t, defaultRetryAttempts, writes)
+				assert.Equal(t, defaultRetryAttempts, streams)
+			} else {
+				assert.Equal(t, 1, writes)
+				assert.Equal(t, 1, streams)
+			}
+		}
+	})
+	t.Run("it should not retry move which succeeded before failing unless allowed", func(t *testing.T) {
+		t.Parallel()
+
+		for _, allow := range []bool{false, true} {
+			// GIVEN
+			mw, err := NewRetryMiddleware(OptionRetryInitialDelay(time.Millisecond), OptionRetryNonIdempotent(allow))
+			require.NoError(t, err)
+
+			failure := errors.New("connection reset")
+			moved, calls := false, 0
+			fs, err := New(
+				OptionMoveHandler(func(string, string, Arguments) error {
+					calls++
+					if moved {
+						return ErrFileNotFound
+					}
+					moved = true
+					return failure
+				}),
+				OptionMiddleware(mw),
+			)
+			require.NoError(t, err)
+
+			// WHEN
+			err = Move(fs, "source", "target")
+
+			// THEN
+			if allow {
+				require.ErrorIs(t, err, ErrFileNotFound)
+				assert.Equal(t, 2, calls)
+			} else {
+				require.ErrorIs(t, err, failure)
+				assert.Equal(t, 1, calls)
+			}
+		}
+	})
+	t.Run("it should retry only creation of file allowed to overwrite existing one", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		creates, directories, removals, copies := 0, 0, 0, 0
+		fs, err := New(
+			OptionCreateFileHandler(func(string, Arguments) error {
+				creates++
+				return errors.New("connection reset")
+			}),
+			OptionCreateDirectory(func(string, Arguments) error {
+				directories++
+				return errors.New("connection reset")
+			}),
+			OptionRemoveHandler(func(string, Arguments) error {
+				removals++
+				return errors.New("connection reset")
+			}),
+			OptionCopyHandler(func(string, string, Arguments) error {
+				copies++
+				return errors.New("connection reset")
+			}),
+			OptionMiddleware(newRetry(t)),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		overwriteErr := CreateFile(fs, "target", WithAllowOverwrite(true))
+		createErr := CreateFile(fs, "target")
+		directoryErr := CreateDirectory(fs, "directory")
+		removeErr := Remove(fs, "target")
+		copyErr := Copy(fs, "source", "target", WithAllowOverwrite(true))
+
+		// THEN
+		require.Error(t, overwriteErr)
+		require.Error(t, createErr)
+		require.Error(t, directoryErr)
+		require.Error(t, removeErr)
+		require.Error(t, copyErr)
+		assert.Equal(t, defaultRetryAttempts+1, creates)
+		assert.Equal(t, 1, directories)
+		assert.Equal(t, 1, removals)
+		assert.Equal(t, 1, copies)
+	})
+	t.Run("it should never retry stream with reader that cannot be rewound", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		mw, err := NewRetryMiddleware(OptionRetryInitialDelay(time.Millisecond), OptionRetryNonIdempotent(true))
+		require.NoError(t, err)
+
+		var received []string
+		fs, err := New(
+			OptionStreamContentToHandler(func(_ string, r io.Reader, _ Arguments) error {
+				buff := make([]byte, 2)
+				n, rErr := r.Read(buff)
+				require.NoError(t, rErr)
+				received = append(received, string(buff[:n]))
+				return errors.New("connection reset")
+			}),
+			OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = StreamContentTo(fs, "target", io.MultiReader(strings.NewReader("TEST")), WithContentOperation(ContentOperationOverwrite))
+
+		// THEN
+		require.Error(t, err)
+		assert.Equal(t, []string{"TE"}, received)
+	})
+	t.Run("it should rewind seekable reader before performing stream again", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var received []string
+		fs, err := New(
+			OptionStreamContentToHandler(func(_ string, r io.Reader, _ Arguments) error {
+				data, rErr := io.ReadAll(r)
+				require.NoError(t, rErr)
+				received = append(received, string(data))
+				if len(received) < 2 {
+					return errors.New("connection reset")
+				}
+				return nil
+			}),
+			OptionMiddleware(newRetry(t)),
+		)
+		require.NoError(t, err)
+
+		reader := strings.NewReader("SKIP TEST")
+		_, err = reader.Seek(5, io.SeekStart)
+		require.NoError(t, err)
+
+		// WHEN
+		err = StreamContentTo(fs, "target", reader, WithContentOperation(ContentOperationOverwrite))
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"TEST", "TEST"}, received)
+	})
+	t.Run("it should rewind reader counted by middlewares placed before", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		logger, buff := newTestLogger()
+		logging, err := NewLoggingMiddleware(logger)
+		require.NoError(t, err)
+
+		var received []string
+		fs, err := New(
+			OptionStreamContentToHandler(func(_ string, r io.Reader, _ Arguments) error {
+				data, rErr := io.ReadAll(r)
+				require.NoError(t, rErr)
+				received = append(received, string(data))
+				if len(received) < 2 {
+					return errors.New("connection reset")
+				}
+				return nil
+			}),
+			OptionMiddleware(logging, newRetry(t)),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = StreamContentTo(fs, "target", strings.NewReader("TEST"), WithContentOperation(ContentOperationOverwrite))
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"TEST", "TEST"}, received)
+
+		entries := readLogEntries(t, buff)
+		require.Len(t, entries, 1)
+		assert.Equal(t, float64(4), entries[0]["bytes"])
+	})
+	t.Run("it should stop waiting for next attempt when context is done", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		mw, err := NewRetryMiddleware(OptionRetryInitialDelay(time.Hour), OptionRetryMaxDelay(time.Hour))
+		require.NoError(t, err)
+
+		calls := 0
+		fs, err := New(
+			OptionReadContentOfHandler(func(string) (Content, error) {
+				calls++
+				return nil, errors.New("connection reset")
+			}),
+			OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// WHEN
+		_, err = ReadContentOf(InContext(fs, ctx), "source")
+
+		// THEN
+		require.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+	t.Run("it should reject non-positive amount of attempts", func(t *testing.T) {
+		t.Parallel()
+
+		// WHEN
+		_, err := NewRetryMiddleware(OptionRetryAttempts(0))
+
+		// THEN
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,618 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	// maxErrorBodyLength limits amount of response body read to describe failure.
+	maxErrorBodyLength = 4096
+
+	headerCopySource = "X-Amz-Copy-Source"
+)
+
+// errorCodeSentinels translates codes of S3 errors to sentinel errors.
+var errorCodeSentinels = map[string]error{
+	"NoSuchKey":             filesystem.ErrFileNotFound,
+	"NoSuchBucket":          filesystem.ErrFileNotFound,
+	"AccessDenied":          filesystem.ErrPermissionDenied,
+	"AllAccessDisabled":     filesystem.ErrPermissionDenied,
+	"EntityTooLarge":        filesystem.ErrTooLarge,
+	"PreconditionFailed":    filesystem.ErrFileFound,
+	"InvalidObjectState":    filesystem.ErrReadOnly,
+	"MethodNotAllowed":      filesystem.ErrReadOnly,
+	"SignatureDoesNotMatch": filesystem.ErrPermissionDenied,
+}
+
+// Error describes failure reported by the storage. It's wrapped along with matching sentinel error
+// (e.g. filesystem.ErrFileNotFound for NoSuchKey).
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+	RequestID  string `xml:"RequestId"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3 request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3 request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+type (
+	// entryKind describes what is stored under the key.
+	entryKind uint8
+
+	listResult struct {
+		IsTruncated           bool   `xml:"IsTruncated"`
+		NextContinuationToken string `xml:"NextContinuationToken"`
+		Contents              []struct {
+			Key          string    `xml:"Key"`
+			LastModified time.Time `xml:"LastModified"`
+			Size         int64     `xml:"Size"`
+		} `xml:"Contents"`
+		CommonPrefixes []struct {
+			Prefix string `xml:"Prefix"`
+		} `xml:"CommonPrefixes"`
+	}
+
+	client struct {
+		http          *http.Client
+		endpoint      *url.URL
+		bucket        string
+		pathStyle     bool
+		partSize      int
+		emulateAppend bool
+		signer        *signer
+	}
+)
+
+const (
+	kindMissing entryKind = iota
+	kindFile
+	kindDirectory
+)
+
+func (c *client) readContentOf(ctx context.Context, p string) (filesystem.Content, error) {
+	rc, err := c.streamContentOf(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	return io.ReadAll(rc)
+}
+
+func (c *client) streamContentOf(ctx context.Context, p string) (io.ReadCloser, error) {
+	k := key(p)
+	if k == "" {
+		return nil, filesystem.ErrDirectory
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, k, nil, nil, nil)
+	if err != nil {
+		if errors.Is(err, filesystem.ErrFileNotFound) {
+			if kind, kErr := c.kindOf(ctx, k); kErr == nil && kind == kindDirectory {
+				return nil, filesystem.ErrDirectory
+			}
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *client) checkIfExists(ctx context.Context, p string) (bool, error) {
+	kind, err := c.kindOf(ctx, key(p))
+	return kind != kindMissing, err
+}
+
+func (c *client) createFile(ctx context.Context, p string, arg filesystem.Arguments) error {
+	k := key(p)
+	if err := c.prepareParent(ctx, k, arg); err != nil {
+		return err
+	}
+
+	kind, err := c.kindOf(ctx, k)
+	if err != nil {
+		return err
+	}
+	if kind != kindMissing && !arg.AllowOverwrite {
+		return filesystem.ErrFileFound
+	}
+	if kind == kindDirectory {
+		return filesystem.ErrDirectory
+	}
+
+	return c.put(ctx, k, nil)
+}
+
+func (c *client) writeContentTo(ctx context.Context, p string, content []byte, arg filesystem.Arguments) error {
+	return c.streamContentTo(ctx, p, bytes.NewReader(content), arg)
+}
+
+func (c *client) streamContentTo(ctx context.Context, p string, content io.Reader, arg filesystem.Arguments) error {
+	appending := arg.ContentOperation.Is(filesystem.ContentOperationAppend)
+	switch {
+	case appending && !c.emulateAppend:
+		return fmt.Errorf("%w: objects cannot be appended to (see s3fs.OptionEmulateAppend)", filesystem.ErrUnsupportedContentOperation)
+	case !appending && !arg.ContentOperation.Is(filesystem.ContentOperationOverwrite):
+		return filesystem.ErrUnsupportedContentOperation
+	}
+
+	k := key(p)
+	kind, err := c.kindOf(ctx, k)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case kindMissing:
+		return filesystem.ErrFileNotFound
+	case kindDirectory:
+		return filesystem.ErrDirectory
+	}
+
+	if appending {
+		resp, gErr := c.do(ctx, http.MethodGet, k, nil, nil, nil)
+		if gErr != nil {
+			return gErr
+		}
+		defer func() { _ = resp.Body.Close() }()
+		content = io.MultiReader(resp.Body, content)
+	}
+	return c.upload(ctx, k, content)
+}
+
+func (c *client) createDirectory(ctx context.Context, p string, arg filesystem.Arguments) error {
+	k := key(p)
+	if k == "" {
+		return filesystem.ErrDirectoryFound
+	}
+	if err := c.prepareParent(ctx, k, arg); err != nil {
+		return err
+	}
+
+	kind, err := c.kindOf(ctx, k)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case kindDirectory:
+		return filesystem.ErrDirectoryFound
+	case kindFile:
+		return filesystem.ErrFile
+	}
+
+	return c.put(ctx, k+"/", nil)
+}
+
+func (c *client) remove(ctx context.Context, p string, arg filesystem.Arguments) error {
+	k := key(p)
+	kind, err := c.kindOf(ctx, k)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case kindMissing:
+		return filesystem.ErrFileNotFound
+	case kindFile:
+		return c.delete(ctx, k)
+	}
+
+	keys, err := c.keysUnder(ctx, k)
+	if err != nil {
+		return err
+	}
+	if !arg.Recursive && (len(keys) > 1 || (len(keys) == 1 && keys[0] != k+"/")) {
+		return filesystem.ErrNotEmpty
+	}
+	for _, each := range keys {
+		if err = c.delete(ctx, each); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *client) move(ctx context.Context, source, target string, arg filesystem.Arguments) error {
+	src, dst := key(source), key(target)
+	if err := checkTransfer(source, src, dst); err != nil {
+		return err
+	}
+	kind, err := c.kindOf(ctx, src)
+	if err != nil {
+		return err
+	}
+	if kind == kindMissing {
+		return filesystem.ErrFileNotFound
+	}
+	existing, err := c.prepareTarget(ctx, dst, arg)
+	if err != nil {
+		return err
+	}
+	if existing != kindMissing && strings.HasPrefix(src, dst+"/") {
+		return fmt.Errorf("cannot replace %s with its own content: %w", target, filesystem.ErrUnresolvableDirectoryStructure)
+	}
+	stale, err := c.keysOf(ctx, dst, existing)
+	if err != nil {
+		return err
+	}
+
+	// Content is copied before anything gets deleted, so failed copy leaves both source and target intact.
+	written := []string{dst}
+	if kind == kindFile {
+		err = c.copyObject(ctx, src, dst)
+	} else {
+		written, err = c.copyDirectory(ctx, src, dst)
+	}
+	if err != nil {
+		return err
+	}
+
+	copied := make(map[string]bool, len(written))
+	for _, each := range written {
+		copied[each] = true
+	}
+	for _, each := range stale {
+		if copied[each] {
+			continue
+		}
+		if err = c.delete(ctx, each); err != nil {
+			return err
+		}
+	}
+	if kind == kindFile {
+		return c.delete(ctx, src)
+	}
+	return c.remove(ctx, src, filesystem.Arguments{Recursive: true})
+}
+
+func (c *client) copy(ctx context.Context, source, target string, arg filesystem.Arguments) error {
+	src, dst := key(source), key(target)
+	if err := checkTransfer(source, src, dst); err != nil {
+		return err
+	}
+	kind, err := c.kindOf(ctx, src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case kind == kindMissing:
+		return filesystem.ErrFileNotFound
+	case kind == kindDirectory && !arg.Recursive:
+		return filesystem.ErrDirectory
+	}
+	if _, err = c.prepareTarget(ctx, dst, arg); err != nil {
+		return err
+	}
+
+	if kind == kindFile {
+		return c.copyObject(ctx, src, dst)
+	}
+	_, err = c.copyDirectory(ctx, src, dst)
+	return err
+}
+
+func (c *client) listFilesIn(ctx context.Context, p string) ([]filesystem.Entry, error) {
+	k := key(p)
+	prefix := ""
+	if k != "" {
+		prefix = k + "/"
+	}
+
+	var (
+		res    []filesystem.Entry
+		marker bool
+		token  string
+	)
+	for {
+		page, err := c.list(ctx, prefix, "/", token, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range page.Contents {
+			if o.Key == prefix {
+				marker = true
+				continue
+			}
+			res = append(res, filesystem.Entry{
+				Name:    strings.TrimPrefix(o.Key, prefix),
+				Size:    o.Size,
+				Mode:    filesystem.ModeAllReadWrite,
+				ModTime: o.LastModified,
+			})
+		}
+		for _, cp := range page.CommonPrefixes {
+			res = append(res, filesystem.Entry{
+				Name:        strings.TrimSuffix(strings.TrimPrefix(cp.Prefix, prefix), "/"),
+				Mode:        filesystem.ModeAllReadWriteExecute,
+				IsDirectory: true,
+			})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+
+	if len(res) == 0 && !marker && k != "" {
+		kind, err := c.kindOf(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		if kind == kindFile {
+			return nil, filesystem.ErrFile
+		}
+		return nil, filesystem.ErrFileNotFound
+	}
+	return res, nil
+}
+
+// kindOf checks whether key is an object, a directory (prefix of other keys) or does not exist.
+func (c *client) kindOf(ctx context.Context, k string) (entryKind, error) {
+	if k == "" {
+		return kindDirectory, nil
+	}
+
+	resp, err := c.do(ctx, http.MethodHead, k, nil, nil, nil)
+	if err == nil {
+		_ = resp.Body.Close()
+		return kindFile, nil
+	}
+	if !errors.Is(err, filesystem.ErrFileNotFound) {
+		return kindMissing, err
+	}
+
+	page, err := c.list(ctx, k+"/", "/", "", 1)
+	if err != nil {
+		return kindMissing, err
+	}
+	if len(page.Contents) > 0 || len(page.CommonPrefixes) > 0 {
+		return kindDirectory, nil
+	}
+	return kindMissing, nil
+}
+
+// prepareParent verifies if parent directory of key exists and creates markers of missing ones (if allowed).
+func (c *client) prepareParent(ctx context.Context, k string, arg filesystem.Arguments) error {
+	var missing []string
+	for dir := path.Dir(k); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		kind, err := c.kindOf(ctx, dir)
+		if err != nil {
+			return err
+		}
+		if kind == kindFile {
+			return fmt.Errorf("location structure does not contain valid directory as target: %w", filesystem.ErrUnresolvableDirectoryStructure)
+		}
+		if kind == kindDirectory {
+			break
+		}
+		missing = append(missing, dir)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !arg.AllowCreationOfDirectoryStructure {
+		return fmt.Errorf("creation of non-existing directory structure is forbidden by current settings: %w", filesystem.ErrUnresolvableDirectoryStructure)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := c.put(ctx, missing[i]+"/", nil); err != nil {
+			return fmt.Errorf("cannot create directory structure: %w", err)
+		}
+	}
+	return nil
+}
+
+// prepareTarget verifies if target of Move or Copy can be overwritten and creates its directory structure
+// (if allowed). It returns kind of existing target.
+func (c *client) prepareTarget(ctx context.Context, k string, arg filesystem.Arguments) (entryKind, error) {
+	kind, err := c.kindOf(ctx, k)
+	if err != nil {
+		return kindMissing, err
+	}
+	if kind != kindMissing && !arg.AllowOverwrite {
+		if kind == kindDirectory {
+			return kind, filesystem.ErrDirectoryFound
+		}
+		return kind, filesystem.ErrFileFound
+	}
+	return kind, c.prepareParent(ctx, k, arg)
+}
+
+// keysUnder returns every key (including directory marker) placed under directory.
+func (c *client) keysUnder(ctx context.Context, k string) ([]string, error) {
+	var (
+		res   []string
+		token string
+	)
+	for {
+		page, err := c.list(ctx, k+"/", "", token, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range page.Contents {
+			res = append(res, o.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return res, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// keysOf returns keys of existing entry (the object itself or every key placed under directory).
+func (c *client) keysOf(ctx context.Context, k string, kind entryKind) ([]string, error) {
+	switch kind {
+	case kindFile:
+		return []string{k}, nil
+	case kindDirectory:
+		return c.keysUnder(ctx, k)
+	default:
+		return nil, nil
+	}
+}
+
+// copyDirectory copies every key placed under src to dst and returns keys it has written.
+func (c *client) copyDirectory(ctx context.Context, src, dst string) ([]string, error) {
+	keys, err := c.keysUnder(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.put(ctx, dst+"/", nil); err != nil {
+		return nil, err
+	}
+	written := []string{dst + "/"}
+	for _, each := range keys {
+		target := dst + "/" + strings.TrimPrefix(each, src+"/")
+		if target == dst+"/" {
+			continue
+		}
+		if err = c.copyObject(ctx, each, target); err != nil {
+			return nil, err
+		}
+		written = append(written, target)
+	}
+	return written, nil
+}
+
+func (c *client) list(ctx context.Context, prefix, delimiter, token string, maxKeys int) (*listResult, error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+
+	var res listResult
+	if err := c.send(ctx, http.MethodGet, "", query, nil, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *client) put(ctx context.Context, k string, content []byte) error {
+	return c.send(ctx, http.MethodPut, k, nil, nil, content, nil)
+}
+
+func (c *client) delete(ctx context.Context, k string) error {
+	return c.send(ctx, http.MethodDelete, k, nil, nil, nil, nil)
+}
+
+func (c *client) copyObject(ctx context.Context, src, dst string) error {
+	header := http.Header{headerCopySource: {"/" + c.bucket + "/" + escape(src, true)}}
+	return c.send(ctx, http.MethodPut, dst, nil, header, nil, nil)
+}
+
+// send performs request and decodes XML response into out (if provided). Storage can report failure
+// of some operations (e.g. CopyObject) with successful status, so body is always checked for errors.
+func (c *client) send(ctx context.Context, method, k string, query url.Values, header http.Header, body []byte, out any) error {
+	resp, err := c.do(ctx, method, k, query, header, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, []byte("<Error>")) {
+		return responseError(resp.StatusCode, data)
+	}
+	if out != nil {
+		if err = xml.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("cannot decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// do performs request and returns response with successful status (body has to be closed by the caller)
+// or error describing failed one.
+func (c *client) do(ctx context.Context, method, k string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := *c.endpoint
+	objectPath := "/" + k
+	if c.pathStyle {
+		objectPath = "/" + c.bucket + objectPath
+	} else {
+		u.Host = c.bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + objectPath
+	u.RawPath = strings.TrimSuffix(c.endpoint.EscapedPath(), "/") + escape(objectPath, true)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if c.signer != nil {
+		c.signer.sign(req, hashHex(body))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+	return nil, responseError(resp.StatusCode, data)
+}
+
+// responseError restores error from XML body (or status code when body is empty, e.g. for HEAD requests).
+func responseError(status int, body []byte) error {
+	e := &Error{StatusCode: status}
+	_ = xml.Unmarshal(body, e)
+
+	if sentinel, ok := errorCodeSentinels[e.Code]; ok {
+		return fmt.Errorf("%w: %w", sentinel, e)
+	}
+	switch status {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %w", filesystem.ErrFileNotFound, e)
+	case http.StatusForbidden, http.StatusUnauthorized:
+		return fmt.Errorf("%w: %w", filesystem.ErrPermissionDenied, e)
+	}
+	return e
+}
+
+// checkTransfer rejects transfer of the root, to the root and of key onto itself or into its own subdirectory.
+func checkTransfer(source, src, dst string) error {
+	if src == "" || dst == "" {
+		return filesystem.ErrPermissionDenied
+	}
+	if dst == src || strings.HasPrefix(dst, src+"/") {
+		return fmt.Errorf("cannot transfer %s into itself: %w", source, filesystem.ErrUnresolvableDirectoryStructure)
+	}
+	return nil
+}
+
+// key converts path to the key of object (slash separated, without leading slash, "." and "..").
+func key(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(p, `\`, "/")), "/")
+}
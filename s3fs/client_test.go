@@ -0,0 +1,265 @@
+package s3fs
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+func TestClient(t *testing.T) {
+	t.Run("it should store and read objects", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		fs := srv.filesystem(t)
+
+		// WHEN
+		require.NoError(t, filesystem.CreateDirectory(fs, "conf.d"))
+		require.NoError(t, filesystem.CreateFile(fs, "conf.d/a.yaml"))
+		require.NoError(t, filesystem.WriteContentTo(fs, "conf.d/a.yaml", "A", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite)))
+		require.NoError(t, filesystem.CreateFile(fs, "deep/inner/b.txt", filesystem.WithAllowCreationOfDirectoryStructure(true)))
+
+		// THEN
+		assert.Equal(t, []string{"conf.d/", "conf.d/a.yaml", "deep/", "deep/inner/", "deep/inner/b.txt"}, srv.keys())
+
+		content, err := filesystem.ReadContentOf(fs, "conf.d/a.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("A"), content)
+
+		stream, err := filesystem.StreamContentOf(fs, "/conf.d/a.yaml")
+		require.NoError(t, err)
+		streamed, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, "A", string(streamed))
+
+		for p, expected := range map[string]bool{"conf.d": true, "deep/inner": true, "conf.d/a.yaml": true, "conf.d/missing": false, ".": true} {
+			exists, eErr := filesystem.CheckIfExists(fs, p)
+			require.NoError(t, eErr)
+			assert.Equal(t, expected, exists, p)
+		}
+	})
+	t.Run("it should detect directories by prefix of keys", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		srv.put("logs/2024/app.log", []byte("LOG"))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		exists, err := filesystem.CheckIfExists(fs, "logs/2024")
+		_, errDirectory := filesystem.ReadContentOf(fs, "logs")
+
+		// THEN
+		require.NoError(t, err)
+		assert.True(t, exists)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+	})
+	t.Run("it should reject append unless emulation is enabled", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		srv.put("test.txt", []byte("A"))
+		rejecting := srv.filesystem(t)
+		emulating := srv.filesystem(t, OptionEmulateAppend(true))
+
+		// WHEN
+		errRejected := filesystem.WriteContentTo(rejecting, "test.txt", "B")
+		errEmulated := filesystem.WriteContentTo(emulating, "test.txt", "B")
+		errStreamed := filesystem.StreamContentTo(emulating, "test.txt", strings.NewReader("C"))
+
+		// THEN
+		require.ErrorIs(t, errRejected, filesystem.ErrUnsupportedContentOperation)
+		require.NoError(t, errEmulated)
+		require.NoError(t, errStreamed)
+		content, _ := srv.object("test.txt")
+		assert.Equal(t, "ABC", string(content))
+	})
+	t.Run("it should stream content with multipart upload when it exceeds part size", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		srv.put("large.bin", nil)
+		fs := srv.filesystem(t, OptionPartSize(MinPartSize))
+		content := bytes.Repeat([]byte("0123456789"), (2*MinPartSize+MinPartSize/2)/10)
+
+		// WHEN
+		err := filesystem.StreamContentTo(fs, "large.bin", bytes.NewReader(content), filesystem.WithContentOperation(filesystem.ContentOperationOverwrite))
+
+		// THEN
+		require.NoError(t, err)
+		stored, _ := srv.object("large.bin")
+		assert.Equal(t, content, stored)
+		assert.Subset(t, srv.recorded(), []string{"POST uploads", "PUT part 1", "PUT part 2", "PUT part 3", "POST upload"})
+	})
+	t.Run("it should upload small content with single request", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		srv.put("small.txt", nil)
+		fs := srv.filesystem(t)
+
+		// WHEN
+		err := filesystem.StreamContentTo(fs, "small.txt", strings.NewReader("SMALL"), filesystem.WithContentOperation(filesystem.ContentOperationOverwrite))
+
+		// THEN
+		require.NoError(t, err)
+		stored, _ := srv.object("small.txt")
+		assert.Equal(t, "SMALL", string(stored))
+		assert.NotContains(t, srv.recorded(), "POST uploads")
+	})
+	t.Run("it should report sentinel errors", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		srv.put("test.txt", []byte("TEST"))
+		srv.put("dir/inner.txt", []byte("INNER"))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		_, errMissing := filesystem.ReadContentOf(fs, "missing.txt")
+		errWriteMissing := filesystem.WriteContentTo(fs, "missing.txt", "TEST", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite))
+		errFileFound := filesystem.CreateFile(fs, "test.txt")
+		errDirectoryFound := filesystem.CreateDirectory(fs, "dir")
+		errFile := filesystem.CreateDirectory(fs, "test.txt")
+		errStructure := filesystem.CreateFile(fs, "missing/test.txt")
+		errStructureOfFile := filesystem.CreateFile(fs, "test.txt/test.txt", filesystem.WithAllowCreationOfDirectoryStructure(true))
+		errNotEmpty := filesystem.Remove(fs, "dir")
+		errRemoveMissing := filesystem.Remove(fs, "missing")
+		_, errListFile := filesystem.ListFilesIn(fs, "test.txt")
+		_, errListMissing := filesystem.ListFilesIn(fs, "missing")
+
+		// THEN
+		require.ErrorIs(t, errMissing, filesystem.ErrFileNotFound)
+		var s3Err *Error
+		require.ErrorAs(t, errMissing, &s3Err)
+		assert.Equal(t, "NoSuchKey", s3Err.Code)
+		require.ErrorIs(t, errWriteMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errFileFound, filesystem.ErrFileFound)
+		require.ErrorIs(t, errDirectoryFound, filesystem.ErrDirectoryFound)
+		require.ErrorIs(t, errFile, filesystem.ErrFile)
+		require.ErrorIs(t, errStructure, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errStructureOfFile, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errNotEmpty, filesystem.ErrNotEmpty)
+		require.ErrorIs(t, errRemoveMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errListFile, filesystem.ErrFile)
+		require.ErrorIs(t, errListMissing, filesystem.ErrFileNotFound)
+	})
+	t.Run("it should remove, move and copy objects and directories", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		srv.put("src/", nil)
+		srv.put("src/a.txt", []byte("A"))
+		srv.put("src/inner/b.txt", []byte("B"))
+		srv.put("test.txt", []byte("TEST"))
+		srv.put("existing.txt", []byte("OLD"))
+		srv.put("empty/", nil)
+		fs := srv.filesystem(t)
+
+		// WHEN
+		require.NoError(t, filesystem.Copy(fs, "src", "dst/copy", filesystem.WithRecursive(true), filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.Move(fs, "test.txt", "existing.txt", filesystem.WithAllowOverwrite(true)))
+		require.NoError(t, filesystem.Move(fs, "src", "moved"))
+		require.NoError(t, filesystem.Remove(fs, "empty"))
+
+		// THEN
+		assert.Equal(t, []string{
+			"dst/", "dst/copy/", "dst/copy/a.txt", "dst/copy/inner/b.txt",
+			"existing.txt",
+			"moved/", "moved/a.txt", "moved/inner/b.txt",
+		}, srv.keys())
+		moved, _ := srv.object("existing.txt")
+		assert.Equal(t, "TEST", string(moved))
+	})
+	t.Run("it should replace target only after content was copied", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		srv.put("src/", nil)
+		srv.put("src/a.txt", []byte("A"))
+		srv.put("dst/", nil)
+		srv.put("dst/a.txt", []byte("OLD"))
+		srv.put("dst/stale.txt", []byte("STALE"))
+		srv.put("test.txt", []byte("TEST"))
+		srv.put("existing.txt", []byte("OLD"))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		errSame := filesystem.Move(fs, "test.txt", "/test.txt", filesystem.WithAllowOverwrite(true))
+		errCopySame := filesystem.Copy(fs, "test.txt", "test.txt", filesystem.WithAllowOverwrite(true))
+		errNested := filesystem.Move(fs, "src", "src/inner")
+		errOwnContent := filesystem.Move(fs, "dst/a.txt", "dst", filesystem.WithAllowOverwrite(true))
+		srv.failCopy.Store(true)
+		errFailed := filesystem.Move(fs, "test.txt", "existing.txt", filesystem.WithAllowOverwrite(true))
+		keysAfterFailure := srv.keys()
+		srv.failCopy.Store(false)
+		errDirectory := filesystem.Move(fs, "src", "dst", filesystem.WithAllowOverwrite(true))
+
+		// THEN
+		require.ErrorIs(t, errSame, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errCopySame, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errNested, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errOwnContent, filesystem.ErrUnresolvableDirectoryStructure)
+		require.Error(t, errFailed)
+		assert.Equal(t, []string{"dst/", "dst/a.txt", "dst/stale.txt", "existing.txt", "src/", "src/a.txt", "test.txt"}, keysAfterFailure)
+		existing, _ := srv.object("existing.txt")
+		assert.Equal(t, "OLD", string(existing))
+		require.NoError(t, errDirectory)
+		assert.Equal(t, []string{"dst/", "dst/a.txt", "existing.txt", "test.txt"}, srv.keys())
+		moved, _ := srv.object("dst/a.txt")
+		assert.Equal(t, "A", string(moved))
+	})
+	t.Run("it should list entries of every page", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		srv.pageSize = 2
+		srv.put("dir/", nil)
+		srv.put("dir/a.txt", []byte("A"))
+		srv.put("dir/b.txt", []byte("BB"))
+		srv.put("dir/c.yaml", []byte("C"))
+		srv.put("dir/inner/d.txt", []byte("D"))
+		srv.put("dir/other/e.txt", []byte("E"))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		entries, listErr := filesystem.ListFilesIn(fs, "dir")
+		paths, globErr := filesystem.Glob(fs, "dir/**/*.txt")
+
+		// THEN
+		require.NoError(t, listErr)
+		require.NoError(t, globErr)
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		assert.ElementsMatch(t, []string{"a.txt", "b.txt", "c.yaml", "inner", "other"}, names)
+		for _, e := range entries {
+			switch e.Name {
+			case "b.txt":
+				assert.Equal(t, int64(2), e.Size)
+				assert.Equal(t, filesystem.ModeAllReadWrite, e.Mode)
+				assert.False(t, e.ModTime.IsZero())
+			case "inner":
+				assert.True(t, e.IsDirectory)
+			}
+		}
+		assert.Equal(t, []string{"dir/a.txt", "dir/b.txt", "dir/inner/d.txt", "dir/other/e.txt"}, paths)
+	})
+}
@@ -0,0 +1,193 @@
+// Package s3fs provides filesystem.Filesystem backed by bucket of S3-compatible object storage.
+//
+// Paths are mapped to keys (slash separated, without leading slash). Object storage has no directories,
+// so directory exists when there is at least one key under its prefix; CreateDirectory puts zero-byte
+// marker key ending with slash ("dir/") to keep empty directories visible.
+//
+// Operations are mapped to requests as follows:
+//
+//	ReadContentOf, StreamContentOf  GetObject
+//	CheckIfExists                   HeadObject (and ListObjectsV2 with prefix when object does not exist)
+//	CreateFile, WriteContentTo      PutObject
+//	StreamContentTo                 PutObject or multipart upload (when content exceeds part size)
+//	CreateDirectory                 PutObject of marker key
+//	Remove                          DeleteObject (of every key under prefix for directories)
+//	Move, Copy                      CopyObject (and DeleteObject of source for Move)
+//	ListFilesIn                     ListObjectsV2 with prefix and "/" delimiter
+//
+// Objects cannot be appended to, so ContentOperationAppend (default of WriteContentTo and StreamContentTo)
+// is rejected with filesystem.ErrUnsupportedContentOperation unless OptionEmulateAppend is enabled.
+// Modes are not supported by object storage: Mode arguments are ignored and entries are reported
+// with filesystem.ModeAllReadWrite (filesystem.ModeAllReadWriteExecute for directories).
+package s3fs
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionEndpoint      options.OptionKey = `s3_endpoint`
+	optionRegion        options.OptionKey = `s3_region`
+	optionCredentials   options.OptionKey = `s3_credentials`
+	optionPathStyle     options.OptionKey = `s3_path_style`
+	optionPartSize      options.OptionKey = `s3_part_size`
+	optionEmulateAppend options.OptionKey = `s3_emulate_append`
+	optionClient        options.OptionKey = `http_client`
+	optionBackendName   options.OptionKey = `backend_name`
+)
+
+const (
+	defaultBackendName = "s3"
+	defaultRegion      = "us-east-1"
+	defaultPartSize    = 8 << 20
+
+	// MinPartSize is the smallest part (except the last one) accepted by multipart upload.
+	MinPartSize = 5 << 20
+)
+
+// OptionEndpoint changes URL of the storage (default is AWS endpoint of the region, e.g. "https://s3.us-east-1.amazonaws.com").
+func OptionEndpoint(endpoint string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionEndpoint, endpoint)
+	}
+}
+
+// OptionRegion changes region used for signing requests (default "us-east-1").
+func OptionRegion(region string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionRegion, region)
+	}
+}
+
+// OptionCredentials signs every request with provided credentials (requests are anonymous without them).
+func OptionCredentials(credentials Credentials) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[Credentials](r, optionCredentials, credentials)
+	}
+}
+
+// OptionPathStyle places bucket in path of URL ("https://endpoint/bucket/key") instead of host name
+// ("https://bucket.endpoint/key"). Most of self-hosted storages require it (default false).
+func OptionPathStyle(enabled bool) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[bool](r, optionPathStyle, enabled)
+	}
+}
+
+// OptionPartSize changes size of parts of multipart upload used by StreamContentTo (default 8 MiB, at least MinPartSize).
+// Content smaller than single part is uploaded with PutObject. Every part is kept in memory until it's uploaded.
+func OptionPartSize(size int) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[int](r, optionPartSize, size)
+	}
+}
+
+// OptionEmulateAppend allows ContentOperationAppend by downloading existing content and uploading it again
+// along with the new one. It's not atomic (concurrent writes can be lost) and costs transfer of entire object.
+func OptionEmulateAppend(enabled bool) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[bool](r, optionEmulateAppend, enabled)
+	}
+}
+
+// OptionClient changes HTTP client used for requests (default is a new http.Client without timeout).
+func OptionClient(c *http.Client) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[*http.Client](r, optionClient, c)
+	}
+}
+
+// OptionBackendName changes name of the backend reported in errors and to middlewares (default "s3").
+func OptionBackendName(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBackendName, name)
+	}
+}
+
+// New creates filesystem.Filesystem storing content in provided bucket (see package documentation
+// for mapping of operations). Glob is resolved with ListFilesIn.
+func New(bucket string, opts ...options.Option) (filesystem.Filesystem, error) {
+	opt := options.Resolve(opts)
+
+	if bucket == "" {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: bucket is required")
+	}
+
+	optRegion, err := options.ReadOrDefault[string](opt, optionRegion, defaultRegion)
+	if err != nil {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: %w", err)
+	}
+	optEndpoint, err := options.ReadOrDefault[string](opt, optionEndpoint, fmt.Sprintf("https://s3.%s.amazonaws.com", optRegion))
+	if err != nil {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: %w", err)
+	}
+	optCredentials, err := options.ReadOrDefault[Credentials](opt, optionCredentials, Credentials{})
+	if err != nil {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: %w", err)
+	}
+	optPathStyle, err := options.ReadOrDefault[bool](opt, optionPathStyle, false)
+	if err != nil {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: %w", err)
+	}
+	optPartSize, err := options.ReadOrDefault[int](opt, optionPartSize, defaultPartSize)
+	if err != nil {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: %w", err)
+	}
+	optEmulateAppend, err := options.ReadOrDefault[bool](opt, optionEmulateAppend, false)
+	if err != nil {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: %w", err)
+	}
+	optClient, err := options.ReadOrDefault[*http.Client](opt, optionClient, &http.Client{})
+	if err != nil {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: %w", err)
+	}
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: %w", err)
+	}
+
+	endpoint, err := url.Parse(optEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: unsupported scheme %q", endpoint.Scheme)
+	}
+	if optPartSize < MinPartSize {
+		return nil, fmt.Errorf("s3 filesystem initialization failed: part size has to be at least %d bytes (got %d)", MinPartSize, optPartSize)
+	}
+
+	c := &client{
+		http:          optClient,
+		endpoint:      endpoint,
+		bucket:        bucket,
+		pathStyle:     optPathStyle,
+		partSize:      optPartSize,
+		emulateAppend: optEmulateAppend,
+	}
+	if optCredentials.AccessKeyID != "" {
+		c.signer = &signer{credentials: optCredentials, region: optRegion, now: time.Now}
+	}
+
+	return filesystem.New(
+		filesystem.OptionBackendName(optBackendName),
+		filesystem.OptionReadContentOfContextHandler(c.readContentOf),
+		filesystem.OptionStreamContentOfContextHandler(c.streamContentOf),
+		filesystem.OptionCheckIfExistsContextHandler(c.checkIfExists),
+		filesystem.OptionCreateFileContextHandler(c.createFile),
+		filesystem.OptionWriteContentToContextHandler(c.writeContentTo),
+		filesystem.OptionStreamContentToContextHandler(c.streamContentTo),
+		filesystem.OptionCreateDirectoryContextHandler(c.createDirectory),
+		filesystem.OptionRemoveContextHandler(c.remove),
+		filesystem.OptionMoveContextHandler(c.move),
+		filesystem.OptionCopyContextHandler(c.copy),
+		filesystem.OptionListFilesInContextHandler(c.listFilesIn),
+	)
+}
@@ -0,0 +1,351 @@
+package s3fs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	testBucket = "bucket"
+	testRegion = "eu-central-1"
+)
+
+var testCredentials = Credentials{AccessKeyID: "TEST", SecretAccessKey: "SECRET"}
+
+type fakeObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+// fakeS3 is in-memory S3-compatible storage (path-style, single bucket) verifying signatures of requests.
+type fakeS3 struct {
+	*httptest.Server
+
+	// pageSize limits amount of keys returned by single ListObjectsV2 request.
+	pageSize int
+	// failCopy makes every CopyObject request fail.
+	failCopy atomic.Bool
+
+	mu       sync.Mutex
+	objects  map[string]fakeObject
+	uploads  map[string]map[int][]byte
+	requests []string
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	t.Helper()
+
+	s := &fakeS3{pageSize: 1000, objects: map[string]fakeObject{}, uploads: map[string]map[int][]byte{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// filesystem creates filesystem connected to the storage with valid credentials.
+func (s *fakeS3) filesystem(t *testing.T, opts ...options.Option) filesystem.Filesystem {
+	t.Helper()
+
+	fs, err := New(testBucket, append([]options.Option{
+		OptionEndpoint(s.URL),
+		OptionRegion(testRegion),
+		OptionPathStyle(true),
+		OptionCredentials(testCredentials),
+	}, opts...)...)
+	require.NoError(t, err)
+
+	return fs
+}
+
+func (s *fakeS3) object(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.objects[key]
+	return o.data, ok
+}
+
+func (s *fakeS3) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (s *fakeS3) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+func (s *fakeS3) put(key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = fakeObject{data: data, modTime: time.Now().UTC().Truncate(time.Second)}
+}
+
+func (s *fakeS3) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !verifySignature(r) {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != testBucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, describeRequest(r))
+
+	switch {
+	case r.Method == http.MethodGet && q.Get("list-type") == "2":
+		s.list(w, q.Get("prefix"), q.Get("delimiter"), q.Get("continuation-token"), q.Get("max-keys"))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		o, ok := s.objects[key]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(o.data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(o.data)
+		}
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		parts, ok := s.uploads[q.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		number, _ := strconv.Atoi(q.Get("partNumber"))
+		parts[number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"part-%d"`, number))
+	case r.Method == http.MethodPut && r.Header.Get(headerCopySource) != "" && s.failCopy.Load():
+		writeError(w, http.StatusInternalServerError, "InternalError")
+	case r.Method == http.MethodPut && r.Header.Get(headerCopySource) != "":
+		source := strings.TrimPrefix(r.Header.Get(headerCopySource), "/"+testBucket+"/")
+		o, ok := s.objects[source]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		s.objects[key] = fakeObject{data: append([]byte(nil), o.data...), modTime: time.Now().UTC()}
+		_, _ = w.Write([]byte("<CopyObjectResult><ETag>\"copy\"</ETag></CopyObjectResult>"))
+	case r.Method == http.MethodPut:
+		s.objects[key] = fakeObject{data: body, modTime: time.Now().UTC().Truncate(time.Second)}
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		id := strconv.Itoa(len(s.uploads) + 1)
+		s.uploads[id] = map[int][]byte{}
+		_, _ = fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		var complete completeMultipartUpload
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			writeError(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		parts := s.uploads[q.Get("uploadId")]
+		var data []byte
+		for _, p := range complete.Parts {
+			data = append(data, parts[p.PartNumber]...)
+		}
+		delete(s.uploads, q.Get("uploadId"))
+		s.objects[key] = fakeObject{data: data, modTime: time.Now().UTC()}
+		_, _ = w.Write([]byte("<CompleteMultipartUploadResult></CompleteMultipartUploadResult>"))
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		delete(s.uploads, q.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (s *fakeS3) list(w http.ResponseWriter, prefix, delimiter, token, maxKeys string) {
+	limit := s.pageSize
+	if n, err := strconv.Atoi(maxKeys); err == nil && n < limit {
+		limit = n
+	}
+
+	keys := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var (
+		b        strings.Builder
+		count    int
+		last     string
+		prefixes = map[string]bool{}
+	)
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) || k <= token {
+			continue
+		}
+		if count == limit {
+			_, _ = fmt.Fprintf(&b, "<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>", last)
+			break
+		}
+		rest := strings.TrimPrefix(k, prefix)
+		if i := strings.Index(rest, delimiter); delimiter != "" && i >= 0 {
+			cp := prefix + rest[:i+1]
+			if !prefixes[cp] {
+				prefixes[cp] = true
+				_, _ = fmt.Fprintf(&b, "<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>", cp)
+				count++
+			}
+			last = cp + "\xff"
+			continue
+		}
+		o := s.objects[k]
+		_, _ = fmt.Fprintf(&b, "<Contents><Key>%s</Key><LastModified>%s</LastModified><Size>%d</Size></Contents>",
+			k, o.modTime.Format(time.RFC3339), len(o.data))
+		count++
+		last = k
+	}
+	_, _ = fmt.Fprintf(w, "<ListBucketResult>%s</ListBucketResult>", b.String())
+}
+
+func describeRequest(r *http.Request) string {
+	q := r.URL.Query()
+	switch {
+	case q.Has("uploads"):
+		return r.Method + " uploads"
+	case q.Has("partNumber"):
+		return r.Method + " part " + q.Get("partNumber")
+	case q.Has("uploadId"):
+		return r.Method + " upload"
+	case q.Has("list-type"):
+		return r.Method + " list"
+	case r.Header.Get(headerCopySource) != "":
+		return r.Method + " copy"
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+// verifySignature signs copy of received request (with headers listed as signed) and compares signatures.
+func verifySignature(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	_, signed, ok := strings.Cut(auth, "SignedHeaders=")
+	if !ok {
+		return false
+	}
+	signed, _, _ = strings.Cut(signed, ",")
+
+	date, err := time.Parse(timeFormat, r.Header.Get(headerDate))
+	if err != nil {
+		return false
+	}
+	req := &http.Request{Method: r.Method, URL: r.URL, Host: r.Host, Header: http.Header{}}
+	for _, name := range strings.Split(signed, ";") {
+		if name != "host" && name != "x-amz-date" && name != "x-amz-content-sha256" {
+			req.Header[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+		}
+	}
+	s := &signer{credentials: testCredentials, region: testRegion, now: func() time.Time { return date }}
+	s.sign(req, r.Header.Get(headerContentSHA256))
+
+	return req.Header.Get("Authorization") == auth
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message><RequestId>test</RequestId></Error>", code, code)
+}
+
+func TestNew(t *testing.T) {
+	t.Run("it should sign requests with provided credentials", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newFakeS3(t)
+		srv.put("test.txt", []byte("TEST"))
+		invalid, err := New(testBucket, OptionEndpoint(srv.URL), OptionRegion(testRegion), OptionPathStyle(true),
+			OptionCredentials(Credentials{AccessKeyID: "TEST", SecretAccessKey: "INVALID"}))
+		require.NoError(t, err)
+
+		// WHEN
+		content, errValid := filesystem.ReadContentOf(srv.filesystem(t), "test.txt")
+		_, errInvalid := filesystem.ReadContentOf(invalid, "test.txt")
+
+		// THEN
+		require.NoError(t, errValid)
+		assert.Equal(t, filesystem.Content("TEST"), content)
+		require.ErrorIs(t, errInvalid, filesystem.ErrPermissionDenied)
+		var s3Err *Error
+		require.ErrorAs(t, errInvalid, &s3Err)
+		assert.Equal(t, "SignatureDoesNotMatch", s3Err.Code)
+		assert.Equal(t, http.StatusForbidden, s3Err.StatusCode)
+	})
+	t.Run("it should place bucket in host name unless path style is enabled", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var hosts []string
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			hosts = append(hosts, req.URL.Host+req.URL.EscapedPath())
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(nil)), Header: http.Header{}}, nil
+		})
+		fs, err := New(testBucket, OptionRegion(testRegion), OptionClient(&http.Client{Transport: transport}))
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = filesystem.ReadContentOf(fs, "dir/a b.txt")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bucket.s3.eu-central-1.amazonaws.com/dir/a%20b.txt"}, hosts)
+	})
+	t.Run("it should reject invalid configuration", func(t *testing.T) {
+		t.Parallel()
+
+		// WHEN
+		_, errBucket := New("")
+		_, errPartSize := New(testBucket, OptionPartSize(1024))
+		_, errScheme := New(testBucket, OptionEndpoint("ftp://localhost"))
+
+		// THEN
+		require.ErrorContains(t, errBucket, "bucket is required")
+		require.ErrorContains(t, errPartSize, "part size has to be at least")
+		require.ErrorContains(t, errScheme, "unsupported scheme")
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
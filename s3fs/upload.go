@@ -0,0 +1,89 @@
+package s3fs
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type (
+	initiateMultipartUploadResult struct {
+		UploadID string `xml:"UploadId"`
+	}
+
+	completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+
+	completeMultipartUpload struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}
+)
+
+// upload stores content under key. Content not exceeding single part is uploaded with PutObject,
+// larger one with multipart upload (aborted when any of the parts fails).
+func (c *client) upload(ctx context.Context, k string, content io.Reader) error {
+	part := make([]byte, c.partSize)
+	n, err := io.ReadFull(content, part)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return c.put(ctx, k, part[:n])
+	}
+	if err != nil {
+		return err
+	}
+
+	var initiated initiateMultipartUploadResult
+	if err = c.send(ctx, http.MethodPost, k, url.Values{"uploads": {""}}, nil, nil, &initiated); err != nil {
+		return fmt.Errorf("cannot initiate multipart upload: %w", err)
+	}
+	if initiated.UploadID == "" {
+		return errors.New("cannot initiate multipart upload: missing upload ID")
+	}
+
+	parts, err := c.uploadParts(ctx, k, initiated.UploadID, part, content)
+	if err != nil {
+		// Upload is aborted even when ctx got canceled, so its parts are not left behind.
+		_ = c.send(context.WithoutCancel(ctx), http.MethodDelete, k, url.Values{"uploadId": {initiated.UploadID}}, nil, nil, nil)
+		return err
+	}
+
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	if err = c.send(ctx, http.MethodPost, k, url.Values{"uploadId": {initiated.UploadID}}, nil, body, nil); err != nil {
+		return fmt.Errorf("cannot complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+// uploadParts uploads first (already read) part followed by the rest of content.
+func (c *client) uploadParts(ctx context.Context, k, uploadID string, first []byte, content io.Reader) ([]completedPart, error) {
+	var parts []completedPart
+	for number, data := 1, first; len(data) > 0; number++ {
+		resp, err := c.do(ctx, http.MethodPut, k, url.Values{
+			"partNumber": {strconv.Itoa(number)},
+			"uploadId":   {uploadID},
+		}, nil, data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot upload part %d: %w", number, err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		n, err := io.ReadFull(content, first)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+		data = first[:n]
+	}
+	return parts, nil
+}
@@ -0,0 +1,541 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sandboxBackendName = "sandbox"
+
+const (
+	ChangeCreated ChangeKind = iota
+	ChangeModified
+	ChangeRemoved
+)
+
+const (
+	sandboxFile sandboxKind = iota
+	sandboxDirectory
+	sandboxRemoved
+)
+
+var changeKindNames = map[ChangeKind]string{
+	ChangeCreated:  "created",
+	ChangeModified: "modified",
+	ChangeRemoved:  "removed",
+}
+
+type (
+	ChangeKind uint8
+
+	// Change describes single pending modification of Sandbox. Before holds content of the file in underlying
+	// Filesystem (nil for directories and created files) and After its content in Sandbox (nil for directories
+	// and removed files).
+	Change struct {
+		Path        string
+		Kind        ChangeKind
+		IsDirectory bool
+		Before      Content
+		After       Content
+	}
+
+	// Sandbox is a Filesystem keeping every modification in memory on top of underlying Filesystem
+	// which is not touched until Commit is called. Reads see pending modifications applied.
+	// It's safe for concurrent use.
+	Sandbox struct {
+		base    Filesystem
+		mu      sync.RWMutex
+		entries map[string]*sandboxEntry
+	}
+
+	sandboxKind uint8
+
+	sandboxEntry struct {
+		kind    sandboxKind
+		content []byte
+		mode    Mode
+		modTime time.Time
+		// replaced is set when entry was created in place of path removed earlier from underlying Filesystem.
+		replaced bool
+	}
+)
+
+func (k ChangeKind) Is(val ChangeKind) bool {
+	return k == val
+}
+
+func (k ChangeKind) String() string {
+	if name, ok := changeKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(k))
+}
+
+// NewSandbox creates Sandbox on top of provided Filesystem. Moved and copied files are kept in memory
+// the same way as written ones, so Move of large directory loads its entire content.
+func NewSandbox(fs Filesystem) *Sandbox {
+	return &Sandbox{base: fs, entries: make(map[string]*sandboxEntry)}
+}
+
+// Diff returns pending changes ordered by path.
+func (s *Sandbox) Diff() ([]Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ctx := context.Background()
+	res := make([]Change, 0, len(s.entries))
+	for _, p := range s.paths() {
+		e := s.entries[p]
+
+		before, existed, err := s.baseEntry(ctx, p)
+		if err != nil {
+			return nil, newPathError(s.base, OperationCheckIfExists, p, err)
+		}
+		var beforeContent Content
+		if existed && !before.IsDirectory {
+			if beforeContent, err = s.base.handleReadContentOf(ctx, p); err != nil {
+				return nil, newPathError(s.base, OperationReadContentOf, p, err)
+			}
+		}
+
+		switch {
+		case e.kind == sandboxRemoved:
+			res = append(res, Change{Path: p, Kind: ChangeRemoved, IsDirectory: before.IsDirectory, Before: beforeContent})
+			continue
+		case e.kind == sandboxFile && existed && !before.IsDirectory:
+			res = append(res, Change{Path: p, Kind: ChangeModified, Before: beforeContent, After: append(Content{}, e.content...)})
+			continue
+		case existed:
+			res = append(res, Change{Path: p, Kind: ChangeRemoved, IsDirectory: before.IsDirectory, Before: beforeContent})
+		}
+
+		change := Change{Path: p, Kind: ChangeCreated, IsDirectory: e.kind == sandboxDirectory}
+		if e.kind == sandboxFile {
+			change.After = append(Content{}, e.content...)
+		}
+		res = append(res, change)
+	}
+
+	return res, nil
+}
+
+// Commit applies pending changes to underlying Filesystem (parents before their content). Changes applied
+// successfully are dropped from Sandbox, so Commit can be called again after failure to apply the remaining ones.
+func (s *Sandbox) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx := context.Background()
+	for _, p := range s.paths() {
+		if err := s.commit(ctx, p, s.entries[p]); err != nil {
+			return fmt.Errorf("sandbox commit failed: %w", err)
+		}
+		delete(s.entries, p)
+	}
+	return nil
+}
+
+// Discard drops every pending change.
+func (s *Sandbox) Discard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = make(map[string]*sandboxEntry)
+}
+
+func (s *Sandbox) backendName() string {
+	return sandboxBackendName
+}
+
+func (s *Sandbox) capabilities() capabilities {
+	return capabilities{}
+}
+
+func (s *Sandbox) handleReadContentOf(ctx context.Context, p string) (Content, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p = sandboxPath(p)
+	if e, ok := s.entries[p]; ok {
+		switch e.kind {
+		case sandboxRemoved:
+			return nil, ErrFileNotFound
+		case sandboxDirectory:
+			return nil, ErrDirectory
+		default:
+			return bytes.Clone(e.content), nil
+		}
+	}
+	if s.shadowed(p) {
+		return nil, ErrFileNotFound
+	}
+	return s.base.handleReadContentOf(ctx, p)
+}
+
+func (s *Sandbox) handleStreamContentOf(ctx context.Context, p string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	_, pending := s.entries[sandboxPath(p)]
+	shadowed := s.shadowed(sandboxPath(p))
+	s.mu.RUnlock()
+
+	if !pending && !shadowed {
+		return s.base.handleStreamContentOf(ctx, p)
+	}
+
+	content, err := s.handleReadContentOf(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *Sandbox) handleCheckIfExists(ctx context.Context, p string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists, err := s.stat(ctx, sandboxPath(p))
+	return exists, err
+}
+
+func (s *Sandbox) handleCreateFile(ctx context.Context, p string, arg Arguments) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p = sandboxPath(p)
+	entry, exists, err := s.stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if !arg.AllowOverwrite {
+			return ErrFileFound
+		}
+		if entry.IsDirectory {
+			return ErrDirectory
+		}
+	} else if err = s.ensureParent(ctx, p, arg); err != nil {
+		return err
+	}
+
+	s.put(p, &sandboxEntry{kind: sandboxFile, mode: arg.Mode})
+	return nil
+}
+
+func (s *Sandbox) handleWriteContentTo(ctx context.Context, p string, content []byte, arg Arguments) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(ctx, sandboxPath(p), content, arg)
+}
+
+func (s *Sandbox) handleStreamContentTo(ctx context.Context, p string, content io.Reader, arg Arguments) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(ctx, sandboxPath(p), data, arg)
+}
+
+func (s *Sandbox) handleCreateDirectory(ctx context.Context, p string, arg Arguments) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p = sandboxPath(p)
+	entry, exists, err := s.stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if entry.IsDirectory {
+			return ErrDirectoryFound
+		}
+		return ErrFile
+	}
+	if err = s.ensureParent(ctx, p, arg); err != nil {
+		return err
+	}
+
+	s.put(p, &sandboxEntry{kind: sandboxDirectory, mode: arg.Mode})
+	return nil
+}
+
+func (s *Sandbox) handleRemove(ctx context.Context, p string, arg Arguments) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p = sandboxPath(p)
+	entry, exists, err := s.stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrFileNotFound
+	}
+	if entry.IsDirectory && !arg.Recursive {
+		entries, lErr := s.list(ctx, p)
+		if lErr != nil {
+			return lErr
+		}
+		if len(entries) > 0 {
+			return ErrNotEmpty
+		}
+	}
+
+	for q := range s.entries {
+		if sandboxBeneath(p, q) {
+			delete(s.entries, q)
+		}
+	}
+
+	_, inBase, err := s.baseEntry(ctx, p)
+	if err != nil {
+		return err
+	}
+	if inBase {
+		s.entries[p] = &sandboxEntry{kind: sandboxRemoved, modTime: time.Now()}
+	} else {
+		delete(s.entries, p)
+	}
+	return nil
+}
+
+func (s *Sandbox) handleMove(ctx context.Context, source, target string, arg Arguments) error {
+	arg.Recursive = true
+	if err := transfer(ctx, s, source, s, target, arg); err != nil {
+		return err
+	}
+	return s.handleRemove(ctx, source, Arguments{Recursive: true})
+}
+
+func (s *Sandbox) handleCopy(ctx context.Context, source, target string, arg Arguments) error {
+	return transfer(ctx, s, source, s, target, arg)
+}
+
+func (s *Sandbox) handleListFilesIn(ctx context.Context, p string) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list(ctx, sandboxPath(p))
+}
+
+func (s *Sandbox) handleGlob(ctx context.Context, pattern string) ([]string, error) {
+	return globWithListing(ctx, s, pattern)
+}
+
+// stat returns entry of path with pending changes applied and reports if it exists.
+func (s *Sandbox) stat(ctx context.Context, p string) (Entry, bool, error) {
+	if e, ok := s.entries[p]; ok {
+		if e.kind == sandboxRemoved {
+			return Entry{}, false, nil
+		}
+		return e.asEntry(p), true, nil
+	}
+	return s.baseEntry(ctx, p)
+}
+
+// baseEntry returns entry of path in underlying Filesystem unless it's hidden by removal (or replacement) of its parent.
+func (s *Sandbox) baseEntry(ctx context.Context, p string) (Entry, bool, error) {
+	if s.shadowed(p) {
+		return Entry{}, false, nil
+	}
+	exists, err := s.base.handleCheckIfExists(ctx, p)
+	if err != nil || !exists {
+		return Entry{}, false, err
+	}
+	entry, err := entryOf(ctx, s.base, p)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return entry, true, nil
+}
+
+// shadowed reports if one of parents of path was removed (or replaced) in Sandbox.
+func (s *Sandbox) shadowed(p string) bool {
+	for current := p; ; {
+		parent := path.Dir(current)
+		if parent == current {
+			return false
+		}
+		if e, ok := s.entries[parent]; ok && (e.kind == sandboxRemoved || e.replaced) {
+			return true
+		}
+		current = parent
+	}
+}
+
+func (s *Sandbox) list(ctx context.Context, p string) ([]Entry, error) {
+	entry, exists, err := s.stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrFileNotFound
+	}
+	if !entry.IsDirectory {
+		return nil, ErrFile
+	}
+
+	found := make(map[string]Entry)
+	if e, ok := s.entries[p]; !s.shadowed(p) && (!ok || !e.replaced) {
+		entries, lErr := s.base.handleListFilesIn(ctx, p)
+		if lErr != nil && !errors.Is(lErr, ErrFileNotFound) {
+			return nil, lErr
+		}
+		for _, entry := range entries {
+			found[entry.Name] = entry
+		}
+	}
+	for q, e := range s.entries {
+		if q == p || path.Dir(q) != p {
+			continue
+		}
+		name := path.Base(q)
+		if e.kind == sandboxRemoved {
+			delete(found, name)
+		} else {
+			found[name] = e.asEntry(q)
+		}
+	}
+
+	res := make([]Entry, 0, len(found))
+	for _, entry := range found {
+		res = append(res, entry)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+func (s *Sandbox) write(ctx context.Context, p string, content []byte, arg Arguments) error {
+	if err := arg.ContentOperation.assetValid(); err != nil {
+		return err
+	}
+
+	entry, exists, err := s.stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrFileNotFound
+	}
+	if entry.IsDirectory {
+		return ErrDirectory
+	}
+
+	var current []byte
+	if arg.ContentOperation.Is(ContentOperationAppend) {
+		if e, ok := s.entries[p]; ok {
+			current = e.content
+		} else if current, err = s.base.handleReadContentOf(ctx, p); err != nil {
+			return err
+		}
+	}
+
+	data := make([]byte, 0, len(current)+len(content))
+	data = append(append(data, current...), content...)
+	s.put(p, &sandboxEntry{kind: sandboxFile, content: data, mode: entry.Mode})
+	return nil
+}
+
+// ensureParent verifies that parent of path is a directory and creates missing ones if allowed by arguments.
+func (s *Sandbox) ensureParent(ctx context.Context, p string, arg Arguments) error {
+	parent := path.Dir(p)
+	if parent == p {
+		return nil
+	}
+
+	entry, exists, err := s.stat(ctx, parent)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if !entry.IsDirectory {
+			return fmt.Errorf("location structure does not contain valid directory as target: %w", ErrUnresolvableDirectoryStructure)
+		}
+		return nil
+	}
+	if !arg.AllowCreationOfDirectoryStructure {
+		return fmt.Errorf("creation of non-existing directory structure is forbidden by current settings: %w", ErrUnresolvableDirectoryStructure)
+	}
+	if err = s.ensureParent(ctx, parent, arg); err != nil {
+		return err
+	}
+
+	s.put(parent, &sandboxEntry{kind: sandboxDirectory, mode: arg.DirectoryStructureMode})
+	return nil
+}
+
+func (s *Sandbox) put(p string, e *sandboxEntry) {
+	if old, ok := s.entries[p]; ok {
+		e.replaced = old.replaced || old.kind == sandboxRemoved
+	}
+	e.modTime = time.Now()
+	s.entries[p] = e
+}
+
+func (s *Sandbox) commit(ctx context.Context, p string, e *sandboxEntry) error {
+	if e.kind == sandboxRemoved || e.replaced {
+		if err := s.base.handleRemove(ctx, p, Arguments{Recursive: true}); err != nil && !errors.Is(err, ErrFileNotFound) {
+			return newPathError(s.base, OperationRemove, p, err)
+		}
+	}
+
+	switch e.kind {
+	case sandboxDirectory:
+		if err := s.base.handleCreateDirectory(ctx, p, Arguments{Mode: e.mode}); err != nil && !errors.Is(err, ErrDirectoryFound) {
+			return newPathError(s.base, OperationCreateDirectory, p, err)
+		}
+	case sandboxFile:
+		if err := s.base.handleCreateFile(ctx, p, Arguments{Mode: e.mode, AllowOverwrite: true}); err != nil {
+			return newPathError(s.base, OperationCreateFile, p, err)
+		}
+		if err := s.base.handleWriteContentTo(ctx, p, e.content, Arguments{ContentOperation: ContentOperationOverwrite}); err != nil {
+			return newPathError(s.base, OperationWriteContentTo, p, err)
+		}
+	}
+	return nil
+}
+
+// paths returns paths of pending changes sorted so that parents precede their content.
+func (s *Sandbox) paths() []string {
+	res := make([]string, 0, len(s.entries))
+	for p := range s.entries {
+		res = append(res, p)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (e *sandboxEntry) asEntry(p string) Entry {
+	return Entry{
+		Name:        path.Base(p),
+		Size:        int64(len(e.content)),
+		Mode:        e.mode,
+		ModTime:     e.modTime,
+		IsDirectory: e.kind == sandboxDirectory,
+	}
+}
+
+func sandboxPath(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// sandboxBeneath reports if path q is placed inside directory p.
+func sandboxBeneath(p, q string) bool {
+	if p == "." {
+		return q != "." && !path.IsAbs(q)
+	}
+	return strings.HasPrefix(q, strings.TrimSuffix(p, "/")+"/")
+}
@@ -0,0 +1,183 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSandbox(t *testing.T, workdir string) *Sandbox {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(workdir, "conf.d"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "app.yaml"), []byte("APP"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "conf.d", "a.yaml"), []byte("A"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "conf.d", "b.yaml"), []byte("B"), 0o600))
+
+	local, err := New()
+	require.NoError(t, err)
+
+	return NewSandbox(Sub(local, workdir))
+}
+
+func TestSandbox(t *testing.T) {
+	t.Run("it should apply pending changes to reads without modifying underlying filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestSandbox(t, workdir)
+
+			// WHEN
+			appendErr := WriteContentTo(fs, "app.yaml", " MORE")
+			createErr := CreateFile(fs, "new/file.txt", WithAllowCreationOfDirectoryStructure(true))
+			writeErr := WriteContentTo(fs, "new/file.txt", "NEW")
+			removeErr := Remove(fs, "conf.d/a.yaml")
+
+			// THEN
+			require.NoError(t, appendErr)
+			require.NoError(t, createErr)
+			require.NoError(t, writeErr)
+			require.NoError(t, removeErr)
+
+			app, err := ReadContentOf(fs, "app.yaml")
+			require.NoError(t, err)
+			assert.Equal(t, Content("APP MORE"), app)
+			created, err := ReadContentOf(fs, "new/file.txt")
+			require.NoError(t, err)
+			assert.Equal(t, Content("NEW"), created)
+			_, err = ReadContentOf(fs, "conf.d/a.yaml")
+			require.ErrorIs(t, err, ErrFileNotFound)
+			assert.Equal(t, []string{"app.yaml", "conf.d", "new"}, listNames(t, fs, "."))
+			assert.Equal(t, []string{"b.yaml"}, listNames(t, fs, "conf.d"))
+
+			onDisk, err := os.ReadFile(filepath.Join(workdir, "app.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("APP"), onDisk)
+			assert.FileExists(t, filepath.Join(workdir, "conf.d", "a.yaml"))
+			assert.NoDirExists(t, filepath.Join(workdir, "new"))
+		})
+	})
+	t.Run("it should list pending changes with content before and after", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestSandbox(t, workdir)
+			require.NoError(t, WriteContentTo(fs, "app.yaml", "NEW APP", WithContentOperation(ContentOperationOverwrite)))
+			require.NoError(t, CreateDirectory(fs, "data"))
+			require.NoError(t, Remove(fs, "conf.d/b.yaml"))
+
+			// WHEN
+			changes, err := fs.Diff()
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, []Change{
+				{Path: "app.yaml", Kind: ChangeModified, Before: Content("APP"), After: Content("NEW APP")},
+				{Path: "conf.d/b.yaml", Kind: ChangeRemoved, Before: Content("B")},
+				{Path: "data", Kind: ChangeCreated, IsDirectory: true},
+			}, changes)
+		})
+	})
+	t.Run("it should report directory removed and created again as replaced", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestSandbox(t, workdir)
+			require.NoError(t, Remove(fs, "conf.d", WithRecursive(true)))
+			require.NoError(t, CreateDirectory(fs, "conf.d", WithMode(ModeAllReadWriteExecute)))
+			require.NoError(t, CreateFile(fs, "conf.d/a.yaml"))
+
+			// WHEN
+			changes, err := fs.Diff()
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a.yaml"}, listNames(t, fs, "conf.d"))
+			assert.Equal(t, []Change{
+				{Path: "conf.d", Kind: ChangeRemoved, IsDirectory: true},
+				{Path: "conf.d", Kind: ChangeCreated, IsDirectory: true},
+				{Path: "conf.d/a.yaml", Kind: ChangeCreated, After: Content{}},
+			}, changes)
+		})
+	})
+	t.Run("it should apply pending changes to underlying filesystem on commit", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestSandbox(t, workdir)
+			require.NoError(t, WriteContentTo(fs, "app.yaml", " MORE"))
+			require.NoError(t, Move(fs, "conf.d", "moved.d"))
+			require.NoError(t, CreateFile(fs, "moved.d/c.yaml"))
+
+			// WHEN
+			err := fs.Commit()
+
+			// THEN
+			require.NoError(t, err)
+			changes, dErr := fs.Diff()
+			require.NoError(t, dErr)
+			assert.Empty(t, changes)
+
+			app, rErr := os.ReadFile(filepath.Join(workdir, "app.yaml"))
+			require.NoError(t, rErr)
+			assert.Equal(t, []byte("APP MORE"), app)
+			a, rErr := os.ReadFile(filepath.Join(workdir, "moved.d", "a.yaml"))
+			require.NoError(t, rErr)
+			assert.Equal(t, []byte("A"), a)
+			assert.FileExists(t, filepath.Join(workdir, "moved.d", "c.yaml"))
+			assert.NoDirExists(t, filepath.Join(workdir, "conf.d"))
+		})
+	})
+	t.Run("it should not lose file moved onto itself", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestSandbox(t, workdir)
+
+			// WHEN
+			errFile := Move(fs, "app.yaml", "./app.yaml", WithAllowOverwrite(true))
+			errDirectory := Move(fs, "conf.d", "conf.d/inner", WithAllowOverwrite(true))
+			errCopy := Copy(fs, "app.yaml", "app.yaml", WithAllowOverwrite(true))
+
+			// THEN
+			require.ErrorIs(t, errFile, ErrUnresolvableDirectoryStructure)
+			require.ErrorIs(t, errDirectory, ErrUnresolvableDirectoryStructure)
+			require.ErrorIs(t, errCopy, ErrUnresolvableDirectoryStructure)
+			changes, err := fs.Diff()
+			require.NoError(t, err)
+			assert.Empty(t, changes)
+			require.NoError(t, fs.Commit())
+			app, err := os.ReadFile(filepath.Join(workdir, "app.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("APP"), app)
+		})
+	})
+	t.Run("it should drop pending changes on discard", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs := newTestSandbox(t, workdir)
+			require.NoError(t, Remove(fs, "app.yaml"))
+
+			// WHEN
+			fs.Discard()
+
+			// THEN
+			app, err := ReadContentOf(fs, "app.yaml")
+			require.NoError(t, err)
+			assert.Equal(t, Content("APP"), app)
+			changes, err := fs.Diff()
+			require.NoError(t, err)
+			assert.Empty(t, changes)
+		})
+	})
+}
@@ -0,0 +1,527 @@
+package sftpfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// SFTP status codes (draft-ietf-secsh-filexfer-13, section 9.1) translated to sentinel errors.
+const (
+	statusNoSuchFile          uint32 = 2
+	statusPermissionDenied    uint32 = 3
+	statusNoSuchPath          uint32 = 10
+	statusFileAlreadyExists   uint32 = 11
+	statusWriteProtect        uint32 = 12
+	statusNoSpaceOnFilesystem uint32 = 14
+	statusQuotaExceeded       uint32 = 15
+	statusDirNotEmpty         uint32 = 18
+	statusNotADirectory       uint32 = 19
+	statusCannotDelete        uint32 = 22
+	statusFileIsADirectory    uint32 = 24
+)
+
+// posixRenameExtension replaces existing target atomically (supported by OpenSSH).
+const posixRenameExtension = "posix-rename@openssh.com"
+
+var statusSentinels = map[uint32]error{
+	statusNoSuchFile:          filesystem.ErrFileNotFound,
+	statusNoSuchPath:          filesystem.ErrFileNotFound,
+	statusPermissionDenied:    filesystem.ErrPermissionDenied,
+	statusCannotDelete:        filesystem.ErrPermissionDenied,
+	statusFileAlreadyExists:   filesystem.ErrFileFound,
+	statusWriteProtect:        filesystem.ErrReadOnly,
+	statusNoSpaceOnFilesystem: filesystem.ErrNoSpace,
+	statusQuotaExceeded:       filesystem.ErrNoSpace,
+	statusDirNotEmpty:         filesystem.ErrNotEmpty,
+	statusNotADirectory:       filesystem.ErrUnresolvableDirectoryStructure,
+	statusFileIsADirectory:    filesystem.ErrDirectory,
+}
+
+func (c *Client) readContentOf(ctx context.Context, p string) (filesystem.Content, error) {
+	var res filesystem.Content
+	err := c.pool.with(ctx, true, func(sc *sftp.Client) error {
+		f, err := openForReading(sc, p)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+
+		res, err = io.ReadAll(f)
+		return translateError(err)
+	})
+	return res, err
+}
+
+func (c *Client) streamContentOf(ctx context.Context, p string) (io.ReadCloser, error) {
+	conn, err := c.pool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := openForReading(conn.sftp, p)
+	if err != nil {
+		c.pool.release(conn, err)
+		return nil, err
+	}
+	return &pooledReader{File: f, release: func(err error) { c.pool.release(conn, err) }}, nil
+}
+
+func (c *Client) checkIfExists(ctx context.Context, p string) (bool, error) {
+	var exists bool
+	err := c.pool.with(ctx, true, func(sc *sftp.Client) error {
+		fi, err := stat(sc, p)
+		exists = fi != nil
+		return err
+	})
+	return exists, err
+}
+
+func (c *Client) createFile(ctx context.Context, p string, arg filesystem.Arguments) error {
+	return c.pool.with(ctx, false, func(sc *sftp.Client) error {
+		p = cleanPath(p)
+		if err := prepareParent(sc, p, arg); err != nil {
+			return err
+		}
+
+		fi, err := stat(sc, p)
+		if err != nil {
+			return err
+		}
+		if fi != nil && !arg.AllowOverwrite {
+			return filesystem.ErrFileFound
+		}
+		if fi != nil && fi.IsDir() {
+			return filesystem.ErrDirectory
+		}
+
+		f, err := sc.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return translateError(err)
+		}
+		if err = f.Close(); err != nil {
+			return translateError(err)
+		}
+		return translateError(sc.Chmod(p, fs.FileMode(arg.Mode)))
+	})
+}
+
+func (c *Client) writeContentTo(ctx context.Context, p string, content []byte, arg filesystem.Arguments) error {
+	return c.streamContentTo(ctx, p, bytes.NewReader(content), arg)
+}
+
+func (c *Client) streamContentTo(ctx context.Context, p string, content io.Reader, arg filesystem.Arguments) error {
+	flags := os.O_WRONLY
+	switch {
+	case arg.ContentOperation.Is(filesystem.ContentOperationOverwrite):
+		flags |= os.O_TRUNC
+	case !arg.ContentOperation.Is(filesystem.ContentOperationAppend):
+		return filesystem.ErrUnsupportedContentOperation
+	}
+
+	return c.pool.with(ctx, false, func(sc *sftp.Client) (err error) {
+		f, err := sc.OpenFile(cleanPath(p), flags)
+		if err != nil {
+			return translateError(err)
+		}
+		defer func() {
+			if cErr := f.Close(); cErr != nil && err == nil {
+				err = translateError(cErr)
+			}
+		}()
+
+		// Offset is moved explicitly as not every server honors append flag.
+		if flags&os.O_TRUNC == 0 {
+			if _, err = f.Seek(0, io.SeekEnd); err != nil {
+				return translateError(err)
+			}
+		}
+		_, err = io.Copy(f, content)
+		return translateError(err)
+	})
+}
+
+func (c *Client) createDirectory(ctx context.Context, p string, arg filesystem.Arguments) error {
+	return c.pool.with(ctx, false, func(sc *sftp.Client) error {
+		p = cleanPath(p)
+		if err := prepareParent(sc, p, arg); err != nil {
+			return err
+		}
+
+		fi, err := stat(sc, p)
+		if err != nil {
+			return err
+		}
+		if fi != nil {
+			if fi.IsDir() {
+				return filesystem.ErrDirectoryFound
+			}
+			return filesystem.ErrFile
+		}
+
+		if err = sc.Mkdir(p); err != nil {
+			return translateError(err)
+		}
+		return translateError(sc.Chmod(p, fs.FileMode(arg.Mode)))
+	})
+}
+
+func (c *Client) remove(ctx context.Context, p string, arg filesystem.Arguments) error {
+	return c.pool.with(ctx, false, func(sc *sftp.Client) error {
+		p = cleanPath(p)
+		fi, err := sc.Lstat(p)
+		if err != nil {
+			return translateError(err)
+		}
+		if !fi.IsDir() {
+			return translateError(sc.Remove(p))
+		}
+		if arg.Recursive {
+			return removeAll(sc, p)
+		}
+
+		entries, err := sc.ReadDir(p)
+		if err != nil {
+			return translateError(err)
+		}
+		if len(entries) > 0 {
+			return filesystem.ErrNotEmpty
+		}
+		return translateError(sc.RemoveDirectory(p))
+	})
+}
+
+func (c *Client) move(ctx context.Context, source, target string, arg filesystem.Arguments) error {
+	return c.pool.with(ctx, false, func(sc *sftp.Client) error {
+		source, target = cleanPath(source), cleanPath(target)
+		if err := checkTransfer(source, target); err != nil {
+			return err
+		}
+		si, err := sc.Lstat(source)
+		if err != nil {
+			return translateError(err)
+		}
+		existing, err := prepareTarget(sc, target, arg)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			return replace(sc, source, target, si, existing)
+		}
+		return translateError(sc.Rename(source, target))
+	})
+}
+
+func (c *Client) copy(ctx context.Context, source, target string, arg filesystem.Arguments) error {
+	return c.pool.with(ctx, false, func(sc *sftp.Client) error {
+		source, target = cleanPath(source), cleanPath(target)
+		if err := checkTransfer(source, target); err != nil {
+			return err
+		}
+		si, err := sc.Stat(source)
+		if err != nil {
+			return translateError(err)
+		}
+		if si.IsDir() && !arg.Recursive {
+			return filesystem.ErrDirectory
+		}
+		if _, err = prepareTarget(sc, target, arg); err != nil {
+			return err
+		}
+
+		return copyAll(sc, source, target, si)
+	})
+}
+
+func (c *Client) listFilesIn(ctx context.Context, p string) ([]filesystem.Entry, error) {
+	var res []filesystem.Entry
+	err := c.pool.with(ctx, true, func(sc *sftp.Client) error {
+		p = cleanPath(p)
+		di, err := sc.Stat(p)
+		if err != nil {
+			return translateError(err)
+		}
+		if !di.IsDir() {
+			return filesystem.ErrFile
+		}
+
+		fis, err := sc.ReadDir(p)
+		if err != nil {
+			return translateError(err)
+		}
+		res = make([]filesystem.Entry, 0, len(fis))
+		for _, fi := range fis {
+			entry := filesystem.Entry{
+				Name:        fi.Name(),
+				Size:        fi.Size(),
+				Mode:        filesystem.Mode(fi.Mode().Perm()),
+				ModTime:     fi.ModTime(),
+				IsDirectory: fi.IsDir(),
+				IsSymlink:   fi.Mode()&fs.ModeSymlink != 0,
+			}
+			if entry.IsSymlink {
+				entry.LinkTarget, _ = sc.ReadLink(path.Join(p, entry.Name))
+			}
+			res = append(res, entry)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// pooledReader returns connection to the pool once file is closed (only the first Close does it).
+type pooledReader struct {
+	*sftp.File
+	release func(error)
+	once    sync.Once
+}
+
+func (r *pooledReader) Read(b []byte) (int, error) {
+	n, err := r.File.Read(b)
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = translateError(err)
+	}
+	return n, err
+}
+
+func (r *pooledReader) Close() error {
+	err := fs.ErrClosed
+	r.once.Do(func() {
+		cErr := r.File.Close()
+		r.release(cErr)
+		err = translateError(cErr)
+	})
+	return err
+}
+
+func openForReading(sc *sftp.Client, p string) (*sftp.File, error) {
+	p = cleanPath(p)
+	fi, err := sc.Stat(p)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if fi.IsDir() {
+		return nil, filesystem.ErrDirectory
+	}
+
+	f, err := sc.Open(p)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return f, nil
+}
+
+// stat returns details of entry or nil (without error) if it does not exist.
+func stat(sc *sftp.Client, p string) (os.FileInfo, error) {
+	fi, err := sc.Stat(cleanPath(p))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, translateError(err)
+	}
+	return fi, nil
+}
+
+// prepareParent verifies if parent directory of p exists and creates it (if allowed).
+func prepareParent(sc *sftp.Client, p string, arg filesystem.Arguments) error {
+	dir := path.Dir(p)
+	di, err := stat(sc, dir)
+	if err != nil {
+		return err
+	}
+	if di == nil {
+		if !arg.AllowCreationOfDirectoryStructure {
+			return fmt.Errorf("creation of non-existing directory structure is forbidden by current settings: %w", filesystem.ErrUnresolvableDirectoryStructure)
+		}
+		if err = mkdirAll(sc, dir, arg.DirectoryStructureMode); err != nil {
+			return fmt.Errorf("cannot create directory structure: %w", err)
+		}
+		return nil
+	}
+	if !di.IsDir() {
+		return fmt.Errorf("location structure does not contain valid directory as target: %w", filesystem.ErrUnresolvableDirectoryStructure)
+	}
+	return nil
+}
+
+// prepareTarget verifies if target of Move or Copy can be overwritten and creates its directory structure
+// (if allowed). It returns details of existing target.
+func prepareTarget(sc *sftp.Client, target string, arg filesystem.Arguments) (os.FileInfo, error) {
+	ti, err := sc.Lstat(target)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, translateError(err)
+	}
+	if ti != nil && !arg.AllowOverwrite {
+		if ti.IsDir() {
+			return nil, filesystem.ErrDirectoryFound
+		}
+		return nil, filesystem.ErrFileFound
+	}
+	return ti, prepareParent(sc, target, arg)
+}
+
+// checkTransfer rejects transfer of path onto itself or into its own subdirectory.
+func checkTransfer(source, target string) error {
+	if target == source || strings.HasPrefix(target, strings.TrimSuffix(source, "/")+"/") {
+		return fmt.Errorf("cannot transfer %s into itself: %w", source, filesystem.ErrUnresolvableDirectoryStructure)
+	}
+	return nil
+}
+
+// replace moves source in place of existing target (SFTP rename refuses to replace existing entries).
+// Files are replaced atomically with posix-rename extension when server supports it. Otherwise target is renamed
+// aside and removed only after source took its place (failed rename restores it).
+func replace(sc *sftp.Client, source, target string, si, ti os.FileInfo) error {
+	if _, ok := sc.HasExtension(posixRenameExtension); ok && !si.IsDir() && !ti.IsDir() {
+		return translateError(sc.PosixRename(source, target))
+	}
+
+	aside := path.Join(path.Dir(target), fmt.Sprintf(".%s.replaced-%d", path.Base(target), time.Now().UnixNano()))
+	if err := sc.Rename(target, aside); err != nil {
+		return translateError(err)
+	}
+	if err := sc.Rename(source, target); err != nil {
+		_ = sc.Rename(aside, target)
+		return translateError(err)
+	}
+	return removeAll(sc, aside)
+}
+
+// mkdirAll creates directory with all missing parents using provided mode.
+func mkdirAll(sc *sftp.Client, dir string, mode filesystem.Mode) error {
+	if dir == "." || dir == "/" {
+		return nil
+	}
+	di, err := stat(sc, dir)
+	if err != nil {
+		return err
+	}
+	if di != nil {
+		if !di.IsDir() {
+			return filesystem.ErrUnresolvableDirectoryStructure
+		}
+		return nil
+	}
+
+	if err = mkdirAll(sc, path.Dir(dir), mode); err != nil {
+		return err
+	}
+	if err = sc.Mkdir(dir); err != nil {
+		return translateError(err)
+	}
+	return translateError(sc.Chmod(dir, fs.FileMode(mode)))
+}
+
+// removeAll removes entry with its content without following symlinks.
+func removeAll(sc *sftp.Client, p string) error {
+	fi, err := sc.Lstat(p)
+	if err != nil {
+		return translateError(err)
+	}
+	if !fi.IsDir() {
+		return translateError(sc.Remove(p))
+	}
+
+	entries, err := sc.ReadDir(p)
+	if err != nil {
+		return translateError(err)
+	}
+	for _, e := range entries {
+		if err = removeAll(sc, path.Join(p, e.Name())); err != nil {
+			return err
+		}
+	}
+	return translateError(sc.RemoveDirectory(p))
+}
+
+// copyAll copies file (or directory with its content) keeping its mode.
+func copyAll(sc *sftp.Client, source, target string, si os.FileInfo) error {
+	if !si.IsDir() {
+		return copyFile(sc, source, target, si.Mode().Perm())
+	}
+
+	if err := sc.Mkdir(target); err != nil {
+		if ti, sErr := sc.Stat(target); sErr != nil || !ti.IsDir() {
+			return translateError(err)
+		}
+	}
+	if err := sc.Chmod(target, si.Mode().Perm()|fs.FileMode(filesystem.ModeUserReadWriteExecute)); err != nil {
+		return translateError(err)
+	}
+
+	entries, err := sc.ReadDir(source)
+	if err != nil {
+		return translateError(err)
+	}
+	for _, e := range entries {
+		if err = copyAll(sc, path.Join(source, e.Name()), path.Join(target, e.Name()), e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(sc *sftp.Client, source, target string, mode fs.FileMode) (err error) {
+	src, err := sc.Open(source)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := sc.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		if cErr := dst.Close(); cErr != nil && err == nil {
+			err = translateError(cErr)
+		}
+	}()
+
+	if _, err = io.Copy(dst, src); err != nil {
+		return translateError(err)
+	}
+	return translateError(sc.Chmod(target, mode))
+}
+
+// translateError wraps error reported by the server with matching sentinel error (keeping the original one
+// available for inspection with errors.As).
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var status *sftp.StatusError
+	if errors.As(err, &status) {
+		if sentinel, ok := statusSentinels[status.Code]; ok {
+			return fmt.Errorf("%w: %w", sentinel, err)
+		}
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%w: %w", filesystem.ErrFileNotFound, err)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%w: %w", filesystem.ErrPermissionDenied, err)
+	}
+
+	return err
+}
+
+// cleanPath converts path to the form used by SFTP (slash separated, without "." and "..").
+func cleanPath(p string) string {
+	return path.Clean(strings.ReplaceAll(p, `\`, "/"))
+}
@@ -0,0 +1,265 @@
+package sftpfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+func TestClient(t *testing.T) {
+	t.Run("it should read and write content of files", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		fs := srv.filesystem(t)
+
+		// WHEN
+		require.NoError(t, filesystem.CreateFile(fs, "test.txt"))
+		require.NoError(t, filesystem.WriteContentTo(fs, "test.txt", "A"))
+		require.NoError(t, filesystem.StreamContentTo(fs, "test.txt", strings.NewReader(" MORE")))
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "other.txt"), []byte("OLD CONTENT"), 0o600))
+		require.NoError(t, filesystem.CreateFile(fs, "other.txt", filesystem.WithAllowOverwrite(true)))
+		require.NoError(t, filesystem.WriteContentTo(fs, "other.txt", "NEW", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite)))
+
+		// THEN
+		content, err := filesystem.ReadContentOf(fs, "test.txt")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("A MORE"), content)
+
+		stream, err := filesystem.StreamContentOf(fs, "other.txt")
+		require.NoError(t, err)
+		streamed, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, "NEW", string(streamed))
+	})
+	t.Run("it should honor mode of created files and directories", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		fs := srv.filesystem(t)
+
+		// WHEN
+		require.NoError(t, filesystem.CreateFile(fs, "a/b/test.txt",
+			filesystem.WithMode(filesystem.ModeUserReadWrite),
+			filesystem.WithAllowCreationOfDirectoryStructure(true),
+			filesystem.WithDirectoryStructureMode(filesystem.ModeUserReadWriteExecute),
+		))
+		require.NoError(t, filesystem.CreateDirectory(fs, "c", filesystem.WithMode(filesystem.ModeUserReadWriteExecute|filesystem.ModeGroupRead)))
+
+		// THEN
+		assertMode(t, filepath.Join(srv.workdir, "a/b/test.txt"), 0o600)
+		assertMode(t, filepath.Join(srv.workdir, "a/b"), 0o700)
+		assertMode(t, filepath.Join(srv.workdir, "a"), 0o700)
+		assertMode(t, filepath.Join(srv.workdir, "c"), 0o740)
+	})
+	t.Run("it should report sentinel errors", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "test.txt"), []byte("TEST"), 0o600))
+		require.NoError(t, os.MkdirAll(filepath.Join(srv.workdir, "dir", "inner"), 0o700))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		_, errMissing := filesystem.ReadContentOf(fs, "missing.txt")
+		errWriteMissing := filesystem.WriteContentTo(fs, "missing.txt", "TEST")
+		errFileFound := filesystem.CreateFile(fs, "test.txt")
+		errDirectoryFound := filesystem.CreateDirectory(fs, "dir")
+		errFile := filesystem.CreateDirectory(fs, "test.txt")
+		errNotEmpty := filesystem.Remove(fs, "dir")
+		_, errDirectory := filesystem.ReadContentOf(fs, "dir")
+		errStructure := filesystem.CreateFile(fs, "missing/test.txt")
+		errCopyDirectory := filesystem.Copy(fs, "dir", "copy")
+		errMoveFound := filesystem.Move(fs, "test.txt", "dir")
+
+		// THEN
+		require.ErrorIs(t, errMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errMissing, os.ErrNotExist)
+		require.ErrorIs(t, errWriteMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errFileFound, filesystem.ErrFileFound)
+		require.ErrorIs(t, errDirectoryFound, filesystem.ErrDirectoryFound)
+		require.ErrorIs(t, errFile, filesystem.ErrFile)
+		require.ErrorIs(t, errNotEmpty, filesystem.ErrNotEmpty)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errStructure, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errCopyDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errMoveFound, filesystem.ErrDirectoryFound)
+	})
+	t.Run("it should remove, move and copy entries", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.MkdirAll(filepath.Join(srv.workdir, "src", "inner"), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "src", "inner", "a.txt"), []byte("A"), 0o640))
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "test.txt"), []byte("TEST"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "existing.txt"), []byte("OLD"), 0o600))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		require.NoError(t, filesystem.Copy(fs, "src", "dst/copy", filesystem.WithRecursive(true), filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.Move(fs, "test.txt", "existing.txt", filesystem.WithAllowOverwrite(true)))
+		require.NoError(t, filesystem.Remove(fs, "src", filesystem.WithRecursive(true)))
+
+		// THEN
+		copied, err := os.ReadFile(filepath.Join(srv.workdir, "dst", "copy", "inner", "a.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("A"), copied)
+		assertMode(t, filepath.Join(srv.workdir, "dst", "copy", "inner", "a.txt"), 0o640)
+
+		moved, err := os.ReadFile(filepath.Join(srv.workdir, "existing.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("TEST"), moved)
+		assert.NoFileExists(t, filepath.Join(srv.workdir, "test.txt"))
+		assert.NoDirExists(t, filepath.Join(srv.workdir, "src"))
+	})
+	t.Run("it should keep entries moved or copied onto themselves", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.MkdirAll(filepath.Join(srv.workdir, "dir"), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "test.txt"), []byte("TEST"), 0o600))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		errMove := fs.move(context.Background(), "test.txt", "./test.txt", filesystem.Arguments{AllowOverwrite: true})
+		errCopy := fs.copy(context.Background(), "test.txt", "test.txt", filesystem.Arguments{AllowOverwrite: true})
+		errNested := filesystem.Move(fs, "dir", "dir/inner", filesystem.WithAllowCreationOfDirectoryStructure(true))
+
+		// THEN
+		require.ErrorIs(t, errMove, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errCopy, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errNested, filesystem.ErrUnresolvableDirectoryStructure)
+		content, err := os.ReadFile(filepath.Join(srv.workdir, "test.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("TEST"), content)
+	})
+	t.Run("it should replace existing directory with moved one", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.MkdirAll(filepath.Join(srv.workdir, "src"), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "src", "new.txt"), []byte("NEW"), 0o600))
+		require.NoError(t, os.MkdirAll(filepath.Join(srv.workdir, "dst"), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "dst", "old.txt"), []byte("OLD"), 0o600))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		errMissing := filesystem.Move(fs, "missing", "dst", filesystem.WithAllowOverwrite(true))
+		err := filesystem.Move(fs, "src", "dst", filesystem.WithAllowOverwrite(true))
+
+		// THEN
+		require.ErrorIs(t, errMissing, filesystem.ErrFileNotFound)
+		require.NoError(t, err)
+		content, rErr := os.ReadFile(filepath.Join(srv.workdir, "dst", "new.txt"))
+		require.NoError(t, rErr)
+		assert.Equal(t, []byte("NEW"), content)
+		assert.NoFileExists(t, filepath.Join(srv.workdir, "dst", "old.txt"))
+		entries, rErr := os.ReadDir(srv.workdir)
+		require.NoError(t, rErr)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "dst", entries[0].Name())
+	})
+	t.Run("it should list entries and resolve glob with them", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.MkdirAll(filepath.Join(srv.workdir, "conf.d"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "conf.d", "a.yaml"), []byte("A"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "conf.d", "b.txt"), []byte("BB"), 0o640))
+		require.NoError(t, os.Symlink("a.yaml", filepath.Join(srv.workdir, "conf.d", "link.yaml")))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		entries, listErr := filesystem.ListFilesIn(fs, "conf.d")
+		paths, globErr := filesystem.Glob(fs, "**/*.txt")
+		exists, existsErr := filesystem.CheckIfExists(fs, "conf.d/b.txt")
+		missing, missingErr := filesystem.CheckIfExists(fs, "conf.d/c.txt")
+
+		// THEN
+		require.NoError(t, listErr)
+		require.NoError(t, globErr)
+		require.NoError(t, existsErr)
+		require.NoError(t, missingErr)
+		assert.True(t, exists)
+		assert.False(t, missing)
+		assert.Equal(t, []string{"conf.d/b.txt"}, paths)
+
+		byName := make(map[string]filesystem.Entry, len(entries))
+		for _, e := range entries {
+			byName[e.Name] = e
+		}
+		require.Len(t, byName, 3)
+		assert.Equal(t, int64(2), byName["b.txt"].Size)
+		assert.Equal(t, filesystem.Mode(0o640), byName["b.txt"].Mode)
+		assert.True(t, byName["link.yaml"].IsSymlink)
+		assert.Equal(t, "a.yaml", byName["link.yaml"].LinkTarget)
+	})
+}
+
+func TestTranslateError(t *testing.T) {
+	t.Run("it should translate SFTP status codes to sentinel errors", func(t *testing.T) {
+		t.Parallel()
+
+		cases := map[uint32]error{
+			statusNoSuchFile:          filesystem.ErrFileNotFound,
+			statusPermissionDenied:    filesystem.ErrPermissionDenied,
+			statusFileAlreadyExists:   filesystem.ErrFileFound,
+			statusWriteProtect:        filesystem.ErrReadOnly,
+			statusNoSpaceOnFilesystem: filesystem.ErrNoSpace,
+			statusDirNotEmpty:         filesystem.ErrNotEmpty,
+			statusFileIsADirectory:    filesystem.ErrDirectory,
+		}
+		for code, expected := range cases {
+			// GIVEN
+			status := &sftp.StatusError{Code: code}
+
+			// WHEN
+			err := translateError(status)
+
+			// THEN
+			require.ErrorIs(t, err, expected)
+			var original *sftp.StatusError
+			require.ErrorAs(t, err, &original)
+			assert.Equal(t, code, original.Code)
+		}
+	})
+	t.Run("it should translate errors normalised by SFTP client", func(t *testing.T) {
+		t.Parallel()
+
+		// WHEN
+		errNotExist := translateError(os.ErrNotExist)
+		errPermission := translateError(os.ErrPermission)
+		errOther := translateError(errors.New("other"))
+
+		// THEN
+		require.ErrorIs(t, errNotExist, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errPermission, filesystem.ErrPermissionDenied)
+		assert.Equal(t, "other", errOther.Error())
+	})
+}
+
+func assertMode(t *testing.T, p string, expected os.FileMode) {
+	t.Helper()
+
+	fi, err := os.Stat(p)
+	require.NoError(t, err)
+	assert.Equal(t, expected, fi.Mode().Perm(), p)
+}
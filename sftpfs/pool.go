@@ -0,0 +1,179 @@
+package sftpfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"sync"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+type (
+	// connection is single SSH connection with SFTP session started on it.
+	connection struct {
+		ssh       *ssh.Client
+		sftp      *sftp.Client
+		closed    chan struct{}
+		closeOnce sync.Once
+	}
+
+	// pool keeps up to size connections, reusing idle ones and replacing those dropped by the server.
+	pool struct {
+		dial  func() (*connection, error)
+		slots chan struct{}
+
+		mu     sync.Mutex
+		idle   []*connection
+		closed bool
+	}
+)
+
+func newConnection(sshClient *ssh.Client, sftpClient *sftp.Client) *connection {
+	c := &connection{ssh: sshClient, sftp: sftpClient, closed: make(chan struct{})}
+	go func() {
+		_ = sftpClient.Wait()
+		close(c.closed)
+	}()
+	return c
+}
+
+// alive reports whether connection was not closed (by either side).
+func (c *connection) alive() bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *connection) close() {
+	c.closeOnce.Do(func() {
+		_ = c.sftp.Close()
+		_ = c.ssh.Close()
+	})
+}
+
+func newPool(size int, dial func() (*connection, error)) *pool {
+	return &pool{dial: dial, slots: make(chan struct{}, size)}
+}
+
+// prime establishes first connection and keeps it idle.
+func (p *pool) prime() error {
+	c, err := p.acquire(context.Background())
+	if err != nil {
+		return err
+	}
+	p.release(c, nil)
+	return nil
+}
+
+// acquire returns idle connection (skipping dropped ones) or establishes a new one. It blocks when all
+// connections are in use (until ctx is done). Connection has to be returned with release.
+func (p *pool) acquire(ctx context.Context) (*connection, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return nil, fs.ErrClosed
+	}
+	for len(p.idle) > 0 {
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if c.alive() {
+			p.mu.Unlock()
+			return c, nil
+		}
+		c.close()
+	}
+	p.mu.Unlock()
+
+	c, err := p.dial()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return c, nil
+}
+
+// release returns connection to the pool. Connection is discarded when it was dropped or err
+// indicates that it's no longer usable.
+func (p *pool) release(c *connection, err error) {
+	defer func() { <-p.slots }()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || !c.alive() || isConnectionError(err) {
+		c.close()
+		return
+	}
+	p.idle = append(p.idle, c)
+}
+
+// close closes idle connections and makes pool close released ones. It reports false if pool was already closed.
+func (p *pool) close() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return false
+	}
+	p.closed = true
+	for _, c := range p.idle {
+		c.close()
+	}
+	p.idle = nil
+	return true
+}
+
+// discard closes connection instead of returning it to the pool.
+func (p *pool) discard(c *connection) {
+	defer func() { <-p.slots }()
+	c.close()
+}
+
+// with performs fn on pooled connection. When retry is enabled and fn fails because connection was dropped,
+// it's performed once again on a new connection. Connection is closed once ctx is done while fn is performed
+// (SFTP requests cannot be canceled otherwise), so fn fails with error of ctx.
+func (p *pool) with(ctx context.Context, retry bool, fn func(*sftp.Client) error) error {
+	for attempt := 0; ; attempt++ {
+		c, err := p.acquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		stop := context.AfterFunc(ctx, c.close)
+		err = fn(c.sftp)
+		if !stop() {
+			p.discard(c)
+			if err != nil {
+				err = ctx.Err()
+			}
+			return err
+		}
+		dropped := err != nil && (isConnectionError(err) || !c.alive())
+		p.release(c, err)
+		if !retry || attempt > 0 || !dropped {
+			return err
+		}
+	}
+}
+
+// isConnectionError reports whether err was caused by connection which is no longer usable.
+func isConnectionError(err error) bool {
+	return errors.Is(err, sftp.ErrSSHFxConnectionLost) ||
+		errors.Is(err, sftp.ErrSSHFxNoConnection) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
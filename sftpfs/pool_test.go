@@ -0,0 +1,179 @@
+package sftpfs
+
+import (
+	"context"
+	iofs "io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+func TestPool(t *testing.T) {
+	t.Run("it should reuse idle connection", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "test.txt"), []byte("TEST"), 0o600))
+		fs := srv.filesystem(t)
+
+		// WHEN
+		for i := 0; i < 5; i++ {
+			_, err := filesystem.ReadContentOf(fs, "test.txt")
+			require.NoError(t, err)
+		}
+
+		// THEN
+		assert.Equal(t, int32(1), srv.dials.Load())
+	})
+	t.Run("it should reconnect after connection was dropped", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "test.txt"), []byte("TEST"), 0o600))
+		fs := srv.filesystem(t)
+		_, err := filesystem.ReadContentOf(fs, "test.txt")
+		require.NoError(t, err)
+
+		// WHEN
+		srv.dropConnections()
+		content, err := filesystem.ReadContentOf(fs, "test.txt")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("TEST"), content)
+		assert.Equal(t, int32(2), srv.dials.Load())
+	})
+	t.Run("it should wait for connection when all of them are in use", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "test.txt"), []byte("TEST"), 0o600))
+		fs := srv.filesystem(t, OptionPoolSize(1))
+		stream, err := filesystem.StreamContentOf(fs, "test.txt")
+		require.NoError(t, err)
+
+		// WHEN
+		done := make(chan error, 1)
+		go func() {
+			_, cErr := filesystem.CheckIfExists(fs, "test.txt")
+			done <- cErr
+		}()
+
+		// THEN
+		select {
+		case <-done:
+			t.Fatal("operation should wait for the connection held by stream")
+		case <-time.After(50 * time.Millisecond):
+		}
+		require.NoError(t, stream.Close())
+		select {
+		case cErr := <-done:
+			require.NoError(t, cErr)
+		case <-time.After(5 * time.Second):
+			t.Fatal("operation should be performed once stream was closed")
+		}
+		assert.Equal(t, int32(1), srv.dials.Load())
+	})
+	t.Run("it should return connection of stream to the pool only once", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "test.txt"), []byte("TEST"), 0o600))
+		fs := srv.filesystem(t, OptionPoolSize(1))
+		stream, err := filesystem.StreamContentOf(fs, "test.txt")
+		require.NoError(t, err)
+
+		// WHEN
+		firstErr := stream.Close()
+		secondErr := stream.Close()
+		exists, existsErr := filesystem.CheckIfExists(fs, "test.txt")
+
+		// THEN
+		require.NoError(t, firstErr)
+		require.ErrorIs(t, secondErr, iofs.ErrClosed)
+		require.NoError(t, existsErr)
+		assert.True(t, exists)
+		assert.Equal(t, int32(1), srv.dials.Load())
+	})
+	t.Run("it should retry only operations marked as retryable on dropped connection", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		p := newPool(1, func() (*connection, error) {
+			return dial((&net.Dialer{}).Dial, srv.address, &ssh.ClientConfig{
+				User:            testUser,
+				Auth:            []ssh.AuthMethod{ssh.Password(testPassword)},
+				HostKeyCallback: ssh.FixedHostKey(srv.hostKey),
+			})
+		})
+		failing := func(calls *int) func(*sftp.Client) error {
+			return func(*sftp.Client) error {
+				*calls++
+				if *calls == 1 {
+					return sftp.ErrSSHFxConnectionLost
+				}
+				return nil
+			}
+		}
+
+		// WHEN
+		var retried, notRetried int
+		errRetried := p.with(context.Background(), true, failing(&retried))
+		errNotRetried := p.with(context.Background(), false, failing(&notRetried))
+
+		// THEN
+		require.NoError(t, errRetried)
+		assert.Equal(t, 2, retried)
+		require.ErrorIs(t, errNotRetried, sftp.ErrSSHFxConnectionLost)
+		assert.Equal(t, 1, notRetried)
+		assert.Equal(t, int32(2), srv.dials.Load())
+	})
+	t.Run("it should close connection once context is done during operation", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		p := newPool(1, func() (*connection, error) {
+			return dial((&net.Dialer{}).Dial, srv.address, &ssh.ClientConfig{
+				User:            testUser,
+				Auth:            []ssh.AuthMethod{ssh.Password(testPassword)},
+				HostKeyCallback: ssh.FixedHostKey(srv.hostKey),
+			})
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// WHEN
+		err := p.with(ctx, true, func(sc *sftp.Client) error {
+			for {
+				if _, wErr := sc.Getwd(); wErr != nil {
+					return wErr
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		})
+		nextErr := p.with(context.Background(), false, func(sc *sftp.Client) error {
+			_, wErr := sc.Getwd()
+			return wErr
+		})
+
+		// THEN
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NoError(t, nextErr)
+		assert.Equal(t, int32(2), srv.dials.Load())
+	})
+}
@@ -0,0 +1,267 @@
+// Package sftpfs provides filesystem.Filesystem backed by remote server available over SFTP.
+//
+// Operations are performed on a pool of SSH connections (see OptionPoolSize). Connection dropped by the server
+// is replaced with a new one the next time it's needed and operations which do not modify anything
+// (ReadContentOf, CheckIfExists and ListFilesIn) interrupted by a dropped connection are retried once.
+//
+// Paths are slash separated and relative ones are resolved by the server (usually against home directory
+// of the user). Copy is performed by streaming content through the client as SFTP has no server-side copy.
+package sftpfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionUser            options.OptionKey = `sftp_user`
+	optionPassword        options.OptionKey = `sftp_password`
+	optionPrivateKeys     options.OptionKey = `sftp_private_keys`
+	optionHostKeyCallback options.OptionKey = `sftp_host_key_callback`
+	optionKnownHosts      options.OptionKey = `sftp_known_hosts`
+	optionPoolSize        options.OptionKey = `sftp_pool_size`
+	optionDialTimeout     options.OptionKey = `sftp_dial_timeout`
+	optionDialer          options.OptionKey = `sftp_dialer`
+	optionBackendName     options.OptionKey = `backend_name`
+)
+
+const (
+	defaultBackendName = "sftp"
+	defaultPoolSize    = 4
+	defaultDialTimeout = 30 * time.Second
+)
+
+// Client is filesystem.Filesystem performing every operation on SFTP server over pooled connections.
+// It's safe for concurrent use and has to be closed with Close to release connections.
+type Client struct {
+	filesystem.Filesystem
+
+	pool *pool
+}
+
+// ErrHostKeyVerificationNotConfigured is returned by New when none of the host key options was provided.
+var ErrHostKeyVerificationNotConfigured = errors.New("host key verification is not configured")
+
+// DialFunc opens network connection to the server (e.g. through a proxy or jump host).
+type DialFunc func(network, address string) (net.Conn, error)
+
+// OptionUser sets name of the user to authenticate as.
+func OptionUser(user string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionUser, user)
+	}
+}
+
+// OptionPassword authenticates with provided password.
+func OptionPassword(password string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionPassword, password)
+	}
+}
+
+// OptionPrivateKeys authenticates with provided keys (tried before password, see OptionPassword).
+// Keys can be parsed with ssh.ParsePrivateKey.
+func OptionPrivateKeys(signers ...ssh.Signer) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[[]ssh.Signer](r, optionPrivateKeys, signers)
+	}
+}
+
+// OptionHostKey accepts only server presenting provided key.
+func OptionHostKey(key ssh.PublicKey) options.Option {
+	return OptionHostKeyCallback(ssh.FixedHostKey(key))
+}
+
+// OptionKnownHosts accepts only servers with keys listed in provided known_hosts files.
+func OptionKnownHosts(files ...string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[[]string](r, optionKnownHosts, files)
+	}
+}
+
+// OptionHostKeyCallback verifies key of the server with provided callback (takes precedence over OptionKnownHosts).
+func OptionHostKeyCallback(callback ssh.HostKeyCallback) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[ssh.HostKeyCallback](r, optionHostKeyCallback, callback)
+	}
+}
+
+// OptionInsecureIgnoreHostKey accepts any server. It should be used only for testing.
+func OptionInsecureIgnoreHostKey() options.Option {
+	return OptionHostKeyCallback(ssh.InsecureIgnoreHostKey()) //nolint:gosec
+}
+
+// OptionPoolSize limits number of connections opened at the same time (default 4). Operations exceeding
+// the limit wait for one of the connections to be released.
+func OptionPoolSize(size int) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[int](r, optionPoolSize, size)
+	}
+}
+
+// OptionDialTimeout limits time of establishing connection including SSH handshake (default 30s).
+func OptionDialTimeout(timeout time.Duration) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Duration](r, optionDialTimeout, timeout)
+	}
+}
+
+// OptionDialer changes function used to open network connections (default is net.Dialer).
+func OptionDialer(dial DialFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[DialFunc](r, optionDialer, dial)
+	}
+}
+
+// OptionBackendName changes name of the backend reported in errors and to middlewares (default "sftp").
+func OptionBackendName(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBackendName, name)
+	}
+}
+
+// New creates Client performing every operation on SFTP server available at address (host:port).
+// Host key verification has to be configured with OptionHostKey, OptionKnownHosts or OptionHostKeyCallback.
+// First connection is established immediately, so invalid credentials or host key are reported right away.
+func New(address string, opts ...options.Option) (*Client, error) {
+	opt := options.Resolve(opts)
+
+	optUser, err := options.ReadOrDefault[string](opt, optionUser, "")
+	if err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+	optPassword, err := options.ReadOrDefault[string](opt, optionPassword, "")
+	if err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+	optPrivateKeys, err := options.ReadOrDefault[[]ssh.Signer](opt, optionPrivateKeys, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+	optHostKeyCallback, err := options.ReadOrDefault[ssh.HostKeyCallback](opt, optionHostKeyCallback, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+	optKnownHosts, err := options.ReadOrDefault[[]string](opt, optionKnownHosts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+	optPoolSize, err := options.ReadOrDefault[int](opt, optionPoolSize, defaultPoolSize)
+	if err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+	optDialTimeout, err := options.ReadOrDefault[time.Duration](opt, optionDialTimeout, defaultDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+	optDialer, err := options.ReadOrDefault[DialFunc](opt, optionDialer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+
+	if optPoolSize < 1 {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: pool size has to be positive (got %d)", optPoolSize)
+	}
+	if optHostKeyCallback == nil && len(optKnownHosts) > 0 {
+		if optHostKeyCallback, err = knownhosts.New(optKnownHosts...); err != nil {
+			return nil, fmt.Errorf("sftp filesystem initialization failed: cannot load known hosts: %w", err)
+		}
+	}
+	if optHostKeyCallback == nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", ErrHostKeyVerificationNotConfigured)
+	}
+	if optDialer == nil {
+		optDialer = (&net.Dialer{Timeout: optDialTimeout}).Dial
+	}
+
+	config := &ssh.ClientConfig{
+		User:            optUser,
+		HostKeyCallback: optHostKeyCallback,
+		Timeout:         optDialTimeout,
+	}
+	if len(optPrivateKeys) > 0 {
+		config.Auth = append(config.Auth, ssh.PublicKeys(optPrivateKeys...))
+	}
+	if optPassword != "" {
+		config.Auth = append(config.Auth, ssh.Password(optPassword))
+	}
+
+	p := newPool(optPoolSize, func() (*connection, error) {
+		return dial(optDialer, address, config)
+	})
+	if err = p.prime(); err != nil {
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+
+	c := &Client{pool: p}
+	c.Filesystem, err = filesystem.New(
+		filesystem.OptionBackendName(optBackendName),
+		filesystem.OptionReadContentOfContextHandler(c.readContentOf),
+		filesystem.OptionStreamContentOfContextHandler(c.streamContentOf),
+		filesystem.OptionCheckIfExistsContextHandler(c.checkIfExists),
+		filesystem.OptionCreateFileContextHandler(c.createFile),
+		filesystem.OptionWriteContentToContextHandler(c.writeContentTo),
+		filesystem.OptionStreamContentToContextHandler(c.streamContentTo),
+		filesystem.OptionCreateDirectoryContextHandler(c.createDirectory),
+		filesystem.OptionRemoveContextHandler(c.remove),
+		filesystem.OptionMoveContextHandler(c.move),
+		filesystem.OptionCopyContextHandler(c.copy),
+		filesystem.OptionListFilesInContextHandler(c.listFilesIn),
+	)
+	if err != nil {
+		p.close()
+		return nil, fmt.Errorf("sftp filesystem initialization failed: %w", err)
+	}
+
+	return c, nil
+}
+
+// Close closes idle connections of the pool (the ones in use are closed once operation using them finishes).
+// Every operation performed afterwards fails with fs.ErrClosed.
+func (c *Client) Close() error {
+	if !c.pool.close() {
+		return fs.ErrClosed
+	}
+	return nil
+}
+
+// dial establishes SSH connection and starts SFTP session on it.
+func dial(dialer DialFunc, address string, config *ssh.ClientConfig) (*connection, error) {
+	conn, err := dialer("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to %s: %w", address, err)
+	}
+	if config.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(config.Timeout))
+	}
+
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("cannot establish SSH connection with %s: %w", address, err)
+	}
+	sshClient := ssh.NewClient(sshConn, channels, requests)
+
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, fmt.Errorf("cannot start SFTP session with %s: %w", address, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return newConnection(sshClient, sftpClient), nil
+}
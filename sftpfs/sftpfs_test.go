@@ -0,0 +1,277 @@
+package sftpfs
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	testUser     = "test"
+	testPassword = "secret"
+)
+
+// testServer is in-process SSH server (listening on loopback interface) serving SFTP subsystem
+// with working directory set to temporary directory.
+type testServer struct {
+	address string
+	hostKey ssh.PublicKey
+	workdir string
+	dials   atomic.Int32
+	active  atomic.Int32
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == testUser && string(password) == testPassword {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("invalid credentials of %s", meta.User())
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	s := &testServer{address: listener.Addr().String(), hostKey: signer.PublicKey(), workdir: t.TempDir()}
+	go func() {
+		for {
+			conn, aErr := listener.Accept()
+			if aErr != nil {
+				return
+			}
+			s.dials.Add(1)
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn, config)
+		}
+	}()
+	t.Cleanup(s.dropConnections)
+
+	return s
+}
+
+func (s *testServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	s.active.Add(1)
+	defer s.active.Add(-1)
+	go ssh.DiscardRequests(requests)
+
+	for nc := range channels {
+		if nc.ChannelType() != "session" {
+			_ = nc.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, channelRequests, aErr := nc.Accept()
+		if aErr != nil {
+			continue
+		}
+		go func() {
+			for req := range channelRequests {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				_ = req.Reply(ok, nil)
+				if !ok {
+					continue
+				}
+				srv, sErr := sftp.NewServer(channel, sftp.WithServerWorkingDirectory(s.workdir))
+				if sErr == nil {
+					_ = srv.Serve()
+				}
+				_ = channel.Close()
+			}
+		}()
+	}
+}
+
+// dropConnections closes every connection on the server side.
+func (s *testServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+// filesystem creates Client connected to the server with valid credentials and host key (closed when test ends).
+func (s *testServer) filesystem(t *testing.T, opts ...options.Option) *Client {
+	t.Helper()
+
+	fs, err := New(s.address, append([]options.Option{
+		OptionUser(testUser),
+		OptionPassword(testPassword),
+		OptionHostKey(s.hostKey),
+	}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = fs.Close() })
+
+	return fs
+}
+
+func TestNew(t *testing.T) {
+	t.Run("it should connect to the server with password", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "test.txt"), []byte("TEST"), 0o600))
+
+		// WHEN
+		fs := srv.filesystem(t, OptionBackendName("remote"))
+
+		// THEN
+		content, err := filesystem.ReadContentOf(fs, "test.txt")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("TEST"), content)
+		assert.Equal(t, int32(1), srv.dials.Load())
+
+		_, err = filesystem.ReadContentOf(fs, "missing.txt")
+		var pathErr *filesystem.PathError
+		require.ErrorAs(t, err, &pathErr)
+		assert.Equal(t, "remote", pathErr.Backend)
+	})
+	t.Run("it should verify host key with known hosts file", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+		line := knownhosts.Line([]string{srv.address}, srv.hostKey)
+		require.NoError(t, os.WriteFile(knownHosts, []byte(line+"\n"), 0o600))
+
+		// WHEN
+		fs, err := New(srv.address, OptionUser(testUser), OptionPassword(testPassword), OptionKnownHosts(knownHosts))
+
+		// THEN
+		require.NoError(t, err)
+		require.NoError(t, fs.Close())
+	})
+	t.Run("it should reject server with unexpected host key", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		other := newTestServer(t)
+
+		// WHEN
+		_, err := New(srv.address, OptionUser(testUser), OptionPassword(testPassword), OptionHostKey(other.hostKey))
+
+		// THEN
+		require.ErrorContains(t, err, "sftp filesystem initialization failed")
+		require.ErrorContains(t, err, "host key mismatch")
+	})
+	t.Run("it should require host key verification to be configured", func(t *testing.T) {
+		t.Parallel()
+
+		// WHEN
+		_, err := New("127.0.0.1:22", OptionUser(testUser), OptionPassword(testPassword))
+
+		// THEN
+		require.ErrorIs(t, err, ErrHostKeyVerificationNotConfigured)
+	})
+	t.Run("it should fail with invalid credentials", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+
+		// WHEN
+		_, err := New(srv.address, OptionUser(testUser), OptionPassword("invalid"), OptionHostKey(srv.hostKey))
+
+		// THEN
+		require.ErrorContains(t, err, "unable to authenticate")
+	})
+	t.Run("it should open connections with provided dialer", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		var dialed atomic.Int32
+		dialer := func(network, address string) (net.Conn, error) {
+			dialed.Add(1)
+			return net.Dial(network, srv.address)
+		}
+
+		// WHEN
+		fs, err := New("sftp.example.com:22", OptionUser(testUser), OptionPassword(testPassword),
+			OptionInsecureIgnoreHostKey(), OptionDialer(dialer))
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), dialed.Load())
+		require.NoError(t, fs.Close())
+	})
+	t.Run("it should close pooled connections", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv := newTestServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(srv.workdir, "test.txt"), []byte("TEST"), 0o600))
+		fs := srv.filesystem(t)
+		stream, err := filesystem.StreamContentOf(fs, "test.txt")
+		require.NoError(t, err)
+		_, err = filesystem.ReadContentOf(fs, "test.txt")
+		require.NoError(t, err)
+		require.Equal(t, int32(2), srv.active.Load())
+
+		// WHEN
+		errClose := fs.Close()
+		errClosedAgain := fs.Close()
+		_, errRead := filesystem.ReadContentOf(fs, "test.txt")
+		streamed, errStream := io.ReadAll(stream)
+		require.NoError(t, stream.Close())
+
+		// THEN
+		require.NoError(t, errClose)
+		require.ErrorIs(t, errClosedAgain, iofs.ErrClosed)
+		require.ErrorIs(t, errRead, iofs.ErrClosed)
+		require.NoError(t, errStream)
+		assert.Equal(t, "TEST", string(streamed))
+		assert.Eventually(t, func() bool { return srv.active.Load() == 0 }, 5*time.Second, 10*time.Millisecond)
+	})
+	t.Run("it should reject pool without connections", func(t *testing.T) {
+		t.Parallel()
+
+		// WHEN
+		_, err := New("127.0.0.1:22", OptionInsecureIgnoreHostKey(), OptionPoolSize(0))
+
+		// THEN
+		require.ErrorContains(t, err, "pool size has to be positive")
+	})
+}
@@ -0,0 +1,161 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Sub returns Filesystem resolving every path relative to base directory of fs.
+// Absolute paths and paths escaping base with ".." are rejected with ErrPathOutsideRoot.
+// When fs resolves paths on local disk (see OptionLocalDisk) symlinks are resolved as well (with openat2 and
+// RESOLVE_BENEATH where kernel supports it) and the ones pointing outside of base (including absolute ones) are
+// rejected the same way. Path is verified before the operation is performed, so symlink swapped in between
+// (by another process with write access to base) can still lead outside of it.
+// Glob is resolved by listing directories through Sub (unless fs has native handler) and returns paths relative to base.
+func Sub(fs Filesystem, base string) Filesystem {
+	caps := fs.capabilities()
+	// Symlinks are resolved beneath base as seen on local disk (joined with base of every Sub placed below).
+	root := filepath.Join(caps.root, base)
+
+	sub := Wrap(fs, func(next Invoker) Invoker {
+		return func(call Call) (Result, error) {
+			if call.Operation == OperationGlob && !caps.nativeGlob {
+				// Directories are listed through this middleware again, so each of them gets resolved.
+				return next(call)
+			}
+
+			var err error
+			if call.Path, err = subResolve(base, root, call.Path, caps.localDisk && call.Operation != OperationGlob); err != nil {
+				return Result{}, err
+			}
+			if call.Operation == OperationMove || call.Operation == OperationCopy {
+				if call.Target, err = subResolve(base, root, call.Target, caps.localDisk); err != nil {
+					return Result{}, err
+				}
+			}
+
+			res, err := next(call)
+
+			if call.Operation == OperationGlob {
+				res.Paths = trimBase(base, res.Paths)
+			}
+			return res, err
+		}
+	})
+
+	return &subFilesystem{Filesystem: sub, root: root}
+}
+
+// subFilesystem is Filesystem returned by Sub, reporting directory its paths are resolved beneath.
+type subFilesystem struct {
+	Filesystem
+	root string
+}
+
+func (fs *subFilesystem) capabilities() capabilities {
+	caps := fs.Filesystem.capabilities()
+	caps.root = fs.root
+	return caps
+}
+
+// subResolve joins path with base after verifying (lexically and, for local disk, with symlinks resolved beneath root)
+// that it stays inside.
+func subResolve(base, root, p string, local bool) (string, error) {
+	rel, err := subPath(p)
+	if err != nil {
+		return "", err
+	}
+	if local {
+		if err = resolveBeneath(root, rel); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(base, rel), nil
+}
+
+// subPath cleans path and verifies that it doesn't leave its root lexically.
+func subPath(p string) (string, error) {
+	if p == "" {
+		return ".", nil
+	}
+	if !filepath.IsLocal(p) {
+		return "", ErrPathOutsideRoot
+	}
+	return filepath.Clean(p), nil
+}
+
+func trimBase(base string, paths []string) []string {
+	prefix := strings.TrimSuffix(filepath.Clean(base), string(filepath.Separator)) + string(filepath.Separator)
+	for i, p := range paths {
+		paths[i] = strings.TrimPrefix(p, prefix)
+	}
+	return paths
+}
+
+// resolveBeneath verifies that rel (already lexically local) doesn't leave base when symlinks are followed.
+func resolveBeneath(base, rel string) error {
+	if ok, err := resolveBeneathNative(base, rel); ok {
+		return err
+	}
+	return resolveBeneathManually(base, rel)
+}
+
+// resolveBeneathManually follows symlinks component by component the same way kernel does with RESOLVE_BENEATH:
+// ".." may not leave base and absolute symlinks are not allowed. Missing components are resolved lexically.
+func resolveBeneathManually(base, rel string) error {
+	pending := splitPath(rel)
+	resolved := make([]string, 0, len(pending))
+	hops := 0
+
+	for len(pending) > 0 {
+		component := pending[0]
+		pending = pending[1:]
+
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return ErrPathOutsideRoot
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		candidate := filepath.Join(append([]string{base}, append(resolved, component)...)...)
+		info, err := os.Lstat(candidate)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR):
+			resolved = append(resolved, component)
+			continue
+		case err != nil:
+			return translateError(err)
+		case info.Mode()&os.ModeSymlink == 0:
+			resolved = append(resolved, component)
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return ErrSymlinkLoop
+		}
+		target, err := os.Readlink(candidate)
+		if err != nil {
+			return translateError(err)
+		}
+		if filepath.IsAbs(target) {
+			return ErrPathOutsideRoot
+		}
+		pending = append(splitPath(target), pending...)
+	}
+
+	return nil
+}
+
+func splitPath(p string) []string {
+	return strings.Split(filepath.ToSlash(p), "/")
+}
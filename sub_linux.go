@@ -0,0 +1,33 @@
+package filesystem
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// resolveBeneathNative asks kernel (openat2 with RESOLVE_BENEATH) if rel stays inside base.
+// It reports false when answer couldn't be obtained (old kernel, missing path) so manual resolution is performed.
+func resolveBeneathNative(base, rel string) (bool, error) {
+	dir, err := unix.Open(base, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return false, nil
+	}
+	defer func() { _ = unix.Close(dir) }()
+
+	fd, err := unix.Openat2(dir, rel, &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+	})
+	switch {
+	case err == nil:
+		_ = unix.Close(fd)
+		return true, nil
+	case errors.Is(err, unix.EXDEV):
+		return true, ErrPathOutsideRoot
+	case errors.Is(err, unix.ELOOP):
+		return true, ErrSymlinkLoop
+	default:
+		return false, nil
+	}
+}
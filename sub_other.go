@@ -0,0 +1,8 @@
+//go:build !linux
+
+package filesystem
+
+// resolveBeneathNative is available only on Linux, other systems always use manual resolution.
+func resolveBeneathNative(_, _ string) (bool, error) {
+	return false, nil
+}
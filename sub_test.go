@@ -0,0 +1,211 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSub(t *testing.T) {
+	t.Run("it should resolve paths relative to base directory", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			require.NoError(t, os.MkdirAll(filepath.Join(workdir, "uploads", "a"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "uploads", "a", "b.txt"), []byte("TEST"), 0o644))
+
+			fs, err := New()
+			require.NoError(t, err)
+			sub := Sub(fs, filepath.Join(workdir, "uploads"))
+
+			// WHEN
+			content, readErr := ReadContentOf(sub, "a/../a/b.txt")
+			createErr := CreateFile(sub, "c.txt")
+			paths, globErr := Glob(sub, "*/*.txt")
+
+			// THEN
+			require.NoError(t, readErr)
+			require.NoError(t, createErr)
+			require.NoError(t, globErr)
+			assert.Equal(t, Content("TEST"), content)
+			assert.FileExists(t, filepath.Join(workdir, "uploads", "c.txt"))
+			assert.Equal(t, []string{filepath.Join("a", "b.txt")}, paths)
+		})
+	})
+	t.Run("it should reject paths escaping base lexically", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "secret"), []byte("TEST"), 0o644))
+
+			fs, err := New()
+			require.NoError(t, err)
+			sub := Sub(fs, filepath.Join(workdir, "uploads"))
+
+			for _, p := range []string{"../secret", "a/../../secret", filepath.Join(workdir, "secret"), ".."} {
+				// WHEN
+				_, readErr := ReadContentOf(sub, p)
+				createErr := CreateFile(sub, p)
+
+				// THEN
+				require.ErrorIs(t, readErr, ErrPathOutsideRoot, p)
+				require.ErrorIs(t, createErr, ErrPathOutsideRoot, p)
+			}
+		})
+	})
+	t.Run("it should reject symlinks pointing outside of base", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			base := filepath.Join(workdir, "uploads")
+			require.NoError(t, os.MkdirAll(filepath.Join(base, "inner"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "secret"), []byte("TEST"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(base, "inner", "allowed"), []byte("TEST"), 0o644))
+			require.NoError(t, os.Symlink("../secret", filepath.Join(base, "relative")))
+			require.NoError(t, os.Symlink(filepath.Join(workdir, "secret"), filepath.Join(base, "absolute")))
+			require.NoError(t, os.Symlink("..", filepath.Join(base, "parent")))
+			require.NoError(t, os.Symlink("../missing", filepath.Join(base, "dangling")))
+			require.NoError(t, os.Symlink("inner/allowed", filepath.Join(base, "inside")))
+
+			fs, err := New()
+			require.NoError(t, err)
+			sub := Sub(fs, base)
+
+			// WHEN
+			_, relativeErr := ReadContentOf(sub, "relative")
+			_, absoluteErr := ReadContentOf(sub, "absolute")
+			_, parentErr := ReadContentOf(sub, "parent/secret")
+			danglingErr := WriteContentTo(sub, "dangling", "TEST")
+			content, insideErr := ReadContentOf(sub, "inside")
+
+			// THEN
+			require.ErrorIs(t, relativeErr, ErrPathOutsideRoot)
+			require.ErrorIs(t, absoluteErr, ErrPathOutsideRoot)
+			require.ErrorIs(t, parentErr, ErrPathOutsideRoot)
+			require.ErrorIs(t, danglingErr, ErrPathOutsideRoot)
+			assert.NoFileExists(t, filepath.Join(workdir, "missing"))
+			require.NoError(t, insideErr)
+			assert.Equal(t, Content("TEST"), content)
+		})
+	})
+	t.Run("it should reject symlinks pointing outside of base of nested Sub", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			outer := filepath.Join(workdir, "a")
+			require.NoError(t, os.MkdirAll(filepath.Join(outer, "b", "inner"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(outer, "secret"), []byte("TEST"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(outer, "b", "inner", "allowed"), []byte("TEST"), 0o644))
+			require.NoError(t, os.Symlink("../secret", filepath.Join(outer, "b", "escaping")))
+			require.NoError(t, os.Symlink("inner/allowed", filepath.Join(outer, "b", "inside")))
+
+			fs, err := New()
+			require.NoError(t, err)
+			sub := Sub(Sub(fs, outer), "b")
+
+			// WHEN
+			_, escapingErr := ReadContentOf(sub, "escaping")
+			content, insideErr := ReadContentOf(sub, "inside")
+
+			// THEN
+			require.ErrorIs(t, escapingErr, ErrPathOutsideRoot)
+			require.NoError(t, insideErr)
+			assert.Equal(t, Content("TEST"), content)
+		})
+	})
+	t.Run("it should reject glob listing symlinked directory outside of base", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			base := filepath.Join(workdir, "uploads")
+			require.NoError(t, os.MkdirAll(filepath.Join(workdir, "private"), 0o755))
+			require.NoError(t, os.MkdirAll(base, 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "private", "secret"), []byte("TEST"), 0o644))
+			require.NoError(t, os.Symlink("../private", filepath.Join(base, "link")))
+
+			fs, err := New()
+			require.NoError(t, err)
+			sub := Sub(fs, base)
+
+			// WHEN
+			paths, globErr := Glob(sub, "link/*")
+			_, nestedErr := Glob(sub, "*/secret")
+
+			// THEN
+			require.ErrorIs(t, globErr, ErrPathOutsideRoot)
+			require.ErrorIs(t, nestedErr, ErrPathOutsideRoot)
+			assert.Empty(t, paths)
+		})
+	})
+	t.Run("it should resolve symlinks on local disk regardless of backend name", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			base := filepath.Join(workdir, "uploads")
+			require.NoError(t, os.MkdirAll(base, 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, "secret"), []byte("TEST"), 0o644))
+			require.NoError(t, os.Symlink("../secret", filepath.Join(base, "relative")))
+
+			fs, err := New(OptionBackendName("disk"))
+			require.NoError(t, err)
+			sub := Sub(fs, base)
+
+			// WHEN
+			_, readErr := ReadContentOf(sub, "relative")
+
+			// THEN
+			require.ErrorIs(t, readErr, ErrPathOutsideRoot)
+		})
+	})
+	t.Run("it should resolve symlinks manually the same way as kernel", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			base := filepath.Join(workdir, "uploads")
+			require.NoError(t, os.MkdirAll(filepath.Join(base, "a", "b"), 0o755))
+			require.NoError(t, os.Symlink("b/..", filepath.Join(base, "a", "up")))
+			require.NoError(t, os.Symlink("../..", filepath.Join(base, "a", "out")))
+			require.NoError(t, os.Symlink("loop", filepath.Join(base, "loop")))
+
+			// WHEN & THEN
+			require.NoError(t, resolveBeneathManually(base, "a/up/b/new.txt"))
+			require.NoError(t, resolveBeneathManually(base, "missing/file.txt"))
+			require.ErrorIs(t, resolveBeneathManually(base, "a/out/x"), ErrPathOutsideRoot)
+			require.ErrorIs(t, resolveBeneathManually(base, "loop"), ErrSymlinkLoop)
+		})
+	})
+	t.Run("it should check paths only lexically for other backends", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var requested string
+		fs, err := New(
+			OptionBackendName("memory"),
+			OptionReadContentOfHandler(func(p string) (Content, error) {
+				requested = p
+				return Content("TEST"), nil
+			}),
+		)
+		require.NoError(t, err)
+		sub := Sub(fs, "tenants/1")
+
+		// WHEN
+		_, readErr := ReadContentOf(sub, "a/./b.txt")
+		_, escapeErr := ReadContentOf(sub, "../2/b.txt")
+
+		// THEN
+		require.NoError(t, readErr)
+		assert.Equal(t, filepath.Join("tenants", "1", "a", "b.txt"), requested)
+		require.ErrorIs(t, escapeErr, ErrPathOutsideRoot)
+	})
+}
@@ -0,0 +1,297 @@
+package tarfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// maxSymlinkHops limits amount of symlinks followed while resolving single path.
+const maxSymlinkHops = 40
+
+// implicitDirectoryMode is reported for directories which are not stored in archive but contain its entries.
+const implicitDirectoryMode = filesystem.ModeAllRead | filesystem.ModeAllExecute | filesystem.ModeUserWrite
+
+type (
+	// archive indexes tar stream lazily. Every field is guarded by mu.
+	archive struct {
+		mu       sync.Mutex
+		stream   *countingReader
+		source   io.ReaderAt
+		tr       *tar.Reader
+		done     bool
+		err      error
+		entries  map[string]*tarEntry
+		children map[string]map[string]struct{}
+	}
+
+	tarEntry struct {
+		typeflag byte
+		mode     filesystem.Mode
+		size     int64
+		modTime  time.Time
+		linkname string
+		// content of the entry is kept in memory unless it can be read from source at offset.
+		content []byte
+		offset  int64
+	}
+
+	countingReader struct {
+		r io.Reader
+		n int64
+	}
+)
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (a *archive) open(p string) (io.ReadCloser, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, e, err := a.resolve(entryName(p), true, 0)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, filesystem.ErrFileNotFound
+	}
+	if e.typeflag == tar.TypeLink {
+		if e, err = a.hardLinkTarget(e); err != nil {
+			return nil, err
+		}
+	}
+	if e.typeflag == tar.TypeDir {
+		return nil, filesystem.ErrDirectory
+	}
+
+	if e.content == nil && a.source != nil {
+		return io.NopCloser(io.NewSectionReader(a.source, e.offset, e.size)), nil
+	}
+	return io.NopCloser(bytes.NewReader(e.content)), nil
+}
+
+func (a *archive) checkIfExists(p string) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, e, err := a.resolve(entryName(p), true, 0)
+	if errors.Is(err, filesystem.ErrFileNotFound) {
+		return false, nil
+	}
+	return e != nil, err
+}
+
+func (a *archive) listFilesIn(p string) ([]filesystem.Entry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	name, e, err := a.resolve(entryName(p), true, 0)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, filesystem.ErrFileNotFound
+	}
+	if e.typeflag != tar.TypeDir {
+		return nil, filesystem.ErrFile
+	}
+	if err = a.indexAll(); err != nil {
+		return nil, err
+	}
+
+	res := make([]filesystem.Entry, 0, len(a.children[name]))
+	for child := range a.children[name] {
+		ce := a.entries[child]
+		entry := filesystem.Entry{
+			Name:        path.Base(child),
+			Size:        ce.size,
+			Mode:        ce.mode,
+			ModTime:     ce.modTime,
+			IsDirectory: ce.typeflag == tar.TypeDir,
+			IsSymlink:   ce.typeflag == tar.TypeSymlink,
+		}
+		switch ce.typeflag {
+		case tar.TypeSymlink:
+			entry.LinkTarget = ce.linkname
+		case tar.TypeLink:
+			if target, tErr := a.hardLinkTarget(ce); tErr == nil {
+				entry.Size = target.size
+			}
+		}
+		res = append(res, entry)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// resolve follows symlinks found on the path (and in its last element if follow is set) and returns resolved name
+// with its entry (nil if it does not exist).
+func (a *archive) resolve(name string, follow bool, hops int) (string, *tarEntry, error) {
+	if name == "" {
+		return "", a.root(), nil
+	}
+
+	elements := strings.Split(name, "/")
+	current := ""
+	var e *tarEntry
+	for i, element := range elements {
+		if current != "" && e.typeflag != tar.TypeDir {
+			return "", nil, filesystem.ErrFileNotFound
+		}
+
+		candidate := path.Join(current, element)
+		found, err := a.lookup(candidate)
+		if err != nil {
+			return "", nil, err
+		}
+		if found == nil {
+			return candidate, nil, nil
+		}
+
+		if found.typeflag == tar.TypeSymlink && (follow || i < len(elements)-1) {
+			if hops++; hops > maxSymlinkHops {
+				return "", nil, filesystem.ErrSymlinkLoop
+			}
+			target := found.linkname
+			if !path.IsAbs(target) {
+				target = path.Join(current, target)
+			}
+			resolved, te, tErr := a.resolve(entryName(target), true, hops)
+			if tErr != nil {
+				return "", nil, tErr
+			}
+			if te == nil {
+				return "", nil, filesystem.ErrFileNotFound
+			}
+			current, e = resolved, te
+			continue
+		}
+		current, e = candidate, found
+	}
+	return current, e, nil
+}
+
+// hardLinkTarget returns entry which content is shared by hard link.
+func (a *archive) hardLinkTarget(e *tarEntry) (*tarEntry, error) {
+	target, err := a.lookup(entryName(e.linkname))
+	if err != nil {
+		return nil, err
+	}
+	if target == nil || target.typeflag == tar.TypeLink {
+		return nil, fmt.Errorf("%w: hard link target %s is not stored in archive", filesystem.ErrFileNotFound, e.linkname)
+	}
+	return target, nil
+}
+
+// lookup returns entry stored under name reading the stream until it's found.
+func (a *archive) lookup(name string) (*tarEntry, error) {
+	for {
+		if e := a.entries[name]; e != nil {
+			return e, nil
+		}
+		if a.done {
+			return nil, a.err
+		}
+		a.next()
+	}
+}
+
+func (a *archive) indexAll() error {
+	for !a.done {
+		a.next()
+	}
+	return a.err
+}
+
+// next indexes next entry of the stream keeping content of regular file in memory (unless it can be read
+// from source). Sparse files are always kept in memory as their holes are not stored in the stream.
+func (a *archive) next() {
+	header, err := a.tr.Next()
+	if err != nil {
+		a.done = true
+		if !errors.Is(err, io.EOF) {
+			a.err = fmt.Errorf("cannot read tar archive: %w", err)
+		}
+		return
+	}
+
+	name := entryName(header.Name)
+	if name == "" {
+		return
+	}
+	e := &tarEntry{
+		typeflag: header.Typeflag,
+		mode:     filesystem.Mode(header.FileInfo().Mode().Perm()),
+		size:     header.Size,
+		modTime:  header.ModTime,
+		linkname: header.Linkname,
+		offset:   a.stream.n,
+	}
+	switch header.Typeflag {
+	case tar.TypeReg, tar.TypeDir, tar.TypeSymlink, tar.TypeLink:
+	case tar.TypeRegA, tar.TypeGNUSparse: //nolint:staticcheck
+		e.typeflag = tar.TypeReg
+	default:
+		// Devices, FIFOs and other special files cannot be served.
+		return
+	}
+	if e.typeflag == tar.TypeReg && (a.source == nil || header.Typeflag == tar.TypeGNUSparse) {
+		if e.content, err = io.ReadAll(a.tr); err != nil {
+			a.done, a.err = true, fmt.Errorf("cannot read tar archive: %w", err)
+			return
+		}
+		e.size = int64(len(e.content))
+	}
+
+	a.add(name, e)
+}
+
+// add stores entry registering directories containing it.
+func (a *archive) add(name string, e *tarEntry) {
+	if previous := a.entries[name]; previous != nil && previous.typeflag == tar.TypeDir && e.typeflag == tar.TypeDir {
+		// Explicit directory replaces implicit one (or previous occurrence) keeping its children.
+		*previous = *e
+		return
+	}
+	a.entries[name] = e
+
+	for child, dir := name, path.Dir(name); ; child, dir = dir, path.Dir(dir) {
+		if dir == "." {
+			dir = ""
+		}
+		if a.children[dir] == nil {
+			a.children[dir] = make(map[string]struct{})
+		}
+		a.children[dir][child] = struct{}{}
+		if dir == "" {
+			return
+		}
+		if existing := a.entries[dir]; existing != nil && existing.typeflag == tar.TypeDir {
+			return
+		}
+		a.entries[dir] = &tarEntry{typeflag: tar.TypeDir, mode: implicitDirectoryMode}
+	}
+}
+
+func (a *archive) root() *tarEntry {
+	return &tarEntry{typeflag: tar.TypeDir, mode: implicitDirectoryMode}
+}
+
+// entryName converts path to name of tar entry (slash separated, unrooted, without "." and "..", empty for root).
+func entryName(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
@@ -0,0 +1,155 @@
+// Package tarfs provides filesystem.Filesystem backed by tar streams (optionally compressed with gzip).
+//
+// Existing archive is served read-only (see Open and NewReader). Stream is indexed lazily: headers are read
+// only as far as needed to find requested path (ListFilesIn and Glob read the whole stream). Content of entries
+// which were passed over is kept in memory unless the archive is uncompressed and can be read at any offset
+// (e.g. file opened with Open). Symlinks are followed (also in the middle of the path) within the archive
+// and hard links serve content of their targets. When the same path is stored more than once, the last
+// occurrence indexed so far is served. Every mutating operation fails with filesystem.ErrReadOnly.
+//
+// New archive is written as a stream (see NewWriter and Create): entries are emitted as CreateFile, WriteContentTo,
+// StreamContentTo and CreateDirectory are called. Content of a file is buffered until another entry is started
+// (or Writer is flushed), so it can be written in many calls. Mode and Owner arguments are stored in headers.
+package tarfs
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionBackendName      options.OptionKey = `backend_name`
+	optionGzip             options.OptionKey = `tar_gzip`
+	optionModificationTime options.OptionKey = `tar_modification_time`
+)
+
+const defaultBackendName = "tar"
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Reader is read-only filesystem.Filesystem serving content of tar archive opened with Open.
+// It has to be closed to release the archive.
+type Reader struct {
+	filesystem.Filesystem
+	file *os.File
+}
+
+// OptionBackendName changes name of the backend reported in errors and to middlewares (default "tar").
+func OptionBackendName(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBackendName, name)
+	}
+}
+
+// OptionGzip compresses stream written by Writer with gzip at provided level (e.g. gzip.DefaultCompression).
+// Reader detects compression on its own.
+func OptionGzip(level int) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[*int](r, optionGzip, &level)
+	}
+}
+
+// OptionModificationTime sets modification time of every entry written by Writer (default is time when entry
+// is written). Fixed time makes archives with the same content identical.
+func OptionModificationTime(t time.Time) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Time](r, optionModificationTime, t)
+	}
+}
+
+// Open opens tar (or tar.gz) archive stored in local file and serves its content (see NewReader).
+func Open(name string, opts ...options.Option) (*Reader, error) {
+	f, err := os.Open(name) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("tar filesystem initialization failed: %w", err)
+	}
+
+	fs, err := NewReader(f, opts...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &Reader{Filesystem: fs, file: f}, nil
+}
+
+// NewReader creates read-only filesystem.Filesystem serving content of tar archive read from r. Stream compressed
+// with gzip is detected and decompressed. Content of uncompressed archive is read at its offset when r implements
+// io.ReaderAt, otherwise it's kept in memory once passed over. Only OptionBackendName is accepted.
+func NewReader(r io.Reader, opts ...options.Option) (filesystem.Filesystem, error) {
+	opt := options.Resolve(opts)
+
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("tar filesystem initialization failed: %w", err)
+	}
+
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("tar filesystem initialization failed: %w", err)
+	}
+
+	ar := &archive{entries: make(map[string]*tarEntry), children: make(map[string]map[string]struct{})}
+	if string(magic) == string(gzipMagic) {
+		zr, gErr := gzip.NewReader(br)
+		if gErr != nil {
+			return nil, fmt.Errorf("tar filesystem initialization failed: %w", gErr)
+		}
+		ar.stream = &countingReader{r: zr}
+	} else {
+		ar.stream = &countingReader{r: br}
+		ar.source, _ = r.(io.ReaderAt)
+	}
+	ar.tr = tar.NewReader(ar.stream)
+
+	return filesystem.New(
+		filesystem.OptionBackendName(optBackendName),
+		filesystem.OptionReadContentOfHandler(func(p string) (filesystem.Content, error) {
+			rc, oErr := ar.open(p)
+			if oErr != nil {
+				return nil, oErr
+			}
+			defer func() { _ = rc.Close() }()
+			return io.ReadAll(rc)
+		}),
+		filesystem.OptionStreamContentOfHandler(ar.open),
+		filesystem.OptionCheckIfExistsHandler(ar.checkIfExists),
+		filesystem.OptionCreateFileHandler(func(string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionWriteContentToHandler(func(string, []byte, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionStreamContentToHandler(func(string, io.Reader, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionCreateDirectory(func(string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionRemoveHandler(func(string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionMoveHandler(func(string, string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionCopyHandler(func(string, string, filesystem.Arguments) error {
+			return filesystem.ErrReadOnly
+		}),
+		filesystem.OptionListFilesInHandler(ar.listFilesIn),
+	)
+}
+
+// Close releases file of the archive.
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
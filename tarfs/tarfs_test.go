@@ -0,0 +1,238 @@
+package tarfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// newArchive builds tar archive from headers (content of regular files is provided along with them).
+func newArchive(t *testing.T, entries ...any) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := 0; i < len(entries); i += 2 {
+		header := entries[i].(*tar.Header)
+		content := entries[i+1].(string)
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(content))
+		}
+		require.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	return buf.Bytes()
+}
+
+// layer is an archive with links of every kind.
+func layer(t *testing.T) []byte {
+	t.Helper()
+
+	return newArchive(t,
+		&tar.Header{Typeflag: tar.TypeDir, Name: "./etc/", Mode: 0o755}, "",
+		&tar.Header{Typeflag: tar.TypeReg, Name: "./etc/app.yaml", Mode: 0o640}, "key: value",
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "./etc/current.yaml", Linkname: "app.yaml"}, "",
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "./config", Linkname: "/etc"}, "",
+		&tar.Header{Typeflag: tar.TypeLink, Name: "./etc/hard.yaml", Linkname: "./etc/app.yaml"}, "",
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "./loop", Linkname: "loop"}, "",
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "./dangling", Linkname: "missing"}, "",
+		&tar.Header{Typeflag: tar.TypeReg, Name: "usr/bin/tool", Mode: 0o755}, "#!/bin/sh",
+	)
+}
+
+// countingSource records amount of bytes read from the archive.
+type countingSource struct {
+	r    io.Reader
+	read int
+}
+
+func (s *countingSource) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.read += n
+	return n, err
+}
+
+func TestNewReader(t *testing.T) {
+	t.Run("it should serve files, symlinks and hard links", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := NewReader(bytes.NewBuffer(layer(t)))
+		require.NoError(t, err)
+
+		// WHEN
+		for _, p := range []string{"etc/app.yaml", "/etc/current.yaml", "etc/hard.yaml", "config/app.yaml", "config/current.yaml"} {
+			content, rErr := filesystem.ReadContentOf(fs, p)
+
+			// THEN
+			require.NoError(t, rErr, p)
+			assert.Equal(t, filesystem.Content("key: value"), content, p)
+		}
+		stream, err := filesystem.StreamContentOf(fs, "usr/bin/tool")
+		require.NoError(t, err)
+		streamed, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, "#!/bin/sh", string(streamed))
+	})
+	t.Run("it should list entries and check existence", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := NewReader(bytes.NewBuffer(layer(t)))
+		require.NoError(t, err)
+
+		// WHEN
+		entries, listErr := filesystem.ListFilesIn(fs, "config")
+		root, rootErr := filesystem.ListFilesIn(fs, "/")
+		paths, globErr := filesystem.Glob(fs, "**/*.yaml")
+
+		// THEN
+		require.NoError(t, listErr)
+		require.NoError(t, rootErr)
+		require.NoError(t, globErr)
+		require.Len(t, entries, 3)
+		assert.Equal(t, filesystem.Entry{Name: "app.yaml", Size: 10, Mode: 0o640, ModTime: entries[0].ModTime}, entries[0])
+		assert.Equal(t, "current.yaml", entries[1].Name)
+		assert.True(t, entries[1].IsSymlink)
+		assert.Equal(t, "app.yaml", entries[1].LinkTarget)
+		assert.Equal(t, "hard.yaml", entries[2].Name)
+		assert.Equal(t, int64(10), entries[2].Size)
+
+		names := make([]string, 0, len(root))
+		for _, e := range root {
+			names = append(names, e.Name)
+		}
+		assert.Equal(t, []string{"config", "dangling", "etc", "loop", "usr"}, names)
+		assert.Equal(t, implicitDirectoryMode, root[4].Mode)
+		assert.Contains(t, paths, "etc/app.yaml")
+
+		for p, expected := range map[string]bool{"etc": true, "usr/bin": true, "config": true, "dangling": false, "missing": false, "etc/app.yaml/x": false, ".": true} {
+			exists, eErr := filesystem.CheckIfExists(fs, p)
+			require.NoError(t, eErr, p)
+			assert.Equal(t, expected, exists, p)
+		}
+	})
+	t.Run("it should read stream only as far as needed", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		data := newArchive(t,
+			&tar.Header{Typeflag: tar.TypeReg, Name: "first.txt", Mode: 0o644}, "FIRST",
+			&tar.Header{Typeflag: tar.TypeReg, Name: "large.bin", Mode: 0o644}, strings.Repeat("0", 1<<20),
+			&tar.Header{Typeflag: tar.TypeReg, Name: "last.txt", Mode: 0o644}, "LAST",
+		)
+		source := &countingSource{r: bytes.NewReader(data)}
+		fs, err := NewReader(source)
+		require.NoError(t, err)
+
+		// WHEN
+		first, firstErr := filesystem.ReadContentOf(fs, "first.txt")
+		readForFirst := source.read
+		last, lastErr := filesystem.ReadContentOf(fs, "last.txt")
+		again, againErr := filesystem.ReadContentOf(fs, "first.txt")
+
+		// THEN
+		require.NoError(t, firstErr)
+		require.NoError(t, lastErr)
+		require.NoError(t, againErr)
+		assert.Equal(t, filesystem.Content("FIRST"), first)
+		assert.Equal(t, filesystem.Content("LAST"), last)
+		assert.Equal(t, filesystem.Content("FIRST"), again)
+		assert.Less(t, readForFirst, 1<<20)
+		assert.Greater(t, source.read, 1<<20)
+	})
+	t.Run("it should detect gzip compression", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(layer(t))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		// WHEN
+		fs, err := NewReader(&buf)
+		require.NoError(t, err)
+		content, err := filesystem.ReadContentOf(fs, "config/current.yaml")
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("key: value"), content)
+	})
+	t.Run("it should report sentinel errors", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs, err := NewReader(bytes.NewBuffer(layer(t)), OptionBackendName("layer"))
+		require.NoError(t, err)
+
+		// WHEN
+		_, errMissing := filesystem.ReadContentOf(fs, "missing")
+		_, errDangling := filesystem.ReadContentOf(fs, "dangling")
+		_, errLoop := filesystem.ReadContentOf(fs, "loop")
+		_, errDirectory := filesystem.ReadContentOf(fs, "config")
+		_, errListFile := filesystem.ListFilesIn(fs, "etc/app.yaml")
+		errReadOnly := filesystem.CreateFile(fs, "new.txt")
+		_, errCorrupted := filesystem.ReadContentOf(mustReader(t, strings.NewReader(strings.Repeat("x", 1024))), "test.txt")
+
+		// THEN
+		require.ErrorIs(t, errMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errDangling, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errLoop, filesystem.ErrSymlinkLoop)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errListFile, filesystem.ErrFile)
+		require.ErrorIs(t, errReadOnly, filesystem.ErrReadOnly)
+		var pathErr *filesystem.PathError
+		require.ErrorAs(t, errReadOnly, &pathErr)
+		assert.Equal(t, "layer", pathErr.Backend)
+		require.ErrorIs(t, errCorrupted, tar.ErrHeader)
+	})
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("it should read content of uncompressed archive at its offset", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		name := filepath.Join(t.TempDir(), "layer.tar")
+		require.NoError(t, os.WriteFile(name, layer(t), 0o644))
+
+		// WHEN
+		tr, err := Open(name)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = tr.Close() })
+		_, errLast := filesystem.ReadContentOf(tr, "usr/bin/tool")
+		content, err := filesystem.ReadContentOf(tr, "etc/hard.yaml")
+
+		// THEN
+		require.NoError(t, errLast)
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("key: value"), content)
+		_, errOpen := Open(filepath.Join(t.TempDir(), "missing.tar"))
+		require.ErrorIs(t, errOpen, os.ErrNotExist)
+	})
+}
+
+func mustReader(t *testing.T, r io.Reader) filesystem.Filesystem {
+	t.Helper()
+
+	fs, err := NewReader(r)
+	require.NoError(t, err)
+
+	return fs
+}
@@ -0,0 +1,373 @@
+package tarfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// errWriteOnly is reported by operations which cannot be performed on stream being written.
+var errWriteOnly = fmt.Errorf("%w: tar stream is write-only", errors.ErrUnsupported)
+
+type (
+	// Writer is write-only filesystem.Filesystem emitting tar entries to the stream as files and directories
+	// are created. CheckIfExists reports entries written so far, reading, listing, removing, moving and copying
+	// fail with errors.ErrUnsupported. It's safe for concurrent use.
+	Writer struct {
+		filesystem.Filesystem
+
+		mu      sync.Mutex
+		tw      *tar.Writer
+		gz      *gzip.Writer
+		closer  io.Closer
+		modTime time.Time
+		written map[string]*writtenEntry
+		pending *pendingFile
+		err     error
+		closed  bool
+	}
+
+	writtenEntry struct {
+		isDirectory bool
+		mode        filesystem.Mode
+		owner       filesystem.Owner
+	}
+
+	// pendingFile is the last created file which content is buffered until another entry is started.
+	pendingFile struct {
+		name    string
+		mode    filesystem.Mode
+		owner   filesystem.Owner
+		content bytes.Buffer
+	}
+)
+
+// Create creates (or truncates) local file which will receive the stream (see NewWriter).
+func Create(name string, opts ...options.Option) (*Writer, error) {
+	f, err := os.Create(name) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("tar filesystem initialization failed: %w", err)
+	}
+
+	w, err := NewWriter(f, opts...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// NewWriter creates Writer emitting tar stream (compressed with gzip if OptionGzip is set) to w.
+// Stream is complete once Writer is closed. Closing Writer does not close w.
+func NewWriter(w io.Writer, opts ...options.Option) (*Writer, error) {
+	opt := options.Resolve(opts)
+
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("tar filesystem initialization failed: %w", err)
+	}
+	optGzip, err := options.ReadOrDefault[*int](opt, optionGzip, nil)
+	if err != nil {
+		return nil, fmt.Errorf("tar filesystem initialization failed: %w", err)
+	}
+	optModificationTime, err := options.ReadOrDefault[time.Time](opt, optionModificationTime, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("tar filesystem initialization failed: %w", err)
+	}
+
+	tw := &Writer{modTime: optModificationTime, written: make(map[string]*writtenEntry)}
+	if optGzip != nil {
+		if tw.gz, err = gzip.NewWriterLevel(w, *optGzip); err != nil {
+			return nil, fmt.Errorf("tar filesystem initialization failed: %w", err)
+		}
+		w = tw.gz
+	}
+	tw.tw = tar.NewWriter(w)
+
+	tw.Filesystem, err = filesystem.New(
+		filesystem.OptionBackendName(optBackendName),
+		filesystem.OptionReadContentOfHandler(func(string) (filesystem.Content, error) {
+			return nil, errWriteOnly
+		}),
+		filesystem.OptionStreamContentOfHandler(func(string) (io.ReadCloser, error) {
+			return nil, errWriteOnly
+		}),
+		filesystem.OptionCheckIfExistsHandler(tw.checkIfExists),
+		filesystem.OptionCreateFileHandler(tw.createFile),
+		filesystem.OptionWriteContentToHandler(func(p string, content []byte, arg filesystem.Arguments) error {
+			return tw.write(p, bytes.NewReader(content), arg)
+		}),
+		filesystem.OptionStreamContentToHandler(tw.write),
+		filesystem.OptionCreateDirectory(tw.createDirectory),
+		filesystem.OptionRemoveHandler(func(string, filesystem.Arguments) error {
+			return errWriteOnly
+		}),
+		filesystem.OptionMoveHandler(func(string, string, filesystem.Arguments) error {
+			return errWriteOnly
+		}),
+		filesystem.OptionCopyHandler(func(string, string, filesystem.Arguments) error {
+			return errWriteOnly
+		}),
+		filesystem.OptionListFilesInHandler(func(string) ([]filesystem.Entry, error) {
+			return nil, errWriteOnly
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tar filesystem initialization failed: %w", err)
+	}
+	return tw, nil
+}
+
+// Flush emits buffered file and flushes compressed stream. Content cannot be appended to files
+// emitted this way.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.usable(); err != nil {
+		return err
+	}
+	if err := w.emitPending(); err != nil {
+		return err
+	}
+	if err := w.fail(w.tw.Flush()); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.fail(w.gz.Flush())
+	}
+	return nil
+}
+
+// Close emits buffered file, finishes the stream and closes file created with Create. Every operation
+// performed afterwards fails with fs.ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fs.ErrClosed
+	}
+	err := w.err
+	if err == nil {
+		err = w.emitPending()
+	}
+	if err == nil {
+		err = w.tw.Close()
+	}
+	if err == nil && w.gz != nil {
+		err = w.gz.Close()
+	}
+	w.closed = true
+	if w.closer != nil {
+		if cErr := w.closer.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+func (w *Writer) checkIfExists(p string) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return false, fs.ErrClosed
+	}
+	name := entryName(p)
+	return name == "" || w.written[name] != nil, nil
+}
+
+func (w *Writer) createFile(p string, arg filesystem.Arguments) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.usable(); err != nil {
+		return err
+	}
+	name := entryName(p)
+	if err := w.prepareParent(name, arg); err != nil {
+		return err
+	}
+
+	existing := w.written[name]
+	if name == "" || existing != nil {
+		if !arg.AllowOverwrite {
+			return filesystem.ErrFileFound
+		}
+		if name == "" || existing.isDirectory {
+			return filesystem.ErrDirectory
+		}
+	}
+
+	if w.pending != nil && w.pending.name == name {
+		// Overwritten file was not emitted yet.
+		w.pending = nil
+	}
+	if err := w.emitPending(); err != nil {
+		return err
+	}
+	w.pending = &pendingFile{name: name, mode: arg.Mode, owner: arg.Owner}
+	w.written[name] = &writtenEntry{mode: arg.Mode, owner: arg.Owner}
+	return nil
+}
+
+// write buffers content of the pending file. Content of file which was already emitted can only be overwritten
+// (by emitting it again, which replaces it when the stream is extracted).
+func (w *Writer) write(p string, content io.Reader, arg filesystem.Arguments) error {
+	overwrite := arg.ContentOperation.Is(filesystem.ContentOperationOverwrite)
+	if !overwrite && !arg.ContentOperation.Is(filesystem.ContentOperationAppend) {
+		return filesystem.ErrUnsupportedContentOperation
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.usable(); err != nil {
+		return err
+	}
+	name := entryName(p)
+	existing := w.written[name]
+	switch {
+	case name == "" || (existing != nil && existing.isDirectory):
+		return filesystem.ErrDirectory
+	case existing == nil:
+		return filesystem.ErrFileNotFound
+	case w.pending != nil && w.pending.name == name:
+		if overwrite {
+			w.pending.content.Reset()
+		}
+	case !overwrite:
+		return fmt.Errorf("%w: %s was already written to the stream", filesystem.ErrUnsupportedContentOperation, p)
+	default:
+		if err := w.emitPending(); err != nil {
+			return err
+		}
+		w.pending = &pendingFile{name: name, mode: existing.mode, owner: existing.owner}
+	}
+
+	_, err := w.pending.content.ReadFrom(content)
+	return err
+}
+
+func (w *Writer) createDirectory(p string, arg filesystem.Arguments) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.usable(); err != nil {
+		return err
+	}
+	name := entryName(p)
+	if err := w.prepareParent(name, arg); err != nil {
+		return err
+	}
+
+	if existing := w.written[name]; name == "" || existing != nil {
+		if name == "" || existing.isDirectory {
+			return filesystem.ErrDirectoryFound
+		}
+		return filesystem.ErrFile
+	}
+
+	return w.emitDirectory(name, arg.Mode, arg.Owner)
+}
+
+// prepareParent verifies if parent directory of entry was written and emits missing ones (if allowed).
+func (w *Writer) prepareParent(name string, arg filesystem.Arguments) error {
+	var missing []string
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if e := w.written[dir]; e != nil {
+			if !e.isDirectory {
+				return fmt.Errorf("location structure does not contain valid directory as target: %w", filesystem.ErrUnresolvableDirectoryStructure)
+			}
+			break
+		}
+		missing = append(missing, dir)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !arg.AllowCreationOfDirectoryStructure {
+		return fmt.Errorf("creation of non-existing directory structure is forbidden by current settings: %w", filesystem.ErrUnresolvableDirectoryStructure)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := w.emitDirectory(missing[i], arg.DirectoryStructureMode, arg.Owner); err != nil {
+			return fmt.Errorf("cannot create directory structure: %w", err)
+		}
+	}
+	return nil
+}
+
+func (w *Writer) emitDirectory(name string, mode filesystem.Mode, owner filesystem.Owner) error {
+	if err := w.emitPending(); err != nil {
+		return err
+	}
+	if err := w.fail(w.tw.WriteHeader(w.header(name+"/", tar.TypeDir, mode, owner, 0))); err != nil {
+		return err
+	}
+	w.written[name] = &writtenEntry{isDirectory: true, mode: mode, owner: owner}
+	return nil
+}
+
+func (w *Writer) emitPending() error {
+	if w.pending == nil {
+		return nil
+	}
+	pending := w.pending
+	w.pending = nil
+
+	header := w.header(pending.name, tar.TypeReg, pending.mode, pending.owner, int64(pending.content.Len()))
+	if err := w.fail(w.tw.WriteHeader(header)); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(pending.content.Bytes())
+	return w.fail(err)
+}
+
+func (w *Writer) header(name string, typeflag byte, mode filesystem.Mode, owner filesystem.Owner, size int64) *tar.Header {
+	modTime := w.modTime
+	if modTime.IsZero() {
+		modTime = time.Now().Truncate(time.Second)
+	}
+	return &tar.Header{
+		Typeflag: typeflag,
+		Name:     name,
+		Size:     size,
+		Mode:     int64(mode),
+		Uid:      owner.UID,
+		Gid:      owner.GID,
+		Uname:    owner.User,
+		Gname:    owner.Group,
+		ModTime:  modTime,
+	}
+}
+
+// usable verifies if stream can still be written.
+func (w *Writer) usable() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	return w.err
+}
+
+// fail remembers error of the stream (which cannot be continued after failed write).
+func (w *Writer) fail(err error) error {
+	if err != nil && w.err == nil {
+		w.err = fmt.Errorf("tar stream is broken: %w", err)
+	}
+	return err
+}
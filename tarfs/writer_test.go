@@ -0,0 +1,179 @@
+package tarfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// readStream returns headers and content of every entry of the stream.
+func readStream(t *testing.T, r io.Reader) ([]*tar.Header, []string) {
+	t.Helper()
+
+	var headers []*tar.Header
+	var contents []string
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return headers, contents
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		headers, contents = append(headers, header), append(contents, string(content))
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("it should emit entries with mode and owner", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var buf bytes.Buffer
+		modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		w, err := NewWriter(&buf, OptionModificationTime(modTime))
+		require.NoError(t, err)
+		owner := filesystem.Owner{UID: 1000, GID: 100, User: "app", Group: "users"}
+
+		// WHEN
+		require.NoError(t, filesystem.CreateDirectory(w, "etc", filesystem.WithMode(0o755)))
+		require.NoError(t, filesystem.CreateFile(w, "etc/app.yaml", filesystem.WithMode(0o640), filesystem.WithOwner(owner)))
+		require.NoError(t, filesystem.WriteContentTo(w, "etc/app.yaml", "key: "))
+		require.NoError(t, filesystem.StreamContentTo(w, "etc/app.yaml", strings.NewReader("value")))
+		require.NoError(t, filesystem.CreateFile(w, "usr/bin/tool", filesystem.WithMode(0o755), filesystem.WithDirectoryStructureMode(0o711),
+			filesystem.WithAllowCreationOfDirectoryStructure(true), filesystem.WithOwner(owner)))
+		require.NoError(t, w.Close())
+
+		// THEN
+		headers, contents := readStream(t, &buf)
+		require.Len(t, headers, 5)
+		names := make([]string, 0, len(headers))
+		for _, h := range headers {
+			names = append(names, h.Name)
+			assert.True(t, h.ModTime.Equal(modTime), h.Name)
+		}
+		assert.Equal(t, []string{"etc/", "etc/app.yaml", "usr/", "usr/bin/", "usr/bin/tool"}, names)
+		assert.Equal(t, []string{"", "key: value", "", "", ""}, contents)
+
+		assert.Equal(t, byte(tar.TypeDir), headers[0].Typeflag)
+		assert.Equal(t, int64(0o755), headers[0].Mode)
+		assert.Equal(t, int64(0o640), headers[1].Mode)
+		assert.Equal(t, 1000, headers[1].Uid)
+		assert.Equal(t, 100, headers[1].Gid)
+		assert.Equal(t, "app", headers[1].Uname)
+		assert.Equal(t, "users", headers[1].Gname)
+		assert.Equal(t, int64(0o711), headers[3].Mode)
+		assert.Equal(t, 1000, headers[3].Uid)
+		assert.Equal(t, int64(0o755), headers[4].Mode)
+	})
+	t.Run("it should re-emit overwritten files and reject appending to emitted ones", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf)
+		require.NoError(t, err)
+		require.NoError(t, filesystem.CreateFile(w, "a.txt", filesystem.WithMode(0o600)))
+		require.NoError(t, filesystem.WriteContentTo(w, "a.txt", "OLD"))
+		require.NoError(t, filesystem.CreateFile(w, "b.txt"))
+
+		// WHEN
+		errAppend := filesystem.WriteContentTo(w, "a.txt", "MORE")
+		errOverwrite := filesystem.WriteContentTo(w, "a.txt", "NEW", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite))
+		require.NoError(t, w.Close())
+
+		// THEN
+		require.ErrorIs(t, errAppend, filesystem.ErrUnsupportedContentOperation)
+		require.NoError(t, errOverwrite)
+		data := buf.Bytes()
+		headers, contents := readStream(t, bytes.NewReader(data))
+		require.Len(t, headers, 3)
+		assert.Equal(t, "a.txt", headers[2].Name)
+		assert.Equal(t, int64(0o600), headers[2].Mode)
+		assert.Equal(t, []string{"OLD", "", "NEW"}, contents)
+
+		fs, err := NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		_, err = filesystem.ListFilesIn(fs, "/")
+		require.NoError(t, err)
+		content, err := filesystem.ReadContentOf(fs, "a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("NEW"), content)
+	})
+	t.Run("it should compress stream with gzip", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		name := filepath.Join(t.TempDir(), "layer.tar.gz")
+		w, err := Create(name, OptionGzip(gzip.BestCompression))
+		require.NoError(t, err)
+
+		// WHEN
+		require.NoError(t, filesystem.CreateFile(w, "test.txt"))
+		require.NoError(t, filesystem.WriteContentTo(w, "test.txt", "TEST"))
+		require.NoError(t, w.Flush())
+		require.NoError(t, w.Close())
+
+		// THEN
+		tr, err := Open(name)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = tr.Close() })
+		content, err := filesystem.ReadContentOf(tr, "test.txt")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("TEST"), content)
+	})
+	t.Run("it should report sentinel errors like local filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		w, err := NewWriter(io.Discard)
+		require.NoError(t, err)
+		require.NoError(t, filesystem.CreateDirectory(w, "dir"))
+		require.NoError(t, filesystem.CreateFile(w, "dir/test.txt"))
+
+		// WHEN
+		errWriteMissing := filesystem.WriteContentTo(w, "missing.txt", "TEST")
+		errWriteDirectory := filesystem.WriteContentTo(w, "dir", "TEST")
+		errFileFound := filesystem.CreateFile(w, "dir/test.txt")
+		errDirectory := filesystem.CreateFile(w, "dir", filesystem.WithAllowOverwrite(true))
+		errDirectoryFound := filesystem.CreateDirectory(w, "dir")
+		errFile := filesystem.CreateDirectory(w, "dir/test.txt")
+		errStructure := filesystem.CreateFile(w, "missing/test.txt")
+		errStructureOfFile := filesystem.CreateFile(w, "dir/test.txt/test.txt", filesystem.WithAllowCreationOfDirectoryStructure(true))
+		exists, existsErr := filesystem.CheckIfExists(w, "dir/test.txt")
+		_, errRead := filesystem.ReadContentOf(w, "dir/test.txt")
+		errRemove := filesystem.Remove(w, "dir/test.txt")
+		_, errList := filesystem.ListFilesIn(w, "dir")
+		require.NoError(t, w.Close())
+		errClosed := filesystem.CreateFile(w, "late.txt")
+
+		// THEN
+		require.ErrorIs(t, errWriteMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errWriteDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errFileFound, filesystem.ErrFileFound)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errDirectoryFound, filesystem.ErrDirectoryFound)
+		require.ErrorIs(t, errFile, filesystem.ErrFile)
+		require.ErrorIs(t, errStructure, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errStructureOfFile, filesystem.ErrUnresolvableDirectoryStructure)
+		require.NoError(t, existsErr)
+		assert.True(t, exists)
+		require.ErrorIs(t, errRead, errors.ErrUnsupported)
+		require.ErrorIs(t, errRemove, errors.ErrUnsupported)
+		require.ErrorIs(t, errList, errors.ErrUnsupported)
+		require.ErrorIs(t, errClosed, fs.ErrClosed)
+	})
+}
@@ -0,0 +1,164 @@
+// Package tracing provides OpenTelemetry instrumentation for filesystem.Filesystem.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionTracerProvider options.OptionKey = `tracer_provider`
+)
+
+const (
+	instrumentationName = "github.com/SevenOfSpades/go-wrapped-filesystem/tracing"
+	spanNamePrefix      = "filesystem."
+)
+
+const (
+	AttributeOperation                         = attribute.Key("filesystem.operation")
+	AttributeBackend                           = attribute.Key("filesystem.backend")
+	AttributePath                              = attribute.Key("filesystem.path")
+	AttributeTarget                            = attribute.Key("filesystem.target")
+	AttributeBytes                             = attribute.Key("filesystem.bytes")
+	AttributeErrorKind                         = attribute.Key("filesystem.error_kind")
+	AttributeMode                              = attribute.Key("filesystem.arguments.mode")
+	AttributeDirectoryStructureMode            = attribute.Key("filesystem.arguments.directory_structure_mode")
+	AttributeAllowCreationOfDirectoryStructure = attribute.Key("filesystem.arguments.allow_creation_of_directory_structure")
+	AttributeAllowOverwrite                    = attribute.Key("filesystem.arguments.allow_overwrite")
+	AttributeContentOperation                  = attribute.Key("filesystem.arguments.content_operation")
+	AttributeRecursive                         = attribute.Key("filesystem.arguments.recursive")
+)
+
+// OptionTracerProvider changes provider of tracers (default is the global one from otel.GetTracerProvider).
+func OptionTracerProvider(provider trace.TracerProvider) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[trace.TracerProvider](r, optionTracerProvider, provider)
+	}
+}
+
+// NewMiddleware creates filesystem.Middleware starting span (e.g. "filesystem.ReadContentOf") for every operation.
+// Span is a child of context bound with filesystem.InContext and carries path, arguments and outcome as attributes.
+// Span for StreamContentOf ends when returned io.ReadCloser gets closed.
+func NewMiddleware(opts ...options.Option) (filesystem.Middleware, error) {
+	opt := options.Resolve(opts)
+
+	optTracerProvider, err := options.ReadOrDefault[trace.TracerProvider](opt, optionTracerProvider, nil)
+	if err != nil {
+		return nil, fmt.Errorf("tracing middleware initialization failed: %w", err)
+	}
+	if optTracerProvider == nil {
+		optTracerProvider = otel.GetTracerProvider()
+	}
+
+	tracer := optTracerProvider.Tracer(instrumentationName)
+
+	return func(next filesystem.Invoker) filesystem.Invoker {
+		return func(call filesystem.Call) (filesystem.Result, error) {
+			parent := call.Context
+			if parent == nil {
+				parent = context.Background()
+			}
+
+			ctx, span := tracer.Start(
+				parent,
+				spanNamePrefix+call.Operation.String(),
+				trace.WithSpanKind(trace.SpanKindInternal),
+				trace.WithAttributes(callAttributes(call)...),
+			)
+			call.Context = ctx
+
+			var count func() int64
+			if call.Operation == filesystem.OperationStreamContentTo && call.Reader != nil {
+				call.Reader, count = filesystem.CountReader(call.Reader)
+			}
+
+			res, err := next(call)
+			if err != nil {
+				endSpan(span, err)
+				return res, err
+			}
+
+			switch call.Operation {
+			case filesystem.OperationStreamContentOf:
+				// Span ends when the reader gets closed.
+				res.ReadCloser = filesystem.ObserveReadCloser(res.ReadCloser, func(read int64, cErr error) {
+					span.SetAttributes(AttributeBytes.Int64(read))
+					endSpan(span, cErr)
+				})
+				return res, nil
+			case filesystem.OperationStreamContentTo:
+				if count != nil {
+					span.SetAttributes(AttributeBytes.Int64(count()))
+				}
+			case filesystem.OperationReadContentOf:
+				span.SetAttributes(AttributeBytes.Int(res.Content.Length()))
+			case filesystem.OperationWriteContentTo:
+				span.SetAttributes(AttributeBytes.Int(len(call.Content)))
+			}
+			endSpan(span, nil)
+
+			return res, nil
+		}
+	}, nil
+}
+
+func callAttributes(call filesystem.Call) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		AttributeOperation.String(call.Operation.String()),
+		AttributeBackend.String(call.Backend),
+		AttributePath.String(call.Path),
+	}
+	if call.Target != "" {
+		attrs = append(attrs, AttributeTarget.String(call.Target))
+	}
+
+	arg := call.Arguments
+	switch call.Operation {
+	case filesystem.OperationCreateFile:
+		attrs = append(attrs,
+			AttributeMode.String(arg.Mode.String()),
+			AttributeDirectoryStructureMode.String(arg.DirectoryStructureMode.String()),
+			AttributeAllowCreationOfDirectoryStructure.Bool(arg.AllowCreationOfDirectoryStructure),
+			AttributeAllowOverwrite.Bool(arg.AllowOverwrite),
+		)
+	case filesystem.OperationCreateDirectory:
+		attrs = append(attrs,
+			AttributeMode.String(arg.Mode.String()),
+			AttributeDirectoryStructureMode.String(arg.DirectoryStructureMode.String()),
+			AttributeAllowCreationOfDirectoryStructure.Bool(arg.AllowCreationOfDirectoryStructure),
+		)
+	case filesystem.OperationWriteContentTo, filesystem.OperationStreamContentTo:
+		attrs = append(attrs, AttributeContentOperation.String(arg.ContentOperation.String()))
+	case filesystem.OperationRemove:
+		attrs = append(attrs, AttributeRecursive.Bool(arg.Recursive))
+	case filesystem.OperationMove, filesystem.OperationCopy:
+		attrs = append(attrs,
+			AttributeDirectoryStructureMode.String(arg.DirectoryStructureMode.String()),
+			AttributeAllowCreationOfDirectoryStructure.Bool(arg.AllowCreationOfDirectoryStructure),
+			AttributeAllowOverwrite.Bool(arg.AllowOverwrite),
+			AttributeRecursive.Bool(arg.Recursive),
+		)
+	}
+
+	return attrs
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(AttributeErrorKind.String(filesystem.ErrorKind(err)))
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
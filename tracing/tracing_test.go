@@ -0,0 +1,238 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+func newTestTracing(t *testing.T) (filesystem.Middleware, *tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	mw, err := NewMiddleware(OptionTracerProvider(provider))
+	require.NoError(t, err)
+
+	return mw, exporter, provider
+}
+
+func attributesOf(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	res := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		res[kv.Key] = kv.Value
+	}
+	return res
+}
+
+type testReadCloser struct {
+	io.Reader
+}
+
+func (testReadCloser) Close() error {
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("it should create span with path, arguments and outcome", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		mw, exporter, _ := newTestTracing(t)
+		fs, err := filesystem.New(
+			filesystem.OptionBackendName("test"),
+			filesystem.OptionCreateFileHandler(func(string, filesystem.Arguments) error { return nil }),
+			filesystem.OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = filesystem.CreateFile(fs, "path/to/file", filesystem.WithAllowOverwrite(true))
+
+		// THEN
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "filesystem.CreateFile", spans[0].Name)
+		assert.Equal(t, codes.Ok, spans[0].Status.Code)
+
+		attrs := attributesOf(spans[0])
+		assert.Equal(t, "CreateFile", attrs[AttributeOperation].AsString())
+		assert.Equal(t, "test", attrs[AttributeBackend].AsString())
+		assert.Equal(t, "path/to/file", attrs[AttributePath].AsString())
+		assert.Equal(t, "0666", attrs[AttributeMode].AsString())
+		assert.True(t, attrs[AttributeAllowOverwrite].AsBool())
+	})
+	t.Run("it should record error of failed operation", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		mw, exporter, _ := newTestTracing(t)
+		fs, err := filesystem.New(
+			filesystem.OptionReadContentOfHandler(func(string) (filesystem.Content, error) {
+				return nil, filesystem.ErrFileNotFound
+			}),
+			filesystem.OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = filesystem.ReadContentOf(fs, "path/to/file")
+
+		// THEN
+		require.ErrorIs(t, err, filesystem.ErrFileNotFound)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+		assert.Equal(t, "ErrFileNotFound", attributesOf(spans[0])[AttributeErrorKind].AsString())
+		require.Len(t, spans[0].Events, 1)
+		assert.Equal(t, "exception", spans[0].Events[0].Name)
+	})
+	t.Run("it should keep span of stream open until reader gets closed", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		mw, exporter, _ := newTestTracing(t)
+		fs, err := filesystem.New(
+			filesystem.OptionStreamContentOfHandler(func(string) (io.ReadCloser, error) {
+				return testReadCloser{Reader: strings.NewReader("TEST")}, nil
+			}),
+			filesystem.OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		rc, err := filesystem.StreamContentOf(fs, "path/to/file")
+		require.NoError(t, err)
+		assert.Empty(t, exporter.GetSpans())
+
+		_, err = io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		// THEN
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "filesystem.StreamContentOf", spans[0].Name)
+		assert.Equal(t, int64(4), attributesOf(spans[0])[AttributeBytes].AsInt64())
+	})
+	t.Run("it should create span as a child of bound context", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		mw, exporter, provider := newTestTracing(t)
+		fs, err := filesystem.New(
+			filesystem.OptionCheckIfExistsHandler(func(string) (bool, error) { return true, nil }),
+			filesystem.OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+		// WHEN
+		_, err = filesystem.CheckIfExists(filesystem.InContext(fs, ctx), "path/to/file")
+		parent.End()
+
+		// THEN
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		assert.Equal(t, "filesystem.CheckIfExists", spans[0].Name)
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+		assert.Equal(t, parent.SpanContext().TraceID(), spans[0].SpanContext.TraceID())
+	})
+	t.Run("it should count bytes streamed to file", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		mw, exporter, _ := newTestTracing(t)
+		fs, err := filesystem.New(
+			filesystem.OptionStreamContentToHandler(func(_ string, r io.Reader, _ filesystem.Arguments) error {
+				_, err := io.Copy(io.Discard, r)
+				return err
+			}),
+			filesystem.OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = filesystem.StreamContentTo(fs, "path/to/file", strings.NewReader("TEST"))
+
+		// THEN
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		attrs := attributesOf(spans[0])
+		assert.Equal(t, int64(4), attrs[AttributeBytes].AsInt64())
+		assert.Equal(t, "append", attrs[AttributeContentOperation].AsString())
+	})
+	t.Run("it should keep streamed reader rewindable for retries", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		mw, exporter, _ := newTestTracing(t)
+		retry, err := filesystem.NewRetryMiddleware(filesystem.OptionRetryInitialDelay(time.Millisecond))
+		require.NoError(t, err)
+
+		var received []string
+		fs, err := filesystem.New(
+			filesystem.OptionStreamContentToHandler(func(_ string, r io.Reader, _ filesystem.Arguments) error {
+				data, rErr := io.ReadAll(r)
+				require.NoError(t, rErr)
+				received = append(received, string(data))
+				if len(received) < 2 {
+					return errors.New("connection reset")
+				}
+				return nil
+			}),
+			filesystem.OptionMiddleware(mw, retry),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = filesystem.StreamContentTo(fs, "path/to/file", strings.NewReader("TEST"), filesystem.WithContentOperation(filesystem.ContentOperationOverwrite))
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"TEST", "TEST"}, received)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, int64(4), attributesOf(spans[0])[AttributeBytes].AsInt64())
+	})
+	t.Run("it should return up-stream error", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		mw, _, _ := newTestTracing(t)
+		expectedErr := errors.New("something went wrong")
+		fs, err := filesystem.New(
+			filesystem.OptionWriteContentToHandler(func(string, []byte, filesystem.Arguments) error { return expectedErr }),
+			filesystem.OptionMiddleware(mw),
+		)
+		require.NoError(t, err)
+
+		// WHEN
+		err = filesystem.WriteContentTo(fs, "path/to/file", "TEST")
+
+		// THEN
+		require.ErrorIs(t, err, expectedErr)
+	})
+}
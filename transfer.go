@@ -0,0 +1,99 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// checkTransfer rejects Move and Copy of path onto itself or into its own subdirectory (lexically, after cleaning
+// both paths) since backends would destroy the source while preparing the target.
+func checkTransfer(source, target string) error {
+	src, dst := path.Clean(filepath.ToSlash(source)), path.Clean(filepath.ToSlash(target))
+	inside := strings.HasPrefix(dst, strings.TrimSuffix(src, "/")+"/")
+	if src == "." {
+		inside = !path.IsAbs(dst) && dst != ".." && !strings.HasPrefix(dst, "../")
+	}
+	if dst == src || inside {
+		return fmt.Errorf("cannot transfer %s into itself: %w", source, ErrUnresolvableDirectoryStructure)
+	}
+	return nil
+}
+
+// transferFileFunc transfers single file of provided mode between filesystems.
+type transferFileFunc func(ctx context.Context, src Filesystem, source string, dst Filesystem, target string, mode Mode, arg Arguments) error
+
+// transfer copies file (or directory with its content if allowed) between filesystems by streaming its content.
+// It's used when operation spans filesystems which can't perform it natively (e.g. different mounts).
+func transfer(ctx context.Context, src Filesystem, source string, dst Filesystem, target string, arg Arguments) error {
+	return transferWith(ctx, src, source, dst, target, arg, transferFile)
+}
+
+// transferWith recreates structure of file (or directory if allowed) in dst and transfers files with provided function.
+func transferWith(ctx context.Context, src Filesystem, source string, dst Filesystem, target string, arg Arguments, file transferFileFunc) error {
+	entry, err := entryOf(ctx, src, source)
+	if err != nil {
+		return err
+	}
+
+	if !entry.IsDirectory {
+		return file(ctx, src, source, dst, target, entry.Mode, arg)
+	}
+	if !arg.Recursive {
+		return ErrDirectory
+	}
+
+	err = dst.handleCreateDirectory(ctx, target, Arguments{
+		Mode:                              entry.Mode | ModeUserReadWriteExecute,
+		DirectoryStructureMode:            arg.DirectoryStructureMode,
+		AllowCreationOfDirectoryStructure: arg.AllowCreationOfDirectoryStructure,
+	})
+	if err != nil && (!errors.Is(err, ErrDirectoryFound) || !arg.AllowOverwrite) {
+		return err
+	}
+
+	entries, err := src.handleListFilesIn(ctx, source)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err = transferWith(ctx, src, path.Join(source, e.Name), dst, path.Join(target, e.Name), arg, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func transferFile(ctx context.Context, src Filesystem, source string, dst Filesystem, target string, mode Mode, arg Arguments) error {
+	rc, err := src.handleStreamContentOf(ctx, source)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	err = dst.handleCreateFile(ctx, target, Arguments{
+		Mode:                              mode | ModeUserReadWrite,
+		DirectoryStructureMode:            arg.DirectoryStructureMode,
+		AllowCreationOfDirectoryStructure: arg.AllowCreationOfDirectoryStructure,
+		AllowOverwrite:                    arg.AllowOverwrite,
+	})
+	if err != nil {
+		return err
+	}
+
+	return dst.handleStreamContentTo(ctx, target, rc, Arguments{ContentOperation: ContentOperationOverwrite})
+}
+
+// transferFileStructure creates (empty) target of the file without reading its content.
+func transferFileStructure(ctx context.Context, _ Filesystem, _ string, dst Filesystem, target string, mode Mode, arg Arguments) error {
+	return dst.handleCreateFile(ctx, target, Arguments{
+		Mode:                              mode | ModeUserReadWrite,
+		DirectoryStructureMode:            arg.DirectoryStructureMode,
+		AllowCreationOfDirectoryStructure: arg.AllowCreationOfDirectoryStructure,
+		AllowOverwrite:                    arg.AllowOverwrite,
+	})
+}
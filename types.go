@@ -1,27 +1,93 @@
 package filesystem
 
 import (
+	"context"
 	"errors"
 	"io"
+	"io/fs"
+	"time"
 )
 
 var (
-	ErrFileNotFound                   = errors.New("file not found")
-	ErrFileFound                      = errors.New("file already exists")
-	ErrDirectoryFound                 = errors.New("directory already exists")
+	ErrFileNotFound                   = newSentinelError("file not found", fs.ErrNotExist)
+	ErrFileFound                      = newSentinelError("file already exists", fs.ErrExist)
+	ErrDirectoryFound                 = newSentinelError("directory already exists", fs.ErrExist)
 	ErrUnresolvableDirectoryStructure = errors.New("unresolvable directory structure")
 	ErrDirectory                      = errors.New("location contains directory but handler expects file")
 	ErrFile                           = errors.New("location contains file but handler expects directory")
 	ErrWriteLengthMismatch            = errors.New("amount of written bytes does not match the reported amount")
 	ErrUnsupportedContentOperation    = errors.New("content operation is not supported")
+	ErrUnsupportedTraversalOrder      = errors.New("traversal order is not supported")
+	ErrUnsupportedErrorPolicy         = errors.New("error policy is not supported")
+	ErrSymlinkLoop                    = errors.New("symlink creates a loop")
+	ErrUnsupportedOperation           = errors.New("operation is not supported")
+	ErrPermissionDenied               = newSentinelError("permission denied", fs.ErrPermission)
+	ErrReadOnly                       = errors.New("filesystem is read-only")
+	ErrNoSpace                        = errors.New("no space left on device")
+	ErrNotEmpty                       = errors.New("directory is not empty")
+	ErrTooLarge                       = errors.New("file is too large")
+	ErrPathOutsideRoot                = errors.New("path is outside of the root directory")
 )
 
+var errorKinds = []struct {
+	name string
+	err  error
+}{
+	{name: "ErrFileNotFound", err: ErrFileNotFound},
+	{name: "ErrFileFound", err: ErrFileFound},
+	{name: "ErrDirectoryFound", err: ErrDirectoryFound},
+	{name: "ErrUnresolvableDirectoryStructure", err: ErrUnresolvableDirectoryStructure},
+	{name: "ErrDirectory", err: ErrDirectory},
+	{name: "ErrFile", err: ErrFile},
+	{name: "ErrWriteLengthMismatch", err: ErrWriteLengthMismatch},
+	{name: "ErrUnsupportedContentOperation", err: ErrUnsupportedContentOperation},
+	{name: "ErrUnsupportedTraversalOrder", err: ErrUnsupportedTraversalOrder},
+	{name: "ErrUnsupportedErrorPolicy", err: ErrUnsupportedErrorPolicy},
+	{name: "ErrSymlinkLoop", err: ErrSymlinkLoop},
+	{name: "ErrUnsupportedOperation", err: ErrUnsupportedOperation},
+	{name: "ErrPermissionDenied", err: ErrPermissionDenied},
+	{name: "ErrReadOnly", err: ErrReadOnly},
+	{name: "ErrNoSpace", err: ErrNoSpace},
+	{name: "ErrNotEmpty", err: ErrNotEmpty},
+	{name: "ErrTooLarge", err: ErrTooLarge},
+	{name: "ErrPathOutsideRoot", err: ErrPathOutsideRoot},
+}
+
+// Entry describes single element (file, directory or symlink) found inside directory.
+type Entry struct {
+	Name        string
+	Size        int64
+	Mode        Mode
+	ModTime     time.Time
+	IsDirectory bool
+	IsSymlink   bool
+	// LinkTarget contains destination of symlink (if backend is able to provide it).
+	LinkTarget string
+}
+
+// capabilities describes properties of Filesystem which decorators have to take into account.
+type capabilities struct {
+	// localDisk is set when paths are resolved on local disk (see OptionLocalDisk).
+	localDisk bool
+	// root is directory on local disk which paths are relative to (set by Sub, empty for working directory).
+	root string
+	// nativeGlob is set when Glob is resolved by dedicated handler instead of listing directories.
+	nativeGlob bool
+}
+
 type Filesystem interface {
-	handleReadContentOf(string) (Content, error)
-	handleStreamContentOf(string) (io.ReadCloser, error)
-	handleCheckIfExists(string) (bool, error)
-	handleCreateFile(string, ...Argument) error
-	handleWriteContentTo(string, []byte, ...Argument) error
-	handleStreamContentTo(string, io.Reader, ...Argument) error
-	handleCreateDirectory(string, ...Argument) error
+	backendName() string
+	capabilities() capabilities
+	handleReadContentOf(context.Context, string) (Content, error)
+	handleStreamContentOf(context.Context, string) (io.ReadCloser, error)
+	handleCheckIfExists(context.Context, string) (bool, error)
+	handleCreateFile(context.Context, string, Arguments) error
+	handleWriteContentTo(context.Context, string, []byte, Arguments) error
+	handleStreamContentTo(context.Context, string, io.Reader, Arguments) error
+	handleCreateDirectory(context.Context, string, Arguments) error
+	handleRemove(context.Context, string, Arguments) error
+	handleMove(context.Context, string, string, Arguments) error
+	handleCopy(context.Context, string, string, Arguments) error
+	handleListFilesIn(context.Context, string) ([]Entry, error)
+	handleGlob(context.Context, string) ([]string, error)
 }
@@ -0,0 +1,296 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	TraversalOrderLexical TraversalOrder = iota
+	TraversalOrderUnordered
+)
+
+const (
+	ErrorPolicyAbort ErrorPolicy = iota
+	ErrorPolicyContinue
+)
+
+// maxSymlinkHops limits number of symlinks followed within single branch of the tree
+// to stop traversal on loops that cannot be detected by comparing link targets.
+const maxSymlinkHops = 40
+
+var (
+	// SkipDir returned from WalkFunc skips directory in context (or remaining entries of containing directory
+	// if returned for a file). It is the same value as fs.SkipDir.
+	SkipDir = fs.SkipDir
+	// SkipAll returned from WalkFunc stops traversal without reporting an error. It is the same value as fs.SkipAll.
+	SkipAll = fs.SkipAll
+)
+
+var validTraversalOrders = map[TraversalOrder]bool{
+	TraversalOrderLexical:   true,
+	TraversalOrderUnordered: true,
+}
+
+var validErrorPolicies = map[ErrorPolicy]bool{
+	ErrorPolicyAbort:    true,
+	ErrorPolicyContinue: true,
+}
+
+type (
+	TraversalOrder uint8
+
+	ErrorPolicy uint8
+
+	// WalkFunc is called by Walk for every visited entry. If listing of a directory fails, function is called
+	// second time for that directory with non-nil err (same as in case of fs.WalkDirFunc).
+	WalkFunc func(path string, entry Entry, err error) error
+)
+
+func (o TraversalOrder) Is(val TraversalOrder) bool {
+	return o == val
+}
+
+func (o TraversalOrder) assetValid() error {
+	if validTraversalOrders[o] {
+		return nil
+	}
+	return ErrUnsupportedTraversalOrder
+}
+
+func (p ErrorPolicy) Is(val ErrorPolicy) bool {
+	return p == val
+}
+
+func (p ErrorPolicy) assetValid() error {
+	if validErrorPolicies[p] {
+		return nil
+	}
+	return ErrUnsupportedErrorPolicy
+}
+
+type walkOutcome uint8
+
+const (
+	walkProceed walkOutcome = iota
+	walkSkipEntry
+	walkSkipDir
+	walkHalt
+)
+
+type walker struct {
+	ctx context.Context
+	fs  Filesystem
+	fn  WalkFunc
+	arg Arguments
+
+	stopped atomic.Bool
+	mu      sync.Mutex
+	errs    []error
+
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+func walk(ctx context.Context, fs Filesystem, root string, fn WalkFunc, arg Arguments) error {
+	if err := arg.TraversalOrder.assetValid(); err != nil {
+		return err
+	}
+	if err := arg.ErrorPolicy.assetValid(); err != nil {
+		return err
+	}
+
+	w := &walker{ctx: ctx, fs: fs, fn: fn, arg: arg}
+	if arg.Concurrency > 1 {
+		w.sem = make(chan struct{}, arg.Concurrency-1)
+	}
+
+	entry, err := entryOf(w.ctx, w.fs, root)
+	if err != nil {
+		w.outcome(fn(root, Entry{Name: path.Base(root)}, err))
+	} else {
+		w.visit(root, entry, resolvedRoot(fs, root), nil, 0)
+	}
+	w.wg.Wait()
+
+	return errors.Join(w.errs...)
+}
+
+// entryOf finds details of path by listing its parent since handlers do not expose information about single path.
+func entryOf(ctx context.Context, fs Filesystem, p string) (Entry, error) {
+	name := path.Base(p)
+	parent := path.Dir(p)
+	if name == "/" || name == "." || parent == p {
+		if _, err := fs.handleListFilesIn(ctx, p); err != nil {
+			return Entry{}, err
+		}
+		return Entry{Name: name, IsDirectory: true}, nil
+	}
+
+	entries, err := fs.handleListFilesIn(ctx, parent)
+	if err != nil {
+		if errors.Is(err, ErrFile) {
+			return Entry{}, ErrFileNotFound
+		}
+		return Entry{}, err
+	}
+	for _, e := range entries {
+		if e.Name == name {
+			return e, nil
+		}
+	}
+
+	return Entry{}, ErrFileNotFound
+}
+
+// visit calls WalkFunc for entry and descends into it if it's a directory (or followed symlink to directory).
+// Resolved contains path with followed symlinks replaced by their targets and chain holds resolved paths
+// of all directories above entry.
+func (w *walker) visit(p string, entry Entry, resolved string, chain []string, hops int) walkOutcome {
+	if w.stopped.Load() {
+		return walkHalt
+	}
+
+	var preloaded []Entry
+	if entry.IsSymlink && w.arg.FollowSymlinks {
+		entries, err := w.fs.handleListFilesIn(w.ctx, p)
+		if err == nil {
+			resolved = resolveLinkTarget(path.Dir(resolved), entry, resolved)
+			hops++
+			if hops > maxSymlinkHops || isLoop(resolved, chain) {
+				if res := w.outcome(w.fn(p, entry, ErrSymlinkLoop)); res == walkHalt {
+					return walkHalt
+				}
+				return walkProceed
+			}
+			entry.IsDirectory = true
+			preloaded = entries
+		}
+	}
+
+	res := w.outcome(w.fn(p, entry, nil))
+	if res != walkProceed || !entry.IsDirectory {
+		if entry.IsDirectory && res == walkSkipDir {
+			return walkProceed
+		}
+		return res
+	}
+
+	entries := preloaded
+	if entries == nil {
+		var err error
+		if entries, err = w.fs.handleListFilesIn(w.ctx, p); err != nil {
+			if res = w.outcome(w.fn(p, entry, err)); res == walkHalt {
+				return walkHalt
+			}
+			return walkProceed
+		}
+	}
+
+	w.visitEntries(p, entries, resolved, append(chain[:len(chain):len(chain)], resolved), hops)
+
+	return walkProceed
+}
+
+func (w *walker) visitEntries(dir string, entries []Entry, resolved string, chain []string, hops int) {
+	if w.arg.TraversalOrder.Is(TraversalOrderLexical) {
+		sorted := make([]Entry, len(entries))
+		copy(sorted, entries)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+		entries = sorted
+	}
+
+	for _, e := range entries {
+		if w.stopped.Load() {
+			return
+		}
+
+		p, r := path.Join(dir, e.Name), path.Join(resolved, e.Name)
+		if e.IsDirectory && w.sem != nil {
+			select {
+			case w.sem <- struct{}{}:
+				w.wg.Add(1)
+				go func(p, r string, e Entry) {
+					defer func() {
+						<-w.sem
+						w.wg.Done()
+					}()
+					w.visit(p, e, r, chain, hops)
+				}(p, r, e)
+				continue
+			default:
+			}
+		}
+
+		switch w.visit(p, e, r, chain, hops) {
+		case walkHalt:
+			return
+		case walkSkipDir:
+			// SkipDir returned for a file skips remaining entries of containing directory.
+			return
+		default:
+		}
+	}
+}
+
+// outcome translates result of WalkFunc into action taken by walker according to error policy.
+func (w *walker) outcome(err error) walkOutcome {
+	switch {
+	case err == nil:
+		return walkProceed
+	case errors.Is(err, SkipDir):
+		return walkSkipDir
+	case errors.Is(err, SkipAll):
+		w.stopped.Store(true)
+		return walkHalt
+	}
+
+	w.mu.Lock()
+	w.errs = append(w.errs, err)
+	w.mu.Unlock()
+
+	if w.arg.ErrorPolicy.Is(ErrorPolicyContinue) {
+		return walkSkipEntry
+	}
+	w.stopped.Store(true)
+
+	return walkHalt
+}
+
+// resolvedRoot returns root the way targets of absolute symlinks refer to it, so they can be compared:
+// absolute for local disk (joined with base of Sub) and cleaned one for other backends.
+func resolvedRoot(fs Filesystem, root string) string {
+	caps := fs.capabilities()
+	if caps.localDisk {
+		if abs, err := filepath.Abs(filepath.Join(caps.root, root)); err == nil {
+			return filepath.ToSlash(abs)
+		}
+	}
+	return path.Clean(root)
+}
+
+func resolveLinkTarget(dir string, entry Entry, fallback string) string {
+	if entry.LinkTarget == "" {
+		return fallback
+	}
+	if path.IsAbs(entry.LinkTarget) {
+		return path.Clean(entry.LinkTarget)
+	}
+	return path.Join(dir, entry.LinkTarget)
+}
+
+func isLoop(resolved string, chain []string) bool {
+	for _, c := range chain {
+		if c == resolved || strings.HasPrefix(c, strings.TrimSuffix(resolved, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
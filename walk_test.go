@@ -0,0 +1,337 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	files := map[string]string{
+		"root/a.txt":         "a",
+		"root/b/c.txt":       "c",
+		"root/b/d/e.txt":     "e",
+		"root/f/g.txt":       "g",
+		"root/f/h.txt":       "h",
+		"root/empty/":        "",
+		"outside/ignore.txt": "",
+	}
+
+	t.Run("it should visit every entry in lexical order", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		var visited []string
+		err := Walk(fs, "root", func(p string, entry Entry, err error) error {
+			require.NoError(t, err)
+			visited = append(visited, p)
+			return nil
+		})
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"root", "root/a.txt", "root/b", "root/b/c.txt", "root/b/d", "root/b/d/e.txt",
+			"root/empty", "root/f", "root/f/g.txt", "root/f/h.txt",
+		}, visited)
+	})
+	t.Run("it should provide entry details to callback", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		entries := make(map[string]Entry)
+		err := Walk(fs, "root/b", func(p string, entry Entry, _ error) error {
+			entries[p] = entry
+			return nil
+		})
+
+		// THEN
+		require.NoError(t, err)
+		assert.True(t, entries["root/b"].IsDirectory)
+		assert.Equal(t, ModeAllReadWriteExecute, entries["root/b"].Mode)
+		assert.False(t, entries["root/b/c.txt"].IsDirectory)
+		assert.Equal(t, ModeAllReadWrite, entries["root/b/c.txt"].Mode)
+		assert.Equal(t, int64(1), entries["root/b/c.txt"].Size)
+	})
+	t.Run("it should skip directory when callback returns SkipDir", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		var visited []string
+		err := Walk(fs, "root", func(p string, _ Entry, _ error) error {
+			visited = append(visited, p)
+			if p == "root/b" {
+				return SkipDir
+			}
+			return nil
+		})
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"root", "root/a.txt", "root/b", "root/empty", "root/f", "root/f/g.txt", "root/f/h.txt"}, visited)
+	})
+	t.Run("it should skip remaining entries of directory when callback returns SkipDir for a file", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		var visited []string
+		err := Walk(fs, "root/f", func(p string, _ Entry, _ error) error {
+			visited = append(visited, p)
+			if p == "root/f/g.txt" {
+				return SkipDir
+			}
+			return nil
+		})
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"root/f", "root/f/g.txt"}, visited)
+	})
+	t.Run("it should stop traversal when callback returns SkipAll", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		var visited []string
+		err := Walk(fs, "root", func(p string, _ Entry, _ error) error {
+			visited = append(visited, p)
+			if p == "root/b/c.txt" {
+				return SkipAll
+			}
+			return nil
+		})
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []string{"root", "root/a.txt", "root/b", "root/b/c.txt"}, visited)
+	})
+	t.Run("it should abort on first error by default", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+		expectedErr := errors.New("something went wrong")
+
+		// WHEN
+		var visited []string
+		err := Walk(fs, "root", func(p string, _ Entry, _ error) error {
+			visited = append(visited, p)
+			if p == "root/b" {
+				return expectedErr
+			}
+			return nil
+		})
+
+		// THEN
+		require.ErrorIs(t, err, expectedErr)
+		assert.Equal(t, []string{"root", "root/a.txt", "root/b"}, visited)
+	})
+	t.Run("it should continue and collect errors if allowed by arguments", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+		firstErr, secondErr := errors.New("first"), errors.New("second")
+
+		// WHEN
+		var visited []string
+		err := Walk(fs, "root", func(p string, _ Entry, _ error) error {
+			visited = append(visited, p)
+			switch p {
+			case "root/b":
+				return firstErr
+			case "root/f/g.txt":
+				return secondErr
+			}
+			return nil
+		}, WithErrorPolicy(ErrorPolicyContinue))
+
+		// THEN
+		require.ErrorIs(t, err, firstErr)
+		require.ErrorIs(t, err, secondErr)
+		assert.Equal(t, []string{"root", "root/a.txt", "root/b", "root/empty", "root/f", "root/f/g.txt", "root/f/h.txt"}, visited)
+	})
+	t.Run("it should report missing root to callback", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		err := Walk(fs, "missing", func(p string, _ Entry, err error) error {
+			assert.Equal(t, "missing", p)
+			return err
+		})
+
+		// THEN
+		require.ErrorIs(t, err, ErrFileNotFound)
+	})
+	t.Run("it should visit every entry with bounded concurrency", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		var mu sync.Mutex
+		var visited []string
+		err := Walk(fs, "root", func(p string, _ Entry, _ error) error {
+			mu.Lock()
+			defer mu.Unlock()
+			visited = append(visited, p)
+			return nil
+		}, WithConcurrency(4), WithTraversalOrder(TraversalOrderUnordered))
+
+		// THEN
+		require.NoError(t, err)
+		sort.Strings(visited)
+		assert.Equal(t, []string{
+			"root", "root/a.txt", "root/b", "root/b/c.txt", "root/b/d", "root/b/d/e.txt",
+			"root/empty", "root/f", "root/f/g.txt", "root/f/h.txt",
+		}, visited)
+	})
+	t.Run("it should reject unsupported error policy", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		fs := newFakeTreeFilesystem(t, files)
+
+		// WHEN
+		err := Walk(fs, "root", func(string, Entry, error) error { return nil }, WithErrorPolicy(ErrorPolicy(42)))
+
+		// THEN
+		require.ErrorIs(t, err, ErrUnsupportedErrorPolicy)
+	})
+}
+
+func TestDefaultWalk(t *testing.T) {
+	t.Run("it should not follow symlinks by default", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			require.NoError(t, os.MkdirAll(path.Join(workdir, "target"), 0700))
+			require.NoError(t, os.WriteFile(path.Join(workdir, "target", "file.txt"), nil, 0600))
+			require.NoError(t, os.Symlink("target", path.Join(workdir, "link")))
+
+			// WHEN
+			var visited []string
+			err = Walk(fs, workdir, func(p string, _ Entry, err error) error {
+				visited = append(visited, p)
+				return err
+			})
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, []string{
+				workdir,
+				path.Join(workdir, "link"),
+				path.Join(workdir, "target"),
+				path.Join(workdir, "target", "file.txt"),
+			}, visited)
+		})
+	})
+	t.Run("it should follow symlinks and detect loops if allowed by arguments", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			require.NoError(t, os.MkdirAll(path.Join(workdir, "target"), 0700))
+			require.NoError(t, os.WriteFile(path.Join(workdir, "target", "file.txt"), nil, 0600))
+			require.NoError(t, os.Symlink("target", path.Join(workdir, "link")))
+			require.NoError(t, os.Symlink("..", path.Join(workdir, "target", "loop")))
+
+			// WHEN
+			var visited []string
+			var loops []string
+			err = Walk(fs, workdir, func(p string, _ Entry, err error) error {
+				if errors.Is(err, ErrSymlinkLoop) {
+					loops = append(loops, p)
+					return nil
+				}
+				visited = append(visited, p)
+				return err
+			}, WithFollowSymlinks(true))
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, []string{
+				workdir,
+				path.Join(workdir, "link"),
+				path.Join(workdir, "link", "file.txt"),
+				path.Join(workdir, "target"),
+				path.Join(workdir, "target", "file.txt"),
+			}, visited)
+			assert.Equal(t, []string{
+				path.Join(workdir, "link", "loop"),
+				path.Join(workdir, "target", "loop"),
+			}, loops)
+		})
+	})
+	t.Run("it should detect loops through absolute symlinks when root is relative", func(t *testing.T) {
+		t.Parallel()
+
+		withinRandomDirectoryScope(func(workdir string) {
+			// GIVEN
+			fs, err := New()
+			require.NoError(t, err)
+
+			cwd, err := os.Getwd()
+			require.NoError(t, err)
+			root, err := filepath.Rel(cwd, workdir)
+			require.NoError(t, err)
+
+			require.NoError(t, os.MkdirAll(path.Join(workdir, "target"), 0700))
+			require.NoError(t, os.WriteFile(path.Join(workdir, "target", "file.txt"), nil, 0600))
+			require.NoError(t, os.Symlink(workdir, path.Join(workdir, "target", "loop")))
+
+			// WHEN
+			var visited []string
+			var loops []string
+			err = Walk(fs, root, func(p string, _ Entry, err error) error {
+				if errors.Is(err, ErrSymlinkLoop) {
+					loops = append(loops, p)
+					return nil
+				}
+				visited = append(visited, p)
+				return err
+			}, WithFollowSymlinks(true))
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, []string{
+				root,
+				path.Join(root, "target"),
+				path.Join(root, "target", "file.txt"),
+			}, visited)
+			assert.Equal(t, []string{path.Join(root, "target", "loop")}, loops)
+		})
+	})
+}
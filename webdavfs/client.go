@@ -0,0 +1,532 @@
+package webdavfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionClient        options.OptionKey = `http_client`
+	optionTimeout       options.OptionKey = `http_timeout`
+	optionAuthorization options.OptionKey = `http_authorization`
+	optionEmulateAppend options.OptionKey = `webdav_emulate_append`
+	optionBackendName   options.OptionKey = `backend_name`
+)
+
+const (
+	defaultBackendName = "webdav"
+
+	// maxErrorMessageLength limits amount of response body included in error of unexpected response.
+	maxErrorMessageLength = 512
+
+	methodPropfind = "PROPFIND"
+	methodMkcol    = "MKCOL"
+	methodMove     = "MOVE"
+	methodCopy     = "COPY"
+)
+
+// propfindBody requests only properties needed to describe filesystem.Entry.
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>` +
+	`<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/><D:getlastmodified/></D:prop></D:propfind>`
+
+type (
+	multistatus struct {
+		Responses []struct {
+			Href      string `xml:"DAV: href"`
+			Propstats []struct {
+				Status string `xml:"DAV: status"`
+				Prop   struct {
+					ResourceType struct {
+						Collection *struct{} `xml:"DAV: collection"`
+					} `xml:"DAV: resourcetype"`
+					ContentLength string `xml:"DAV: getcontentlength"`
+					LastModified  string `xml:"DAV: getlastmodified"`
+				} `xml:"DAV: prop"`
+			} `xml:"DAV: propstat"`
+		} `xml:"DAV: response"`
+	}
+
+	// resource is entry described by PROPFIND along with its path.
+	resource struct {
+		path  string
+		entry filesystem.Entry
+	}
+
+	client struct {
+		base          *url.URL
+		http          *http.Client
+		authorize     func(*http.Request)
+		emulateAppend bool
+	}
+)
+
+// OptionClient changes HTTP client used for requests (default is a new http.Client without timeout).
+func OptionClient(c *http.Client) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[*http.Client](r, optionClient, c)
+	}
+}
+
+// OptionTimeout limits time of every request including reading of response body.
+func OptionTimeout(timeout time.Duration) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Duration](r, optionTimeout, timeout)
+	}
+}
+
+// OptionBasicAuth authenticates every request with provided username and password.
+func OptionBasicAuth(username, password string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[func(*http.Request)](r, optionAuthorization, func(req *http.Request) {
+			req.SetBasicAuth(username, password)
+		})
+	}
+}
+
+// OptionBearerToken authenticates every request with provided token.
+func OptionBearerToken(token string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[func(*http.Request)](r, optionAuthorization, func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		})
+	}
+}
+
+// OptionEmulateAppend allows ContentOperationAppend by downloading existing content and uploading it again
+// along with the new one. It's not atomic (concurrent writes can be lost) and costs transfer of entire file.
+func OptionEmulateAppend(enabled bool) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[bool](r, optionEmulateAppend, enabled)
+	}
+}
+
+// OptionBackendName changes name of the backend reported in errors and to middlewares (default "webdav").
+func OptionBackendName(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBackendName, name)
+	}
+}
+
+// New creates filesystem.Filesystem performing every operation on WebDAV server available at baseURL
+// (see package documentation for mapping of operations). Glob is resolved with ListFilesIn.
+func New(baseURL string, opts ...options.Option) (filesystem.Filesystem, error) {
+	opt := options.Resolve(opts)
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("webdav filesystem initialization failed: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("webdav filesystem initialization failed: unsupported scheme %q", base.Scheme)
+	}
+
+	optClient, err := options.ReadOrDefault[*http.Client](opt, optionClient, &http.Client{})
+	if err != nil {
+		return nil, fmt.Errorf("webdav filesystem initialization failed: %w", err)
+	}
+	optTimeout, err := options.ReadOrDefault[time.Duration](opt, optionTimeout, 0)
+	if err != nil {
+		return nil, fmt.Errorf("webdav filesystem initialization failed: %w", err)
+	}
+	optAuthorization, err := options.ReadOrDefault[func(*http.Request)](opt, optionAuthorization, nil)
+	if err != nil {
+		return nil, fmt.Errorf("webdav filesystem initialization failed: %w", err)
+	}
+	optEmulateAppend, err := options.ReadOrDefault[bool](opt, optionEmulateAppend, false)
+	if err != nil {
+		return nil, fmt.Errorf("webdav filesystem initialization failed: %w", err)
+	}
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("webdav filesystem initialization failed: %w", err)
+	}
+
+	if optTimeout > 0 {
+		withTimeout := *optClient
+		withTimeout.Timeout = optTimeout
+		optClient = &withTimeout
+	}
+
+	c := &client{base: base, http: optClient, authorize: optAuthorization, emulateAppend: optEmulateAppend}
+
+	return filesystem.New(
+		filesystem.OptionBackendName(optBackendName),
+		filesystem.OptionReadContentOfContextHandler(c.readContentOf),
+		filesystem.OptionStreamContentOfContextHandler(c.streamContentOf),
+		filesystem.OptionCheckIfExistsContextHandler(c.checkIfExists),
+		filesystem.OptionCreateFileContextHandler(c.createFile),
+		filesystem.OptionWriteContentToContextHandler(func(ctx context.Context, p string, content []byte, arg filesystem.Arguments) error {
+			return c.write(ctx, p, bytes.NewReader(content), arg)
+		}),
+		filesystem.OptionStreamContentToContextHandler(c.write),
+		filesystem.OptionCreateDirectoryContextHandler(c.createDirectory),
+		filesystem.OptionRemoveContextHandler(c.remove),
+		filesystem.OptionMoveContextHandler(func(ctx context.Context, source, target string, arg filesystem.Arguments) error {
+			return c.transfer(ctx, methodMove, source, target, arg)
+		}),
+		filesystem.OptionCopyContextHandler(func(ctx context.Context, source, target string, arg filesystem.Arguments) error {
+			return c.transfer(ctx, methodCopy, source, target, arg)
+		}),
+		filesystem.OptionListFilesInContextHandler(c.listFilesIn),
+	)
+}
+
+func (c *client) readContentOf(ctx context.Context, p string) (filesystem.Content, error) {
+	rc, err := c.streamContentOf(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	return io.ReadAll(rc)
+}
+
+func (c *client) streamContentOf(ctx context.Context, p string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, p, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		_ = resp.Body.Close()
+		if r, sErr := c.stat(ctx, p); sErr == nil && r != nil && r.entry.IsDirectory {
+			return nil, filesystem.ErrDirectory
+		}
+		return nil, responseError(resp)
+	}
+	if err = expectSuccess(resp); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *client) checkIfExists(ctx context.Context, p string) (bool, error) {
+	r, err := c.stat(ctx, p)
+	return r != nil, err
+}
+
+func (c *client) createFile(ctx context.Context, p string, arg filesystem.Arguments) error {
+	if err := c.prepareParent(ctx, p, arg); err != nil {
+		return err
+	}
+
+	r, err := c.stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if r != nil && !arg.AllowOverwrite {
+		return filesystem.ErrFileFound
+	}
+	if r != nil && r.entry.IsDirectory {
+		return filesystem.ErrDirectory
+	}
+
+	return c.send(ctx, http.MethodPut, p, nil, http.NoBody)
+}
+
+func (c *client) write(ctx context.Context, p string, content io.Reader, arg filesystem.Arguments) error {
+	appending := arg.ContentOperation.Is(filesystem.ContentOperationAppend)
+	switch {
+	case appending && !c.emulateAppend:
+		return fmt.Errorf("%w: WebDAV cannot append content (see webdavfs.OptionEmulateAppend)", filesystem.ErrUnsupportedContentOperation)
+	case !appending && !arg.ContentOperation.Is(filesystem.ContentOperationOverwrite):
+		return filesystem.ErrUnsupportedContentOperation
+	}
+
+	r, err := c.stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return filesystem.ErrFileNotFound
+	}
+	if r.entry.IsDirectory {
+		return filesystem.ErrDirectory
+	}
+
+	if appending {
+		existing, sErr := c.streamContentOf(ctx, p)
+		if sErr != nil {
+			return sErr
+		}
+		defer func() { _ = existing.Close() }()
+		content = io.MultiReader(existing, content)
+	}
+	return c.send(ctx, http.MethodPut, p, nil, content)
+}
+
+func (c *client) createDirectory(ctx context.Context, p string, arg filesystem.Arguments) error {
+	if err := c.prepareParent(ctx, p, arg); err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, methodMkcol, p, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		r, sErr := c.stat(ctx, p)
+		switch {
+		case sErr != nil:
+			return sErr
+		case r != nil && r.entry.IsDirectory:
+			return filesystem.ErrDirectoryFound
+		case r != nil:
+			return filesystem.ErrFile
+		}
+	}
+	return expectSuccess(resp)
+}
+
+func (c *client) remove(ctx context.Context, p string, arg filesystem.Arguments) error {
+	r, err := c.stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return filesystem.ErrFileNotFound
+	}
+	if r.entry.IsDirectory && !arg.Recursive {
+		children, lErr := c.listFilesIn(ctx, p)
+		if lErr != nil {
+			return lErr
+		}
+		if len(children) > 0 {
+			return filesystem.ErrNotEmpty
+		}
+	}
+
+	return c.send(ctx, http.MethodDelete, p, nil, nil)
+}
+
+func (c *client) transfer(ctx context.Context, method, source, target string, arg filesystem.Arguments) error {
+	r, err := c.stat(ctx, source)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return filesystem.ErrFileNotFound
+	}
+	if method == methodCopy && r.entry.IsDirectory && !arg.Recursive {
+		return filesystem.ErrDirectory
+	}
+
+	existing, err := c.stat(ctx, target)
+	if err != nil {
+		return err
+	}
+	if existing != nil && !arg.AllowOverwrite {
+		if existing.entry.IsDirectory {
+			return filesystem.ErrDirectoryFound
+		}
+		return filesystem.ErrFileFound
+	}
+	if err = c.prepareParent(ctx, target, arg); err != nil {
+		return err
+	}
+
+	overwrite := "F"
+	if arg.AllowOverwrite {
+		overwrite = "T"
+	}
+	return c.send(ctx, method, source, http.Header{
+		"Destination": {c.url(target).String()},
+		"Overwrite":   {overwrite},
+		"Depth":       {"infinity"},
+	}, nil)
+}
+
+func (c *client) listFilesIn(ctx context.Context, p string) ([]filesystem.Entry, error) {
+	resources, err := c.propfind(ctx, p, "1")
+	if err != nil {
+		return nil, err
+	}
+
+	self := cleanPath(c.url(p).Path)
+	res := make([]filesystem.Entry, 0, len(resources))
+	for _, r := range resources {
+		if r.path == self {
+			if !r.entry.IsDirectory {
+				return nil, filesystem.ErrFile
+			}
+			continue
+		}
+		res = append(res, r.entry)
+	}
+	return res, nil
+}
+
+// stat describes resource or returns nil (without error) if it does not exist.
+func (c *client) stat(ctx context.Context, p string) (*resource, error) {
+	resources, err := c.propfind(ctx, p, "0")
+	if err != nil {
+		if errors.Is(err, filesystem.ErrFileNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(resources) == 0 {
+		return nil, nil
+	}
+	return &resources[0], nil
+}
+
+// prepareParent verifies if parent collection of p exists and creates missing ones (if allowed).
+func (c *client) prepareParent(ctx context.Context, p string, arg filesystem.Arguments) error {
+	var missing []string
+	for dir := path.Dir(cleanPath(p)); dir != "/"; dir = path.Dir(dir) {
+		r, err := c.stat(ctx, dir)
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			if !r.entry.IsDirectory {
+				return fmt.Errorf("location structure does not contain valid directory as target: %w", filesystem.ErrUnresolvableDirectoryStructure)
+			}
+			break
+		}
+		missing = append(missing, dir)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !arg.AllowCreationOfDirectoryStructure {
+		return fmt.Errorf("creation of non-existing directory structure is forbidden by current settings: %w", filesystem.ErrUnresolvableDirectoryStructure)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := c.send(ctx, methodMkcol, missing[i], nil, nil); err != nil {
+			return fmt.Errorf("cannot create directory structure: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *client) propfind(ctx context.Context, p, depth string) ([]resource, error) {
+	resp, err := c.do(ctx, methodPropfind, p, http.Header{
+		"Depth":        {depth},
+		"Content-Type": {"application/xml; charset=utf-8"},
+	}, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err = expectSuccess(resp); err != nil {
+		return nil, err
+	}
+
+	var ms multistatus
+	if err = xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("cannot decode PROPFIND response: %w", err)
+	}
+
+	res := make([]resource, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		href, hErr := url.Parse(r.Href)
+		if hErr != nil {
+			return nil, fmt.Errorf("invalid href %q in PROPFIND response: %w", r.Href, hErr)
+		}
+		p := cleanPath(href.Path)
+
+		entry := filesystem.Entry{Name: path.Base(p), Mode: filesystem.ModeAllReadWrite}
+		for _, ps := range r.Propstats {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			if ps.Prop.ResourceType.Collection != nil {
+				entry.IsDirectory = true
+				entry.Mode = filesystem.ModeAllReadWriteExecute
+			}
+			if ps.Prop.ContentLength != "" {
+				entry.Size, _ = strconv.ParseInt(strings.TrimSpace(ps.Prop.ContentLength), 10, 64)
+			}
+			if ps.Prop.LastModified != "" {
+				entry.ModTime, _ = http.ParseTime(ps.Prop.LastModified)
+			}
+		}
+		res = append(res, resource{path: p, entry: entry})
+	}
+	return res, nil
+}
+
+// send performs request expecting successful response and discards it.
+func (c *client) send(ctx context.Context, method, p string, header http.Header, body io.Reader) error {
+	resp, err := c.do(ctx, method, p, header, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err = expectSuccess(resp); err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// do performs request and returns its response regardless of status (body has to be closed by the caller).
+func (c *client) do(ctx context.Context, method, p string, header http.Header, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url(p).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if c.authorize != nil {
+		c.authorize(req)
+	}
+
+	return c.http.Do(req)
+}
+
+func (c *client) url(p string) *url.URL {
+	return c.base.JoinPath(cleanPath(p))
+}
+
+// expectSuccess returns error restored from response with unsuccessful status (closing its body).
+func expectSuccess(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return responseError(resp)
+}
+
+// responseError restores sentinel error from status code of failed response.
+func responseError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return filesystem.ErrFileNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s", filesystem.ErrPermissionDenied, resp.Status)
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %s", filesystem.ErrUnresolvableDirectoryStructure, resp.Status)
+	case http.StatusPreconditionFailed:
+		return filesystem.ErrFileFound
+	case http.StatusRequestEntityTooLarge:
+		return filesystem.ErrTooLarge
+	case http.StatusInsufficientStorage:
+		return filesystem.ErrNoSpace
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorMessageLength))
+	if msg := strings.TrimSpace(string(message)); msg != "" {
+		return fmt.Errorf("unexpected response status %s: %s", resp.Status, msg)
+	}
+	return fmt.Errorf("unexpected response status %s", resp.Status)
+}
@@ -0,0 +1,243 @@
+package webdavfs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// newReferenceServer starts reference WebDAV server of golang.org/x/net/webdav serving temporary directory
+// (returned along with the server).
+func newReferenceServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	workdir := t.TempDir()
+	srv := httptest.NewServer(&webdav.Handler{FileSystem: webdav.Dir(workdir), LockSystem: webdav.NewMemLS()})
+	t.Cleanup(srv.Close)
+
+	return srv, workdir
+}
+
+// newClient creates filesystem connected to the server.
+func newClient(t *testing.T, srv *httptest.Server, opts ...options.Option) filesystem.Filesystem {
+	t.Helper()
+
+	fs, err := New(srv.URL, opts...)
+	require.NoError(t, err)
+
+	return fs
+}
+
+func TestNew(t *testing.T) {
+	t.Run("it should reject invalid URL", func(t *testing.T) {
+		t.Parallel()
+
+		// WHEN
+		_, errScheme := New("ftp://localhost")
+		_, errURL := New("http://local host")
+
+		// THEN
+		require.Error(t, errScheme)
+		require.Error(t, errURL)
+	})
+	t.Run("it should authenticate requests", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var authorizations []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		t.Cleanup(srv.Close)
+		basic, err := New(srv.URL, OptionBasicAuth("user", "secret"), OptionBackendName("dav"))
+		require.NoError(t, err)
+		bearer, err := New(srv.URL, OptionBearerToken("token"))
+		require.NoError(t, err)
+
+		// WHEN
+		_, errBasic := filesystem.CheckIfExists(basic, "test.txt")
+		_, errBearer := filesystem.CheckIfExists(bearer, "test.txt")
+
+		// THEN
+		require.ErrorIs(t, errBasic, filesystem.ErrPermissionDenied)
+		require.ErrorIs(t, errBearer, filesystem.ErrPermissionDenied)
+		assert.Equal(t, []string{"Basic dXNlcjpzZWNyZXQ=", "Bearer token"}, authorizations)
+	})
+}
+
+func TestClient(t *testing.T) {
+	t.Run("it should perform operations on WebDAV server", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newReferenceServer(t)
+		fs := newClient(t, srv)
+
+		// WHEN
+		require.NoError(t, filesystem.CreateDirectory(fs, "conf.d"))
+		require.NoError(t, filesystem.CreateFile(fs, "conf.d/a.yaml"))
+		require.NoError(t, filesystem.WriteContentTo(fs, "conf.d/a.yaml", "A", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite)))
+		require.NoError(t, filesystem.CreateFile(fs, "deep/inner/b.txt", filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.StreamContentTo(fs, "deep/inner/b.txt", strings.NewReader("B"), filesystem.WithContentOperation(filesystem.ContentOperationOverwrite)))
+
+		// THEN
+		content, err := os.ReadFile(filepath.Join(workdir, "conf.d", "a.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "A", string(content))
+
+		read, err := filesystem.ReadContentOf(fs, "deep/inner/b.txt")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("B"), read)
+
+		stream, err := filesystem.StreamContentOf(fs, "/conf.d/a.yaml")
+		require.NoError(t, err)
+		streamed, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, "A", string(streamed))
+
+		for p, expected := range map[string]bool{"conf.d": true, "deep/inner": true, "conf.d/a.yaml": true, "conf.d/missing": false, ".": true} {
+			exists, eErr := filesystem.CheckIfExists(fs, p)
+			require.NoError(t, eErr)
+			assert.Equal(t, expected, exists, p)
+		}
+	})
+	t.Run("it should reject append unless emulation is enabled", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newReferenceServer(t)
+		fs := newClient(t, srv)
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("A"), 0o644))
+		emulating := newClient(t, srv, OptionEmulateAppend(true))
+
+		// WHEN
+		errRejected := filesystem.WriteContentTo(fs, "test.txt", "B")
+		errEmulated := filesystem.WriteContentTo(emulating, "test.txt", "B")
+		errStreamed := filesystem.StreamContentTo(emulating, "test.txt", strings.NewReader("C"))
+
+		// THEN
+		require.ErrorIs(t, errRejected, filesystem.ErrUnsupportedContentOperation)
+		require.NoError(t, errEmulated)
+		require.NoError(t, errStreamed)
+		content, _ := os.ReadFile(filepath.Join(workdir, "test.txt"))
+		assert.Equal(t, "ABC", string(content))
+	})
+	t.Run("it should report sentinel errors", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newReferenceServer(t)
+		fs := newClient(t, srv)
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("TEST"), 0o644))
+		require.NoError(t, os.MkdirAll(filepath.Join(workdir, "dir"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "dir", "inner.txt"), []byte("INNER"), 0o644))
+
+		// WHEN
+		_, errMissing := filesystem.ReadContentOf(fs, "missing.txt")
+		_, errDirectory := filesystem.ReadContentOf(fs, "dir")
+		errWriteMissing := filesystem.WriteContentTo(fs, "missing.txt", "TEST", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite))
+		errFileFound := filesystem.CreateFile(fs, "test.txt")
+		errDirectoryFound := filesystem.CreateDirectory(fs, "dir")
+		errFile := filesystem.CreateDirectory(fs, "test.txt")
+		errStructure := filesystem.CreateFile(fs, "missing/test.txt")
+		errStructureOfFile := filesystem.CreateFile(fs, "test.txt/test.txt", filesystem.WithAllowCreationOfDirectoryStructure(true))
+		errNotEmpty := filesystem.Remove(fs, "dir")
+		errRemoveMissing := filesystem.Remove(fs, "missing")
+		errCopyDirectory := filesystem.Copy(fs, "dir", "copy")
+		errMoveOnto := filesystem.Move(fs, "test.txt", "dir/inner.txt")
+		_, errListFile := filesystem.ListFilesIn(fs, "test.txt")
+		_, errListMissing := filesystem.ListFilesIn(fs, "missing")
+
+		// THEN
+		require.ErrorIs(t, errMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errWriteMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errFileFound, filesystem.ErrFileFound)
+		require.ErrorIs(t, errDirectoryFound, filesystem.ErrDirectoryFound)
+		require.ErrorIs(t, errFile, filesystem.ErrFile)
+		require.ErrorIs(t, errStructure, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errStructureOfFile, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errNotEmpty, filesystem.ErrNotEmpty)
+		require.ErrorIs(t, errRemoveMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errCopyDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errMoveOnto, filesystem.ErrFileFound)
+		require.ErrorIs(t, errListFile, filesystem.ErrFile)
+		require.ErrorIs(t, errListMissing, filesystem.ErrFileNotFound)
+	})
+	t.Run("it should remove, move and copy files and directories", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newReferenceServer(t)
+		fs := newClient(t, srv)
+		require.NoError(t, os.MkdirAll(filepath.Join(workdir, "src", "inner"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "src", "a.txt"), []byte("A"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "src", "inner", "b.txt"), []byte("B"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("TEST"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "existing.txt"), []byte("OLD"), 0o644))
+		require.NoError(t, os.MkdirAll(filepath.Join(workdir, "empty"), 0o755))
+
+		// WHEN
+		require.NoError(t, filesystem.Copy(fs, "src", "dst/copy", filesystem.WithRecursive(true), filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.Move(fs, "test.txt", "existing.txt", filesystem.WithAllowOverwrite(true)))
+		require.NoError(t, filesystem.Move(fs, "src", "moved"))
+		require.NoError(t, filesystem.Remove(fs, "empty"))
+
+		// THEN
+		for p, expected := range map[string]string{"dst/copy/a.txt": "A", "dst/copy/inner/b.txt": "B", "moved/inner/b.txt": "B", "existing.txt": "TEST"} {
+			content, err := os.ReadFile(filepath.Join(workdir, p))
+			require.NoError(t, err, p)
+			assert.Equal(t, expected, string(content), p)
+		}
+		for _, p := range []string{"src", "test.txt", "empty"} {
+			_, err := os.Stat(filepath.Join(workdir, p))
+			require.ErrorIs(t, err, os.ErrNotExist, p)
+		}
+	})
+	t.Run("it should list entries of directory", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newReferenceServer(t)
+		fs := newClient(t, srv)
+		require.NoError(t, os.MkdirAll(filepath.Join(workdir, "dir", "inner"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "dir", "a.txt"), []byte("A"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "dir", "b c.txt"), []byte("BB"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "dir", "inner", "d.txt"), []byte("D"), 0o644))
+
+		// WHEN
+		entries, listErr := filesystem.ListFilesIn(fs, "dir")
+		paths, globErr := filesystem.Glob(fs, "dir/**/*.txt")
+
+		// THEN
+		require.NoError(t, listErr)
+		require.NoError(t, globErr)
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name)
+			switch e.Name {
+			case "b c.txt":
+				assert.Equal(t, int64(2), e.Size)
+				assert.Equal(t, filesystem.ModeAllReadWrite, e.Mode)
+				assert.False(t, e.ModTime.IsZero())
+			case "inner":
+				assert.True(t, e.IsDirectory)
+			}
+		}
+		assert.ElementsMatch(t, []string{"a.txt", "b c.txt", "inner"}, names)
+		assert.Equal(t, []string{"dir/a.txt", "dir/b c.txt", "dir/inner/d.txt"}, paths)
+	})
+}
@@ -0,0 +1,211 @@
+package webdavfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// errNotWritable is reported by Write of files opened without os.O_TRUNC or os.O_APPEND.
+var errNotWritable = errors.New("file is not opened for writing")
+
+type (
+	// readFile reads content lazily (opening stream with first Read) and lists directories with ListFilesIn.
+	readFile struct {
+		fs       filesystem.Filesystem
+		listings *listings
+		path     string
+		entry    filesystem.Entry
+
+		stream   io.ReadCloser
+		position int64 // position of stream
+		offset   int64 // position requested by Seek
+
+		listed  bool
+		entries []filesystem.Entry
+	}
+
+	// writeFile streams everything written to the file with StreamContentTo running in background.
+	writeFile struct {
+		listings *listings
+		path     string
+		entry    filesystem.Entry
+		pipe     *io.PipeWriter
+		done     chan error
+		written  int64
+		closed   bool
+	}
+)
+
+func (f *readFile) Read(p []byte) (int, error) {
+	if f.entry.IsDirectory {
+		return 0, osError("read", f.path, filesystem.ErrDirectory)
+	}
+	if err := f.prepareStream(); err != nil {
+		return 0, err
+	}
+
+	n, err := f.stream.Read(p)
+	f.position += int64(n)
+	f.offset = f.position
+	return n, err
+}
+
+func (f *readFile) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = f.offset + offset
+	case io.SeekEnd:
+		abs = f.entry.Size + offset
+	default:
+		return 0, osError("seek", f.path, fmt.Errorf("invalid whence %d", whence))
+	}
+	if abs < 0 {
+		return 0, osError("seek", f.path, errors.New("negative position"))
+	}
+	f.offset = abs
+	return abs, nil
+}
+
+func (f *readFile) Readdir(count int) ([]fs.FileInfo, error) {
+	if !f.entry.IsDirectory {
+		return nil, osError("readdir", f.path, filesystem.ErrFile)
+	}
+	if !f.listed {
+		entries, err := f.listings.list(f.fs, f.path)
+		if err != nil {
+			return nil, osError("readdir", f.path, err)
+		}
+		f.entries, f.listed = entries, true
+	}
+
+	n := len(f.entries)
+	if count > 0 {
+		if n == 0 {
+			return nil, io.EOF
+		}
+		n = min(count, n)
+	}
+	res := make([]fs.FileInfo, 0, n)
+	for _, e := range f.entries[:n] {
+		res = append(res, fileInfo{entry: e})
+	}
+	f.entries = f.entries[n:]
+	return res, nil
+}
+
+func (f *readFile) Stat() (fs.FileInfo, error) {
+	return fileInfo{entry: f.entry}, nil
+}
+
+func (f *readFile) Write([]byte) (int, error) {
+	return 0, osError("write", f.path, errNotWritable)
+}
+
+func (f *readFile) Close() error {
+	if f.stream == nil {
+		return nil
+	}
+	return f.stream.Close()
+}
+
+// prepareStream moves stream to the offset requested by Seek. Seekable streams are seeked, others are skipped
+// forward or reopened when offset is behind current position.
+func (f *readFile) prepareStream() error {
+	if f.stream != nil && f.position == f.offset {
+		return nil
+	}
+	if seeker, ok := f.stream.(io.Seeker); ok {
+		position, err := seeker.Seek(f.offset, io.SeekStart)
+		if err != nil {
+			return osError("seek", f.path, err)
+		}
+		f.position = position
+		return nil
+	}
+	if f.stream == nil || f.offset < f.position {
+		if f.stream != nil {
+			_ = f.stream.Close()
+		}
+		stream, err := filesystem.StreamContentOf(f.fs, f.path)
+		if err != nil {
+			f.stream = nil
+			return osError("open", f.path, err)
+		}
+		f.stream, f.position = stream, 0
+		if f.offset == 0 {
+			return nil
+		}
+		return f.prepareStream()
+	}
+
+	skipped, err := io.CopyN(io.Discard, f.stream, f.offset-f.position)
+	f.position += skipped
+	if err != nil && !errors.Is(err, io.EOF) {
+		return osError("seek", f.path, err)
+	}
+	return nil
+}
+
+func newWriteFile(fs filesystem.Filesystem, cached *listings, path string, entry filesystem.Entry, operation filesystem.ContentOperation) *writeFile {
+	r, w := io.Pipe()
+	f := &writeFile{listings: cached, path: path, entry: entry, pipe: w, done: make(chan error, 1)}
+
+	go func() {
+		err := filesystem.StreamContentTo(fs, path, r, filesystem.WithContentOperation(operation))
+		_ = r.CloseWithError(err)
+		f.done <- err
+	}()
+
+	if operation.Is(filesystem.ContentOperationOverwrite) {
+		f.entry.Size = 0
+	}
+	return f
+}
+
+func (f *writeFile) Write(p []byte) (int, error) {
+	n, err := f.pipe.Write(p)
+	f.written += int64(n)
+	if err != nil {
+		return n, osError("write", f.path, err)
+	}
+	return n, nil
+}
+
+func (f *writeFile) Close() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+
+	_ = f.pipe.Close()
+	defer f.listings.reset()
+	return osError("close", f.path, <-f.done)
+}
+
+func (f *writeFile) Stat() (fs.FileInfo, error) {
+	entry := f.entry
+	entry.Size += f.written
+	entry.ModTime = time.Now()
+	return fileInfo{entry: entry}, nil
+}
+
+func (f *writeFile) Read([]byte) (int, error) {
+	return 0, osError("read", f.path, errors.New("file is opened for writing only"))
+}
+
+func (f *writeFile) Seek(int64, int) (int64, error) {
+	return 0, osError("seek", f.path, errors.New("file is opened for writing only"))
+}
+
+func (f *writeFile) Readdir(int) ([]fs.FileInfo, error) {
+	return nil, osError("readdir", f.path, filesystem.ErrFile)
+}
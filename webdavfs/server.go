@@ -0,0 +1,313 @@
+package webdavfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"golang.org/x/net/webdav"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionPrefix      options.OptionKey = `webdav_prefix`
+	optionLockSystem  options.OptionKey = `webdav_lock_system`
+	optionErrorLogger options.OptionKey = `webdav_error_logger`
+)
+
+// osErrors lists sentinel errors reported by adapter as errors of io/fs (webdav.Handler recognizes only them).
+var osErrors = []struct {
+	sentinels []error
+	err       error
+}{
+	{sentinels: []error{filesystem.ErrFileNotFound, filesystem.ErrUnresolvableDirectoryStructure}, err: fs.ErrNotExist},
+	{sentinels: []error{filesystem.ErrFileFound, filesystem.ErrDirectoryFound}, err: fs.ErrExist},
+	{sentinels: []error{filesystem.ErrPermissionDenied, filesystem.ErrReadOnly}, err: fs.ErrPermission},
+}
+
+type (
+	// ErrorLoggerFunc receives every failure of request handled by webdav.Handler.
+	ErrorLoggerFunc func(r *http.Request, err error)
+
+	adapter struct {
+		fs filesystem.Filesystem
+	}
+
+	fileInfo struct {
+		entry filesystem.Entry
+	}
+
+	// listings keeps content of directories listed during single request. PROPFIND opens every child
+	// of described directory separately, so each of them would list the same parent again otherwise.
+	listings struct {
+		mu      sync.Mutex
+		entries map[string][]filesystem.Entry
+	}
+
+	listingsKey struct{}
+)
+
+// OptionPrefix changes URL path prefix stripped from requests before they are resolved in filesystem.
+func OptionPrefix(prefix string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionPrefix, prefix)
+	}
+}
+
+// OptionLockSystem changes webdav.LockSystem used by handler (default is webdav.NewMemLS).
+func OptionLockSystem(ls webdav.LockSystem) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[webdav.LockSystem](r, optionLockSystem, ls)
+	}
+}
+
+// OptionErrorLogger registers function receiving every failure of handled requests.
+func OptionErrorLogger(logger ErrorLoggerFunc) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[ErrorLoggerFunc](r, optionErrorLogger, logger)
+	}
+}
+
+// NewFileSystem adapts fs to webdav.FileSystem. Every call is performed with context of the request
+// (see filesystem.InContext) and names are resolved relative to root of fs (use filesystem.Sub to confine
+// it to a directory).
+//
+// Files opened for writing have to be truncated (os.O_TRUNC) or appended to (os.O_APPEND) as only whole content
+// can be streamed to the filesystem. Content is written when File is closed. Files opened for reading
+// are seekable: backends returning io.Seeker are seeked directly, otherwise content is skipped or reopened.
+func NewFileSystem(fs filesystem.Filesystem) webdav.FileSystem {
+	return &adapter{fs: fs}
+}
+
+// NewHandler creates http.Handler serving fs over WebDAV (see NewFileSystem).
+// Directories listed while handling a request are remembered until it's handled (or until modification
+// performed within it), so describing every child of a directory with PROPFIND lists it only once.
+func NewHandler(fs filesystem.Filesystem, opts ...options.Option) (http.Handler, error) {
+	opt := options.Resolve(opts)
+
+	optPrefix, err := options.ReadOrDefault[string](opt, optionPrefix, "")
+	if err != nil {
+		return nil, fmt.Errorf("webdav handler initialization failed: %w", err)
+	}
+	optLockSystem, err := options.ReadOrDefault[webdav.LockSystem](opt, optionLockSystem, nil)
+	if err != nil {
+		return nil, fmt.Errorf("webdav handler initialization failed: %w", err)
+	}
+	optErrorLogger, err := options.ReadOrDefault[ErrorLoggerFunc](opt, optionErrorLogger, nil)
+	if err != nil {
+		return nil, fmt.Errorf("webdav handler initialization failed: %w", err)
+	}
+
+	if optLockSystem == nil {
+		optLockSystem = webdav.NewMemLS()
+	}
+	h := &webdav.Handler{Prefix: optPrefix, FileSystem: NewFileSystem(fs), LockSystem: optLockSystem}
+	if optErrorLogger != nil {
+		h.Logger = func(r *http.Request, err error) {
+			if err != nil {
+				optErrorLogger(r, err)
+			}
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), listingsKey{}, &listings{entries: make(map[string][]filesystem.Entry)})
+		h.ServeHTTP(w, r.WithContext(ctx))
+	}), nil
+}
+
+func (a *adapter) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	p := relativePath(name)
+	defer listingsOf(ctx).reset()
+	err := filesystem.CreateDirectory(filesystem.InContext(a.fs, ctx), p, filesystem.WithMode(filesystem.Mode(perm.Perm())))
+	if errors.Is(err, filesystem.ErrFile) {
+		// File found in place of the directory is reported like by os.Mkdir.
+		err = fmt.Errorf("%w: %w", filesystem.ErrFileFound, err)
+	}
+	return osError("mkdir", name, err)
+}
+
+func (a *adapter) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	fs := filesystem.InContext(a.fs, ctx)
+	p := relativePath(name)
+	cached := listingsOf(ctx)
+
+	entry, err := stat(fs, cached, p)
+	exists := err == nil
+	if err != nil && !errors.Is(err, filesystem.ErrFileNotFound) {
+		return nil, osError("open", name, err)
+	}
+
+	switch {
+	case exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, osError("open", name, filesystem.ErrFileFound)
+	case !exists && flag&os.O_CREATE == 0:
+		return nil, osError("open", name, filesystem.ErrFileNotFound)
+	}
+
+	writing := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if !writing || (exists && flag&(os.O_TRUNC|os.O_APPEND) == 0 && flag&os.O_RDWR != 0) {
+		if !exists {
+			return nil, osError("open", name, filesystem.ErrFileNotFound)
+		}
+		return &readFile{fs: fs, listings: cached, path: p, entry: entry}, nil
+	}
+
+	if flag&(os.O_TRUNC|os.O_APPEND) == 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.ErrUnsupported}
+	}
+	if exists && entry.IsDirectory {
+		return nil, osError("open", name, filesystem.ErrDirectory)
+	}
+	cached.reset()
+	if !exists {
+		if err = filesystem.CreateFile(fs, p, filesystem.WithMode(filesystem.Mode(perm.Perm()))); err != nil {
+			return nil, osError("open", name, err)
+		}
+		entry = filesystem.Entry{Name: path.Base(p), Mode: filesystem.Mode(perm.Perm())}
+	}
+
+	operation := filesystem.ContentOperationOverwrite
+	if flag&os.O_APPEND != 0 && flag&os.O_TRUNC == 0 {
+		operation = filesystem.ContentOperationAppend
+	}
+	return newWriteFile(fs, cached, p, entry, operation), nil
+}
+
+func (a *adapter) RemoveAll(ctx context.Context, name string) error {
+	defer listingsOf(ctx).reset()
+	err := filesystem.Remove(filesystem.InContext(a.fs, ctx), relativePath(name), filesystem.WithRecursive(true))
+	if errors.Is(err, filesystem.ErrFileNotFound) {
+		return nil
+	}
+	return osError("remove", name, err)
+}
+
+func (a *adapter) Rename(ctx context.Context, oldName, newName string) error {
+	defer listingsOf(ctx).reset()
+	err := filesystem.Move(filesystem.InContext(a.fs, ctx), relativePath(oldName), relativePath(newName))
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: underlyingError(err)}
+	}
+	return nil
+}
+
+func (a *adapter) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	entry, err := stat(filesystem.InContext(a.fs, ctx), listingsOf(ctx), relativePath(name))
+	if err != nil {
+		return nil, osError("stat", name, err)
+	}
+	return fileInfo{entry: entry}, nil
+}
+
+// stat describes element found in its parent directory (root is always a directory).
+func stat(fs filesystem.Filesystem, cached *listings, p string) (filesystem.Entry, error) {
+	if p == "." {
+		return filesystem.Entry{Name: "/", Mode: filesystem.ModeAllReadWriteExecute, IsDirectory: true}, nil
+	}
+
+	entries, err := cached.list(fs, path.Dir(p))
+	if err != nil {
+		if errors.Is(err, filesystem.ErrFile) {
+			return filesystem.Entry{}, filesystem.ErrFileNotFound
+		}
+		return filesystem.Entry{}, err
+	}
+	name := path.Base(p)
+	for _, e := range entries {
+		if e.Name == name {
+			return e, nil
+		}
+	}
+	return filesystem.Entry{}, filesystem.ErrFileNotFound
+}
+
+// listingsOf returns listings of the request (nil when adapter is used without NewHandler).
+func listingsOf(ctx context.Context) *listings {
+	l, _ := ctx.Value(listingsKey{}).(*listings)
+	return l
+}
+
+// list returns entries of directory listing it only if it wasn't listed before.
+func (l *listings) list(fs filesystem.Filesystem, dir string) ([]filesystem.Entry, error) {
+	if l == nil {
+		return filesystem.ListFilesIn(fs, dir)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if entries, ok := l.entries[dir]; ok {
+		return entries, nil
+	}
+	entries, err := filesystem.ListFilesIn(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	l.entries[dir] = entries
+	return entries, nil
+}
+
+// reset forgets every listing (after modification of the filesystem).
+func (l *listings) reset() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clear(l.entries)
+}
+
+// relativePath converts name used by webdav.Handler to path relative to root of the filesystem.
+func relativePath(name string) string {
+	p := strings.TrimPrefix(cleanPath(name), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// osError wraps err in *os.PathError (see underlyingError).
+func osError(op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &os.PathError{Op: op, Path: name, Err: underlyingError(err)}
+}
+
+// underlyingError replaces sentinel errors of filesystem with their io/fs equivalents. They are returned
+// unwrapped because webdav.Handler checks them with os.IsNotExist and os.IsExist.
+func underlyingError(err error) error {
+	for _, m := range osErrors {
+		for _, sentinel := range m.sentinels {
+			if errors.Is(err, sentinel) {
+				return m.err
+			}
+		}
+	}
+	return err
+}
+
+func (i fileInfo) Name() string       { return i.entry.Name }
+func (i fileInfo) Size() int64        { return i.entry.Size }
+func (i fileInfo) ModTime() time.Time { return i.entry.ModTime }
+func (i fileInfo) IsDir() bool        { return i.entry.IsDirectory }
+func (i fileInfo) Sys() any           { return i.entry }
+
+func (i fileInfo) Mode() fs.FileMode {
+	mode := fs.FileMode(i.entry.Mode).Perm()
+	if i.entry.IsDirectory {
+		mode |= fs.ModeDir
+	}
+	return mode
+}
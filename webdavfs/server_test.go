@@ -0,0 +1,263 @@
+package webdavfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SevenOfSpades/go-just-options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// newAdapterServer starts server exposing temporary directory with NewHandler (returned along with the server).
+func newAdapterServer(t *testing.T, opts ...options.Option) (*httptest.Server, string) {
+	t.Helper()
+
+	workdir := t.TempDir()
+	local, err := filesystem.New()
+	require.NoError(t, err)
+
+	h, err := NewHandler(filesystem.Sub(local, workdir), opts...)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	return srv, workdir
+}
+
+// request performs request against the server and returns its status and body.
+func request(t *testing.T, srv *httptest.Server, method, p string, header http.Header, body string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+p, strings.NewReader(body))
+	require.NoError(t, err)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp.StatusCode, string(content)
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Run("it should perform operations requested by client", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newAdapterServer(t)
+		fs := newClient(t, srv)
+
+		// WHEN
+		require.NoError(t, filesystem.CreateDirectory(fs, "conf.d"))
+		require.NoError(t, filesystem.CreateFile(fs, "conf.d/a.yaml"))
+		require.NoError(t, filesystem.WriteContentTo(fs, "conf.d/a.yaml", "A", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite)))
+		require.NoError(t, filesystem.Copy(fs, "conf.d", "backup/conf.d", filesystem.WithRecursive(true), filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.Move(fs, "conf.d/a.yaml", "conf.d/b.yaml"))
+		require.NoError(t, filesystem.CreateFile(fs, "tmp.txt"))
+		require.NoError(t, filesystem.Remove(fs, "tmp.txt"))
+
+		// THEN
+		content, err := filesystem.ReadContentOf(fs, "conf.d/b.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("A"), content)
+
+		copied, err := os.ReadFile(filepath.Join(workdir, "backup", "conf.d", "a.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "A", string(copied))
+
+		entries, err := filesystem.ListFilesIn(fs, "/")
+		require.NoError(t, err)
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name)
+			assert.True(t, e.IsDirectory, e.Name)
+		}
+		assert.ElementsMatch(t, []string{"backup", "conf.d"}, names)
+
+		_, err = filesystem.ReadContentOf(fs, "conf.d/a.yaml")
+		require.ErrorIs(t, err, filesystem.ErrFileNotFound)
+	})
+	t.Run("it should respond with status codes of WebDAV", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		srv, workdir := newAdapterServer(t)
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("0123456789"), 0o644))
+		require.NoError(t, os.MkdirAll(filepath.Join(workdir, "dir"), 0o755))
+
+		// WHEN
+		statusRange, bodyRange := request(t, srv, http.MethodGet, "/test.txt", http.Header{"Range": {"bytes=2-4"}}, "")
+		statusMissing, _ := request(t, srv, http.MethodGet, "/missing.txt", nil, "")
+		statusDirectory, _ := request(t, srv, http.MethodGet, "/dir", nil, "")
+		statusPut, _ := request(t, srv, http.MethodPut, "/dir/new.txt", nil, "NEW")
+		statusPutStructure, _ := request(t, srv, http.MethodPut, "/missing/new.txt", nil, "NEW")
+		statusMkcolFound, _ := request(t, srv, "MKCOL", "/dir", nil, "")
+		statusMkcolStructure, _ := request(t, srv, "MKCOL", "/missing/dir", nil, "")
+		statusMoveFound, _ := request(t, srv, "MOVE", "/dir/new.txt", http.Header{"Destination": {srv.URL + "/test.txt"}, "Overwrite": {"F"}}, "")
+		statusPropfind, bodyPropfind := request(t, srv, "PROPFIND", "/", http.Header{"Depth": {"1"}}, "")
+		statusDelete, _ := request(t, srv, http.MethodDelete, "/dir", nil, "")
+
+		// THEN
+		assert.Equal(t, http.StatusPartialContent, statusRange)
+		assert.Equal(t, "234", bodyRange)
+		assert.Equal(t, http.StatusNotFound, statusMissing)
+		assert.Equal(t, http.StatusMethodNotAllowed, statusDirectory)
+		assert.Equal(t, http.StatusCreated, statusPut)
+		assert.Equal(t, http.StatusNotFound, statusPutStructure)
+		assert.Equal(t, http.StatusMethodNotAllowed, statusMkcolFound)
+		assert.Equal(t, http.StatusConflict, statusMkcolStructure)
+		assert.Equal(t, http.StatusPreconditionFailed, statusMoveFound)
+		assert.Equal(t, http.StatusMultiStatus, statusPropfind)
+		assert.Contains(t, bodyPropfind, "<D:href>/test.txt</D:href>")
+		assert.Contains(t, bodyPropfind, "<D:href>/dir/</D:href>")
+		assert.Equal(t, http.StatusNoContent, statusDelete)
+		_, err := os.Stat(filepath.Join(workdir, "dir"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+	t.Run("it should strip prefix and log failures", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var logged []error
+		srv, workdir := newAdapterServer(t, OptionPrefix("/dav"), OptionErrorLogger(func(_ *http.Request, err error) {
+			logged = append(logged, err)
+		}))
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("TEST"), 0o644))
+
+		// WHEN
+		status, body := request(t, srv, http.MethodGet, "/dav/test.txt", nil, "")
+		statusMissing, _ := request(t, srv, http.MethodGet, "/dav/missing.txt", nil, "")
+
+		// THEN
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "TEST", body)
+		assert.Equal(t, http.StatusNotFound, statusMissing)
+		require.Len(t, logged, 1)
+		assert.True(t, os.IsNotExist(logged[0]))
+	})
+	t.Run("it should list every directory once while describing its children", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		workdir := t.TempDir()
+		for i := 0; i < 20; i++ {
+			require.NoError(t, os.WriteFile(filepath.Join(workdir, fmt.Sprintf("%02d.txt", i)), []byte("TEST"), 0o644))
+		}
+		local, err := filesystem.New()
+		require.NoError(t, err)
+
+		var listings atomic.Int32
+		counted := filesystem.Wrap(filesystem.Sub(local, workdir), func(next filesystem.Invoker) filesystem.Invoker {
+			return func(call filesystem.Call) (filesystem.Result, error) {
+				if call.Operation == filesystem.OperationListFilesIn {
+					listings.Add(1)
+				}
+				return next(call)
+			}
+		})
+		h, err := NewHandler(counted)
+		require.NoError(t, err)
+		srv := httptest.NewServer(h)
+		t.Cleanup(srv.Close)
+
+		// WHEN
+		status, body := request(t, srv, "PROPFIND", "/", http.Header{"Depth": {"1"}}, "")
+
+		// THEN
+		assert.Equal(t, http.StatusMultiStatus, status)
+		assert.Contains(t, body, "<D:href>/19.txt</D:href>")
+		assert.Equal(t, int32(1), listings.Load())
+	})
+}
+
+func TestNewFileSystem(t *testing.T) {
+	t.Run("it should report errors of io/fs", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		workdir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("TEST"), 0o644))
+		local, err := filesystem.New()
+		require.NoError(t, err)
+		fs := NewFileSystem(filesystem.Sub(local, workdir))
+		ctx := context.Background()
+
+		// WHEN
+		_, errStat := fs.Stat(ctx, "/missing.txt")
+		_, errOpen := fs.OpenFile(ctx, "/missing.txt", os.O_RDONLY, 0)
+		_, errExclusive := fs.OpenFile(ctx, "/test.txt", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+		errMkdirFound := fs.Mkdir(ctx, "/test.txt", 0o755)
+		errMkdirStructure := fs.Mkdir(ctx, "/missing/dir", 0o755)
+		errRemoveMissing := fs.RemoveAll(ctx, "/missing")
+		errReadOnly := NewFileSystem(filesystem.ReadOnly(filesystem.Sub(local, workdir))).Mkdir(ctx, "/dir", 0o755)
+
+		// THEN
+		assert.True(t, os.IsNotExist(errStat))
+		assert.True(t, os.IsNotExist(errOpen))
+		assert.True(t, os.IsExist(errExclusive))
+		assert.True(t, os.IsExist(errMkdirFound))
+		assert.True(t, os.IsNotExist(errMkdirStructure))
+		require.NoError(t, errRemoveMissing)
+		assert.True(t, os.IsPermission(errReadOnly))
+	})
+	t.Run("it should seek and append content of files", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		workdir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(workdir, "test.txt"), []byte("0123456789"), 0o644))
+		local, err := filesystem.New()
+		require.NoError(t, err)
+		fs := NewFileSystem(filesystem.Sub(local, workdir))
+		ctx := context.Background()
+
+		// WHEN
+		w, err := fs.OpenFile(ctx, "/test.txt", os.O_WRONLY|os.O_APPEND, 0)
+		require.NoError(t, err)
+		_, err = w.Write([]byte("ABC"))
+		require.NoError(t, err)
+		info, err := w.Stat()
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		r, err := fs.OpenFile(ctx, "/test.txt", os.O_RDONLY, 0)
+		require.NoError(t, err)
+		end, err := r.Seek(0, io.SeekEnd)
+		require.NoError(t, err)
+		_, err = r.Seek(8, io.SeekStart)
+		require.NoError(t, err)
+		tail, err := io.ReadAll(r)
+		require.NoError(t, err)
+		_, err = r.Seek(2, io.SeekStart)
+		require.NoError(t, err)
+		head := make([]byte, 3)
+		_, err = io.ReadFull(r, head)
+		require.NoError(t, err)
+		_, errWrite := r.Write([]byte("X"))
+		require.NoError(t, r.Close())
+
+		// THEN
+		assert.Equal(t, int64(13), info.Size())
+		assert.Equal(t, int64(13), end)
+		assert.Equal(t, "89ABC", string(tail))
+		assert.Equal(t, "234", string(head))
+		require.True(t, errors.Is(errWrite, errNotWritable))
+	})
+}
@@ -0,0 +1,34 @@
+// Package webdavfs provides filesystem.Filesystem backed by WebDAV server (see New) and adapter serving
+// any filesystem.Filesystem over WebDAV with golang.org/x/net/webdav (see NewFileSystem and NewHandler).
+//
+// Client maps operations to requests as follows:
+//
+//	ReadContentOf, StreamContentOf  GET
+//	CheckIfExists                   PROPFIND (Depth: 0)
+//	CreateFile                      PUT (with empty body)
+//	WriteContentTo, StreamContentTo PUT
+//	CreateDirectory                 MKCOL
+//	Remove                          DELETE
+//	Move, Copy                      MOVE, COPY (with Destination and Overwrite headers)
+//	ListFilesIn                     PROPFIND (Depth: 1)
+//
+// WebDAV has no way to append content, so ContentOperationAppend (default of WriteContentTo and StreamContentTo)
+// is rejected with filesystem.ErrUnsupportedContentOperation unless OptionEmulateAppend is enabled.
+// Modes are not part of the protocol: Mode arguments are ignored by the client and entries are reported
+// with filesystem.ModeAllReadWrite (filesystem.ModeAllReadWriteExecute for collections).
+//
+// Sentinel errors are mapped in both directions: client restores them from status codes (404 as
+// filesystem.ErrFileNotFound, 403 as filesystem.ErrPermissionDenied, etc.) and adapter reports them
+// as errors of io/fs (fs.ErrNotExist, fs.ErrExist, fs.ErrPermission) which webdav.Handler turns into
+// status codes.
+package webdavfs
+
+import (
+	"path"
+	"strings"
+)
+
+// cleanPath converts path to the form used in URLs (slash separated, rooted, without "." and "..").
+func cleanPath(p string) string {
+	return path.Clean("/" + strings.ReplaceAll(p, `\`, "/"))
+}
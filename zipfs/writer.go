@@ -0,0 +1,490 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// ErrNotRewindable is reported by Flush of Writer whose destination already received the archive and cannot
+// be truncated to receive it again (see NewWriter).
+var ErrNotRewindable = errors.New("destination of the archive cannot be rewritten")
+
+type (
+	// Writer is filesystem.Filesystem accumulating files and directories in memory and writing them as zip archive
+	// with Flush or Close. Content written so far can be read back. It's safe for concurrent use.
+	Writer struct {
+		filesystem.Filesystem
+
+		mu          sync.RWMutex
+		dest        io.Writer
+		closer      io.Closer
+		compression uint16
+		modTime     time.Time
+		entries     map[string]*entry
+		written     bool
+		dirty       bool
+		closed      bool
+	}
+
+	// rewindable destination can be cleared before the archive is written again (e.g. *os.File).
+	rewindable interface {
+		io.Seeker
+		Truncate(size int64) error
+	}
+
+	entry struct {
+		isDirectory bool
+		content     []byte
+		mode        filesystem.Mode
+		modTime     time.Time
+	}
+)
+
+// Create creates (or truncates) local file which will receive the archive (see NewWriter).
+// Every Flush rewrites the file with current content of the archive.
+func Create(name string, opts ...options.Option) (*Writer, error) {
+	f, err := os.Create(name) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("zip filesystem initialization failed: %w", err)
+	}
+
+	w, err := NewWriter(f, opts...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// NewWriter creates Writer writing the archive to w. Archive can be written to w only once unless it can be
+// rewound (implements io.Seeker and Truncate like *os.File), further Flush calls report ErrNotRewindable.
+// Closing Writer does not close w.
+func NewWriter(w io.Writer, opts ...options.Option) (*Writer, error) {
+	opt := options.Resolve(opts)
+
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("zip filesystem initialization failed: %w", err)
+	}
+	optCompression, err := options.ReadOrDefault[uint16](opt, optionCompression, zip.Deflate)
+	if err != nil {
+		return nil, fmt.Errorf("zip filesystem initialization failed: %w", err)
+	}
+	optModificationTime, err := options.ReadOrDefault[time.Time](opt, optionModificationTime, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("zip filesystem initialization failed: %w", err)
+	}
+
+	zw := &Writer{
+		dest:        w,
+		compression: optCompression,
+		modTime:     optModificationTime,
+		entries:     make(map[string]*entry),
+		dirty:       true,
+	}
+	zw.Filesystem, err = filesystem.New(
+		filesystem.OptionBackendName(optBackendName),
+		filesystem.OptionReadContentOfHandler(zw.readContentOf),
+		filesystem.OptionStreamContentOfHandler(func(p string) (io.ReadCloser, error) {
+			content, rErr := zw.readContentOf(p)
+			if rErr != nil {
+				return nil, rErr
+			}
+			return io.NopCloser(bytes.NewReader(content)), nil
+		}),
+		filesystem.OptionCheckIfExistsHandler(zw.checkIfExists),
+		filesystem.OptionCreateFileHandler(zw.createFile),
+		filesystem.OptionWriteContentToHandler(zw.writeContentTo),
+		filesystem.OptionStreamContentToHandler(func(p string, content io.Reader, arg filesystem.Arguments) error {
+			data, rErr := io.ReadAll(content)
+			if rErr != nil {
+				return rErr
+			}
+			return zw.writeContentTo(p, data, arg)
+		}),
+		filesystem.OptionCreateDirectory(zw.createDirectory),
+		filesystem.OptionRemoveHandler(zw.remove),
+		filesystem.OptionMoveHandler(func(source, target string, arg filesystem.Arguments) error {
+			return zw.transfer(source, target, arg, true)
+		}),
+		filesystem.OptionCopyHandler(func(source, target string, arg filesystem.Arguments) error {
+			return zw.transfer(source, target, arg, false)
+		}),
+		filesystem.OptionListFilesInHandler(zw.listFilesIn),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("zip filesystem initialization failed: %w", err)
+	}
+	return zw, nil
+}
+
+// Flush writes current content to the destination (replacing archive written previously). It does nothing
+// when nothing changed since the last Flush.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.flush()
+}
+
+// Close flushes the archive (see Flush) and closes file created with Create. Every operation performed
+// afterwards fails with fs.ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fs.ErrClosed
+	}
+	err := w.flush()
+	w.closed = true
+	if w.closer != nil {
+		if cErr := w.closer.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+func (w *Writer) flush() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	if !w.dirty {
+		return nil
+	}
+	if w.written {
+		r, ok := w.dest.(rewindable)
+		if !ok {
+			return ErrNotRewindable
+		}
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := r.Truncate(0); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(w.entries))
+	for name := range w.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w.dest)
+	for _, name := range names {
+		e := w.entries[name]
+		header := &zip.FileHeader{Name: name, Method: w.compression, Modified: e.modTime}
+		header.SetMode(fs.FileMode(e.mode).Perm())
+		if e.isDirectory {
+			header.Name += "/"
+			header.Method = zip.Store
+			header.SetMode(fs.ModeDir | fs.FileMode(e.mode).Perm())
+		}
+		if !w.modTime.IsZero() {
+			header.Modified = w.modTime
+		}
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("cannot write %s: %w", name, err)
+		}
+		if _, err = fw.Write(e.content); err != nil {
+			return fmt.Errorf("cannot write %s: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	w.written, w.dirty = true, false
+	return nil
+}
+
+func (w *Writer) readContentOf(p string) (filesystem.Content, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	e, err := w.lookup(p)
+	if err != nil {
+		return nil, err
+	}
+	if e.isDirectory {
+		return nil, filesystem.ErrDirectory
+	}
+	return bytes.Clone(e.content), nil
+}
+
+func (w *Writer) checkIfExists(p string) (bool, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return false, fs.ErrClosed
+	}
+	name := entryName(p)
+	return name == "" || w.entries[name] != nil, nil
+}
+
+func (w *Writer) createFile(p string, arg filesystem.Arguments) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fs.ErrClosed
+	}
+	name := entryName(p)
+	if err := w.prepareParent(name, arg); err != nil {
+		return err
+	}
+
+	existing := w.entries[name]
+	if name == "" || existing != nil {
+		if !arg.AllowOverwrite {
+			return filesystem.ErrFileFound
+		}
+		if name == "" || existing.isDirectory {
+			return filesystem.ErrDirectory
+		}
+	}
+
+	w.put(name, &entry{mode: arg.Mode})
+	return nil
+}
+
+func (w *Writer) writeContentTo(p string, content []byte, arg filesystem.Arguments) error {
+	if !arg.ContentOperation.Is(filesystem.ContentOperationOverwrite) && !arg.ContentOperation.Is(filesystem.ContentOperationAppend) {
+		return filesystem.ErrUnsupportedContentOperation
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	e, err := w.lookup(p)
+	if err != nil {
+		return err
+	}
+	if e.isDirectory {
+		return filesystem.ErrDirectory
+	}
+
+	if arg.ContentOperation.Is(filesystem.ContentOperationOverwrite) {
+		e.content = bytes.Clone(content)
+	} else {
+		e.content = append(e.content, content...)
+	}
+	e.modTime = time.Now()
+	w.dirty = true
+	return nil
+}
+
+func (w *Writer) createDirectory(p string, arg filesystem.Arguments) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fs.ErrClosed
+	}
+	name := entryName(p)
+	if err := w.prepareParent(name, arg); err != nil {
+		return err
+	}
+
+	if existing := w.entries[name]; name == "" || existing != nil {
+		if name == "" || existing.isDirectory {
+			return filesystem.ErrDirectoryFound
+		}
+		return filesystem.ErrFile
+	}
+
+	w.put(name, &entry{isDirectory: true, mode: arg.Mode})
+	return nil
+}
+
+func (w *Writer) remove(p string, arg filesystem.Arguments) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	e, err := w.lookup(p)
+	if err != nil {
+		return err
+	}
+	name := entryName(p)
+	if name == "" {
+		return filesystem.ErrPermissionDenied
+	}
+
+	children := w.childrenOf(name)
+	if e.isDirectory && len(children) > 0 && !arg.Recursive {
+		return filesystem.ErrNotEmpty
+	}
+	for _, child := range children {
+		delete(w.entries, child)
+	}
+	delete(w.entries, name)
+	w.dirty = true
+	return nil
+}
+
+// transfer copies source (with its content if recursive) to target and removes source when moving.
+func (w *Writer) transfer(source, target string, arg filesystem.Arguments, move bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	e, err := w.lookup(source)
+	if err != nil {
+		return err
+	}
+	src, dst := entryName(source), entryName(target)
+	if src == "" || dst == "" {
+		return filesystem.ErrPermissionDenied
+	}
+	if e.isDirectory && !move && !arg.Recursive {
+		return filesystem.ErrDirectory
+	}
+	if dst == src || strings.HasPrefix(dst, src+"/") {
+		return fmt.Errorf("cannot transfer %s into itself: %w", source, filesystem.ErrUnresolvableDirectoryStructure)
+	}
+
+	if existing := w.entries[dst]; existing != nil {
+		if !arg.AllowOverwrite {
+			if existing.isDirectory {
+				return filesystem.ErrDirectoryFound
+			}
+			return filesystem.ErrFileFound
+		}
+		for _, child := range w.childrenOf(dst) {
+			delete(w.entries, child)
+		}
+		delete(w.entries, dst)
+	}
+	if err = w.prepareParent(dst, arg); err != nil {
+		return err
+	}
+
+	for _, name := range append([]string{src}, w.childrenOf(src)...) {
+		moved := dst + strings.TrimPrefix(name, src)
+		c := *w.entries[name]
+		c.content = bytes.Clone(c.content)
+		w.entries[moved] = &c
+		if move {
+			delete(w.entries, name)
+		}
+	}
+	w.dirty = true
+	return nil
+}
+
+func (w *Writer) listFilesIn(p string) ([]filesystem.Entry, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	name := entryName(p)
+	if name != "" {
+		e, err := w.lookup(p)
+		if err != nil {
+			return nil, err
+		}
+		if !e.isDirectory {
+			return nil, filesystem.ErrFile
+		}
+	} else if w.closed {
+		return nil, fs.ErrClosed
+	}
+
+	res := make([]filesystem.Entry, 0)
+	for child, e := range w.entries {
+		if parent := strings.TrimPrefix(path.Dir(child), "."); parent != name {
+			continue
+		}
+		res = append(res, filesystem.Entry{
+			Name:        path.Base(child),
+			Size:        int64(len(e.content)),
+			Mode:        e.mode,
+			ModTime:     e.modTime,
+			IsDirectory: e.isDirectory,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// lookup returns entry stored under path (root is not an entry).
+func (w *Writer) lookup(p string) (*entry, error) {
+	if w.closed {
+		return nil, fs.ErrClosed
+	}
+	name := entryName(p)
+	if name == "" {
+		return &entry{isDirectory: true, mode: filesystem.ModeAllReadWriteExecute}, nil
+	}
+	e := w.entries[name]
+	if e == nil {
+		return nil, filesystem.ErrFileNotFound
+	}
+	return e, nil
+}
+
+// prepareParent verifies if parent directory of entry exists and creates missing ones (if allowed).
+func (w *Writer) prepareParent(name string, arg filesystem.Arguments) error {
+	var missing []string
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if e := w.entries[dir]; e != nil {
+			if !e.isDirectory {
+				return fmt.Errorf("location structure does not contain valid directory as target: %w", filesystem.ErrUnresolvableDirectoryStructure)
+			}
+			break
+		}
+		missing = append(missing, dir)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !arg.AllowCreationOfDirectoryStructure {
+		return fmt.Errorf("creation of non-existing directory structure is forbidden by current settings: %w", filesystem.ErrUnresolvableDirectoryStructure)
+	}
+
+	for _, dir := range missing {
+		w.put(dir, &entry{isDirectory: true, mode: arg.DirectoryStructureMode})
+	}
+	return nil
+}
+
+// childrenOf returns names of all entries placed (at any depth) inside directory.
+func (w *Writer) childrenOf(name string) []string {
+	var res []string
+	for child := range w.entries {
+		if strings.HasPrefix(child, name+"/") {
+			res = append(res, child)
+		}
+	}
+	return res
+}
+
+func (w *Writer) put(name string, e *entry) {
+	e.modTime = time.Now()
+	w.entries[name] = e
+	w.dirty = true
+}
+
+// entryName converts path to name of zip entry (slash separated, unrooted, without "." and "..", empty for root).
+func entryName(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
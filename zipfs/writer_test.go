@@ -0,0 +1,247 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// writeReport produces the same tree in any filesystem.
+func writeReport(fs filesystem.Filesystem) error {
+	if err := filesystem.CreateDirectory(fs, "report", filesystem.WithMode(filesystem.ModeUserReadWriteExecute)); err != nil {
+		return err
+	}
+	if err := filesystem.CreateFile(fs, "report/summary.txt", filesystem.WithMode(filesystem.ModeUserReadWrite)); err != nil {
+		return err
+	}
+	if err := filesystem.WriteContentTo(fs, "report/summary.txt", "OK"); err != nil {
+		return err
+	}
+	if err := filesystem.CreateFile(fs, "report/data/run.sh", filesystem.WithMode(0o755), filesystem.WithAllowCreationOfDirectoryStructure(true)); err != nil {
+		return err
+	}
+	return filesystem.StreamContentTo(fs, "report/data/run.sh", strings.NewReader("#!/bin/sh"))
+}
+
+// readArchive returns content of archive entries (directories with empty content) along with their modes.
+func readArchive(t *testing.T, data []byte) (map[string]string, map[string]fs.FileMode) {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	contents, modes := make(map[string]string), make(map[string]fs.FileMode)
+	for _, f := range zr.File {
+		rc, oErr := f.Open()
+		require.NoError(t, oErr)
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		contents[f.Name], modes[f.Name] = buf.String(), f.Mode()
+	}
+	return contents, modes
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("it should write the same tree as local filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		workdir := t.TempDir()
+		local, err := filesystem.New()
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		zw, err := NewWriter(&buf)
+		require.NoError(t, err)
+
+		// WHEN
+		require.NoError(t, writeReport(filesystem.Sub(local, workdir)))
+		require.NoError(t, writeReport(zw))
+		require.NoError(t, zw.Close())
+
+		// THEN
+		contents, modes := readArchive(t, buf.Bytes())
+		assert.Equal(t, map[string]string{
+			"report/":            "",
+			"report/data/":       "",
+			"report/data/run.sh": "#!/bin/sh",
+			"report/summary.txt": "OK",
+		}, contents)
+		assert.Equal(t, fs.ModeDir|0o700, modes["report/"])
+		assert.Equal(t, fs.ModeDir|0o777, modes["report/data/"])
+		assert.Equal(t, fs.FileMode(0o600), modes["report/summary.txt"])
+		assert.Equal(t, fs.FileMode(0o755), modes["report/data/run.sh"])
+
+		for name, content := range contents {
+			if content == "" {
+				continue
+			}
+			local, rErr := os.ReadFile(filepath.Join(workdir, name))
+			require.NoError(t, rErr)
+			assert.Equal(t, content, string(local))
+		}
+	})
+	t.Run("it should read back accumulated content", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		zw, err := NewWriter(&bytes.Buffer{})
+		require.NoError(t, err)
+		require.NoError(t, writeReport(zw))
+
+		// WHEN
+		content, readErr := filesystem.ReadContentOf(zw, "report/summary.txt")
+		exists, existsErr := filesystem.CheckIfExists(zw, "/report/data")
+		entries, listErr := filesystem.ListFilesIn(zw, "report")
+		paths, globErr := filesystem.Glob(zw, "**/*.sh")
+
+		// THEN
+		require.NoError(t, readErr)
+		require.NoError(t, existsErr)
+		require.NoError(t, listErr)
+		require.NoError(t, globErr)
+		assert.Equal(t, filesystem.Content("OK"), content)
+		assert.True(t, exists)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "data", entries[0].Name)
+		assert.True(t, entries[0].IsDirectory)
+		assert.Equal(t, "summary.txt", entries[1].Name)
+		assert.Equal(t, int64(2), entries[1].Size)
+		assert.Equal(t, filesystem.ModeUserReadWrite, entries[1].Mode)
+		assert.Equal(t, []string{"report/data/run.sh"}, paths)
+	})
+	t.Run("it should remove, move and copy entries", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var buf bytes.Buffer
+		zw, err := NewWriter(&buf)
+		require.NoError(t, err)
+		require.NoError(t, writeReport(zw))
+		require.NoError(t, filesystem.CreateFile(zw, "tmp.txt"))
+
+		// WHEN
+		require.NoError(t, filesystem.Copy(zw, "report", "backup/report", filesystem.WithRecursive(true), filesystem.WithAllowCreationOfDirectoryStructure(true)))
+		require.NoError(t, filesystem.Move(zw, "report/data", "data"))
+		require.NoError(t, filesystem.Remove(zw, "tmp.txt"))
+		require.NoError(t, filesystem.Remove(zw, "report", filesystem.WithRecursive(true)))
+		require.NoError(t, zw.Close())
+
+		// THEN
+		contents, _ := readArchive(t, buf.Bytes())
+		assert.Equal(t, map[string]string{
+			"backup/":                   "",
+			"backup/report/":            "",
+			"backup/report/data/":       "",
+			"backup/report/data/run.sh": "#!/bin/sh",
+			"backup/report/summary.txt": "OK",
+			"data/":                     "",
+			"data/run.sh":               "#!/bin/sh",
+		}, contents)
+	})
+	t.Run("it should report sentinel errors like local filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		zw, err := NewWriter(&bytes.Buffer{})
+		require.NoError(t, err)
+		require.NoError(t, writeReport(zw))
+
+		// WHEN
+		errWriteMissing := filesystem.WriteContentTo(zw, "missing.txt", "TEST")
+		errFileFound := filesystem.CreateFile(zw, "report/summary.txt")
+		errDirectory := filesystem.CreateFile(zw, "report", filesystem.WithAllowOverwrite(true))
+		errDirectoryFound := filesystem.CreateDirectory(zw, "report")
+		errFile := filesystem.CreateDirectory(zw, "report/summary.txt")
+		errStructure := filesystem.CreateFile(zw, "missing/test.txt")
+		errStructureOfFile := filesystem.CreateFile(zw, "report/summary.txt/test.txt", filesystem.WithAllowCreationOfDirectoryStructure(true))
+		errNotEmpty := filesystem.Remove(zw, "report")
+		errRemoveMissing := filesystem.Remove(zw, "missing")
+		errCopyDirectory := filesystem.Copy(zw, "report", "copy")
+		errMoveOnto := filesystem.Move(zw, "report/data/run.sh", "report/summary.txt")
+		_, errReadDirectory := filesystem.ReadContentOf(zw, "report")
+		_, errListFile := filesystem.ListFilesIn(zw, "report/summary.txt")
+		_, errListMissing := filesystem.ListFilesIn(zw, "missing")
+
+		// THEN
+		require.ErrorIs(t, errWriteMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errFileFound, filesystem.ErrFileFound)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errDirectoryFound, filesystem.ErrDirectoryFound)
+		require.ErrorIs(t, errFile, filesystem.ErrFile)
+		require.ErrorIs(t, errStructure, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errStructureOfFile, filesystem.ErrUnresolvableDirectoryStructure)
+		require.ErrorIs(t, errNotEmpty, filesystem.ErrNotEmpty)
+		require.ErrorIs(t, errRemoveMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errCopyDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errMoveOnto, filesystem.ErrFileFound)
+		require.ErrorIs(t, errReadDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errListFile, filesystem.ErrFile)
+		require.ErrorIs(t, errListMissing, filesystem.ErrFileNotFound)
+	})
+	t.Run("it should rewrite file on every flush", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		name := filepath.Join(t.TempDir(), "report.zip")
+		modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		zw, err := Create(name, OptionModificationTime(modTime), OptionCompression(zip.Store))
+		require.NoError(t, err)
+
+		// WHEN
+		require.NoError(t, writeReport(zw))
+		require.NoError(t, zw.Flush())
+		require.NoError(t, filesystem.WriteContentTo(zw, "report/summary.txt", "FAILED", filesystem.WithContentOperation(filesystem.ContentOperationOverwrite)))
+		require.NoError(t, zw.Close())
+		errClosed := filesystem.CreateFile(zw, "late.txt")
+		errCloseAgain := zw.Close()
+
+		// THEN
+		require.ErrorIs(t, errClosed, fs.ErrClosed)
+		require.ErrorIs(t, errCloseAgain, fs.ErrClosed)
+
+		zr, err := Open(name)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = zr.Close() })
+		content, err := filesystem.ReadContentOf(zr, "report/summary.txt")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("FAILED"), content)
+		entries, err := filesystem.ListFilesIn(zr, "report")
+		require.NoError(t, err)
+		for _, e := range entries {
+			assert.True(t, e.ModTime.Equal(modTime), e.Name)
+		}
+	})
+	t.Run("it should refuse to rewrite destination which cannot be rewound", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		var buf bytes.Buffer
+		zw, err := NewWriter(&buf)
+		require.NoError(t, err)
+		require.NoError(t, filesystem.CreateFile(zw, "a.txt"))
+		require.NoError(t, zw.Flush())
+
+		// WHEN
+		errUnchanged := zw.Flush()
+		require.NoError(t, filesystem.CreateFile(zw, "b.txt"))
+		errChanged := zw.Flush()
+
+		// THEN
+		require.NoError(t, errUnchanged)
+		require.ErrorIs(t, errChanged, ErrNotRewindable)
+		contents, _ := readArchive(t, buf.Bytes())
+		assert.Equal(t, map[string]string{"a.txt": ""}, contents)
+	})
+}
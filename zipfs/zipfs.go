@@ -0,0 +1,104 @@
+// Package zipfs provides filesystem.Filesystem backed by zip archive.
+//
+// Existing archive is served read-only (see Open and NewReader): ReadContentOf, StreamContentOf, CheckIfExists,
+// ListFilesIn and Glob are resolved from its entries (directories are also recognized by paths of files)
+// and every mutating operation fails with filesystem.ErrReadOnly.
+//
+// New archive is accumulated in memory (see Create and NewWriter) and written when Writer is flushed or closed.
+// Writer behaves like the local filesystem (the same sentinel errors are reported in the same situations),
+// so code producing directory tree can produce zip archive without changes. Mode of files and directories
+// is preserved in headers of the archive.
+package zipfs
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/SevenOfSpades/go-just-options"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+const (
+	optionBackendName      options.OptionKey = `backend_name`
+	optionCompression      options.OptionKey = `zip_compression`
+	optionModificationTime options.OptionKey = `zip_modification_time`
+)
+
+const defaultBackendName = "zip"
+
+// Reader is read-only filesystem.Filesystem serving content of zip archive opened with Open.
+// It has to be closed to release the archive.
+type Reader struct {
+	filesystem.Filesystem
+	file *os.File
+}
+
+// OptionBackendName changes name of the backend reported in errors and to middlewares (default "zip").
+func OptionBackendName(name string) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[string](r, optionBackendName, name)
+	}
+}
+
+// OptionCompression changes compression method of files written by Writer (default zip.Deflate).
+// Directories are always stored.
+func OptionCompression(method uint16) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[uint16](r, optionCompression, method)
+	}
+}
+
+// OptionModificationTime sets modification time of every entry written by Writer (default is time of the last
+// modification of the entry). Fixed time makes archives with the same content identical.
+func OptionModificationTime(t time.Time) options.Option {
+	return func(r options.Resolver) {
+		options.WriteOrPanic[time.Time](r, optionModificationTime, t)
+	}
+}
+
+// Open opens zip archive stored in local file and serves its content (see NewReader).
+func Open(name string, opts ...options.Option) (*Reader, error) {
+	f, err := os.Open(name) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("zip filesystem initialization failed: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("zip filesystem initialization failed: %w", err)
+	}
+
+	fs, err := NewReader(f, fi.Size(), opts...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &Reader{Filesystem: fs, file: f}, nil
+}
+
+// NewReader creates read-only filesystem.Filesystem serving content of zip archive read from r which has
+// the given size. Only OptionBackendName is accepted.
+func NewReader(r io.ReaderAt, size int64, opts ...options.Option) (filesystem.Filesystem, error) {
+	opt := options.Resolve(opts)
+
+	optBackendName, err := options.ReadOrDefault[string](opt, optionBackendName, defaultBackendName)
+	if err != nil {
+		return nil, fmt.Errorf("zip filesystem initialization failed: %w", err)
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("zip filesystem initialization failed: %w", err)
+	}
+
+	return filesystem.NewFromFS(zr, filesystem.OptionBackendName(optBackendName))
+}
+
+// Close releases file of the archive.
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
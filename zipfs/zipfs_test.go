@@ -0,0 +1,106 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	filesystem "github.com/SevenOfSpades/go-wrapped-filesystem"
+)
+
+// newArchive builds zip archive with provided files (names ending with slash are directories).
+func newArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		header.SetMode(0o640)
+		if name[len(name)-1] == '/' {
+			header.SetMode(fs.ModeDir | 0o750)
+		}
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("it should serve content of the archive", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		name := filepath.Join(t.TempDir(), "test.zip")
+		require.NoError(t, os.WriteFile(name, newArchive(t, map[string]string{
+			"conf.d/":       "",
+			"conf.d/a.yaml": "A",
+			"deep/inner/b":  "B",
+		}), 0o644))
+
+		// WHEN
+		zr, err := Open(name)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = zr.Close() })
+
+		// THEN
+		content, err := filesystem.ReadContentOf(zr, "/conf.d/a.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.Content("A"), content)
+
+		stream, err := filesystem.StreamContentOf(zr, "deep/inner/b")
+		require.NoError(t, err)
+		streamed, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, "B", string(streamed))
+
+		for p, expected := range map[string]bool{"conf.d": true, "deep/inner": true, "conf.d/a.yaml": true, "missing": false, ".": true} {
+			exists, eErr := filesystem.CheckIfExists(zr, p)
+			require.NoError(t, eErr)
+			assert.Equal(t, expected, exists, p)
+		}
+
+		entries, err := filesystem.ListFilesIn(zr, "conf.d")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "a.yaml", entries[0].Name)
+		assert.Equal(t, filesystem.Mode(0o640), entries[0].Mode)
+	})
+	t.Run("it should report sentinel errors", func(t *testing.T) {
+		t.Parallel()
+
+		// GIVEN
+		data := newArchive(t, map[string]string{"dir/test.txt": "TEST"})
+
+		// WHEN
+		zr, err := NewReader(bytes.NewReader(data), int64(len(data)), OptionBackendName("bundle"))
+		require.NoError(t, err)
+		_, errMissing := filesystem.ReadContentOf(zr, "missing.txt")
+		_, errDirectory := filesystem.ReadContentOf(zr, "dir")
+		errReadOnly := filesystem.CreateFile(zr, "new.txt")
+		_, errOpen := Open(filepath.Join(t.TempDir(), "missing.zip"))
+		_, errInvalid := NewReader(bytes.NewReader([]byte("not a zip")), 9)
+
+		// THEN
+		require.ErrorIs(t, errMissing, filesystem.ErrFileNotFound)
+		require.ErrorIs(t, errDirectory, filesystem.ErrDirectory)
+		require.ErrorIs(t, errReadOnly, filesystem.ErrReadOnly)
+		var pathErr *filesystem.PathError
+		require.ErrorAs(t, errReadOnly, &pathErr)
+		assert.Equal(t, "bundle", pathErr.Backend)
+		require.ErrorIs(t, errOpen, fs.ErrNotExist)
+		require.ErrorIs(t, errInvalid, zip.ErrFormat)
+	})
+}